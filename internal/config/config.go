@@ -2,7 +2,14 @@ package config
 
 import (
 	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"golang.org/x/crypto/bcrypt"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // Config содержит конфигурацию приложения, включая настройки базы данных и сервера.
@@ -13,6 +20,60 @@ type Config struct {
 	DBPassword string // Пароль базы данных
 	DBName     string // Имя базы данных
 	ServerPort string // Порт сервера приложения
+
+	ReferralLandingURL string // Страница, на которую ведет реферальная ссылка
+
+	TransferDailyLimit int // Максимальная сумма исходящих переводов пользователя за сутки (0 - без лимита)
+	TransferMinBalance int // Минимальный баланс, который должен остаться после перевода
+
+	PointsTTL            time.Duration // Срок действия начисленных баллов (0 - баллы не сгорают)
+	PointsExpiryInterval time.Duration // Периодичность фоновой задачи сгорания баллов
+
+	StreakBonusPercent    int // Надбавка к награде за каждый день серии после первого, в процентах
+	StreakMaxBonusPercent int // Максимальная надбавка за серию, в процентах
+	StreakFreezes         int // Количество заморозок серии, доступных пользователю
+
+	LevelThresholds []int // Заработанные за все время баллы, необходимые для уровней начиная со второго
+	LevelUpBonus    int   // Бонус за каждый новый уровень
+
+	TeamMaxMembers int // Наибольший размер команды (0 - без ограничения)
+
+	PublicURL            string        // Адрес сервиса, из которого строятся ссылки в письмах
+	MailSender           string        // Способ отправки писем: log или file
+	MailFrom             string        // Адрес отправителя писем
+	MailDir              string        // Каталог для писем при MailSender=file
+	EmailVerificationTTL time.Duration // Срок действия ссылки подтверждения адреса
+	PasswordResetURL     string        // Страница установки нового пароля, к ней добавляется параметр token
+	PasswordResetTTL     time.Duration // Срок действия ссылки сброса пароля
+
+	PasswordMinLength    int    // Минимальная длина пароля в символах
+	PasswordBreachedList string // Файл со списком утекших паролей, по одному в строке (пусто - не проверять)
+	BcryptCost           int    // Стоимость хэширования паролей bcrypt
+
+	LoginAttemptWindow   time.Duration // Период без неудачных попыток входа, после которого счетчик начинается заново (0 - без ограничений)
+	LoginBackoffAfter    int           // Неудачных попыток по имени пользователя до появления задержки
+	LoginBackoffBase     time.Duration // Начальная задержка, удваивается с каждой следующей неудачной попыткой
+	LoginBackoffMax      time.Duration // Наибольшая задержка
+	LoginLockoutAfter    int           // Неудачных попыток по имени пользователя до блокировки входа
+	LoginLockoutDuration time.Duration // Срок блокировки входа
+	LoginIPLockoutAfter  int           // Неудачных попыток с одного IP-адреса до блокировки входа с него
+	TrustProxyHeaders    bool          // Брать IP клиента из X-Forwarded-For (только за доверенным прокси)
+
+	TOTPIssuer   string        // Название сервиса в приложении-аутентификаторе
+	TwoFactorTTL time.Duration // Время на ввод кода двухфакторной аутентификации после проверки пароля
+
+	OIDCIssuer       string // Адрес провайдера OpenID Connect (пусто - вход через провайдера отключен)
+	OIDCClientID     string // Идентификатор клиента у провайдера
+	OIDCClientSecret string // Секрет клиента (пусто - публичный клиент, только PKCE)
+	OIDCRedirectURL  string // Адрес возврата от провайдера (пусто - PUBLIC_URL/auth/oidc/callback)
+	OIDCScopes       string // Запрашиваемые области доступа через пробел
+
+	TelegramBotToken   string        // Токен бота виджета Telegram Login (пусто - вход через Telegram отключен)
+	TelegramAuthMaxAge time.Duration // Наибольший возраст данных виджета Telegram Login (0 - без ограничения)
+
+	ExportSyncMaxRecords int           // Наибольшее число записей, при котором выгрузка данных отдается сразу, а не готовится в фоне
+	ExportTTL            time.Duration // Срок хранения подготовленной выгрузки
+	ExportInterval       time.Duration // Периодичность фоновой задачи подготовки выгрузок
 }
 
 // Load загружает конфигурацию из переменных окружения
@@ -31,6 +92,111 @@ func Load() (*Config, error) {
 
 // LoadConfig инициализирует конфигурацию из переменных окружения с значениями по умолчанию.
 func LoadConfig() (*Config, error) {
+	transferDailyLimit, err := getEnvInt("TRANSFER_DAILY_LIMIT", 1000)
+	if err != nil {
+		return nil, err
+	}
+	transferMinBalance, err := getEnvInt("TRANSFER_MIN_BALANCE", 0)
+	if err != nil {
+		return nil, err
+	}
+	pointsTTL, err := getEnvDuration("POINTS_TTL", 0)
+	if err != nil {
+		return nil, err
+	}
+	pointsExpiryInterval, err := getEnvDuration("POINTS_EXPIRY_INTERVAL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	streakBonusPercent, err := getEnvInt("STREAK_BONUS_PERCENT", 10)
+	if err != nil {
+		return nil, err
+	}
+	streakMaxBonusPercent, err := getEnvInt("STREAK_MAX_BONUS_PERCENT", 100)
+	if err != nil {
+		return nil, err
+	}
+	streakFreezes, err := getEnvInt("STREAK_FREEZES", 2)
+	if err != nil {
+		return nil, err
+	}
+	levelThresholds, err := getEnvIntList("LEVEL_THRESHOLDS", []int{100, 300, 600, 1000, 1500, 2500, 4000, 6000, 9000})
+	if err != nil {
+		return nil, err
+	}
+	levelUpBonus, err := getEnvInt("LEVEL_UP_BONUS", 10)
+	if err != nil {
+		return nil, err
+	}
+	teamMaxMembers, err := getEnvInt("TEAM_MAX_MEMBERS", 10)
+	if err != nil {
+		return nil, err
+	}
+	emailVerificationTTL, err := getEnvDuration("EMAIL_VERIFICATION_TTL", 48*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	passwordResetTTL, err := getEnvDuration("PASSWORD_RESET_TTL", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	passwordMinLength, err := getEnvInt("PASSWORD_MIN_LENGTH", 8)
+	if err != nil {
+		return nil, err
+	}
+	bcryptCost, err := getEnvInt("BCRYPT_COST", bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	loginAttemptWindow, err := getEnvDuration("LOGIN_ATTEMPT_WINDOW", time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	loginBackoffAfter, err := getEnvInt("LOGIN_BACKOFF_AFTER", 3)
+	if err != nil {
+		return nil, err
+	}
+	loginBackoffBase, err := getEnvDuration("LOGIN_BACKOFF_BASE", time.Second)
+	if err != nil {
+		return nil, err
+	}
+	loginBackoffMax, err := getEnvDuration("LOGIN_BACKOFF_MAX", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	loginLockoutAfter, err := getEnvInt("LOGIN_LOCKOUT_AFTER", 10)
+	if err != nil {
+		return nil, err
+	}
+	loginLockoutDuration, err := getEnvDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	loginIPLockoutAfter, err := getEnvInt("LOGIN_IP_LOCKOUT_AFTER", 100)
+	if err != nil {
+		return nil, err
+	}
+	twoFactorTTL, err := getEnvDuration("TWO_FACTOR_TTL", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	telegramAuthMaxAge, err := getEnvDuration("TELEGRAM_AUTH_MAX_AGE", 5*time.Minute)
+	if err != nil {
+		return nil, err
+	}
+	exportSyncMaxRecords, err := getEnvInt("EXPORT_SYNC_MAX_RECORDS", 1000)
+	if err != nil {
+		return nil, err
+	}
+	exportTTL, err := getEnvDuration("EXPORT_TTL", 72*time.Hour)
+	if err != nil {
+		return nil, err
+	}
+	exportInterval, err := getEnvDuration("EXPORT_INTERVAL", time.Minute)
+	if err != nil {
+		return nil, err
+	}
+
 	return &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBPort:     getEnv("DB_PORT", "5432"),
@@ -38,9 +204,121 @@ func LoadConfig() (*Config, error) {
 		DBPassword: getEnv("DB_PASSWORD", "postgres"),
 		DBName:     getEnv("DB_NAME", "user_reward_db"),
 		ServerPort: getEnv("SERVER_PORT", "8080"),
+
+		ReferralLandingURL: getEnv("REFERRAL_LANDING_URL", "/"),
+
+		TransferDailyLimit: transferDailyLimit,
+		TransferMinBalance: transferMinBalance,
+
+		PointsTTL:            pointsTTL,
+		PointsExpiryInterval: pointsExpiryInterval,
+
+		StreakBonusPercent:    streakBonusPercent,
+		StreakMaxBonusPercent: streakMaxBonusPercent,
+		StreakFreezes:         streakFreezes,
+
+		LevelThresholds: levelThresholds,
+		LevelUpBonus:    levelUpBonus,
+
+		TeamMaxMembers: teamMaxMembers,
+
+		PublicURL:            getEnv("PUBLIC_URL", "http://localhost:8080"),
+		MailSender:           getEnv("MAIL_SENDER", "log"),
+		MailFrom:             getEnv("MAIL_FROM", "no-reply@localhost"),
+		MailDir:              getEnv("MAIL_DIR", "mail"),
+		EmailVerificationTTL: emailVerificationTTL,
+		PasswordResetURL:     getEnv("PASSWORD_RESET_URL", "http://localhost:8080/reset-password"),
+		PasswordResetTTL:     passwordResetTTL,
+
+		PasswordMinLength:    passwordMinLength,
+		PasswordBreachedList: getEnv("PASSWORD_BREACHED_LIST", ""),
+		BcryptCost:           bcryptCost,
+
+		LoginAttemptWindow:   loginAttemptWindow,
+		LoginBackoffAfter:    loginBackoffAfter,
+		LoginBackoffBase:     loginBackoffBase,
+		LoginBackoffMax:      loginBackoffMax,
+		LoginLockoutAfter:    loginLockoutAfter,
+		LoginLockoutDuration: loginLockoutDuration,
+		LoginIPLockoutAfter:  loginIPLockoutAfter,
+		TrustProxyHeaders:    getEnv("TRUST_PROXY_HEADERS", "false") == "true",
+
+		TOTPIssuer:   getEnv("TOTP_ISSUER", "UserTaskReward"),
+		TwoFactorTTL: twoFactorTTL,
+
+		OIDCIssuer:       getEnv("OIDC_ISSUER", ""),
+		OIDCClientID:     getEnv("OIDC_CLIENT_ID", ""),
+		OIDCClientSecret: getEnv("OIDC_CLIENT_SECRET", ""),
+		OIDCRedirectURL:  getEnv("OIDC_REDIRECT_URL", ""),
+		OIDCScopes:       getEnv("OIDC_SCOPES", "openid email profile"),
+
+		TelegramBotToken:   getEnv("TELEGRAM_BOT_TOKEN", ""),
+		TelegramAuthMaxAge: telegramAuthMaxAge,
+
+		ExportSyncMaxRecords: exportSyncMaxRecords,
+		ExportTTL:            exportTTL,
+		ExportInterval:       exportInterval,
 	}, nil
 }
 
+// StreakRules возвращает настройки бонуса за серию
+func (c *Config) StreakRules() models.StreakRules {
+	return models.StreakRules{
+		BonusPercent:    c.StreakBonusPercent,
+		MaxBonusPercent: c.StreakMaxBonusPercent,
+		Freezes:         c.StreakFreezes,
+	}
+}
+
+// LevelCurve возвращает кривую уровней
+func (c *Config) LevelCurve() models.LevelCurve {
+	return models.LevelCurve{
+		Thresholds:   c.LevelThresholds,
+		LevelUpBonus: c.LevelUpBonus,
+	}
+}
+
+// LoginThrottleRules возвращает ограничения на неудачные попытки входа
+func (c *Config) LoginThrottleRules() models.LoginThrottleRules {
+	return models.LoginThrottleRules{
+		Window:          c.LoginAttemptWindow,
+		BackoffAfter:    c.LoginBackoffAfter,
+		BackoffBase:     c.LoginBackoffBase,
+		BackoffMax:      c.LoginBackoffMax,
+		LockoutAfter:    c.LoginLockoutAfter,
+		LockoutDuration: c.LoginLockoutDuration,
+		IPLockoutAfter:  c.LoginIPLockoutAfter,
+	}
+}
+
+// ExportRules возвращает настройки выгрузки персональных данных
+func (c *Config) ExportRules() models.ExportRules {
+	return models.ExportRules{
+		SyncMaxRecords: c.ExportSyncMaxRecords,
+		TTL:            c.ExportTTL,
+	}
+}
+
+// OIDCEnabled сообщает, настроен ли вход через провайдера OpenID Connect
+func (c *Config) OIDCEnabled() bool {
+	return c.OIDCIssuer != ""
+}
+
+// OIDC возвращает настройки клиента провайдера OpenID Connect
+func (c *Config) OIDC() oidc.Config {
+	redirectURL := c.OIDCRedirectURL
+	if redirectURL == "" {
+		redirectURL = strings.TrimRight(c.PublicURL, "/") + "/auth/oidc/callback"
+	}
+	return oidc.Config{
+		Issuer:       c.OIDCIssuer,
+		ClientID:     c.OIDCClientID,
+		ClientSecret: c.OIDCClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       strings.Fields(c.OIDCScopes),
+	}
+}
+
 // GetDBConnString формирует строку подключения к базе данных.
 func (c *Config) GetDBConnString() string {
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
@@ -55,6 +333,49 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+// getEnvInt возвращает целочисленное значение переменной окружения или значение по умолчанию.
+func getEnvInt(key string, defaultValue int) (int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be an integer: %w", key, err)
+	}
+	return parsed, nil
+}
+
+// getEnvIntList возвращает список целых чисел из переменной окружения через запятую или значение по умолчанию.
+func getEnvIntList(key string, defaultValue []int) ([]int, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	var list []int
+	for _, item := range strings.Split(value, ",") {
+		parsed, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a comma-separated list of integers: %w", key, err)
+		}
+		list = append(list, parsed)
+	}
+	return list, nil
+}
+
+// getEnvDuration возвращает значение переменной окружения в формате time.Duration или значение по умолчанию.
+func getEnvDuration(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s must be a duration like 720h: %w", key, err)
+	}
+	return parsed, nil
+}
+
 // Validate проверяет, что важные параметры конфигурации заполнены.
 func (c *Config) Validate() error {
 	if c.DBHost == "" {
@@ -72,5 +393,84 @@ func (c *Config) Validate() error {
 	if c.ServerPort == "" {
 		return fmt.Errorf("ServerPort cannot be empty")
 	}
+	if c.TransferDailyLimit < 0 {
+		return fmt.Errorf("TransferDailyLimit cannot be negative")
+	}
+	if c.TransferMinBalance < 0 {
+		return fmt.Errorf("TransferMinBalance cannot be negative")
+	}
+	if c.PointsTTL < 0 {
+		return fmt.Errorf("PointsTTL cannot be negative")
+	}
+	if c.PointsTTL > 0 && c.PointsExpiryInterval <= 0 {
+		return fmt.Errorf("PointsExpiryInterval must be positive when PointsTTL is set")
+	}
+	if c.StreakBonusPercent < 0 || c.StreakMaxBonusPercent < 0 {
+		return fmt.Errorf("streak bonus percents cannot be negative")
+	}
+	if c.StreakFreezes < 0 {
+		return fmt.Errorf("StreakFreezes cannot be negative")
+	}
+	for i, threshold := range c.LevelThresholds {
+		if threshold <= 0 || (i > 0 && threshold <= c.LevelThresholds[i-1]) {
+			return fmt.Errorf("LevelThresholds must be positive and strictly increasing")
+		}
+	}
+	if c.LevelUpBonus < 0 {
+		return fmt.Errorf("LevelUpBonus cannot be negative")
+	}
+	if c.TeamMaxMembers < 0 {
+		return fmt.Errorf("TeamMaxMembers cannot be negative")
+	}
+	if c.MailSender != "log" && c.MailSender != "file" {
+		return fmt.Errorf("MailSender must be log or file")
+	}
+	if c.EmailVerificationTTL <= 0 {
+		return fmt.Errorf("EmailVerificationTTL must be positive")
+	}
+	if c.PasswordResetTTL <= 0 {
+		return fmt.Errorf("PasswordResetTTL must be positive")
+	}
+	if c.PasswordMinLength < 1 {
+		return fmt.Errorf("PasswordMinLength must be positive")
+	}
+	if c.BcryptCost < bcrypt.MinCost || c.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("BcryptCost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	if c.LoginAttemptWindow < 0 || c.LoginBackoffBase < 0 || c.LoginBackoffMax < 0 || c.LoginLockoutDuration < 0 {
+		return fmt.Errorf("login throttle durations cannot be negative")
+	}
+	if c.LoginBackoffAfter < 0 || c.LoginLockoutAfter < 0 || c.LoginIPLockoutAfter < 0 {
+		return fmt.Errorf("login throttle thresholds cannot be negative")
+	}
+	if c.LoginLockoutAfter > 0 && c.LoginLockoutDuration == 0 {
+		return fmt.Errorf("LoginLockoutDuration must be positive when LoginLockoutAfter is set")
+	}
+	if c.TOTPIssuer == "" || strings.Contains(c.TOTPIssuer, ":") {
+		return fmt.Errorf("TOTPIssuer must be non-empty and cannot contain a colon")
+	}
+	if c.TwoFactorTTL <= 0 {
+		return fmt.Errorf("TwoFactorTTL must be positive")
+	}
+	if c.OIDCEnabled() {
+		if issuer, err := url.Parse(c.OIDCIssuer); err != nil || (issuer.Scheme != "https" && issuer.Scheme != "http") || issuer.Host == "" {
+			return fmt.Errorf("OIDCIssuer must be an absolute http(s) URL")
+		}
+		if c.OIDCClientID == "" {
+			return fmt.Errorf("OIDCClientID is required when OIDCIssuer is set")
+		}
+	}
+	if c.TelegramAuthMaxAge < 0 {
+		return fmt.Errorf("TelegramAuthMaxAge cannot be negative")
+	}
+	if c.ExportSyncMaxRecords < 0 {
+		return fmt.Errorf("ExportSyncMaxRecords cannot be negative")
+	}
+	if c.ExportTTL <= 0 {
+		return fmt.Errorf("ExportTTL must be positive")
+	}
+	if c.ExportInterval <= 0 {
+		return fmt.Errorf("ExportInterval must be positive")
+	}
 	return nil
 }
@@ -9,35 +9,41 @@ type ErrorType string
 
 // Определение различных типов ошибок.
 const (
-	NotFound      ErrorType = "NOT_FOUND"
-	BadRequest    ErrorType = "BAD_REQUEST"
-	Internal      ErrorType = "INTERNAL"
-	Validation    ErrorType = "VALIDATION"
-	AlreadyExists ErrorType = "ALREADY_EXISTS"
-	InvalidToken  ErrorType = "INVALID_TOKEN" // Новая ошибка для недействительного токена
-	Unauthorized  ErrorType = "UNAUTHORIZED"
+	NotFound        ErrorType = "NOT_FOUND"
+	BadRequest      ErrorType = "BAD_REQUEST"
+	Internal        ErrorType = "INTERNAL"
+	Validation      ErrorType = "VALIDATION"
+	AlreadyExists   ErrorType = "ALREADY_EXISTS"
+	InvalidToken    ErrorType = "INVALID_TOKEN" // Новая ошибка для недействительного токена
+	Unauthorized    ErrorType = "UNAUTHORIZED"
+	Forbidden       ErrorType = "FORBIDDEN"
+	TooManyRequests ErrorType = "TOO_MANY_REQUESTS"
 )
 
 // Сообщения для ошибок.
 var ErrorMessage = map[ErrorType]string{
-	NotFound:      "resource not found",
-	BadRequest:    "invalid input parameters",
-	Internal:      "internal server error",
-	Validation:    "validation failed",
-	AlreadyExists: "resource already exists",
-	InvalidToken:  "invalid token",
-	Unauthorized:  "unauthorized access",
+	NotFound:        "resource not found",
+	BadRequest:      "invalid input parameters",
+	Internal:        "internal server error",
+	Validation:      "validation failed",
+	AlreadyExists:   "resource already exists",
+	InvalidToken:    "invalid token",
+	Unauthorized:    "unauthorized access",
+	Forbidden:       "access denied",
+	TooManyRequests: "too many requests",
 }
 
 // StatusCode - мапа с кодами статуса для каждого типа ошибки.
 var StatusCode = map[ErrorType]int{
-	NotFound:      404,
-	BadRequest:    400,
-	Internal:      500,
-	Validation:    422,
-	AlreadyExists: 409,
-	InvalidToken:  401,
-	Unauthorized:  401,
+	NotFound:        404,
+	BadRequest:      400,
+	Internal:        500,
+	Validation:      422,
+	AlreadyExists:   409,
+	InvalidToken:    401,
+	Unauthorized:    401,
+	Forbidden:       403,
+	TooManyRequests: 429,
 }
 
 // Error - структура, представляющая ошибку с дополнительной информацией.
@@ -108,6 +114,14 @@ func NewUnauthorized(message string, err error) *Error {
 	return NewError(Unauthorized, message, err) // Новая функция для недействительного токена
 }
 
+func NewForbidden(message string, err error) *Error {
+	return NewError(Forbidden, message, err)
+}
+
+func NewTooManyRequests(message string, err error) *Error {
+	return NewError(TooManyRequests, message, err)
+}
+
 // Проверки типов ошибок.
 func IsErrorType(err error, errorType ErrorType) bool {
 	if e, ok := err.(*Error); ok {
@@ -137,6 +151,18 @@ func IsUnauthorized(err error) bool { // Функция для проверки
 	return IsErrorType(err, Unauthorized)
 }
 
+func IsValidation(err error) bool {
+	return IsErrorType(err, Validation)
+}
+
+func IsForbidden(err error) bool {
+	return IsErrorType(err, Forbidden)
+}
+
+func IsTooManyRequests(err error) bool {
+	return IsErrorType(err, TooManyRequests)
+}
+
 // Unwrap для поддержки errors.Is и errors.As
 func (e *Error) Unwrap() error {
 	return e.Err
@@ -0,0 +1,81 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AchievementCreate добавляет описание достижения
+func (h *Handler) AchievementCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AchievementCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	var achievement models.AchievementCreate
+	if err := json.NewDecoder(r.Body).Decode(&achievement); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	achievementID, err := h.Services.Achievement.CreateAchievement(r.Context(), &achievement)
+	if err != nil {
+		logger.Error("Failed to create achievement", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_achievement_id": achievementID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// AchievementGetAll возвращает список активных достижений
+func (h *Handler) AchievementGetAll(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AchievementGetAll"
+	logger := h.logger.With(zap.String("op", op))
+
+	achievements, err := h.Services.Achievement.GetAchievements(r.Context())
+	if err != nil {
+		logger.Error("Failed to get achievements", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Achievement `json:"achievements"`
+	}{
+		Data: achievements,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// UserAchievements возвращает достижения пользователя
+func (h *Handler) UserAchievements(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UserAchievements"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	achievements, err := h.Services.Achievement.GetUserAchievements(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user achievements", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.UserAchievement `json:"achievements"`
+	}{
+		Data: achievements,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
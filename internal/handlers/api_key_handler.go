@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AdminAPIKeyCreate выпускает API-ключ для интеграции. Ключ есть только в этом ответе.
+func (h *Handler) AdminAPIKeyCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminAPIKeyCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.APIKeyCreate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	req.AdminID = adminID
+
+	apiKey, err := h.Services.APIKey.CreateAPIKey(r.Context(), &req)
+	if err != nil {
+		logger.Error("Failed to create api key", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusCreated, apiKey)
+}
+
+// AdminAPIKeys возвращает все API-ключи без самих ключей
+func (h *Handler) AdminAPIKeys(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminAPIKeys"
+	logger := h.logger.With(zap.String("op", op))
+
+	keys, err := h.Services.APIKey.GetAPIKeys(r.Context())
+	if err != nil {
+		logger.Error("Failed to get api keys", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.APIKey `json:"api_keys"`
+	}{
+		Data: keys,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminAPIKeyRevoke отзывает API-ключ
+func (h *Handler) AdminAPIKeyRevoke(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminAPIKeyRevoke"
+	logger := h.logger.With(zap.String("op", op))
+
+	apiKeyID, err := pathID(r, "api_key_id")
+	if err != nil {
+		logger.Info("Invalid api_key_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if err := h.Services.APIKey.RevokeAPIKey(r.Context(), apiKeyID); err != nil {
+		logger.Error("Failed to revoke api key", zap.Int64("api_key_id", apiKeyID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "api key revoked"})
+}
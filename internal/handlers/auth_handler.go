@@ -5,6 +5,7 @@ import (
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"go.uber.org/zap"
+	"io"
 	"net/http"
 	"time"
 )
@@ -23,6 +24,9 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Привязываем регистрацию к переходу по реферальной ссылке, если он был
+	user.ReferralClickToken = referralClickFromCookie(r)
+
 	// Вызов метода регистрации с получением идентификатора пользователя
 	userId, err := h.Services.Auth.Register(r.Context(), &user)
 	if err != nil {
@@ -30,6 +34,9 @@ func (h *Handler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
 		h.handleServiceError(w, err)
 		return
 	}
+	if user.ReferralClickToken != "" {
+		clearReferralCookie(w)
+	}
 
 	// Ответ клиенту с сообщением об успешной регистрации и идентификатором пользователя
 	response := map[string]interface{}{
@@ -53,29 +60,40 @@ func (h *Handler) LoginHandler(w http.ResponseWriter, r *http.Request) {
 		h.httpError(w, errors.NewInvalidArgument("Invalid input data", err))
 		return
 	}
+	user.IP = h.clientIP(r)
 
 	// Вызов метода авторизации
-	token, err := h.Services.Auth.Login(r.Context(), &user)
+	result, err := h.Services.Auth.Login(r.Context(), &user)
 	if err != nil {
 		logger.Error("Failed to authenticate user", zap.Error(err))
 		h.handleServiceError(w, err)
 		return
 	}
 
+	h.loginResponse(w, result)
+}
+
+// loginResponse отвечает на успешный первый шаг входа: выдает JWT
+// или, если нужен код двухфакторной аутентификации, токен второго шага
+func (h *Handler) loginResponse(w http.ResponseWriter, result *models.LoginResult) {
+	// Пароль верный, но нужен код двухфакторной аутентификации
+	if result.TwoFactorRequired {
+		response := map[string]interface{}{
+			"message":             "two-factor code required",
+			"two_factor_required": true,
+			"challenge_token":     result.ChallengeToken,
+		}
+		h.jsonResponse(w, http.StatusOK, response)
+		return
+	}
+
 	// Установка cookie с токеном
-	http.SetCookie(w, &http.Cookie{
-		Name:     "token",
-		Value:    token,
-		Expires:  time.Now().Add(time.Hour),
-		Path:     "/",
-		Secure:   true, // используется HTTPS
-		HttpOnly: true, // Защита от XSS-атак
-	})
+	setTokenCookie(w, result.Token)
 
 	// Ответ клиенту с сообщением об успешном входе
 	response := map[string]interface{}{
 		"message": "User Login successful",
-		"token":   token,
+		"token":   result.Token,
 	}
 	h.jsonResponse(w, http.StatusOK, response)
 }
@@ -104,3 +122,169 @@ func (h *Handler) GetUserHandler(w http.ResponseWriter, r *http.Request) {
 	response := UserIDResponse{Id: user.ID}
 	h.jsonResponse(w, http.StatusOK, response)
 }
+
+// VerifyEmailHandler подтверждает адрес электронной почты по токену из письма.
+// Токен передается параметром token (ссылка из письма) или в теле запроса.
+func (h *Handler) VerifyEmailHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.VerifyEmailHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	req := models.EmailVerificationRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Failed to decode JSON body", zap.Error(err))
+			h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+			return
+		}
+	}
+
+	if err := h.Services.Auth.VerifyEmail(r.Context(), req.Token); err != nil {
+		logger.Info("Failed to verify email", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "email verified"})
+}
+
+// ResendEmailVerification повторно отправляет текущему пользователю письмо для подтверждения адреса
+func (h *Handler) ResendEmailVerification(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ResendEmailVerification"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	if err := h.Services.Auth.ResendEmailVerification(r.Context(), userID); err != nil {
+		logger.Error("Failed to resend email verification", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusAccepted, map[string]string{"message": "verification email sent"})
+}
+
+// ForgotPasswordHandler отправляет письмо со ссылкой для сброса пароля.
+// Ответ не зависит от того, зарегистрирован ли адрес.
+func (h *Handler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ForgotPasswordHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	var req models.PasswordForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.ForgotPassword(r.Context(), req.Email); err != nil {
+		logger.Error("Failed to process password reset request", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusAccepted, map[string]string{
+		"message": "if the email is registered, a password reset link has been sent",
+	})
+}
+
+// ResetPasswordHandler устанавливает новый пароль по токену из письма
+func (h *Handler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ResetPasswordHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.ResetPassword(r.Context(), &req); err != nil {
+		logger.Info("Failed to reset password", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "password has been reset"})
+}
+
+// ChangePasswordHandler меняет пароль текущего пользователя.
+// Остальные сессии пользователя завершаются, текущая получает новый токен.
+func (h *Handler) ChangePasswordHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ChangePasswordHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.PasswordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	token, err := h.Services.Auth.ChangePassword(r.Context(), userID, &req)
+	if err != nil {
+		logger.Info("Failed to change password", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	setTokenCookie(w, token)
+	response := map[string]interface{}{
+		"message": "password changed",
+		"token":   token,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminUnlockLogin снимает блокировку входа пользователя после неудачных попыток.
+// В теле можно передать IP-адрес, блокировку которого нужно снять вместе с пользователем.
+func (h *Handler) AdminUnlockLogin(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminUnlockLogin"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req models.LoginUnlockRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.UnlockLogin(r.Context(), userID, req.IP); err != nil {
+		logger.Error("Failed to unlock login", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "login unlocked"})
+}
+
+// setTokenCookie сохраняет JWT в cookie
+func setTokenCookie(w http.ResponseWriter, token string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "token",
+		Value:    token,
+		Expires:  time.Now().Add(time.Hour),
+		Path:     "/",
+		Secure:   true, // используется HTTPS
+		HttpOnly: true, // Защита от XSS-атак
+	})
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// UserTransfer переводит баллы пользователя другому пользователю
+func (h *Handler) UserTransfer(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UserTransfer"
+	logger := h.logger.With(zap.String("op", op))
+
+	actorID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	fromUserID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req struct {
+		ToUserID int64 `json:"to_user_id"`
+		Amount   int   `json:"amount"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	transfer := models.Transfer{FromUserID: fromUserID, ToUserID: req.ToUserID, Amount: req.Amount}
+	result, err := h.Services.Balance.Transfer(r.Context(), actorID, &transfer)
+	if err != nil {
+		logger.Error("Failed to transfer points", zap.Int64("from_user_id", fromUserID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, result)
+}
+
+// UserTransactions возвращает историю операций с балансом пользователя.
+// Доступна самому пользователю, администраторам и интеграциям с правом transactions:read.
+func (h *Handler) UserTransactions(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UserTransactions"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+	if err := h.authorizeUser(r, userID); err != nil {
+		logger.Info("User transactions access denied", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	transactions, err := h.Services.Balance.GetUserTransactions(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user transactions", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Transaction `json:"transactions"`
+	}{
+		Data: transactions,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// Currencies возвращает список валют
+func (h *Handler) Currencies(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.Currencies"
+	logger := h.logger.With(zap.String("op", op))
+
+	currencies, err := h.Services.Balance.GetCurrencies(r.Context())
+	if err != nil {
+		logger.Error("Failed to get currencies", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Currency `json:"data"`
+	}{
+		Data: currencies,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminAdjustBalance начисляет или списывает баллы пользователя с указанием причины
+func (h *Handler) AdminAdjustBalance(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminAdjustBalance"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var adjustment models.Adjustment
+	if err := json.NewDecoder(r.Body).Decode(&adjustment); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	adjustment.UserID = userID
+	adjustment.AdminID = adminID
+
+	entry, err := h.Services.Balance.AdjustBalance(r.Context(), &adjustment)
+	if err != nil {
+		logger.Error("Failed to adjust balance", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusCreated, entry)
+}
@@ -3,10 +3,15 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/gorilla/mux"
 	"go.uber.org/zap"
+	"net"
 	"net/http"
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +20,26 @@ type UserIDResponse struct {
 	Id int64 `json:"user_id"`
 }
 
+// userIDKey ключ контекста, под которым JWTMiddleware сохраняет ID пользователя
+const userIDKey = "userID"
+
+// apiKeyKey ключ контекста, под которым APIKeyMiddleware сохраняет API-ключ
+const apiKeyKey = "apiKey"
+
+// apiKeyHeader заголовок, в котором интеграции передают API-ключ
+const apiKeyHeader = "X-API-Key"
+
+// Маршруты, доступные по API-ключу, и права, которые для них нужны.
+// Остальные маршруты по API-ключу недоступны.
+var apiKeyRoutes = map[string]map[string]string{
+	"/api/task/{user_id}/complete":      {http.MethodPost: models.ScopeTasksComplete},
+	"/api/users/leaderboard":            {http.MethodGet: models.ScopeUsersRead},
+	"/api/users/{user_id}/status":       {http.MethodGet: models.ScopeUsersRead},
+	"/api/users/{user_id}/achievements": {http.MethodGet: models.ScopeUsersRead},
+	"/api/users/{user_id}/transactions": {http.MethodGet: models.ScopeTransactionsRead},
+	"/api/users/{username_or_email}":    {http.MethodGet: models.ScopeUsersRead},
+}
+
 // Список маршрутов, которые не требуют авторизации
 var noAuthRoutes = map[string]map[string]bool{
 	"/auth/register": {http.MethodPost: true},
@@ -41,8 +66,8 @@ func JWTMiddleware(authService service.Auth, logger *zap.Logger) func(next http.
 			path := r.URL.Path
 			method := r.Method
 
-			// Проверяем маршрут в noAuthRoutes
-			if isNoAuthRoute(path, method) {
+			// Проверяем маршрут в noAuthRoutes; запрос с API-ключом уже проверен APIKeyMiddleware
+			if _, ok := currentAPIKey(r); ok || isNoAuthRoute(path, method) {
 				next.ServeHTTP(w, r)
 				return
 			}
@@ -70,7 +95,7 @@ func JWTMiddleware(authService service.Auth, logger *zap.Logger) func(next http.
 			}
 
 			// Верификация токена
-			userId, err := authService.ParseToken(tokenString)
+			userId, err := authService.ParseToken(r.Context(), tokenString)
 			if err != nil {
 				logger.Warn("JWTMiddleware: invalid token", zap.String("path", path), zap.Error(err))
 				http.Error(w, "Unauthorized", http.StatusUnauthorized)
@@ -78,7 +103,7 @@ func JWTMiddleware(authService service.Auth, logger *zap.Logger) func(next http.
 			}
 
 			// Добавляем userId в контекст
-			ctx := context.WithValue(r.Context(), "userID", userId)
+			ctx := context.WithValue(r.Context(), userIDKey, userId)
 			r = r.WithContext(ctx)
 			// Передаем управление дальше
 			next.ServeHTTP(w, r)
@@ -86,10 +111,177 @@ func JWTMiddleware(authService service.Auth, logger *zap.Logger) func(next http.
 	}
 }
 
+// APIKeyMiddleware создает middleware для запросов интеграций с API-ключом в заголовке X-API-Key.
+// Ключ должен иметь право, нужное маршруту по apiKeyRoutes. Запросы без ключа передаются дальше в JWTMiddleware.
+func APIKeyMiddleware(apiKeys service.APIKey, logger *zap.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "handlers.APIKeyMiddleware"
+			logger := logger.With(zap.String("op", op))
+
+			key := r.Header.Get(apiKeyHeader)
+			if key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			apiKey, err := apiKeys.AuthenticateAPIKey(r.Context(), key)
+			if err != nil {
+				if errors.IsUnauthorized(err) {
+					logger.Warn("APIKeyMiddleware: invalid api key", zap.String("path", r.URL.Path))
+					http.Error(w, "Unauthorized", http.StatusUnauthorized)
+					return
+				}
+				logger.Error("APIKeyMiddleware: cannot check api key", zap.Error(err))
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			scope := apiKeyScope(r)
+			if scope == "" || !apiKey.HasScope(scope) {
+				logger.Warn("APIKeyMiddleware: access denied", zap.Int64("api_key_id", apiKey.APIKeyID),
+					zap.String("path", r.URL.Path), zap.String("scope", scope))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			logger.Debug("APIKeyMiddleware: request authorized", zap.Int64("api_key_id", apiKey.APIKeyID), zap.String("path", r.URL.Path))
+			ctx := context.WithValue(r.Context(), apiKeyKey, apiKey)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// apiKeyScope возвращает право, нужное для маршрута запроса, или пустую строку, если маршрут недоступен по API-ключу
+func apiKeyScope(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return apiKeyRoutes[template][r.Method]
+}
+
+// currentAPIKey возвращает API-ключ, добавленный в контекст APIKeyMiddleware
+func currentAPIKey(r *http.Request) (models.APIKey, bool) {
+	apiKey, ok := r.Context().Value(apiKeyKey).(models.APIKey)
+	return apiKey, ok
+}
+
+// AdminMiddleware создает middleware, пропускающее к маршрутам только администраторов.
+// Должно применяться после JWTMiddleware.
+func AdminMiddleware(userService service.User, logger *zap.Logger) func(next http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			const op = "handlers.AdminMiddleware"
+			logger := logger.With(zap.String("op", op))
+
+			userID, ok := currentUserID(r)
+			if !ok {
+				logger.Info("AdminMiddleware: missing user in context", zap.String("path", r.URL.Path))
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			isAdmin, err := userService.IsAdmin(r.Context(), userID)
+			if err != nil && !errors.IsNotFound(err) {
+				logger.Error("AdminMiddleware: cannot check admin role", zap.Int64("user_id", userID), zap.Error(err))
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if !isAdmin {
+				logger.Warn("AdminMiddleware: access denied", zap.Int64("user_id", userID), zap.String("path", r.URL.Path))
+				http.Error(w, "Forbidden", http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// currentUserID возвращает ID пользователя, добавленный в контекст JWTMiddleware
+func currentUserID(r *http.Request) (int64, bool) {
+	userID, ok := r.Context().Value(userIDKey).(int64)
+	return userID, ok
+}
+
+// authorizeUser проверяет, что текущий пользователь обращается к своим данным или является администратором.
+// Запросы с API-ключом пропускаются: право ключа на маршрут уже проверено APIKeyMiddleware.
+func (h *Handler) authorizeUser(r *http.Request, userID int64) error {
+	if _, ok := currentAPIKey(r); ok {
+		return nil
+	}
+	currentID, ok := currentUserID(r)
+	if !ok {
+		return errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
+	}
+	if currentID == userID {
+		return nil
+	}
+	isAdmin, err := h.isCurrentUserAdmin(r)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return errors.NewForbidden(errors.ErrorMessage[errors.Forbidden], nil)
+	}
+	return nil
+}
+
+// isCurrentUserAdmin проверяет, является ли текущий пользователь администратором
+func (h *Handler) isCurrentUserAdmin(r *http.Request) (bool, error) {
+	currentID, ok := currentUserID(r)
+	if !ok {
+		return false, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
+	}
+	isAdmin, err := h.Services.User.IsAdmin(r.Context(), currentID)
+	if err != nil && !errors.IsNotFound(err) {
+		return false, err
+	}
+	return isAdmin, nil
+}
+
+// pathID извлекает из URL числовой идентификатор с указанным именем
+func pathID(r *http.Request, name string) (int64, error) {
+	value, exists := mux.Vars(r)[name]
+	if !exists || value == "" {
+		return 0, errors.NewBadRequest(fmt.Sprintf("Missing %s param", name), nil)
+	}
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, errors.NewBadRequest(fmt.Sprintf("Invalid %s param", name), err)
+	}
+	return id, nil
+}
+
+// clientIP возвращает IP-адрес клиента.
+// X-Forwarded-For учитывается только за доверенным прокси, иначе его может подставить сам клиент.
+func (h *Handler) clientIP(r *http.Request) string {
+	if h.TrustProxyHeaders {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			ip := strings.TrimSpace(strings.Split(forwarded, ",")[0])
+			if net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
 // Handler структура для работы с HTTP-запросами
 type Handler struct {
 	Services *service.Service
 	logger   *zap.Logger
+	// TrustProxyHeaders разрешает брать IP клиента из X-Forwarded-For
+	TrustProxyHeaders bool
 }
 
 // NewHandler создает новый экземпляр Handler
@@ -113,6 +305,12 @@ func (h *Handler) handleServiceError(w http.ResponseWriter, err error) {
 		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], err))
 	case errors.IsAlreadyExists(err):
 		h.httpError(w, errors.NewAlreadyExists(errors.ErrorMessage[errors.AlreadyExists], err))
+	case errors.IsValidation(err):
+		h.httpError(w, errors.NewValidation(err.(*errors.Error).Message, err))
+	case errors.IsForbidden(err):
+		h.httpError(w, errors.NewForbidden(errors.ErrorMessage[errors.Forbidden], err))
+	case errors.IsTooManyRequests(err):
+		h.httpError(w, errors.NewTooManyRequests(err.(*errors.Error).Message, err))
 	default:
 		h.httpError(w, errors.NewInternal(errors.ErrorMessage[errors.Internal], err))
 	}
@@ -147,3 +345,14 @@ func (h *Handler) jsonResponse(w http.ResponseWriter, statusCode int, payload in
 		http.Error(w, "Error encoding response", http.StatusInternalServerError)
 	}
 }
+
+// fileResponse отправляет клиенту файл для сохранения
+func (h *Handler) fileResponse(w http.ResponseWriter, file models.ExportFile) {
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Name))
+	w.Header().Set("Content-Length", strconv.Itoa(len(file.Content)))
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(file.Content); err != nil {
+		h.logger.Error("Error writing file response", zap.Error(err))
+	}
+}
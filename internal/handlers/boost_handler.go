@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AdminBoostCreate создает временный множитель наград
+func (h *Handler) AdminBoostCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminBoostCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var boost models.BoostCreate
+	if err := json.NewDecoder(r.Body).Decode(&boost); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	boost.AdminID = adminID
+
+	boostID, err := h.Services.Boost.CreateBoost(r.Context(), &boost)
+	if err != nil {
+		logger.Error("Failed to create boost", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_boost_id": boostID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// BoostGetAll возвращает действующие и запланированные множители наград
+func (h *Handler) BoostGetAll(w http.ResponseWriter, r *http.Request) {
+	h.boosts(w, r, true)
+}
+
+// AdminBoostGetAll возвращает все множители наград, включая закончившиеся
+func (h *Handler) AdminBoostGetAll(w http.ResponseWriter, r *http.Request) {
+	h.boosts(w, r, false)
+}
+
+func (h *Handler) boosts(w http.ResponseWriter, r *http.Request, upcomingOnly bool) {
+	const op = "handlers.boosts"
+	logger := h.logger.With(zap.String("op", op))
+
+	boosts, err := h.Services.Boost.GetBoosts(r.Context(), upcomingOnly)
+	if err != nil {
+		logger.Error("Failed to get boosts", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Boost `json:"boosts"`
+	}{
+		Data: boosts,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// AdminCampaignCreate создает кампанию в статусе draft
+func (h *Handler) AdminCampaignCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminCampaignCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var campaign models.CampaignCreate
+	if err := json.NewDecoder(r.Body).Decode(&campaign); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	campaign.AdminID = adminID
+
+	campaignID, err := h.Services.Campaign.CreateCampaign(r.Context(), &campaign)
+	if err != nil {
+		logger.Error("Failed to create campaign", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_campaign_id": campaignID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// AdminCampaigns возвращает все кампании
+func (h *Handler) AdminCampaigns(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminCampaigns"
+	logger := h.logger.With(zap.String("op", op))
+
+	campaigns, err := h.Services.Campaign.GetCampaigns(r.Context())
+	if err != nil {
+		logger.Error("Failed to get campaigns", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Campaign `json:"campaigns"`
+	}{
+		Data: campaigns,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminCampaignStatus переводит кампанию в новый статус
+func (h *Handler) AdminCampaignStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminCampaignStatus"
+	logger := h.logger.With(zap.String("op", op))
+
+	campaignID, err := pathID(r, "campaign_id")
+	if err != nil {
+		logger.Info("Invalid campaign_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	campaign, err := h.Services.Campaign.UpdateCampaignStatus(r.Context(), campaignID, req.Status)
+	if err != nil {
+		logger.Error("Failed to update campaign status", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, campaign)
+}
+
+// AdminCampaignStats возвращает статистику кампании: участников, выполнения по заданиям и расход бюджета
+func (h *Handler) AdminCampaignStats(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminCampaignStats"
+	logger := h.logger.With(zap.String("op", op))
+
+	campaignID, err := pathID(r, "campaign_id")
+	if err != nil {
+		logger.Info("Invalid campaign_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	stats, err := h.Services.Campaign.GetCampaignStats(r.Context(), campaignID)
+	if err != nil {
+		logger.Error("Failed to get campaign stats", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, stats)
+}
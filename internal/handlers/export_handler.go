@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// ExportUserData выгружает персональные данные текущего пользователя.
+// Небольшая выгрузка сразу отдается файлом, для большой возвращается 202 со ссылкой на ее состояние.
+func (h *Handler) ExportUserData(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ExportUserData"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	result, err := h.Services.Export.ExportUserData(r.Context(), userID, r.URL.Query().Get("format"))
+	if err != nil {
+		logger.Info("Failed to export user data", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if result.File != nil {
+		h.fileResponse(w, *result.File)
+		return
+	}
+	h.jsonResponse(w, http.StatusAccepted, result.Export)
+}
+
+// GetExport возвращает состояние выгрузки текущего пользователя
+func (h *Handler) GetExport(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.GetExport"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	exportID, err := pathID(r, "export_id")
+	if err != nil {
+		logger.Info("Invalid export_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	export, err := h.Services.Export.GetExport(r.Context(), userID, exportID)
+	if err != nil {
+		logger.Info("Failed to get export", zap.Int64("export_id", exportID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, export)
+}
+
+// DownloadExport отдает файл готовой выгрузки текущего пользователя
+func (h *Handler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DownloadExport"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	exportID, err := pathID(r, "export_id")
+	if err != nil {
+		logger.Info("Invalid export_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	file, err := h.Services.Export.DownloadExport(r.Context(), userID, exportID)
+	if err != nil {
+		logger.Info("Failed to download export", zap.Int64("export_id", exportID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.fileResponse(w, file)
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	// oidcStateCookieName cookie со state незавершенного входа через провайдера OpenID Connect
+	oidcStateCookieName = "oidc_state"
+	// oidcStateCookiePath cookie нужна только адресу возврата от провайдера
+	oidcStateCookiePath = "/auth/oidc"
+	// oidcStateCookieTTL время жизни cookie, совпадает со сроком незавершенного входа
+	oidcStateCookieTTL = 10 * time.Minute
+)
+
+// OIDCLoginHandler начинает вход через провайдера OpenID Connect и перенаправляет на его страницу входа
+func (h *Handler) OIDCLoginHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.OIDCLoginHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	login, err := h.Services.Auth.OIDCLogin(r.Context())
+	if err != nil {
+		logger.Error("Failed to start oidc login", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Провайдер возвращает пользователя переходом с другого сайта, поэтому нужен режим Lax
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    login.State,
+		Expires:  time.Now().Add(oidcStateCookieTTL),
+		Path:     oidcStateCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, login.URL, http.StatusFound)
+}
+
+// OIDCCallbackHandler обрабатывает возврат от провайдера OpenID Connect и выдает JWT
+// или токен второго шага, если у пользователя включена двухфакторная аутентификация
+func (h *Handler) OIDCCallbackHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.OIDCCallbackHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	query := r.URL.Query()
+	req := models.OIDCCallback{
+		Code:             query.Get("code"),
+		State:            query.Get("state"),
+		Error:            query.Get("error"),
+		ErrorDescription: query.Get("error_description"),
+	}
+	if cookie, err := r.Cookie(oidcStateCookieName); err == nil {
+		req.CookieState = cookie.Value
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     oidcStateCookiePath,
+		Secure:   true,
+		HttpOnly: true,
+	})
+
+	result, err := h.Services.Auth.OIDCCallback(r.Context(), &req)
+	if err != nil {
+		logger.Info("Failed to complete oidc login", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.loginResponse(w, result)
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// GetProfile возвращает профиль текущего пользователя
+func (h *Handler) GetProfile(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.GetProfile"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	profile, err := h.Services.Auth.GetProfile(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get profile", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, profile)
+}
+
+// UpdateProfile меняет профиль текущего пользователя
+func (h *Handler) UpdateProfile(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UpdateProfile"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.ProfileUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	profile, err := h.Services.Auth.UpdateProfile(r.Context(), userID, &req)
+	if err != nil {
+		logger.Info("Failed to update profile", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, profile)
+}
+
+// DeleteAccount удаляет аккаунт текущего пользователя
+func (h *Handler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.DeleteAccount"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.AccountDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.DeleteAccount(r.Context(), userID, &req); err != nil {
+		logger.Info("Failed to delete account", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "account deleted"})
+}
+
+// ConfirmEmailChangeHandler подтверждает новый адрес по токену из письма.
+// Токен передается параметром token (ссылка из письма) или в теле запроса.
+func (h *Handler) ConfirmEmailChangeHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ConfirmEmailChangeHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	req := models.EmailChangeRequest{Token: r.URL.Query().Get("token")}
+	if req.Token == "" && r.Method == http.MethodPost {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			logger.Error("Failed to decode JSON body", zap.Error(err))
+			h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+			return
+		}
+	}
+
+	if err := h.Services.Auth.ConfirmEmailChange(r.Context(), req.Token); err != nil {
+		logger.Info("Failed to change email", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "email changed"})
+}
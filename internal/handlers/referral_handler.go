@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+const (
+	// referralCookieName cookie со случайным идентификатором перехода по реферальной ссылке
+	referralCookieName = "ref_click"
+	// referralCookieTTL время жизни атрибуции перехода
+	referralCookieTTL = 30 * 24 * time.Hour
+)
+
+// ReferralLink обрабатывает переход по реферальной ссылке
+func (h *Handler) ReferralLink(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ReferralLink"
+	logger := h.logger.With(zap.String("op", op))
+
+	click := models.ReferralClick{
+		ReferCode: mux.Vars(r)["code"],
+		UserAgent: r.UserAgent(),
+		Referrer:  r.Referer(),
+	}
+
+	location, err := h.Services.Referral.TrackClick(r.Context(), &click)
+	if err != nil {
+		logger.Error("Failed to track referral click", zap.String("refer_code", click.ReferCode), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Сохраняем переход в cookie, чтобы привязать к нему последующую регистрацию
+	http.SetCookie(w, &http.Cookie{
+		Name:     referralCookieName,
+		Value:    click.Token,
+		Expires:  time.Now().Add(referralCookieTTL),
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// ReferralStats возвращает воронку по реферальному коду. Доступна владельцу кода и администраторам;
+// остальным на чужой код отвечается так же, как на несуществующий.
+func (h *Handler) ReferralStats(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.ReferralStats"
+	logger := h.logger.With(zap.String("op", op))
+
+	referCode := mux.Vars(r)["code"]
+	if referCode == "" {
+		logger.Info("Missing code param in URL", zap.String("url", r.URL.String()))
+		h.httpError(w, errors.NewBadRequest("Missing code param", nil))
+		return
+	}
+
+	stats, err := h.Services.Referral.GetReferralStats(r.Context(), referCode)
+	if err != nil {
+		logger.Error("Failed to get referral stats", zap.String("refer_code", referCode), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+	if err := h.authorizeUser(r, stats.UserID); err != nil {
+		logger.Info("Referral stats access denied", zap.String("refer_code", referCode), zap.Error(err))
+		if errors.IsForbidden(err) {
+			// Чужой код выглядит так же, как несуществующий, иначе по ответу можно проверять, существует ли код
+			err = errors.NewNotFound(fmt.Sprintf("user with refer_code \"%s\" not found", referCode), nil)
+		}
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, stats)
+}
+
+// referralClickFromCookie возвращает переход по реферальной ссылке, сохраненный в cookie
+func referralClickFromCookie(r *http.Request) string {
+	cookie, err := r.Cookie(referralCookieName)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// clearReferralCookie удаляет cookie атрибуции после регистрации
+func clearReferralCookie(w http.ResponseWriter) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     referralCookieName,
+		Value:    "",
+		MaxAge:   -1,
+		Path:     "/",
+		Secure:   true,
+		HttpOnly: true,
+	})
+}
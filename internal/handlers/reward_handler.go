@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// RewardCreate добавляет награду в каталог
+func (h *Handler) RewardCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RewardCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	var reward models.RewardCreate
+	if err := json.NewDecoder(r.Body).Decode(&reward); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	rewardID, err := h.Services.Reward.CreateReward(r.Context(), &reward)
+	if err != nil {
+		logger.Error("Failed to create reward", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_reward_id": rewardID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// RewardGetAll возвращает каталог наград
+func (h *Handler) RewardGetAll(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RewardGetAll"
+	logger := h.logger.With(zap.String("op", op))
+
+	rewards, err := h.Services.Reward.GetRewards(r.Context())
+	if err != nil {
+		logger.Error("Failed to get rewards", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Reward `json:"rewards"`
+	}{
+		Data: rewards,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// RewardRedeem обменивает баллы текущего пользователя на награду
+func (h *Handler) RewardRedeem(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.RewardRedeem"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	rewardID, err := pathID(r, "reward_id")
+	if err != nil {
+		logger.Info("Invalid reward_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	redemption, err := h.Services.Reward.RedeemReward(r.Context(), userID, rewardID)
+	if err != nil {
+		logger.Error("Failed to redeem reward", zap.Int64("user_id", userID), zap.Int64("reward_id", rewardID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusCreated, redemption)
+}
+
+// UserRedemptions возвращает историю обменов пользователя. Доступна самому пользователю и администраторам.
+func (h *Handler) UserRedemptions(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.UserRedemptions"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+	if err := h.authorizeUser(r, userID); err != nil {
+		logger.Info("User redemptions access denied", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	redemptions, err := h.Services.Reward.GetUserRedemptions(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user redemptions", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Redemption `json:"redemptions"`
+	}{
+		Data: redemptions,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminRedemptions возвращает заявки на выдачу наград, опционально по статусу
+func (h *Handler) AdminRedemptions(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminRedemptions"
+	logger := h.logger.With(zap.String("op", op))
+
+	status := r.URL.Query().Get("status")
+	redemptions, err := h.Services.Reward.GetRedemptions(r.Context(), status)
+	if err != nil {
+		logger.Error("Failed to get redemptions", zap.String("status", status), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Redemption `json:"redemptions"`
+	}{
+		Data: redemptions,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminRedemptionStatus переводит заявку на выдачу награды в новый статус
+func (h *Handler) AdminRedemptionStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminRedemptionStatus"
+	logger := h.logger.With(zap.String("op", op))
+
+	redemptionID, err := pathID(r, "redemption_id")
+	if err != nil {
+		logger.Info("Invalid redemption_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	redemption, err := h.Services.Reward.UpdateRedemptionStatus(r.Context(), redemptionID, req.Status)
+	if err != nil {
+		logger.Error("Failed to update redemption status", zap.Int64("redemption_id", redemptionID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, redemption)
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+// AdminSeasonCreate открывает новый сезон
+func (h *Handler) AdminSeasonCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminSeasonCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	var season models.SeasonCreate
+	if err := json.NewDecoder(r.Body).Decode(&season); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	seasonID, err := h.Services.Season.CreateSeason(r.Context(), &season)
+	if err != nil {
+		logger.Error("Failed to create season", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_season_id": seasonID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// AdminSeasonClose закрывает сезон и начисляет призы первым местам
+func (h *Handler) AdminSeasonClose(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminSeasonClose"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	seasonID, err := pathID(r, "season_id")
+	if err != nil {
+		logger.Info("Invalid season_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	// Тело запроса необязательно: без него сезон закрывается без призов
+	var closing models.SeasonClose
+	if err := json.NewDecoder(r.Body).Decode(&closing); err != nil && err != io.EOF {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	closing.SeasonID = seasonID
+	closing.AdminID = adminID
+
+	result, err := h.Services.Season.CloseSeason(r.Context(), &closing)
+	if err != nil {
+		logger.Error("Failed to close season", zap.Int64("season_id", seasonID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, result)
+}
+
+// SeasonGetAll возвращает текущий и прошедшие сезоны
+func (h *Handler) SeasonGetAll(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.SeasonGetAll"
+	logger := h.logger.With(zap.String("op", op))
+
+	seasons, err := h.Services.Season.GetSeasons(r.Context())
+	if err != nil {
+		logger.Error("Failed to get seasons", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.Season `json:"seasons"`
+	}{
+		Data: seasons,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// SeasonStandings возвращает места участников сезона
+func (h *Handler) SeasonStandings(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.SeasonStandings"
+	logger := h.logger.With(zap.String("op", op))
+
+	seasonID, err := pathID(r, "season_id")
+	if err != nil {
+		logger.Info("Invalid season_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	result, err := h.Services.Season.GetSeasonStandings(r.Context(), seasonID)
+	if err != nil {
+		logger.Error("Failed to get season standings", zap.Int64("season_id", seasonID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, result)
+}
@@ -8,9 +8,10 @@ import (
 	"go.uber.org/zap"
 	"net/http"
 	"strconv"
+	"strings"
 )
 
-// TaskCreate создает новую задачу
+// TaskCreate создает новую задачу. Привязать задачу к кампании может только администратор.
 func (h *Handler) TaskCreate(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.TaskCreate"
 	logger := h.logger.With(zap.String("op", op))
@@ -25,6 +26,16 @@ func (h *Handler) TaskCreate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	if task.CampaignID != nil {
+		isAdmin, err := h.isCurrentUserAdmin(r)
+		if err != nil {
+			logger.Error("Failed to check admin role", zap.Error(err))
+			h.handleServiceError(w, err)
+			return
+		}
+		task.ByAdmin = isAdmin
+	}
+
 	newTaskId, err := h.Services.Task.CreateTask(ctx, &task)
 	if err != nil {
 		logger.Error("Failed to create task", zap.Error(err))
@@ -83,24 +94,152 @@ func (h *Handler) TaskComplete(w http.ResponseWriter, r *http.Request) {
 	h.jsonResponse(w, http.StatusOK, response)
 }
 
-// TaskGetAll получает все задачи
+// TaskGetAll получает страницу заданий.
+// Параметры запроса: q - полнотекстовый поиск, category, tags (через запятую), sort, limit, cursor.
+// Без limit и cursor возвращаются все задания.
 func (h *Handler) TaskGetAll(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.TaskGetAll"
 	logger := h.logger.With(zap.String("op", op))
 
 	logger.Debug("Handling get all tasks from repo request")
 
-	tasks, err := h.Services.Task.GetAllTasks(r.Context())
+	query := r.URL.Query()
+	filter := models.TaskFilter{
+		Query:    query.Get("q"),
+		Category: query.Get("category"),
+		Sort:     query.Get("sort"),
+		Cursor:   query.Get("cursor"),
+	}
+	if tags := query.Get("tags"); tags != "" {
+		filter.Tags = strings.Split(tags, ",")
+	}
+	if limit := query.Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+		if err != nil {
+			logger.Info("Invalid limit param", zap.String("limit", limit), zap.Error(err))
+			h.httpError(w, errors.NewBadRequest("invalid limit", err))
+			return
+		}
+		filter.Limit = value
+	}
+
+	page, err := h.Services.Task.GetAllTasks(r.Context(), filter)
 	if err != nil {
 		logger.Error("Failed to get all tasks", zap.Error(err))
-		h.httpError(w, errors.NewInternal("Failed to get all tasks", err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, page)
+}
+
+// CategoryCreate создает категорию заданий
+func (h *Handler) CategoryCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.CategoryCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	var category models.CategoryCreate
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	categoryID, err := h.Services.Task.CreateCategory(r.Context(), &category)
+	if err != nil {
+		logger.Error("Failed to create category", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_category_id": categoryID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// CategoryGetAll возвращает категории заданий
+func (h *Handler) CategoryGetAll(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.CategoryGetAll"
+	logger := h.logger.With(zap.String("op", op))
+
+	categories, err := h.Services.Task.GetCategories(r.Context())
+	if err != nil {
+		logger.Error("Failed to get categories", zap.Error(err))
+		h.handleServiceError(w, err)
 		return
 	}
 
 	response := struct {
-		Data []models.Task `json:"tasks"`
+		Data []models.Category `json:"categories"`
 	}{
-		Data: tasks,
+		Data: categories,
 	}
 	h.jsonResponse(w, http.StatusOK, response)
 }
+
+// AdminUserCompletions возвращает выполненные пользователем задания
+func (h *Handler) AdminUserCompletions(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminUserCompletions"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	completions, err := h.Services.Task.GetUserCompletions(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user completions", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.TaskCompletion `json:"completions"`
+	}{
+		Data: completions,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// AdminRevokeCompletion отменяет выполнение задания и забирает начисленные баллы
+func (h *Handler) AdminRevokeCompletion(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.AdminRevokeCompletion"
+	logger := h.logger.With(zap.String("op", op))
+
+	adminID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	completionID, err := pathID(r, "completion_id")
+	if err != nil {
+		logger.Info("Invalid completion_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid request payload", err))
+		return
+	}
+
+	revocation := models.Revocation{CompletionID: completionID, Reason: req.Reason, AdminID: adminID}
+	result, err := h.Services.Task.RevokeCompletion(r.Context(), &revocation)
+	if err != nil {
+		logger.Error("Failed to revoke completion", zap.Int64("completion_id", completionID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// TeamCreate создает команду, капитаном которой становится текущий пользователь
+func (h *Handler) TeamCreate(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamCreate"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var team models.TeamCreate
+	if err := json.NewDecoder(r.Body).Decode(&team); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	team.UserID = userID
+
+	teamID, err := h.Services.Team.CreateTeam(r.Context(), &team)
+	if err != nil {
+		logger.Error("Failed to create team", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"new_team_id": teamID,
+	}
+	h.jsonResponse(w, http.StatusCreated, response)
+}
+
+// TeamJoin добавляет текущего пользователя в команду по коду приглашения
+func (h *Handler) TeamJoin(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamJoin"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req struct {
+		InviteCode string `json:"invite_code"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	team, err := h.Services.Team.JoinTeam(r.Context(), userID, req.InviteCode)
+	if err != nil {
+		logger.Error("Failed to join team", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, team)
+}
+
+// TeamLeave исключает текущего пользователя из его команды
+func (h *Handler) TeamLeave(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamLeave"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	if err := h.Services.Team.LeaveTeam(r.Context(), userID); err != nil {
+		logger.Error("Failed to leave team", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "left the team"})
+}
+
+// TeamMine возвращает команду текущего пользователя вместе с кодом приглашения
+func (h *Handler) TeamMine(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamMine"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	team, err := h.Services.Team.GetUserTeam(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get user team", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, team)
+}
+
+// TeamGet возвращает команду с участниками
+func (h *Handler) TeamGet(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamGet"
+	logger := h.logger.With(zap.String("op", op))
+
+	teamID, err := pathID(r, "team_id")
+	if err != nil {
+		logger.Info("Invalid team_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	team, err := h.Services.Team.GetTeam(r.Context(), teamID)
+	if err != nil {
+		logger.Error("Failed to get team", zap.Int64("team_id", teamID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, team)
+}
+
+// TeamSetCaptain передает роль капитана другому участнику команды
+func (h *Handler) TeamSetCaptain(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamSetCaptain"
+	logger := h.logger.With(zap.String("op", op))
+
+	captainID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	teamID, err := pathID(r, "team_id")
+	if err != nil {
+		logger.Info("Invalid team_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	var req struct {
+		UserID int64 `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Team.SetCaptain(r.Context(), captainID, teamID, req.UserID); err != nil {
+		logger.Error("Failed to change team captain", zap.Int64("team_id", teamID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "captain changed"})
+}
+
+// TeamRemoveMember исключает участника из команды по решению капитана
+func (h *Handler) TeamRemoveMember(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamRemoveMember"
+	logger := h.logger.With(zap.String("op", op))
+
+	captainID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	teamID, err := pathID(r, "team_id")
+	if err != nil {
+		logger.Info("Invalid team_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+	userID, err := pathID(r, "user_id")
+	if err != nil {
+		logger.Info("Invalid user_id param", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	if err := h.Services.Team.RemoveMember(r.Context(), captainID, teamID, userID); err != nil {
+		logger.Error("Failed to remove team member", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "member removed"})
+}
+
+// TeamsLeaderboard получает топ команд по очкам в валюте из параметра currency (по умолчанию coins)
+func (h *Handler) TeamsLeaderboard(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TeamsLeaderboard"
+	logger := h.logger.With(zap.String("op", op))
+
+	currency := r.URL.Query().Get("currency")
+	standings, err := h.Services.Team.GetTeamsLeaderboard(r.Context(), currency)
+	if err != nil {
+		logger.Error("Failed to get teams leaderboard", zap.String("currency", currency), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := struct {
+		Data []models.TeamStanding `json:"data"`
+	}{
+		Data: standings,
+	}
+
+	h.jsonResponse(w, http.StatusOK, response)
+}
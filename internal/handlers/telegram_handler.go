@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// TelegramLoginHandler вход по данным виджета Telegram Login
+func (h *Handler) TelegramLoginHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TelegramLoginHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	var data models.TelegramLogin
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	result, err := h.Services.Auth.TelegramLogin(r.Context(), &data)
+	if err != nil {
+		logger.Info("Failed to login with telegram", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.loginResponse(w, result)
+}
+
+// LinkTelegram привязывает аккаунт Telegram к текущему пользователю по данным виджета Telegram Login
+func (h *Handler) LinkTelegram(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.LinkTelegram"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var data models.TelegramLogin
+	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.LinkTelegram(r.Context(), userID, &data); err != nil {
+		logger.Info("Failed to link telegram account", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"message":     "telegram account linked",
+		"telegram_id": data.ID,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
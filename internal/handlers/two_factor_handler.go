@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"encoding/json"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// LoginTwoFactorHandler второй шаг входа: обмен токена и кода двухфакторной аутентификации на JWT
+func (h *Handler) LoginTwoFactorHandler(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.LoginTwoFactorHandler"
+	logger := h.logger.With(zap.String("op", op))
+
+	var req models.TwoFactorLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+	req.IP = h.clientIP(r)
+
+	token, err := h.Services.Auth.LoginTwoFactor(r.Context(), &req)
+	if err != nil {
+		logger.Info("Failed to complete two-factor login", zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	setTokenCookie(w, token)
+	response := map[string]interface{}{
+		"message": "User Login successful",
+		"token":   token,
+	}
+	h.jsonResponse(w, http.StatusOK, response)
+}
+
+// TwoFactorStatus возвращает состояние двухфакторной аутентификации текущего пользователя
+func (h *Handler) TwoFactorStatus(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TwoFactorStatus"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	status, err := h.Services.Auth.GetTwoFactorStatus(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to get two-factor status", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, status)
+}
+
+// TwoFactorSetup выдает новый секрет TOTP для добавления в приложение-аутентификатор
+func (h *Handler) TwoFactorSetup(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TwoFactorSetup"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	setup, err := h.Services.Auth.SetupTwoFactor(r.Context(), userID)
+	if err != nil {
+		logger.Error("Failed to start two-factor setup", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, setup)
+}
+
+// TwoFactorEnable включает двухфакторную аутентификацию по коду из приложения и возвращает резервные коды
+func (h *Handler) TwoFactorEnable(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TwoFactorEnable"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	codes, err := h.Services.Auth.EnableTwoFactor(r.Context(), userID, req.Code)
+	if err != nil {
+		logger.Info("Failed to enable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, codes)
+}
+
+// TwoFactorDisable отключает двухфакторную аутентификацию
+func (h *Handler) TwoFactorDisable(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TwoFactorDisable"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.TwoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	if err := h.Services.Auth.DisableTwoFactor(r.Context(), userID, &req); err != nil {
+		logger.Info("Failed to disable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, map[string]string{"message": "two-factor authentication disabled"})
+}
+
+// TwoFactorRecoveryCodes выдает новые резервные коды взамен прежних
+func (h *Handler) TwoFactorRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	const op = "handlers.TwoFactorRecoveryCodes"
+	logger := h.logger.With(zap.String("op", op))
+
+	userID, ok := currentUserID(r)
+	if !ok {
+		logger.Error("Missing user in context")
+		h.httpError(w, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil))
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		logger.Error("Failed to decode JSON body", zap.Error(err))
+		h.httpError(w, errors.NewBadRequest("Invalid input body", err))
+		return
+	}
+
+	codes, err := h.Services.Auth.RegenerateRecoveryCodes(r.Context(), userID, req.Code)
+	if err != nil {
+		logger.Info("Failed to regenerate recovery codes", zap.Int64("user_id", userID), zap.Error(err))
+		h.handleServiceError(w, err)
+		return
+	}
+
+	h.jsonResponse(w, http.StatusOK, codes)
+}
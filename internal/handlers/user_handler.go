@@ -47,15 +47,16 @@ func (h *Handler) UserInfo(w http.ResponseWriter, r *http.Request) {
 	h.jsonResponse(w, http.StatusOK, response)
 }
 
-// UsersLeaderboard получает топ пользователей на основе статистики
+// UsersLeaderboard получает топ пользователей по балансу в валюте из параметра currency (по умолчанию coins)
 func (h *Handler) UsersLeaderboard(w http.ResponseWriter, r *http.Request) {
 	const op = "handlers.UsersLeaderboard"
 	logger := h.logger.With(zap.String("op", op))
 
-	users, err := h.Services.User.GetUsersLeaderboard(r.Context())
+	currency := r.URL.Query().Get("currency")
+	users, err := h.Services.User.GetUsersLeaderboard(r.Context(), currency)
 	if err != nil {
-		logger.Error("Failed to get users leaderboard", zap.Error(err))
-		h.httpError(w, errors.NewInternal(err.Error(), nil))
+		logger.Error("Failed to get users leaderboard", zap.String("currency", currency), zap.Error(err))
+		h.handleServiceError(w, err)
 		return
 	}
 
@@ -0,0 +1,97 @@
+// Package mail отправляет письма пользователям.
+// Реальная доставка подключается через интерфейс Sender; для локальной разработки
+// письма пишутся в лог или в файлы.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"go.uber.org/zap"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// Способы отправки писем
+const (
+	SenderLog  = "log"
+	SenderFile = "file"
+)
+
+// Message письмо пользователю
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Sender отправляет письма
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// NewSender создает отправителя писем по названию способа отправки
+func NewSender(kind, from, dir string, logger *zap.Logger) (Sender, error) {
+	switch kind {
+	case SenderLog:
+		return NewLogSender(from, logger), nil
+	case SenderFile:
+		return NewFileSender(from, dir)
+	default:
+		return nil, fmt.Errorf("unknown mail sender %q", kind)
+	}
+}
+
+// LogSender пишет в лог приложения получателя и тему письма.
+// Текст письма не логируется: в нем ссылки с одноразовыми токенами. Чтобы прочитать письма, используйте FileSender.
+type LogSender struct {
+	from   string
+	logger *zap.Logger
+}
+
+// NewLogSender создает LogSender
+func NewLogSender(from string, logger *zap.Logger) *LogSender {
+	return &LogSender{from: from, logger: logger}
+}
+
+// Send пишет в лог получателя и тему письма
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.Info("Mail sent", zap.String("from", s.from), zap.String("to", msg.To), zap.String("subject", msg.Subject))
+	return nil
+}
+
+// FileSender сохраняет каждое письмо в отдельный .eml файл в каталоге
+type FileSender struct {
+	from string
+	dir  string
+	seq  atomic.Int64
+}
+
+// NewFileSender создает FileSender, при необходимости создавая каталог для писем
+func NewFileSender(from, dir string) (*FileSender, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("cannot create mail directory: %w", err)
+	}
+	return &FileSender{from: from, dir: dir}, nil
+}
+
+// Send сохраняет письмо в файл
+func (s *FileSender) Send(ctx context.Context, msg Message) error {
+	now := time.Now()
+	name := fmt.Sprintf("%s-%d.eml", now.Format("20060102T150405.000000000"), s.seq.Add(1))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "From: %s\r\n", s.from)
+	fmt.Fprintf(&b, "To: %s\r\n", msg.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", msg.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	b.WriteString(msg.Body)
+
+	if err := os.WriteFile(filepath.Join(s.dir, name), []byte(b.String()), 0o640); err != nil {
+		return fmt.Errorf("cannot write mail file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// Метрики, по которым выдаются достижения
+const (
+	// AchievementMetricCompletedTasks количество выполненных и не отмененных заданий
+	AchievementMetricCompletedTasks = "completed_tasks"
+	// AchievementMetricReferrals количество приглашенных пользователей
+	AchievementMetricReferrals = "referrals"
+	// AchievementMetricLongestStreak самая длинная серия дней подряд с выполненными заданиями
+	AchievementMetricLongestStreak = "longest_streak"
+)
+
+// Achievement описание достижения.
+// Достижение выдается, когда значение метрики пользователя достигает порога Threshold.
+type Achievement struct {
+	AchievementID int64     `json:"achievement_id" db:"achievement_id"`
+	Code          string    `json:"code" db:"code"`
+	Title         string    `json:"title" db:"title"`
+	Description   string    `json:"description,omitempty" db:"description"`
+	Metric        string    `json:"metric" db:"metric"`
+	Threshold     int       `json:"threshold" db:"threshold"`
+	Bonus         int       `json:"bonus" db:"bonus"`
+	BonusCurrency string    `json:"bonus_currency" db:"bonus_currency"`
+	Active        bool      `json:"active" db:"active"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
+
+// AchievementCreate структура для добавления достижения.
+// Пустая BonusCurrency означает coins.
+type AchievementCreate struct {
+	Code          string `json:"code" db:"code" binding:"required"`
+	Title         string `json:"title" db:"title" binding:"required"`
+	Description   string `json:"description" db:"description"`
+	Metric        string `json:"metric" db:"metric" binding:"required"`
+	Threshold     int    `json:"threshold" db:"threshold"`
+	Bonus         int    `json:"bonus" db:"bonus"`
+	BonusCurrency string `json:"bonus_currency" db:"bonus_currency"`
+}
+
+// UserAchievement достижение, выданное пользователю
+type UserAchievement struct {
+	Achievement
+	AwardedAt time.Time `json:"awarded_at" db:"awarded_at"`
+}
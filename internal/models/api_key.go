@@ -0,0 +1,52 @@
+package models
+
+import "time"
+
+// Права API-ключей
+const (
+	// ScopeTasksComplete отметка о выполнении заданий пользователями
+	ScopeTasksComplete = "tasks:complete"
+	// ScopeUsersRead чтение профилей и достижений пользователей
+	ScopeUsersRead = "users:read"
+	// ScopeTransactionsRead чтение истории операций пользователей; выдается только явно, users:read его не включает
+	ScopeTransactionsRead = "transactions:read"
+)
+
+// APIKey ключ для интеграций, которые обращаются к API без входа пользователя, например ботов.
+// Сам ключ не хранится, только его хэш и начало для отображения.
+type APIKey struct {
+	APIKeyID   int64      `json:"api_key_id" db:"api_key_id"`
+	Name       string     `json:"name" db:"name"`
+	Prefix     string     `json:"prefix" db:"prefix"`
+	Scopes     []string   `json:"scopes" db:"scopes"`
+	CreatedBy  *int64     `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty" db:"last_used_at"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+}
+
+// HasScope проверяет, есть ли у ключа право
+func (k *APIKey) HasScope(scope string) bool {
+	for _, s := range k.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+// APIKeyCreate структура для создания API-ключа
+type APIKeyCreate struct {
+	Name    string   `json:"name"`
+	Scopes  []string `json:"scopes"`
+	AdminID int64    `json:"-"`
+	// Prefix и KeyHash заполняет сервис при выпуске ключа
+	Prefix  string `json:"-"`
+	KeyHash string `json:"-"`
+}
+
+// APIKeyCreated созданный API-ключ; сам ключ показывается только один раз
+type APIKeyCreated struct {
+	APIKey
+	Key string `json:"key"`
+}
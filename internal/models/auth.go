@@ -0,0 +1,136 @@
+package models
+
+import "time"
+
+// Назначения одноразовых токенов, отправляемых по почте
+const (
+	TokenPurposeEmailVerification = "email_verification"
+	TokenPurposePasswordReset     = "password_reset"
+	// TokenPurposeLoginChallenge токен второго шага входа, выдается после проверки пароля
+	TokenPurposeLoginChallenge = "login_2fa"
+	// TokenPurposeEmailChange токен смены адреса, отправляется на новый адрес
+	TokenPurposeEmailChange = "email_change"
+)
+
+// AuthToken выпущенный одноразовый токен.
+// Хранится только идентификатор токена, сам токен знает лишь получатель письма.
+type AuthToken struct {
+	TokenID string `json:"-" db:"token_id"`
+	UserID  int64  `json:"user_id" db:"user_id"`
+	Purpose string `json:"purpose" db:"purpose"`
+	// Email адрес, на который отправлен токен подтверждения
+	Email     string    `json:"email,omitempty" db:"email"`
+	ExpiresAt time.Time `json:"expires_at" db:"expires_at"`
+}
+
+// EmailVerificationRequest запрос на подтверждение адреса по токену из письма
+type EmailVerificationRequest struct {
+	Token string `json:"token"`
+}
+
+// PasswordForgotRequest запрос письма для сброса пароля
+type PasswordForgotRequest struct {
+	Email string `json:"email"`
+}
+
+// PasswordResetRequest установка нового пароля по токену из письма
+type PasswordResetRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+// PasswordChangeRequest смена пароля пользователем, знающим текущий пароль
+type PasswordChangeRequest struct {
+	OldPassword string `json:"old_password"`
+	NewPassword string `json:"new_password"`
+}
+
+// Ключи, по которым считаются неудачные попытки входа
+const (
+	LoginScopeUsername = "username"
+	LoginScopeIP       = "ip"
+)
+
+// LoginThrottleRules ограничения на неудачные попытки входа.
+// Нулевой Window отключает ограничения.
+type LoginThrottleRules struct {
+	// Window период без неудачных попыток, после которого счетчик начинается заново
+	Window time.Duration
+	// BackoffAfter число неудачных попыток по имени пользователя, после которого вводится задержка
+	BackoffAfter int
+	// BackoffBase задержка после первой попытки сверх BackoffAfter, дальше удваивается
+	BackoffBase time.Duration
+	// BackoffMax наибольшая задержка
+	BackoffMax time.Duration
+	// LockoutAfter число неудачных попыток по имени пользователя, после которого вход блокируется
+	LockoutAfter int
+	// LockoutDuration срок блокировки
+	LockoutDuration time.Duration
+	// IPLockoutAfter число неудачных попыток с одного IP-адреса, после которого вход с него блокируется
+	IPLockoutAfter int
+}
+
+// LoginUnlockRequest снятие блокировки входа; IP необязателен
+type LoginUnlockRequest struct {
+	IP string `json:"ip"`
+}
+
+// LoginResult результат входа.
+// Если у пользователя включена двухфакторная аутентификация, вместо JWT выдается токен второго шага.
+type LoginResult struct {
+	Token             string `json:"token,omitempty"`
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+}
+
+// TwoFactor состояние двухфакторной аутентификации пользователя
+type TwoFactor struct {
+	// Secret секрет TOTP; задан и до подтверждения подключения
+	Secret  string
+	Enabled bool
+	// LastStep номер периода последнего принятого кода
+	LastStep          int64
+	RecoveryCodesLeft int
+}
+
+// SecondFactor код второго шага входа: период кода из приложения или хэш резервного кода
+type SecondFactor struct {
+	Step             int64
+	RecoveryCodeHash string
+}
+
+// TwoFactorStatus состояние двухфакторной аутентификации для пользователя
+type TwoFactorStatus struct {
+	Enabled           bool `json:"enabled"`
+	RecoveryCodesLeft int  `json:"recovery_codes_left"`
+}
+
+// TwoFactorSetup секрет для добавления в приложение-аутентификатор
+type TwoFactorSetup struct {
+	Secret string `json:"secret"`
+	URI    string `json:"otpauth_uri"`
+}
+
+// TwoFactorCodeRequest запрос с кодом из приложения-аутентификатора или резервным кодом
+type TwoFactorCodeRequest struct {
+	Code string `json:"code"`
+}
+
+// TwoFactorDisableRequest отключение двухфакторной аутентификации
+type TwoFactorDisableRequest struct {
+	Password string `json:"password"`
+	Code     string `json:"code"`
+}
+
+// TwoFactorLoginRequest второй шаг входа
+type TwoFactorLoginRequest struct {
+	ChallengeToken string `json:"challenge_token"`
+	Code           string `json:"code"`
+	// IP адрес клиента, по нему считаются неудачные попытки входа
+	IP string `json:"-"`
+}
+
+// RecoveryCodes резервные коды; показываются пользователю один раз
+type RecoveryCodes struct {
+	Codes []string `json:"recovery_codes"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// TaskTypeGeneral тип задания по умолчанию
+const TaskTypeGeneral = "general"
+
+// Boost временный множитель наград за задания.
+// Пустые TaskType и MinLevel означают, что множитель действует для всех заданий и всех пользователей.
+type Boost struct {
+	BoostID           int64     `json:"boost_id" db:"boost_id"`
+	Title             string    `json:"title" db:"title"`
+	MultiplierPercent int       `json:"multiplier_percent" db:"multiplier_percent"`
+	TaskType          *string   `json:"task_type,omitempty" db:"task_type"`
+	MinLevel          *int      `json:"min_level,omitempty" db:"min_level"`
+	StartsAt          time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt            time.Time `json:"ends_at" db:"ends_at"`
+	CreatedBy         *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
+
+// BoostCreate структура для создания множителя наград
+type BoostCreate struct {
+	Title             string    `json:"title"`
+	MultiplierPercent int       `json:"multiplier_percent"`
+	TaskType          *string   `json:"task_type"`
+	MinLevel          *int      `json:"min_level"`
+	StartsAt          time.Time `json:"starts_at"`
+	EndsAt            time.Time `json:"ends_at"`
+	AdminID           int64     `json:"-"`
+}
@@ -0,0 +1,53 @@
+package models
+
+import "time"
+
+// Статусы кампании
+const (
+	CampaignDraft  = "draft"
+	CampaignLive   = "live"
+	CampaignPaused = "paused"
+	CampaignEnded  = "ended"
+)
+
+// Campaign маркетинговая кампания, объединяющая задания с общим бюджетом баллов.
+// Задания кампании можно выполнять, только пока она запущена и идет период ее действия.
+type Campaign struct {
+	CampaignID  int64     `json:"campaign_id" db:"campaign_id"`
+	Title       string    `json:"title" db:"title"`
+	Description string    `json:"description,omitempty" db:"description"`
+	Budget      int       `json:"budget" db:"budget"`
+	Spent       int       `json:"spent" db:"spent"`
+	Status      string    `json:"status" db:"status"`
+	StartsAt    time.Time `json:"starts_at" db:"starts_at"`
+	EndsAt      time.Time `json:"ends_at" db:"ends_at"`
+	CreatedBy   *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+// CampaignCreate структура для создания кампании; кампания создается в статусе draft
+type CampaignCreate struct {
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Budget      int       `json:"budget"`
+	StartsAt    time.Time `json:"starts_at"`
+	EndsAt      time.Time `json:"ends_at"`
+	AdminID     int64     `json:"-"`
+}
+
+// CampaignTaskStats статистика выполнения одного задания кампании
+type CampaignTaskStats struct {
+	TaskID       int64  `json:"task_id" db:"task_id"`
+	Title        string `json:"title" db:"title"`
+	Completions  int    `json:"completions" db:"completions"`
+	Participants int    `json:"participants" db:"participants"`
+}
+
+// CampaignStats статистика кампании. Отмененные выполнения не учитываются.
+type CampaignStats struct {
+	Campaign     Campaign            `json:"campaign"`
+	Participants int                 `json:"participants"`
+	Completions  int                 `json:"completions"`
+	Remaining    int                 `json:"remaining"`
+	Tasks        []CampaignTaskStats `json:"tasks"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// Валюты, которые создаются миграциями
+const (
+	// CurrencyCoins тратится на награды и переводы, баланс дублируется в users.balance
+	CurrencyCoins = "coins"
+	// CurrencyXP учитывается только в рейтинге
+	CurrencyXP = "xp"
+)
+
+// Currency вид баллов
+type Currency struct {
+	Code      string `json:"code" db:"code"`
+	Title     string `json:"title" db:"title"`
+	Spendable bool   `json:"spendable" db:"spendable"`
+}
@@ -0,0 +1,104 @@
+package models
+
+import "time"
+
+// Форматы выгрузки персональных данных
+const (
+	ExportFormatJSON = "json"
+	ExportFormatZIP  = "zip"
+)
+
+// Состояния выгрузки персональных данных
+const (
+	ExportStatusPending    = "pending"
+	ExportStatusProcessing = "processing"
+	ExportStatusReady      = "ready"
+	ExportStatusFailed     = "failed"
+)
+
+// ExportRules настройки выгрузки персональных данных
+type ExportRules struct {
+	// SyncMaxRecords наибольшее число выполнений и операций, при котором выгрузка отдается сразу;
+	// для аккаунтов больше выгрузка готовится в фоне
+	SyncMaxRecords int
+	// TTL время хранения готовой выгрузки
+	TTL time.Duration
+}
+
+// DataExport выгрузка персональных данных, которая готовится в фоне
+type DataExport struct {
+	ExportID    int64      `json:"export_id"`
+	UserID      int64      `json:"-"`
+	Format      string     `json:"format"`
+	Status      string     `json:"status"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	// ExpiresAt время, после которого выгрузка удаляется
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// StatusURL адрес, по которому проверяется готовность выгрузки
+	StatusURL string `json:"status_url"`
+	// DownloadURL ссылка на архив, появляется после подготовки выгрузки
+	DownloadURL string `json:"download_url,omitempty"`
+}
+
+// ExportFile файл выгрузки
+type ExportFile struct {
+	Name        string
+	ContentType string
+	Content     []byte
+}
+
+// ExportResult результат запроса выгрузки: готовый файл для небольших аккаунтов
+// или выгрузка, которая готовится в фоне
+type ExportResult struct {
+	File   *ExportFile
+	Export *DataExport
+}
+
+// UserData персональные данные пользователя для выгрузки
+type UserData struct {
+	ExportedAt   time.Time        `json:"exported_at"`
+	Profile      Profile          `json:"profile"`
+	Balances     map[string]int   `json:"balances"`
+	Completions  []TaskCompletion `json:"completions"`
+	Transactions []Transaction    `json:"transactions"`
+	Referrals    ExportReferrals  `json:"referrals"`
+	Sessions     ExportSessions   `json:"sessions"`
+}
+
+// ExportReferrals реферальные данные пользователя
+type ExportReferrals struct {
+	ReferCode string `json:"refer_code,omitempty"`
+	// ReferredBy ID пригласившего пользователя
+	ReferredBy *int64 `json:"referred_by,omitempty"`
+	// Stats воронка по реферальному коду пользователя
+	Stats *ReferralStats `json:"stats,omitempty"`
+	// InvitedUserIDs пользователи, зарегистрированные по приглашению
+	InvitedUserIDs []int64 `json:"invited_user_ids"`
+}
+
+// ExportSessions данные о входе. JWT не хранятся на сервере, поэтому в выгрузку входят
+// способы входа и выданные одноразовые токены
+type ExportSessions struct {
+	TwoFactorEnabled  bool              `json:"two_factor_enabled"`
+	RecoveryCodesLeft int               `json:"recovery_codes_left"`
+	Identities        []ExportIdentity  `json:"identities"`
+	Tokens            []ExportAuthToken `json:"tokens"`
+}
+
+// ExportIdentity привязанная учетная запись внешнего провайдера
+type ExportIdentity struct {
+	Provider string    `json:"provider"`
+	Subject  string    `json:"subject"`
+	Email    string    `json:"email,omitempty"`
+	LinkedAt time.Time `json:"linked_at"`
+}
+
+// ExportAuthToken выданный одноразовый токен; сам токен не хранится
+type ExportAuthToken struct {
+	Purpose   string     `json:"purpose"`
+	Email     string     `json:"email,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+}
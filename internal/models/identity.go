@@ -0,0 +1,83 @@
+package models
+
+import (
+	"strconv"
+	"time"
+)
+
+// Провайдеры внешнего входа
+const (
+	// IdentityProviderOIDC вход через провайдера OpenID Connect
+	IdentityProviderOIDC = "oidc"
+	// IdentityProviderTelegram вход через виджет Telegram Login; subject - ID аккаунта Telegram
+	IdentityProviderTelegram = "telegram"
+)
+
+// ExternalUserCreate пользователь, создаваемый при первом входе через внешнего провайдера
+type ExternalUserCreate struct {
+	Username string
+	// Password хэш случайного пароля: войти по паролю можно только после его сброса
+	Password string
+	Email    string
+	// EmailVerified адрес подтвержден провайдером
+	EmailVerified bool
+	Provider      string
+	Subject       string
+}
+
+// OIDCState незавершенный вход через OpenID Connect
+type OIDCState struct {
+	State        string    `db:"state"`
+	Nonce        string    `db:"nonce"`
+	CodeVerifier string    `db:"code_verifier"`
+	ExpiresAt    time.Time `db:"expires_at"`
+}
+
+// OIDCLogin начало входа через OpenID Connect
+type OIDCLogin struct {
+	// URL страница входа провайдера
+	URL string `json:"url"`
+	// State привязывает ответ провайдера к браузеру, начавшему вход
+	State string `json:"-"`
+}
+
+// OIDCCallback ответ провайдера на адрес возврата
+type OIDCCallback struct {
+	Code  string
+	State string
+	// CookieState state, сохраненный в cookie браузера при начале входа
+	CookieState      string
+	Error            string
+	ErrorDescription string
+}
+
+// TelegramLogin данные, которые виджет Telegram Login передает после входа пользователя
+type TelegramLogin struct {
+	ID        int64  `json:"id"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Username  string `json:"username"`
+	PhotoURL  string `json:"photo_url"`
+	AuthDate  int64  `json:"auth_date"`
+	Hash      string `json:"hash"`
+}
+
+// Fields возвращает поля в том виде, в каком их подписывает Telegram; пустые поля виджет не передает
+func (t *TelegramLogin) Fields() map[string]string {
+	fields := map[string]string{
+		"id":        strconv.FormatInt(t.ID, 10),
+		"auth_date": strconv.FormatInt(t.AuthDate, 10),
+		"hash":      t.Hash,
+	}
+	for key, value := range map[string]string{
+		"first_name": t.FirstName,
+		"last_name":  t.LastName,
+		"username":   t.Username,
+		"photo_url":  t.PhotoURL,
+	} {
+		if value != "" {
+			fields[key] = value
+		}
+	}
+	return fields
+}
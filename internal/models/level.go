@@ -0,0 +1,9 @@
+package models
+
+// LevelCurve кривая уровней пользователя.
+// Thresholds[i] количество заработанных за все время баллов, необходимое для уровня i+2; первый уровень есть у всех.
+type LevelCurve struct {
+	Thresholds []int
+	// LevelUpBonus бонус в coins за каждый достигнутый уровень (0 - без бонуса)
+	LevelUpBonus int
+}
@@ -0,0 +1,38 @@
+package models
+
+// DeletedUsernamePrefix начало имени удаленного пользователя, за ним следует ID.
+// Такие имена нельзя занять при регистрации.
+const DeletedUsernamePrefix = "deleted_user_"
+
+// Profile профиль текущего пользователя
+type Profile struct {
+	UserID      int64  `json:"user_id"`
+	Username    string `json:"username"`
+	DisplayName string `json:"display_name"`
+	AvatarURL   string `json:"avatar_url"`
+	Email       string `json:"email"`
+	// EmailVerified адрес подтвержден переходом по ссылке из письма
+	EmailVerified bool `json:"email_verified"`
+	// PendingEmail новый адрес, ожидающий подтверждения по ссылке из письма
+	PendingEmail string `json:"pending_email,omitempty"`
+	// TelegramID привязанный аккаунт Telegram
+	TelegramID *int64 `json:"telegram_id,omitempty"`
+}
+
+// ProfileUpdate изменение профиля. Поля без значения не меняются, пустая строка очищает
+// отображаемое имя и аватар. Новый адрес начинает действовать после подтверждения по ссылке из письма.
+type ProfileUpdate struct {
+	DisplayName *string `json:"display_name"`
+	AvatarURL   *string `json:"avatar_url"`
+	Email       *string `json:"email"`
+}
+
+// EmailChangeRequest подтверждение нового адреса по токену из письма
+type EmailChangeRequest struct {
+	Token string `json:"token"`
+}
+
+// AccountDeleteRequest удаление аккаунта; Confirm должно совпадать с именем пользователя
+type AccountDeleteRequest struct {
+	Confirm string `json:"confirm"`
+}
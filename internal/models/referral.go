@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// ReferralClick переход по реферальной ссылке
+type ReferralClick struct {
+	ClickID int64 `json:"click_id" db:"click_id"`
+	// Token случайный идентификатор перехода, сохраняется в cookie атрибуции
+	Token     string    `json:"-" db:"token"`
+	ReferCode string    `json:"refer_code" db:"refer_code"`
+	UserAgent string    `json:"user_agent" db:"user_agent"`
+	Referrer  string    `json:"referrer" db:"referrer"`
+	ClickedAt time.Time `json:"clicked_at" db:"clicked_at"`
+}
+
+// ReferralStats воронка по реферальному коду: переходы, регистрации, первые выполненные задания
+type ReferralStats struct {
+	// UserID владелец реферального кода
+	UserID               int64  `json:"-"`
+	ReferCode            string `json:"refer_code"`
+	Clicks               int64  `json:"clicks"`
+	Signups              int64  `json:"signups"`
+	FirstTaskCompletions int64  `json:"first_task_completions"`
+}
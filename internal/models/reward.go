@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Статусы выдачи награды
+const (
+	RedemptionPending    = "pending"
+	RedemptionProcessing = "processing"
+	RedemptionFulfilled  = "fulfilled"
+	RedemptionCancelled  = "cancelled"
+)
+
+// Reward позиция каталога наград
+type Reward struct {
+	RewardID     int64     `json:"reward_id" db:"reward_id"`
+	Title        string    `json:"title" db:"title"`
+	Description  string    `json:"description,omitempty" db:"description"`
+	Cost         int       `json:"cost" db:"cost"`
+	Stock        *int      `json:"stock" db:"stock"`
+	PerUserLimit *int      `json:"per_user_limit" db:"per_user_limit"`
+	Active       bool      `json:"active" db:"active"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// RewardCreate структура для добавления награды в каталог.
+// Пустые Stock и PerUserLimit означают отсутствие ограничений.
+type RewardCreate struct {
+	Title        string `json:"title" db:"title" binding:"required"`
+	Description  string `json:"description" db:"description"`
+	Cost         int    `json:"cost" db:"cost"`
+	Stock        *int   `json:"stock" db:"stock"`
+	PerUserLimit *int   `json:"per_user_limit" db:"per_user_limit"`
+}
+
+// Redemption обмен баллов на награду
+type Redemption struct {
+	RedemptionID int64     `json:"redemption_id" db:"redemption_id"`
+	RewardID     int64     `json:"reward_id" db:"reward_id"`
+	RewardTitle  string    `json:"reward_title" db:"title"`
+	UserID       int64     `json:"user_id" db:"user_id"`
+	Cost         int       `json:"cost" db:"cost"`
+	Status       string    `json:"status" db:"status"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,50 @@
+package models
+
+import "time"
+
+// Статусы сезона
+const (
+	SeasonOpen   = "open"
+	SeasonClosed = "closed"
+)
+
+// Season соревновательный сезон, в течение которого пользователи набирают очки
+type Season struct {
+	SeasonID  int64      `json:"season_id" db:"season_id"`
+	Title     string     `json:"title" db:"title"`
+	Status    string     `json:"status" db:"status"`
+	StartsAt  time.Time  `json:"starts_at" db:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty" db:"ends_at"`
+	ClosedAt  *time.Time `json:"closed_at,omitempty" db:"closed_at"`
+	ClosedBy  *int64     `json:"closed_by,omitempty" db:"closed_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+}
+
+// SeasonCreate структура для открытия сезона
+type SeasonCreate struct {
+	Title  string     `json:"title"`
+	EndsAt *time.Time `json:"ends_at"`
+}
+
+// SeasonStanding место пользователя в сезоне
+type SeasonStanding struct {
+	Rank     int    `json:"rank" db:"rank"`
+	UserID   *int64 `json:"user_id" db:"user_id"`
+	Username string `json:"username" db:"username"`
+	Score    int    `json:"score" db:"score"`
+	Prize    int    `json:"prize,omitempty" db:"prize"`
+}
+
+// SeasonClose закрытие сезона администратором.
+// Prizes[i] приз в coins за место i+1; пустой список означает закрытие без призов.
+type SeasonClose struct {
+	SeasonID int64 `json:"-"`
+	Prizes   []int `json:"prizes"`
+	AdminID  int64 `json:"-"`
+}
+
+// SeasonResult сезон вместе с местами участников
+type SeasonResult struct {
+	Season    Season           `json:"season"`
+	Standings []SeasonStanding `json:"standings"`
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Streak серия дней подряд, в каждый из которых пользователь выполнил хотя бы одно задание
+type Streak struct {
+	Current int `json:"current" db:"current_streak"`
+	Longest int `json:"longest" db:"longest_streak"`
+	// LastActiveDay последний день серии
+	LastActiveDay *time.Time `json:"last_active_day,omitempty" db:"last_active_day"`
+	// FreezesUsed количество израсходованных заморозок, закрывающих пропущенные дни
+	FreezesUsed int `json:"-" db:"freezes_used"`
+	FreezesLeft int `json:"freezes_left"`
+	// BonusPercent надбавка к награде за задание при текущей серии
+	BonusPercent int `json:"bonus_percent"`
+}
+
+// StreakRules настройки бонуса за серию
+type StreakRules struct {
+	// BonusPercent надбавка к награде за каждый день серии после первого, в процентах
+	BonusPercent int
+	// MaxBonusPercent максимальная надбавка, в процентах
+	MaxBonusPercent int
+	// Freezes количество заморозок, доступных пользователю
+	Freezes int
+}
@@ -1,14 +1,119 @@
 package models
 
+import "time"
+
 type Task struct {
 	TaskID      int64  `json:"task_id" validate:"required"`
 	Title       string `json:"title" validate:"required"`
 	Description string `json:"description,omitempty"`
 	Price       int    `json:"price" db:"price"`
+	// Prices стоимость задания по валютам, Price дублирует стоимость в coins
+	Prices map[string]int `json:"prices,omitempty"`
+	Type   string         `json:"type" db:"task_type"`
+	// CampaignID кампания, к которой относится задание
+	CampaignID *int64 `json:"campaign_id,omitempty" db:"campaign_id"`
+	// Category код категории задания
+	Category string   `json:"category,omitempty" db:"category"`
+	Tags     []string `json:"tags,omitempty" db:"tags"`
+	// Completions количество неотмененных выполнений, по нему сортируются популярные задания
+	Completions int `json:"completions" db:"completions"`
 }
 
 type TaskCreate struct {
 	Title       string `json:"title" db:"title" binding:"required"`
 	Description string `json:"description" db:"description"`
 	Price       int    `json:"price" db:"price"`
+	// Prices стоимость задания по валютам; если не задана, задание оплачивается Price в coins
+	Prices map[string]int `json:"prices,omitempty"`
+	// Type тип задания, по которому подбираются множители наград; по умолчанию general
+	Type string `json:"type" db:"task_type"`
+	// CampaignID кампания, из бюджета которой оплачивается задание
+	CampaignID *int64 `json:"campaign_id" db:"campaign_id"`
+	// Category код категории задания; пустой код означает задание без категории
+	Category string `json:"category"`
+	// Tags произвольные метки задания, приводятся к нижнему регистру
+	Tags []string `json:"tags"`
+	// ByAdmin задание создает администратор; только он может привязать задание к кампании
+	ByAdmin bool `json:"-"`
+}
+
+// Сортировки списка заданий
+const (
+	TaskSortID        = "id"
+	TaskSortNewest    = "newest"
+	TaskSortPriceAsc  = "price_asc"
+	TaskSortPriceDesc = "price_desc"
+	TaskSortPopular   = "popular"
+)
+
+// TaskFilter параметры поиска, сортировки и постраничного вывода заданий.
+// Задание должно иметь все метки из Tags.
+type TaskFilter struct {
+	Query    string
+	Category string
+	Tags     []string
+	Sort     string
+	Limit    int
+	Cursor   string
+	// After позиция, после которой начинается страница; заполняется из Cursor
+	After *TaskCursor
+}
+
+// TaskCursor позиция в списке заданий: значение ключа сортировки и ID последнего задания страницы
+type TaskCursor struct {
+	Sort   string `json:"s"`
+	Value  int64  `json:"v,omitempty"`
+	TaskID int64  `json:"id"`
+}
+
+// TaskPage страница списка заданий. Пустой NextCursor означает, что страница последняя.
+type TaskPage struct {
+	Tasks      []Task `json:"tasks"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// Category категория заданий
+type Category struct {
+	CategoryID int64     `json:"category_id" db:"category_id"`
+	Code       string    `json:"code" db:"code"`
+	Title      string    `json:"title" db:"title"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+// CategoryCreate структура для создания категории заданий
+type CategoryCreate struct {
+	Code  string `json:"code"`
+	Title string `json:"title"`
+}
+
+// TaskCompletion запись о выполнении задания пользователем
+type TaskCompletion struct {
+	CompletionID int64      `json:"completion_id" db:"id"`
+	UserID       int64      `json:"user_id" db:"user_id"`
+	TaskID       int64      `json:"task_id" db:"task_id"`
+	TaskTitle    string     `json:"task_title" db:"title"`
+	CompletedAt  time.Time  `json:"completed_at" db:"completed_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedBy    *int64     `json:"revoked_by,omitempty" db:"revoked_by"`
+	RevokeReason string     `json:"revoke_reason,omitempty" db:"revoke_reason"`
+	// MultiplierPercent множитель награды, примененный при выполнении (100 - без множителя)
+	MultiplierPercent int    `json:"multiplier_percent" db:"multiplier_percent"`
+	BoostID           *int64 `json:"boost_id,omitempty" db:"boost_id"`
+	// StreakBonusPercent надбавка за серию, примененная при выполнении
+	StreakBonusPercent int `json:"streak_bonus_percent" db:"streak_bonus_percent"`
+}
+
+// Revocation отмена выполнения задания администратором
+type Revocation struct {
+	CompletionID int64  `json:"completion_id"`
+	Reason       string `json:"reason"`
+	AdminID      int64  `json:"-"`
+}
+
+// RevocationResult результат отмены выполнения: компенсирующие записи журнала
+// и сумма, которой не хватило на балансах (баланс при этом уходит в минус).
+type RevocationResult struct {
+	Completion TaskCompletion `json:"completion"`
+	Clawbacks  []Transaction  `json:"clawbacks"`
+	Shortfall  int            `json:"shortfall"`
 }
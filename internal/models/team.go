@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// Роли участников команды
+const (
+	TeamRoleCaptain = "captain"
+	TeamRoleMember  = "member"
+)
+
+// Team команда пользователей.
+// Код приглашения виден только участникам команды.
+type Team struct {
+	TeamID     int64        `json:"team_id" db:"team_id"`
+	Name       string       `json:"name" db:"name"`
+	InviteCode string       `json:"invite_code,omitempty" db:"invite_code"`
+	CreatedAt  time.Time    `json:"created_at" db:"created_at"`
+	Members    []TeamMember `json:"members"`
+}
+
+// TeamMember участник команды
+type TeamMember struct {
+	UserID   int64     `json:"user_id" db:"user_id"`
+	Username string    `json:"username" db:"username"`
+	Role     string    `json:"role" db:"role"`
+	JoinedAt time.Time `json:"joined_at" db:"joined_at"`
+}
+
+// TeamCreate структура для создания команды
+type TeamCreate struct {
+	Name   string `json:"name"`
+	UserID int64  `json:"-"`
+}
+
+// TeamStanding строка таблицы лидеров команд.
+// Score сумма наград участников за задания, выполненные после вступления в команду.
+type TeamStanding struct {
+	TeamID  int64  `json:"team_id" db:"team_id"`
+	Name    string `json:"name" db:"name"`
+	Members int    `json:"members" db:"members"`
+	Score   int    `json:"score" db:"score"`
+}
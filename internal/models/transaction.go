@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Виды операций в журнале изменений баланса
+const (
+	TransactionTaskReward       = "task_reward"
+	TransactionReferralBonus    = "referral_bonus"
+	TransactionAdjustment       = "admin_adjustment"
+	TransactionReversal         = "reversal"
+	TransactionExpiry           = "expiry"
+	TransactionTransferIn       = "transfer_in"
+	TransactionTransferOut      = "transfer_out"
+	TransactionRedemption       = "redemption"
+	TransactionRedemptionRefund = "redemption_refund"
+	TransactionAchievementBonus = "achievement_bonus"
+	TransactionLevelBonus       = "level_bonus"
+	TransactionSeasonPrize      = "season_prize"
+)
+
+// Transaction запись журнала изменений баланса пользователя.
+// Amount положителен для начислений и отрицателен для списаний.
+type Transaction struct {
+	TransactionID  int64     `json:"transaction_id" db:"transaction_id"`
+	UserID         int64     `json:"user_id" db:"user_id"`
+	Amount         int       `json:"amount" db:"amount"`
+	Currency       string    `json:"currency" db:"currency"`
+	BalanceAfter   int       `json:"balance_after" db:"balance_after"`
+	Kind           string    `json:"kind" db:"kind"`
+	CounterpartyID *int64    `json:"counterparty_id,omitempty" db:"counterparty_id"`
+	Reference      string    `json:"reference,omitempty" db:"reference"`
+	Reason         string    `json:"reason,omitempty" db:"reason"`
+	CreatedBy      *int64    `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at"`
+	// SeasonID сезон, в очки которого вошла операция; для отмены начисления - сезон исходного начисления
+	SeasonID *int64 `json:"-" db:"season_id"`
+}
+
+// Коды причин ручной корректировки баланса
+const (
+	AdjustmentGoodwill     = "goodwill"
+	AdjustmentCorrection   = "correction"
+	AdjustmentCompensation = "compensation"
+	AdjustmentFraud        = "fraud"
+	AdjustmentPromotion    = "promotion"
+	AdjustmentOther        = "other"
+)
+
+// Adjustment ручная корректировка баланса администратором.
+// Положительный Amount начисляет баллы, отрицательный - списывает.
+type Adjustment struct {
+	UserID    int64  `json:"user_id"`
+	Amount    int    `json:"amount"`
+	Reason    string `json:"reason"`
+	Reference string `json:"reference,omitempty"`
+	AdminID   int64  `json:"-"`
+}
+
+// Transfer перевод баллов между пользователями
+type Transfer struct {
+	FromUserID int64 `json:"from_user_id"`
+	ToUserID   int64 `json:"to_user_id"`
+	Amount     int   `json:"amount"`
+}
+
+// TransferLimits ограничения на переводы между пользователями.
+// Нулевой DailyLimit отключает дневной лимит.
+type TransferLimits struct {
+	DailyLimit int
+	MinBalance int
+}
+
+// TransferResult записи журнала по обе стороны перевода
+type TransferResult struct {
+	Outgoing Transaction `json:"outgoing"`
+	Incoming Transaction `json:"incoming"`
+}
+
+// PointExpiration баллы, которые сгорят в указанный день
+type PointExpiration struct {
+	Amount    int       `json:"amount"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -10,12 +10,32 @@ type User struct {
 	Balance   int            `json:"balance" db:"Balance"`
 	ReferCode *string        `json:"refer_code" db:"refer_code"`
 	ReferFrom *int           `json:"refer_from" db:"refer_from"`
+	// EmailVerified адрес подтвержден переходом по ссылке из письма
+	EmailVerified bool `json:"email_verified" db:"email_verified"`
+	// TokenVersion версия сессий; JWT с другой версией недействительны
+	TokenVersion int `json:"-" db:"token_version"`
+	// LifetimePoints баллы в coins, заработанные за все время
+	LifetimePoints int `json:"lifetime_points" db:"lifetime_points"`
+	// Level уровень по заработанным за все время баллам
+	Level int `json:"level"`
+	// NextLevelAt заработанные баллы, необходимые для следующего уровня
+	NextLevelAt *int `json:"next_level_at,omitempty"`
+	// Balances балансы по валютам
+	Balances map[string]int `json:"balances,omitempty"`
+	// Streak серия дней подряд с выполненными заданиями
+	Streak *Streak `json:"streak,omitempty"`
+	// UpcomingExpirations ближайшие сгорания баллов
+	UpcomingExpirations []PointExpiration `json:"upcoming_expirations,omitempty"`
+	// TelegramID привязанный аккаунт Telegram, по нему интеграции проверяют задания в Telegram
+	TelegramID *int64 `json:"telegram_id,omitempty"`
 }
 
 // структура для входа в систему
 type SignIn struct {
 	Username string `json:"username" binding:"required"`
 	Password string `json:"password" binding:"required"`
+	// IP адрес клиента, по нему считаются неудачные попытки входа
+	IP string `json:"-"`
 }
 
 // структура для создания нового пользователя в системе
@@ -23,6 +43,8 @@ type CreateUser struct {
 	Username string `json:"username" db:"username"`
 	Password string `json:"password" binding:"required"`
 	Email    string `json:"email" validate:"required,email"`
+	// ReferralClickToken переход по реферальной ссылке, к которому привязывается регистрация
+	ReferralClickToken string `json:"-"`
 }
 
 // возвращает пользователя по имени и паролю
@@ -0,0 +1,247 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	checkAchievementCodeQuery = `SELECT EXISTS(SELECT 1 FROM achievements WHERE code = $1)`
+	addAchievementQuery       = `
+    INSERT INTO achievements (code, title, description, metric, threshold, bonus, bonus_currency)
+    VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7) RETURNING achievement_id`
+	selectAchievementsQuery = `
+    SELECT a.achievement_id, a.code, a.title, COALESCE(a.description, ''), a.metric, a.threshold, a.bonus,
+        a.bonus_currency, a.active, a.created_at
+    FROM achievements a`
+	getAchievementsQuery = selectAchievementsQuery + ` WHERE a.active ORDER BY a.metric, a.threshold, a.achievement_id`
+	// Активные достижения, которых у пользователя еще нет
+	getPendingAchievementsQuery = selectAchievementsQuery + `
+    WHERE a.active AND NOT EXISTS(SELECT 1 FROM user_achievements ua
+        WHERE ua.achievement_id = a.achievement_id AND ua.user_id = $1)
+    ORDER BY a.achievement_id`
+	awardAchievementQuery = `
+    INSERT INTO user_achievements (user_id, achievement_id) VALUES ($1, $2)
+    ON CONFLICT (user_id, achievement_id) DO NOTHING RETURNING awarded_at`
+	getUserAchievementsQuery = `
+    SELECT a.achievement_id, a.code, a.title, COALESCE(a.description, ''), a.metric, a.threshold, a.bonus,
+        a.bonus_currency, a.active, a.created_at, ua.awarded_at
+    FROM user_achievements ua JOIN achievements a ON a.achievement_id = ua.achievement_id
+    WHERE ua.user_id = $1 ORDER BY ua.awarded_at, a.achievement_id`
+)
+
+// achievementMetricQueries запросы, вычисляющие значение метрики пользователя
+var achievementMetricQueries = map[string]string{
+	models.AchievementMetricCompletedTasks: `SELECT COUNT(*) FROM task_complete WHERE user_id = $1 AND revoked_at IS NULL`,
+	models.AchievementMetricReferrals:      `SELECT COUNT(*) FROM users WHERE refer_from = $1::text`,
+	models.AchievementMetricLongestStreak: `
+    SELECT COALESCE((SELECT longest_streak FROM user_streaks WHERE user_id = $1), 0)`,
+}
+
+// PostgresAchievementRepository реализует репозиторий достижений для PostgreSQL
+type PostgresAchievementRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	ledger *Ledger
+}
+
+// NewPostgresAchievementRepository создает новый экземпляр репозитория достижений
+func NewPostgresAchievementRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger) *PostgresAchievementRepository {
+	return &PostgresAchievementRepository{db: db, logger: logger, ledger: ledger}
+}
+
+// CreateAchievement добавляет описание достижения
+func (r *PostgresAchievementRepository) CreateAchievement(ctx context.Context, achievement *models.AchievementCreate) (int64, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, checkAchievementCodeQuery, achievement.Code).Scan(&exists); err != nil {
+		r.logger.Error("failed to check achievement code", zap.String("code", achievement.Code), zap.Error(err))
+		return 0, errors.NewInternal("failed to check achievement code", err)
+	}
+	if exists {
+		return 0, errors.NewAlreadyExists(fmt.Sprintf("achievement with code %q already exists", achievement.Code), nil)
+	}
+	if err := r.ledger.checkCurrency(ctx, r.db, achievement.BonusCurrency); err != nil {
+		return 0, err
+	}
+
+	var achievementID int64
+	err := r.db.QueryRowContext(ctx, addAchievementQuery, achievement.Code, achievement.Title, achievement.Description,
+		achievement.Metric, achievement.Threshold, achievement.Bonus, achievement.BonusCurrency).Scan(&achievementID)
+	if err != nil {
+		r.logger.Error("Cannot create achievement", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create achievement", err)
+	}
+	return achievementID, nil
+}
+
+// GetAchievements возвращает активные достижения
+func (r *PostgresAchievementRepository) GetAchievements(ctx context.Context) ([]models.Achievement, error) {
+	rows, err := r.db.QueryContext(ctx, getAchievementsQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getAchievementsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var achievements []models.Achievement
+	for rows.Next() {
+		var achievement models.Achievement
+		if err := scanAchievement(rows, &achievement); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		achievements = append(achievements, achievement)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return achievements, nil
+}
+
+// GetUserAchievements возвращает достижения, выданные пользователю
+func (r *PostgresAchievementRepository) GetUserAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	rows, err := r.db.QueryContext(ctx, getUserAchievementsQuery, userID)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getUserAchievementsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var achievements []models.UserAchievement
+	for rows.Next() {
+		var achievement models.UserAchievement
+		if err := scanAchievement(rows, &achievement.Achievement, &achievement.AwardedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		achievements = append(achievements, achievement)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return achievements, nil
+}
+
+// AwardAchievements проверяет условия еще не полученных достижений и выдает выполненные.
+// Повторная выдача невозможна благодаря первичному ключу user_achievements, поэтому вызов идемпотентен.
+// Бонус за достижение начисляется в той же транзакции, что и выдача.
+func (r *PostgresAchievementRepository) AwardAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return nil, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка строки пользователя не дает параллельным проверкам выдать бонус дважды
+	if _, err := r.ledger.lockBalance(ctx, tx, userID); err != nil {
+		return nil, err
+	}
+
+	pending, err := r.pendingAchievements(ctx, tx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	metrics := make(map[string]int)
+	var awarded []models.UserAchievement
+	for _, achievement := range pending {
+		value, ok := metrics[achievement.Metric]
+		if !ok {
+			query, known := achievementMetricQueries[achievement.Metric]
+			if !known {
+				r.logger.Warn("unknown achievement metric", zap.String("code", achievement.Code), zap.String("metric", achievement.Metric))
+				continue
+			}
+			if err := tx.QueryRowContext(ctx, query, userID).Scan(&value); err != nil {
+				r.logger.Error("failed to compute achievement metric", zap.String("metric", achievement.Metric), zap.Error(err))
+				return nil, errors.NewInternal("failed to compute achievement metric", err)
+			}
+			metrics[achievement.Metric] = value
+		}
+		if value < achievement.Threshold {
+			continue
+		}
+
+		userAchievement := models.UserAchievement{Achievement: achievement}
+		err := tx.QueryRowContext(ctx, awardAchievementQuery, userID, achievement.AchievementID).Scan(&userAchievement.AwardedAt)
+		if err == sql.ErrNoRows {
+			continue
+		} else if err != nil {
+			r.logger.Error("failed to award achievement", zap.Int64("user_id", userID), zap.String("code", achievement.Code), zap.Error(err))
+			return nil, errors.NewInternal("failed to award achievement", err)
+		}
+
+		if achievement.Bonus > 0 {
+			bonus := models.Transaction{
+				UserID:    userID,
+				Amount:    achievement.Bonus,
+				Currency:  achievement.BonusCurrency,
+				Kind:      models.TransactionAchievementBonus,
+				Reference: achievementReference(achievement.AchievementID),
+			}
+			if err := r.ledger.apply(ctx, tx, &bonus); err != nil {
+				return nil, err
+			}
+		}
+		awarded = append(awarded, userAchievement)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return nil, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	for _, achievement := range awarded {
+		r.logger.Info("Achievement awarded", zap.Int64("user_id", userID), zap.String("code", achievement.Code))
+	}
+	return awarded, nil
+}
+
+// pendingAchievements возвращает активные достижения, которых у пользователя еще нет
+func (r *PostgresAchievementRepository) pendingAchievements(ctx context.Context, tx *sql.Tx, userID int64) ([]models.Achievement, error) {
+	rows, err := tx.QueryContext(ctx, getPendingAchievementsQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch pending achievements", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch pending achievements", err)
+	}
+	defer rows.Close()
+
+	var achievements []models.Achievement
+	for rows.Next() {
+		var achievement models.Achievement
+		if err := scanAchievement(rows, &achievement); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		achievements = append(achievements, achievement)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return achievements, nil
+}
+
+// scanAchievement сканирует описание достижения и дополнительные колонки строки
+func scanAchievement(rows *sql.Rows, achievement *models.Achievement, extra ...interface{}) error {
+	dest := []interface{}{&achievement.AchievementID, &achievement.Code, &achievement.Title, &achievement.Description,
+		&achievement.Metric, &achievement.Threshold, &achievement.Bonus, &achievement.BonusCurrency,
+		&achievement.Active, &achievement.CreatedAt}
+	return rows.Scan(append(dest, extra...)...)
+}
+
+// achievementReference ссылка на достижение для записи журнала
+func achievementReference(achievementID int64) string {
+	return fmt.Sprintf("achievement:%d", achievementID)
+}
@@ -0,0 +1,119 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	apiKeyColumns     = `api_key_id, name, prefix, scopes, created_by, created_at, last_used_at, revoked_at`
+	createAPIKeyQuery = `
+    INSERT INTO api_keys (name, prefix, key_hash, scopes, created_by)
+    VALUES ($1, $2, $3, $4, $5) RETURNING ` + apiKeyColumns
+	getAPIKeysQuery      = `SELECT ` + apiKeyColumns + ` FROM api_keys ORDER BY api_key_id DESC`
+	getAPIKeyByHashQuery = `SELECT ` + apiKeyColumns + ` FROM api_keys WHERE key_hash = $1`
+	revokeAPIKeyQuery    = `UPDATE api_keys SET revoked_at = COALESCE(revoked_at, now()) WHERE api_key_id = $1`
+	// Время последнего использования обновляется не чаще раза в минуту, чтобы частые запросы не нагружали базу
+	touchAPIKeyQuery = `
+    UPDATE api_keys SET last_used_at = now()
+    WHERE api_key_id = $1 AND (last_used_at IS NULL OR last_used_at < now() - interval '1 minute')`
+)
+
+// PostgresAPIKeyRepository реализует репозиторий API-ключей для PostgreSQL
+type PostgresAPIKeyRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewPostgresAPIKeyRepository создает новый экземпляр репозитория API-ключей
+func NewPostgresAPIKeyRepository(db *sql.DB, logger *zap.Logger) *PostgresAPIKeyRepository {
+	return &PostgresAPIKeyRepository{db: db, logger: logger}
+}
+
+// CreateAPIKey сохраняет хэш нового API-ключа
+func (r *PostgresAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKeyCreate) (models.APIKey, error) {
+	apiKey, err := scanAPIKey(r.db.QueryRowContext(ctx, createAPIKeyQuery, key.Name, key.Prefix, key.KeyHash,
+		pq.Array(key.Scopes), key.AdminID))
+	if err != nil {
+		r.logger.Error("Cannot create api key", zap.Error(err))
+		return models.APIKey{}, errors.NewInternal("Cannot create api key", err)
+	}
+	return apiKey, nil
+}
+
+// GetAPIKeys возвращает все API-ключи, начиная с последнего
+func (r *PostgresAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, getAPIKeysQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getAPIKeysQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var keys []models.APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return keys, nil
+}
+
+// GetAPIKeyByHash возвращает API-ключ по хэшу, в том числе отозванный
+func (r *PostgresAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	key, err := scanAPIKey(r.db.QueryRowContext(ctx, getAPIKeyByHashQuery, keyHash))
+	if err == sql.ErrNoRows {
+		return models.APIKey{}, errors.NewNotFound("api key not found", err)
+	} else if err != nil {
+		r.logger.Error("Error fetching api key", zap.Error(err))
+		return models.APIKey{}, errors.NewInternal("Error fetching api key", err)
+	}
+	return key, nil
+}
+
+// RevokeAPIKey отзывает API-ключ; повторный отзыв не меняет время отзыва
+func (r *PostgresAPIKeyRepository) RevokeAPIKey(ctx context.Context, apiKeyID int64) error {
+	result, err := r.db.ExecContext(ctx, revokeAPIKeyQuery, apiKeyID)
+	if err != nil {
+		r.logger.Error("Cannot revoke api key", zap.Int64("api_key_id", apiKeyID), zap.Error(err))
+		return errors.NewInternal("Cannot revoke api key", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return errors.NewInternal("Failed to get rows affected", err)
+	} else if affected == 0 {
+		return errors.NewNotFound(fmt.Sprintf("api key with id %d not found", apiKeyID), nil)
+	}
+	return nil
+}
+
+// TouchAPIKey отмечает время использования API-ключа
+func (r *PostgresAPIKeyRepository) TouchAPIKey(ctx context.Context, apiKeyID int64) error {
+	if _, err := r.db.ExecContext(ctx, touchAPIKeyQuery, apiKeyID); err != nil {
+		r.logger.Error("Cannot update api key last use", zap.Int64("api_key_id", apiKeyID), zap.Error(err))
+		return errors.NewInternal("Cannot update api key last use", err)
+	}
+	return nil
+}
+
+// scanAPIKey читает API-ключ из строки результата
+func scanAPIKey(row interface{ Scan(dest ...any) error }) (models.APIKey, error) {
+	var key models.APIKey
+	err := row.Scan(&key.APIKeyID, &key.Name, &key.Prefix, pq.Array(&key.Scopes), &key.CreatedBy, &key.CreatedAt,
+		&key.LastUsedAt, &key.RevokedAt)
+	return key, err
+}
@@ -8,18 +8,150 @@ import (
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/service/refercode"
 	"go.uber.org/zap"
+	"time"
 )
 
 const (
 	// Запрос для создания пользователя
 	CreateUserQuery = `
-    INSERT INTO users (username, password, refer_code) VALUES ($1, $2, $3) RETURNING user_id`
+    INSERT INTO users (username, password, email, refer_code, refer_from, referral_click_id)
+    VALUES ($1, $2, $3, $4, $5, $6) RETURNING user_id`
+	// Получение владельца реферальной ссылки по переходу
+	GetClickReferrerQuery = `
+    SELECT c.click_id, u.user_id FROM referral_clicks c JOIN users u ON u.refer_code = c.refer_code WHERE c.token = $1`
 	// Проверка существования пользователя
-	CheckUserExistsQuery = `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 OR email = $2)`
+	CheckUserExistsQuery = `SELECT EXISTS(SELECT 1 FROM users WHERE username = $1 OR lower(email) = lower($2))`
 	// Получение пользователя по имени пользователя и паролю
 	GetUserQuery = `SELECT user_id, username, password, email FROM users WHERE username = $1 AND password = $2`
 	// Получение пользователя по имени пользователя
-	GetUserByUsernameQuery = `SELECT user_id, username, password, email FROM users WHERE username = $1`
+	GetUserByUsernameQuery = `
+    SELECT user_id, username, password, email, email_verified_at IS NOT NULL, token_version FROM users WHERE username = $1`
+	// Получение пользователя по ID
+	GetAuthUserByIDQuery = `
+    SELECT user_id, username, password, email, email_verified_at IS NOT NULL, token_version FROM users WHERE user_id = $1`
+	// Получение пользователя по адресу электронной почты
+	GetUserByEmailQuery = `
+    SELECT user_id, username, password, email, email_verified_at IS NOT NULL, token_version
+    FROM users WHERE lower(email) = lower($1)`
+	// Текущая версия сессий пользователя
+	GetTokenVersionQuery = `SELECT token_version FROM users WHERE user_id = $1`
+	// Смена пароля с отзывом всех выданных пользователю JWT
+	ResetPasswordQuery = `UPDATE users SET password = $1, token_version = token_version + 1 WHERE user_id = $2`
+	// Смена пароля пользователем с отзывом всех выданных JWT
+	ChangePasswordQuery = `
+    UPDATE users SET password = $1, token_version = token_version + 1 WHERE user_id = $2 RETURNING token_version`
+	// Замена хэша пароля без отзыва сессий
+	UpdatePasswordHashQuery = `UPDATE users SET password = $1 WHERE user_id = $2`
+	// Учет неудачной попытки входа; счетчик начинается заново, если с прошлой неудачи прошло больше $3 секунд
+	RecordLoginFailureQuery = `
+    INSERT INTO login_attempts (scope, key, failures, last_failure_at) VALUES ($1, $2, 1, now())
+    ON CONFLICT (scope, key) DO UPDATE SET
+        failures = CASE WHEN login_attempts.last_failure_at < now() - $3 * interval '1 second'
+            THEN 1 ELSE login_attempts.failures + 1 END,
+        last_failure_at = now()
+    RETURNING failures`
+	// Блокировка входа по ключу на $3 секунд
+	BlockLoginQuery = `
+    UPDATE login_attempts SET locked_until = now() + $3 * interval '1 second' WHERE scope = $1 AND key = $2`
+	// Оставшееся время блокировки входа по имени пользователя или IP-адресу в секундах
+	GetLoginBlockQuery = `
+    SELECT COALESCE(EXTRACT(EPOCH FROM MAX(locked_until) - now()), 0)::float8 FROM login_attempts
+    WHERE (scope = 'username' AND key = $1) OR (scope = 'ip' AND key = $2)`
+	// Сброс неудачных попыток входа
+	ResetLoginFailuresQuery = `DELETE FROM login_attempts WHERE scope = $1 AND key = $2`
+	// Отмена остальных неиспользованных токенов пользователя с тем же назначением
+	RevokeAuthTokensQuery = `
+    UPDATE auth_tokens SET used_at = now() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	// Сохранение выпущенного одноразового токена
+	SaveAuthTokenQuery = `
+    INSERT INTO auth_tokens (token_id, user_id, purpose, email, expires_at) VALUES ($1, $2, $3, NULLIF($4, ''), $5)`
+	// Блокировка одноразового токена перед использованием
+	LockAuthTokenQuery = `
+    SELECT user_id, COALESCE(email, ''), expires_at, used_at IS NOT NULL, expires_at <= now()
+    FROM auth_tokens WHERE token_id = $1 AND purpose = $2 FOR UPDATE`
+	// Отметка об использовании одноразового токена
+	UseAuthTokenQuery = `UPDATE auth_tokens SET used_at = now() WHERE token_id = $1`
+	// Подтверждение адреса, если он не изменился после отправки письма
+	VerifyEmailQuery = `
+    UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE user_id = $1 AND email = $2`
+	// Состояние двухфакторной аутентификации пользователя
+	GetTwoFactorQuery = `
+    SELECT COALESCE(totp_secret, ''), totp_enabled_at IS NOT NULL, totp_last_step,
+        (SELECT count(*) FROM recovery_codes WHERE user_id = u.user_id AND used_at IS NULL)
+    FROM users u WHERE user_id = $1`
+	// Сохранение секрета TOTP до подтверждения подключения
+	SetTOTPSecretQuery = `UPDATE users SET totp_secret = $2 WHERE user_id = $1 AND totp_enabled_at IS NULL`
+	// Включение двухфакторной аутентификации
+	EnableTwoFactorQuery = `
+    UPDATE users SET totp_enabled_at = now(), totp_last_step = $2
+    WHERE user_id = $1 AND totp_secret IS NOT NULL AND totp_enabled_at IS NULL`
+	// Отключение двухфакторной аутентификации
+	DisableTwoFactorQuery = `
+    UPDATE users SET totp_secret = null, totp_enabled_at = null, totp_last_step = 0 WHERE user_id = $1`
+	// Отметка принятого кода TOTP; коды прошлых периодов после этого не принимаются
+	UseTOTPStepQuery = `UPDATE users SET totp_last_step = $2 WHERE user_id = $1 AND totp_last_step < $2`
+	// Удаление резервных кодов пользователя
+	DeleteRecoveryCodesQuery = `DELETE FROM recovery_codes WHERE user_id = $1`
+	// Сохранение резервного кода
+	InsertRecoveryCodeQuery = `INSERT INTO recovery_codes (user_id, code_hash) VALUES ($1, $2)`
+	// Использование резервного кода
+	UseRecoveryCodeQuery = `
+    UPDATE recovery_codes SET used_at = now() WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+	// Получение пользователя по учетной записи внешнего провайдера
+	GetUserByIdentityQuery = `
+    SELECT u.user_id, u.username, u.password, u.email, u.email_verified_at IS NOT NULL, u.token_version
+    FROM user_identities i JOIN users u ON u.user_id = i.user_id WHERE i.provider = $1 AND i.subject = $2`
+	// Привязка учетной записи внешнего провайдера к пользователю; не выполняется, если учетная запись
+	// уже привязана или у пользователя есть другая учетная запись того же провайдера
+	LinkIdentityQuery = `
+    INSERT INTO user_identities (provider, subject, user_id, email) VALUES ($1, $2, $3, NULLIF($4, ''))
+    ON CONFLICT DO NOTHING`
+	// Подтверждение адреса, который уже проверил внешний провайдер
+	MarkEmailVerifiedQuery = `UPDATE users SET email_verified_at = COALESCE(email_verified_at, now()) WHERE user_id = $1`
+	// Удаление незавершенных входов через OpenID Connect с истекшим сроком
+	DeleteExpiredOIDCStatesQuery = `DELETE FROM oidc_login_states WHERE expires_at < now()`
+	// Сохранение незавершенного входа через OpenID Connect
+	SaveOIDCStateQuery = `
+    INSERT INTO oidc_login_states (state, nonce, code_verifier, expires_at) VALUES ($1, $2, $3, $4)`
+	// Использование незавершенного входа через OpenID Connect
+	UseOIDCStateQuery = `DELETE FROM oidc_login_states WHERE state = $1 RETURNING nonce, code_verifier, expires_at, expires_at <= now()`
+	// Профиль пользователя с адресом, ожидающим подтверждения
+	GetProfileQuery = `
+    SELECT u.user_id, u.username, COALESCE(u.display_name, ''), COALESCE(u.avatar_url, ''), COALESCE(u.email, ''),
+        u.email_verified_at IS NOT NULL,
+        COALESCE((SELECT email FROM auth_tokens WHERE user_id = u.user_id AND purpose = $2 AND used_at IS NULL
+            AND expires_at > now() ORDER BY expires_at DESC LIMIT 1), ''),
+        (SELECT subject::bigint FROM user_identities WHERE user_id = u.user_id AND provider = $3)
+    FROM users u WHERE u.user_id = $1 AND u.deleted_at IS NULL`
+	// Изменение профиля; поле меняется, только если передан соответствующий флаг
+	UpdateProfileQuery = `
+    UPDATE users SET
+        display_name = CASE WHEN $2 THEN NULLIF($3, '') ELSE display_name END,
+        avatar_url = CASE WHEN $4 THEN NULLIF($5, '') ELSE avatar_url END
+    WHERE user_id = $1 AND deleted_at IS NULL`
+	// Проверка, что адрес занят другим пользователем
+	CheckEmailTakenQuery = `SELECT EXISTS(SELECT 1 FROM users WHERE lower(email) = lower($1) AND user_id <> $2)`
+	// Смена адреса; новый адрес подтвержден переходом по ссылке из письма
+	ChangeEmailQuery = `UPDATE users SET email = $2, email_verified_at = now() WHERE user_id = $1 AND deleted_at IS NULL`
+	// Блокировка пользователя перед удалением аккаунта
+	LockActiveUserQuery = `SELECT username FROM users WHERE user_id = $1 AND deleted_at IS NULL FOR UPDATE`
+	// Обезличивание пользователя. Строка остается: на нее ссылаются транзакции и начисления,
+	// а refer_from сохраняется, чтобы у пригласившего не изменились реферальная статистика и история наград
+	AnonymizeUserQuery = `
+    UPDATE users SET username = $2::text || user_id, email = null, email_verified_at = null, password = '',
+        display_name = null, avatar_url = null, totp_secret = null, totp_enabled_at = null, totp_last_step = 0,
+        refer_code = null, is_admin = false, token_version = token_version + 1, deleted_at = now()
+    WHERE user_id = $1`
+	// Замена имени удаленного пользователя в итогах сезонов
+	AnonymizeSeasonStandingsQuery = `UPDATE season_standings SET username = $2::text || user_id WHERE user_id = $1`
+	// Удаление учетных записей внешних провайдеров пользователя
+	DeleteUserIdentitiesQuery = `DELETE FROM user_identities WHERE user_id = $1`
+	// Удаление одноразовых токенов пользователя
+	DeleteAuthTokensQuery = `DELETE FROM auth_tokens WHERE user_id = $1`
+	// Удаление выгрузок персональных данных пользователя
+	DeleteDataExportsQuery = `DELETE FROM data_exports WHERE user_id = $1`
+	// Удаление счетчика неудачных попыток входа по имени пользователя
+	DeleteLoginAttemptsQuery = `DELETE FROM login_attempts WHERE scope = $1 AND key = lower($2)`
 )
 
 // PostgresAuthRepository реализует репозиторий пользователей для PostgresSQL
@@ -80,9 +212,16 @@ func (r *PostgresAuthRepository) CreateUser(ctx context.Context, user *models.Cr
 	// Генерация реферального кода
 	referCode := refercode.RandStringBytes()
 
+	// Атрибуция регистрации по реферальной ссылке
+	referFrom, clickID, err := r.resolveReferralClick(ctx, user.ReferralClickToken)
+	if err != nil {
+		return 0, err
+	}
+
 	// Подготовка SQL-запроса
 	var lastID int64
-	err = r.db.QueryRowContext(ctx, CreateUserQuery, user.Username, user.Password, referCode).Scan(&lastID)
+	err = r.db.QueryRowContext(ctx, CreateUserQuery, user.Username, user.Password, sql.NullString{String: user.Email, Valid: user.Email != ""},
+		referCode, referFrom, clickID).Scan(&lastID)
 	if err != nil {
 		r.logger.Error("Failed to execute query to create user", zap.Error(err))
 		return 0, errors.NewInternal("Failed to execute query to create user", err)
@@ -91,6 +230,24 @@ func (r *PostgresAuthRepository) CreateUser(ctx context.Context, user *models.Cr
 	return lastID, nil
 }
 
+// resolveReferralClick находит владельца реферальной ссылки по переходу.
+// Неизвестный переход не считается ошибкой: регистрация проходит без атрибуции.
+func (r *PostgresAuthRepository) resolveReferralClick(ctx context.Context, token string) (sql.NullInt64, sql.NullInt64, error) {
+	if token == "" {
+		return sql.NullInt64{}, sql.NullInt64{}, nil
+	}
+	var clickID, referrerID int64
+	err := r.db.QueryRowContext(ctx, GetClickReferrerQuery, token).Scan(&clickID, &referrerID)
+	if err == sql.ErrNoRows {
+		r.logger.Info("Referral click not found, skipping attribution")
+		return sql.NullInt64{}, sql.NullInt64{}, nil
+	} else if err != nil {
+		r.logger.Error("Failed to resolve referral click", zap.Error(err))
+		return sql.NullInt64{}, sql.NullInt64{}, errors.NewInternal("Failed to resolve referral click", err)
+	}
+	return sql.NullInt64{Int64: referrerID, Valid: true}, sql.NullInt64{Int64: clickID, Valid: true}, nil
+}
+
 // checkUserExists проверяет, существует ли пользователь с указанным именем пользователя или электронной почтой
 func (r *PostgresAuthRepository) checkUserExists(ctx context.Context, user *models.CreateUser) (bool, error) {
 	var exists bool
@@ -121,7 +278,7 @@ func (r *PostgresAuthRepository) GetUser(ctx context.Context, req *models.SignIn
 // GetUserByUsername возвращает пользователя по имени пользователя
 func (r *PostgresAuthRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
-	err := r.db.QueryRowContext(ctx, GetUserByUsernameQuery, username).Scan(&user.ID, &user.Username, &user.Password, &user.Email)
+	err := r.db.QueryRowContext(ctx, GetUserByUsernameQuery, username).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.EmailVerified, &user.TokenVersion)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			r.logger.Info("User not found by username", zap.String("username", username))
@@ -133,3 +290,675 @@ func (r *PostgresAuthRepository) GetUserByUsername(ctx context.Context, username
 	r.logger.Info("User fetched successfully by username", zap.Int64("user_id", user.ID))
 	return &user, nil
 }
+
+// GetUserByID возвращает пользователя по ID
+func (r *PostgresAuthRepository) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRowContext(ctx, GetAuthUserByIDQuery, userID).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.EmailVerified, &user.TokenVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.logger.Info("User not found by id", zap.Int64("user_id", userID))
+			return nil, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+		}
+		r.logger.Error("Error fetching user by id", zap.Error(err))
+		return nil, errors.NewInternal("Error fetching user", err)
+	}
+	return &user, nil
+}
+
+// GetUserByEmail возвращает пользователя по адресу электронной почты без учета регистра
+func (r *PostgresAuthRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRowContext(ctx, GetUserByEmailQuery, email).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.EmailVerified, &user.TokenVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.logger.Info("User not found by email")
+			return nil, errors.NewNotFound("User not found", err)
+		}
+		r.logger.Error("Error fetching user by email", zap.Error(err))
+		return nil, errors.NewInternal("Error fetching user", err)
+	}
+	return &user, nil
+}
+
+// GetTokenVersion возвращает текущую версию сессий пользователя
+func (r *PostgresAuthRepository) GetTokenVersion(ctx context.Context, userID int64) (int, error) {
+	var version int
+	err := r.db.QueryRowContext(ctx, GetTokenVersionQuery, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("Error fetching token version", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("Error fetching token version", err)
+	}
+	return version, nil
+}
+
+// ResetPassword использует токен сброса пароля, устанавливает новый хэш пароля
+// и отзывает все выданные пользователю JWT. Возвращает ID пользователя.
+func (r *PostgresAuthRepository) ResetPassword(ctx context.Context, tokenID, passwordHash string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	token, err := r.useAuthToken(ctx, tx, tokenID, models.TokenPurposePasswordReset)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := tx.ExecContext(ctx, ResetPasswordQuery, passwordHash, token.UserID); err != nil {
+		r.logger.Error("failed to reset password", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to reset password", err)
+	}
+	// Письма, запрошенные раньше, больше не должны позволять сменить пароль
+	if _, err := tx.ExecContext(ctx, RevokeAuthTokensQuery, token.UserID, models.TokenPurposePasswordReset); err != nil {
+		r.logger.Error("failed to revoke reset tokens", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to revoke reset tokens", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+	return token.UserID, nil
+}
+
+// ChangePassword устанавливает новый хэш пароля, отзывает все выданные пользователю JWT
+// и неиспользованные ссылки сброса пароля. Возвращает новую версию сессий.
+func (r *PostgresAuthRepository) ChangePassword(ctx context.Context, userID int64, passwordHash string) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var version int
+	err = tx.QueryRowContext(ctx, ChangePasswordQuery, passwordHash, userID).Scan(&version)
+	if err == sql.ErrNoRows {
+		return 0, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("failed to change password", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to change password", err)
+	}
+	if _, err := tx.ExecContext(ctx, RevokeAuthTokensQuery, userID, models.TokenPurposePasswordReset); err != nil {
+		r.logger.Error("failed to revoke reset tokens", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to revoke reset tokens", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+	return version, nil
+}
+
+// UpdatePasswordHash заменяет хэш пароля, не отзывая сессии пользователя
+func (r *PostgresAuthRepository) UpdatePasswordHash(ctx context.Context, userID int64, passwordHash string) error {
+	if _, err := r.executeExec(ctx, UpdatePasswordHashQuery, passwordHash, userID); err != nil {
+		return err
+	}
+	return nil
+}
+
+// SaveAuthToken сохраняет выпущенный одноразовый токен
+func (r *PostgresAuthRepository) SaveAuthToken(ctx context.Context, token *models.AuthToken) error {
+	_, err := r.db.ExecContext(ctx, SaveAuthTokenQuery, token.TokenID, token.UserID, token.Purpose, token.Email, token.ExpiresAt)
+	if err != nil {
+		r.logger.Error("Cannot save auth token", zap.Int64("user_id", token.UserID), zap.String("purpose", token.Purpose), zap.Error(err))
+		return errors.NewInternal("Cannot save auth token", err)
+	}
+	return nil
+}
+
+// VerifyEmail использует токен подтверждения и отмечает адрес пользователя подтвержденным.
+// Возвращает ID пользователя.
+func (r *PostgresAuthRepository) VerifyEmail(ctx context.Context, tokenID string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	token, err := r.useAuthToken(ctx, tx, tokenID, models.TokenPurposeEmailVerification)
+	if err != nil {
+		return 0, err
+	}
+
+	result, err := tx.ExecContext(ctx, VerifyEmailQuery, token.UserID, token.Email)
+	if err != nil {
+		r.logger.Error("failed to verify email", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to verify email", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return 0, errors.NewInternal("Failed to get rows affected", err)
+	} else if affected == 0 {
+		r.logger.Info("email changed after verification was sent", zap.Int64("user_id", token.UserID))
+		return 0, errors.NewValidation("token is not valid for the current email", nil)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+	return token.UserID, nil
+}
+
+// useAuthToken блокирует одноразовый токен, проверяет срок действия и отмечает его использованным
+func (r *PostgresAuthRepository) useAuthToken(ctx context.Context, tx *sql.Tx, tokenID, purpose string) (models.AuthToken, error) {
+	token := models.AuthToken{TokenID: tokenID, Purpose: purpose}
+	var used, expired bool
+	err := tx.QueryRowContext(ctx, LockAuthTokenQuery, tokenID, purpose).Scan(&token.UserID, &token.Email, &token.ExpiresAt, &used, &expired)
+	if err == sql.ErrNoRows {
+		r.logger.Info("auth token not found", zap.String("purpose", purpose))
+		return models.AuthToken{}, errors.NewNotFound("token not found", err)
+	} else if err != nil {
+		r.logger.Error("failed to lock auth token", zap.String("purpose", purpose), zap.Error(err))
+		return models.AuthToken{}, errors.NewInternal("failed to lock auth token", err)
+	}
+	if used {
+		r.logger.Info("auth token already used", zap.Int64("user_id", token.UserID), zap.String("purpose", purpose))
+		return models.AuthToken{}, errors.NewValidation("token has already been used", nil)
+	}
+	if expired {
+		return models.AuthToken{}, errors.NewValidation("token has expired", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, UseAuthTokenQuery, tokenID); err != nil {
+		r.logger.Error("failed to use auth token", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return models.AuthToken{}, errors.NewInternal("failed to use auth token", err)
+	}
+	return token, nil
+}
+
+// RecordLoginFailure учитывает неудачную попытку входа и возвращает число неудачных попыток подряд
+func (r *PostgresAuthRepository) RecordLoginFailure(ctx context.Context, scope, key string, window time.Duration) (int, error) {
+	var failures int
+	err := r.db.QueryRowContext(ctx, RecordLoginFailureQuery, scope, key, window.Seconds()).Scan(&failures)
+	if err != nil {
+		r.logger.Error("failed to record login failure", zap.String("scope", scope), zap.Error(err))
+		return 0, errors.NewInternal("failed to record login failure", err)
+	}
+	return failures, nil
+}
+
+// BlockLogin блокирует вход по ключу на указанный срок
+func (r *PostgresAuthRepository) BlockLogin(ctx context.Context, scope, key string, duration time.Duration) error {
+	if _, err := r.executeExec(ctx, BlockLoginQuery, scope, key, duration.Seconds()); err != nil {
+		return err
+	}
+	return nil
+}
+
+// GetLoginBlock возвращает оставшееся время блокировки входа по имени пользователя или IP-адресу.
+// Значение не больше нуля означает, что вход не заблокирован.
+func (r *PostgresAuthRepository) GetLoginBlock(ctx context.Context, username, ip string) (time.Duration, error) {
+	var seconds float64
+	if err := r.db.QueryRowContext(ctx, GetLoginBlockQuery, username, ip).Scan(&seconds); err != nil {
+		r.logger.Error("failed to get login block", zap.Error(err))
+		return 0, errors.NewInternal("failed to get login block", err)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// ResetLoginFailures сбрасывает неудачные попытки входа и блокировку по ключу
+func (r *PostgresAuthRepository) ResetLoginFailures(ctx context.Context, scope, key string) error {
+	if _, err := r.executeExec(ctx, ResetLoginFailuresQuery, scope, key); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UseAuthToken использует одноразовый токен вне других операций и возвращает его
+func (r *PostgresAuthRepository) UseAuthToken(ctx context.Context, tokenID, purpose string) (models.AuthToken, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.AuthToken{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	token, err := r.useAuthToken(ctx, tx, tokenID, purpose)
+	if err != nil {
+		return models.AuthToken{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.AuthToken{}, errors.NewInternal("failed to commit transaction", err)
+	}
+	return token, nil
+}
+
+// GetTwoFactor возвращает состояние двухфакторной аутентификации пользователя
+func (r *PostgresAuthRepository) GetTwoFactor(ctx context.Context, userID int64) (models.TwoFactor, error) {
+	var tf models.TwoFactor
+	err := r.db.QueryRowContext(ctx, GetTwoFactorQuery, userID).Scan(&tf.Secret, &tf.Enabled, &tf.LastStep, &tf.RecoveryCodesLeft)
+	if err == sql.ErrNoRows {
+		return models.TwoFactor{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("Error fetching two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return models.TwoFactor{}, errors.NewInternal("Error fetching two-factor state", err)
+	}
+	return tf, nil
+}
+
+// SetTOTPSecret сохраняет секрет TOTP, пока двухфакторная аутентификация не включена
+func (r *PostgresAuthRepository) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	affected, err := r.executeExec(ctx, SetTOTPSecretQuery, userID, secret)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.NewValidation("two-factor authentication is already enabled", nil)
+	}
+	return nil
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию, отмечает принятый код и сохраняет хэши резервных кодов
+func (r *PostgresAuthRepository) EnableTwoFactor(ctx context.Context, userID, step int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, EnableTwoFactorQuery, userID, step)
+	if err != nil {
+		r.logger.Error("failed to enable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to enable two-factor authentication", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return errors.NewInternal("Failed to get rows affected", err)
+	} else if affected == 0 {
+		return errors.NewValidation("two-factor authentication is already enabled", nil)
+	}
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию и удаляет резервные коды
+func (r *PostgresAuthRepository) DisableTwoFactor(ctx context.Context, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, DisableTwoFactorQuery, userID); err != nil {
+		r.logger.Error("failed to disable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to disable two-factor authentication", err)
+	}
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
+
+// UseTOTPStep отмечает период принятого кода.
+// Если код этого или более позднего периода уже принимался, возвращается ошибка валидации.
+func (r *PostgresAuthRepository) UseTOTPStep(ctx context.Context, userID, step int64) error {
+	affected, err := r.executeExec(ctx, UseTOTPStepQuery, userID, step)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.NewValidation("code has already been used", nil)
+	}
+	return nil
+}
+
+// UseRecoveryCode отмечает резервный код использованным
+func (r *PostgresAuthRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	affected, err := r.executeExec(ctx, UseRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.NewNotFound("recovery code not found", nil)
+	}
+	return nil
+}
+
+// UseLoginChallenge в одной транзакции использует токен второго шага входа и код второго фактора.
+// Токен проверяется первым, поэтому недействительный токен не расходует код.
+// Если код уже использован, возвращается false, а токен остается действительным.
+func (r *PostgresAuthRepository) UseLoginChallenge(ctx context.Context, tokenID string, userID int64, factor models.SecondFactor) (bool, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return false, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := r.useAuthToken(ctx, tx, tokenID, models.TokenPurposeLoginChallenge); err != nil {
+		return false, err
+	}
+
+	query, args := UseTOTPStepQuery, []interface{}{userID, factor.Step}
+	if factor.RecoveryCodeHash != "" {
+		query, args = UseRecoveryCodeQuery, []interface{}{userID, factor.RecoveryCodeHash}
+	}
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("failed to use second factor", zap.Int64("user_id", userID), zap.Error(err))
+		return false, errors.NewInternal("failed to use second factor", err)
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return false, errors.NewInternal("Failed to get rows affected", err)
+	}
+	if affected == 0 {
+		return false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return false, errors.NewInternal("failed to commit transaction", err)
+	}
+	return true, nil
+}
+
+// ReplaceRecoveryCodes заменяет резервные коды пользователя новыми
+func (r *PostgresAuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.replaceRecoveryCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
+
+// replaceRecoveryCodes удаляет резервные коды пользователя и сохраняет новые в транзакции
+func (r *PostgresAuthRepository) replaceRecoveryCodes(ctx context.Context, tx *sql.Tx, userID int64, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, DeleteRecoveryCodesQuery, userID); err != nil {
+		r.logger.Error("failed to delete recovery codes", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to delete recovery codes", err)
+	}
+	for _, hash := range codeHashes {
+		if _, err := tx.ExecContext(ctx, InsertRecoveryCodeQuery, userID, hash); err != nil {
+			r.logger.Error("failed to save recovery code", zap.Int64("user_id", userID), zap.Error(err))
+			return errors.NewInternal("failed to save recovery code", err)
+		}
+	}
+	return nil
+}
+
+// GetUserByIdentity возвращает пользователя, к которому привязана учетная запись внешнего провайдера
+func (r *PostgresAuthRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	var user models.User
+	err := r.db.QueryRowContext(ctx, GetUserByIdentityQuery, provider, subject).Scan(&user.ID, &user.Username, &user.Password, &user.Email, &user.EmailVerified, &user.TokenVersion)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.logger.Info("User not found by identity", zap.String("provider", provider))
+			return nil, errors.NewNotFound("User not found", err)
+		}
+		r.logger.Error("Error fetching user by identity", zap.String("provider", provider), zap.Error(err))
+		return nil, errors.NewInternal("Error fetching user", err)
+	}
+	return &user, nil
+}
+
+// LinkIdentity привязывает учетную запись внешнего провайдера к пользователю.
+// Если учетная запись уже привязана или у пользователя уже есть учетная запись этого провайдера,
+// возвращается ошибка AlreadyExists.
+func (r *PostgresAuthRepository) LinkIdentity(ctx context.Context, provider, subject string, userID int64, email string) error {
+	affected, err := r.executeExec(ctx, LinkIdentityQuery, provider, subject, userID, email)
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return errors.NewAlreadyExists("identity is already linked", nil)
+	}
+	r.logger.Info("Identity linked", zap.Int64("user_id", userID), zap.String("provider", provider))
+	return nil
+}
+
+// CreateExternalUser создает пользователя при первом входе через внешнего провайдера
+// и привязывает к нему учетную запись провайдера
+func (r *PostgresAuthRepository) CreateExternalUser(ctx context.Context, user *models.ExternalUserCreate) (int64, error) {
+	exists, err := r.checkUserExists(ctx, &models.CreateUser{Username: user.Username, Email: user.Email})
+	if err != nil {
+		r.logger.Error("Can't check user existence", zap.Error(err))
+		return 0, errors.NewValidation("Can't check user existence", err)
+	}
+	if exists {
+		r.logger.Info("User already exists", zap.String("username", user.Username))
+		return 0, errors.NewAlreadyExists("User already exists", nil)
+	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var userID int64
+	err = tx.QueryRowContext(ctx, CreateUserQuery, user.Username, user.Password, sql.NullString{String: user.Email, Valid: user.Email != ""},
+		refercode.RandStringBytes(), sql.NullInt64{}, sql.NullInt64{}).Scan(&userID)
+	if err != nil {
+		r.logger.Error("Failed to execute query to create user", zap.Error(err))
+		return 0, errors.NewInternal("Failed to execute query to create user", err)
+	}
+	if user.EmailVerified && user.Email != "" {
+		if _, err := tx.ExecContext(ctx, MarkEmailVerifiedQuery, userID); err != nil {
+			r.logger.Error("failed to verify email", zap.Int64("user_id", userID), zap.Error(err))
+			return 0, errors.NewInternal("failed to verify email", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, LinkIdentityQuery, user.Provider, user.Subject, userID, user.Email); err != nil {
+		r.logger.Error("failed to link identity", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to link identity", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+	r.logger.Info("External user created successfully", zap.Int64("user_id", userID), zap.String("provider", user.Provider))
+	return userID, nil
+}
+
+// SaveOIDCState сохраняет незавершенный вход через OpenID Connect и удаляет просроченные
+func (r *PostgresAuthRepository) SaveOIDCState(ctx context.Context, state *models.OIDCState) error {
+	if _, err := r.executeExec(ctx, DeleteExpiredOIDCStatesQuery); err != nil {
+		return err
+	}
+	if _, err := r.executeExec(ctx, SaveOIDCStateQuery, state.State, state.Nonce, state.CodeVerifier, state.ExpiresAt); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UseOIDCState возвращает и удаляет незавершенный вход через OpenID Connect
+func (r *PostgresAuthRepository) UseOIDCState(ctx context.Context, state string) (models.OIDCState, error) {
+	st := models.OIDCState{State: state}
+	var expired bool
+	err := r.db.QueryRowContext(ctx, UseOIDCStateQuery, state).Scan(&st.Nonce, &st.CodeVerifier, &st.ExpiresAt, &expired)
+	if err == sql.ErrNoRows {
+		r.logger.Info("oidc login state not found")
+		return models.OIDCState{}, errors.NewNotFound("login state not found", err)
+	} else if err != nil {
+		r.logger.Error("failed to use oidc login state", zap.Error(err))
+		return models.OIDCState{}, errors.NewInternal("failed to use oidc login state", err)
+	}
+	if expired {
+		return models.OIDCState{}, errors.NewValidation("login state has expired", nil)
+	}
+	return st, nil
+}
+
+// RevokeAuthTokens отменяет неиспользованные одноразовые токены пользователя с указанным назначением
+func (r *PostgresAuthRepository) RevokeAuthTokens(ctx context.Context, userID int64, purpose string) error {
+	if _, err := r.executeExec(ctx, RevokeAuthTokensQuery, userID, purpose); err != nil {
+		return errors.NewInternal("Cannot revoke auth tokens", err)
+	}
+	return nil
+}
+
+// GetProfile возвращает профиль пользователя; удаленный пользователь не находится
+func (r *PostgresAuthRepository) GetProfile(ctx context.Context, userID int64) (models.Profile, error) {
+	var profile models.Profile
+	err := r.db.QueryRowContext(ctx, GetProfileQuery, userID, models.TokenPurposeEmailChange, models.IdentityProviderTelegram).
+		Scan(&profile.UserID, &profile.Username, &profile.DisplayName, &profile.AvatarURL, &profile.Email,
+			&profile.EmailVerified, &profile.PendingEmail, &profile.TelegramID)
+	if err == sql.ErrNoRows {
+		return models.Profile{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("Cannot get profile", zap.Int64("user_id", userID), zap.Error(err))
+		return models.Profile{}, errors.NewInternal("Cannot get profile", err)
+	}
+	return profile, nil
+}
+
+// UpdateProfile сохраняет отображаемое имя и аватар; поля без значения не меняются
+func (r *PostgresAuthRepository) UpdateProfile(ctx context.Context, userID int64, update *models.ProfileUpdate) error {
+	var displayName, avatarURL string
+	if update.DisplayName != nil {
+		displayName = *update.DisplayName
+	}
+	if update.AvatarURL != nil {
+		avatarURL = *update.AvatarURL
+	}
+	affected, err := r.executeExec(ctx, UpdateProfileQuery, userID,
+		update.DisplayName != nil, displayName, update.AvatarURL != nil, avatarURL)
+	if err != nil {
+		return errors.NewInternal("Cannot update profile", err)
+	}
+	if affected == 0 {
+		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), nil)
+	}
+	return nil
+}
+
+// ChangeEmail использует токен смены адреса и заменяет адрес пользователя адресом из токена.
+// Возвращает ID пользователя.
+func (r *PostgresAuthRepository) ChangeEmail(ctx context.Context, tokenID string) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	token, err := r.useAuthToken(ctx, tx, tokenID, models.TokenPurposeEmailChange)
+	if err != nil {
+		return 0, err
+	}
+
+	var taken bool
+	if err := tx.QueryRowContext(ctx, CheckEmailTakenQuery, token.Email, token.UserID).Scan(&taken); err != nil {
+		r.logger.Error("failed to check email", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to check email", err)
+	}
+	if taken {
+		r.logger.Info("email was taken after change was requested", zap.Int64("user_id", token.UserID))
+		return 0, errors.NewAlreadyExists("email is already in use", nil)
+	}
+
+	result, err := tx.ExecContext(ctx, ChangeEmailQuery, token.UserID, token.Email)
+	if err != nil {
+		r.logger.Error("failed to change email", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to change email", err)
+	}
+	if affected, err := result.RowsAffected(); err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return 0, errors.NewInternal("Failed to get rows affected", err)
+	} else if affected == 0 {
+		return 0, errors.NewNotFound(fmt.Sprintf("user with id %d not found", token.UserID), nil)
+	}
+
+	// Ссылки подтверждения, отправленные на прежний адрес, больше не действуют
+	if _, err := tx.ExecContext(ctx, RevokeAuthTokensQuery, token.UserID, models.TokenPurposeEmailVerification); err != nil {
+		r.logger.Error("failed to revoke verification tokens", zap.Int64("user_id", token.UserID), zap.Error(err))
+		return 0, errors.NewInternal("failed to revoke verification tokens", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+	return token.UserID, nil
+}
+
+// DeleteAccount удаляет аккаунт: пользователь покидает команду, его персональные данные стираются,
+// сессии отзываются. Строка пользователя, транзакции и начисления сохраняются.
+func (r *PostgresAuthRepository) DeleteAccount(ctx context.Context, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var username string
+	err = tx.QueryRowContext(ctx, LockActiveUserQuery, userID).Scan(&username)
+	if err == sql.ErrNoRows {
+		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock user", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to lock user", err)
+	}
+
+	teams := &PostgresTeamRepository{db: r.db, logger: r.logger}
+	if err := teams.leaveTeam(ctx, tx, userID); err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, AnonymizeUserQuery, userID, models.DeletedUsernamePrefix); err != nil {
+		r.logger.Error("failed to anonymize user", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to anonymize user", err)
+	}
+	if _, err := tx.ExecContext(ctx, AnonymizeSeasonStandingsQuery, userID, models.DeletedUsernamePrefix); err != nil {
+		r.logger.Error("failed to anonymize season standings", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to anonymize season standings", err)
+	}
+	for _, query := range []string{DeleteRecoveryCodesQuery, DeleteUserIdentitiesQuery, DeleteAuthTokensQuery, DeleteDataExportsQuery} {
+		if _, err := tx.ExecContext(ctx, query, userID); err != nil {
+			r.logger.Error("failed to delete user data", zap.Int64("user_id", userID), zap.Error(err))
+			return errors.NewInternal("failed to delete user data", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, DeleteLoginAttemptsQuery, models.LoginScopeUsername, username); err != nil {
+		r.logger.Error("failed to delete login attempts", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to delete login attempts", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
@@ -0,0 +1,268 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	// Сумма исходящих переводов пользователя за текущие сутки
+	sentTodayQuery = `
+    SELECT COALESCE(SUM(-amount), 0) FROM transactions
+    WHERE user_id = $1 AND kind = 'transfer_out' AND currency = 'coins' AND created_at >= date_trunc('day', now())`
+
+	// История операций пользователя
+	getUserTransactionsQuery = selectTransactionsQuery + ` WHERE user_id = $1 ORDER BY created_at DESC, transaction_id DESC`
+
+	// Список валют
+	getCurrenciesQuery = `SELECT code, title, spendable FROM currencies ORDER BY code`
+
+	// Пользователи, у которых есть истекшие непогашенные начисления
+	usersWithExpiredCreditsQuery = `SELECT DISTINCT user_id FROM point_credits WHERE remaining > 0 AND expires_at <= now()`
+	lockExpiredCreditsQuery      = `
+    SELECT remaining FROM point_credits WHERE user_id = $1 AND remaining > 0 AND expires_at <= now() FOR UPDATE`
+	closeExpiredCreditsQuery = `
+    UPDATE point_credits SET remaining = 0 WHERE user_id = $1 AND remaining > 0 AND expires_at <= now()`
+)
+
+// PostgresBalanceRepository реализует репозиторий операций с балансом для PostgreSQL
+type PostgresBalanceRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	ledger *Ledger
+}
+
+// NewPostgresBalanceRepository создает новый экземпляр репозитория операций с балансом
+func NewPostgresBalanceRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger) *PostgresBalanceRepository {
+	return &PostgresBalanceRepository{db: db, logger: logger, ledger: ledger}
+}
+
+// Transfer переводит баллы между пользователями в одной транзакции.
+// Строки обоих пользователей блокируются в порядке возрастания ID, чтобы встречные переводы не приводили к взаимоблокировке.
+func (r *PostgresBalanceRepository) Transfer(ctx context.Context, transfer *models.Transfer, limits models.TransferLimits) (models.TransferResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.TransferResult{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	balances, err := r.ledger.lockBalances(ctx, tx, transfer.FromUserID, transfer.ToUserID)
+	if err != nil {
+		return models.TransferResult{}, err
+	}
+
+	if limits.DailyLimit > 0 {
+		var sentToday int
+		if err := tx.QueryRowContext(ctx, sentTodayQuery, transfer.FromUserID).Scan(&sentToday); err != nil {
+			r.logger.Error("failed to sum today's transfers", zap.Int64("user_id", transfer.FromUserID), zap.Error(err))
+			return models.TransferResult{}, errors.NewInternal("failed to sum today's transfers", err)
+		}
+		if sentToday+transfer.Amount > limits.DailyLimit {
+			r.logger.Info("daily transfer limit exceeded", zap.Int64("user_id", transfer.FromUserID),
+				zap.Int("sent_today", sentToday), zap.Int("amount", transfer.Amount))
+			return models.TransferResult{}, errors.NewValidation(
+				fmt.Sprintf("daily transfer limit exceeded: %d of %d already sent today", sentToday, limits.DailyLimit), nil)
+		}
+	}
+
+	if balances[transfer.FromUserID]-transfer.Amount < limits.MinBalance {
+		r.logger.Info("insufficient balance for transfer", zap.Int64("user_id", transfer.FromUserID),
+			zap.Int("balance", balances[transfer.FromUserID]), zap.Int("amount", transfer.Amount))
+		return models.TransferResult{}, errors.NewValidation(
+			fmt.Sprintf("insufficient balance: at least %d points must remain after transfer", limits.MinBalance), nil)
+	}
+
+	result := models.TransferResult{
+		Outgoing: models.Transaction{
+			UserID:         transfer.FromUserID,
+			Amount:         -transfer.Amount,
+			Kind:           models.TransactionTransferOut,
+			CounterpartyID: &transfer.ToUserID,
+		},
+		Incoming: models.Transaction{
+			UserID:         transfer.ToUserID,
+			Amount:         transfer.Amount,
+			Kind:           models.TransactionTransferIn,
+			CounterpartyID: &transfer.FromUserID,
+		},
+	}
+	if err := r.ledger.transfer(ctx, tx, &result.Outgoing, &result.Incoming); err != nil {
+		return models.TransferResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.TransferResult{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Transfer completed", zap.Int64("from_user_id", transfer.FromUserID),
+		zap.Int64("to_user_id", transfer.ToUserID), zap.Int("amount", transfer.Amount))
+	return result, nil
+}
+
+// GetUserTransactions возвращает историю операций с балансом пользователя
+func (r *PostgresBalanceRepository) GetUserTransactions(ctx context.Context, userID int64) ([]models.Transaction, error) {
+	return r.ledger.queryTransactions(ctx, r.db, getUserTransactionsQuery, userID)
+}
+
+// GetCurrencies возвращает список валют
+func (r *PostgresBalanceRepository) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+	rows, err := r.db.QueryContext(ctx, getCurrenciesQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getCurrenciesQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var currencies []models.Currency
+	for rows.Next() {
+		var currency models.Currency
+		if err := rows.Scan(&currency.Code, &currency.Title, &currency.Spendable); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		currencies = append(currencies, currency)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return currencies, nil
+}
+
+// AdjustBalance применяет ручную корректировку баланса администратором.
+// Списание, после которого баланс стал бы отрицательным, отклоняется.
+func (r *PostgresBalanceRepository) AdjustBalance(ctx context.Context, adjustment *models.Adjustment) (models.Transaction, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.Transaction{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	balance, err := r.ledger.lockBalance(ctx, tx, adjustment.UserID)
+	if err != nil {
+		return models.Transaction{}, err
+	}
+	if balance+adjustment.Amount < 0 {
+		r.logger.Info("insufficient balance for deduction", zap.Int64("user_id", adjustment.UserID),
+			zap.Int("balance", balance), zap.Int("amount", adjustment.Amount))
+		return models.Transaction{}, errors.NewValidation(
+			fmt.Sprintf("insufficient balance: cannot deduct %d points from balance %d", -adjustment.Amount, balance), nil)
+	}
+
+	entry := models.Transaction{
+		UserID:    adjustment.UserID,
+		Amount:    adjustment.Amount,
+		Kind:      models.TransactionAdjustment,
+		Reference: adjustment.Reference,
+		Reason:    adjustment.Reason,
+		CreatedBy: &adjustment.AdminID,
+	}
+	if err := r.ledger.apply(ctx, tx, &entry); err != nil {
+		return models.Transaction{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.Transaction{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Balance adjusted", zap.Int64("user_id", adjustment.UserID), zap.Int("amount", adjustment.Amount),
+		zap.String("reason", adjustment.Reason), zap.Int64("admin_id", adjustment.AdminID))
+	return entry, nil
+}
+
+// GetUsersWithExpiredPoints возвращает пользователей, у которых есть истекшие непогашенные начисления
+func (r *PostgresBalanceRepository) GetUsersWithExpiredPoints(ctx context.Context) ([]int64, error) {
+	rows, err := r.db.QueryContext(ctx, usersWithExpiredCreditsQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", usersWithExpiredCreditsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	var userIDs []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			rows.Close()
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		userIDs = append(userIDs, userID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return userIDs, nil
+}
+
+// ExpireUserPoints закрывает истекшие начисления пользователя и списывает их с баланса.
+// Списывается не больше положительного остатка баланса.
+func (r *PostgresBalanceRepository) ExpireUserPoints(ctx context.Context, userID int64) (int, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	balance, err := r.ledger.lockBalance(ctx, tx, userID)
+	if err != nil {
+		return 0, err
+	}
+
+	rows, err := tx.QueryContext(ctx, lockExpiredCreditsQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to lock expired credits", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to lock expired credits", err)
+	}
+	expired := 0
+	for rows.Next() {
+		var remaining int
+		if err := rows.Scan(&remaining); err != nil {
+			rows.Close()
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return 0, errors.NewInternal("Error scanning row", err)
+		}
+		expired += remaining
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return 0, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, closeExpiredCreditsQuery, userID); err != nil {
+		r.logger.Error("failed to close expired credits", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to close expired credits", err)
+	}
+
+	amount := min(expired, max(balance, 0))
+	if amount > 0 {
+		entry := models.Transaction{
+			UserID: userID,
+			Amount: -amount,
+			Kind:   models.TransactionExpiry,
+		}
+		if err := r.ledger.apply(ctx, tx, &entry); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Points expired", zap.Int64("user_id", userID), zap.Int("amount", amount))
+	return amount, nil
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	addBoostQuery = `
+    INSERT INTO boosts (title, multiplier_percent, task_type, min_level, starts_at, ends_at, created_by)
+    VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING boost_id`
+	// Действующие и еще не закончившиеся множители либо все множители
+	getBoostsQuery = `
+    SELECT boost_id, title, multiplier_percent, task_type, min_level, starts_at, ends_at, created_by, created_at
+    FROM boosts WHERE NOT $1 OR ends_at > now() ORDER BY starts_at, boost_id`
+)
+
+// PostgresBoostRepository реализует репозиторий множителей наград для PostgreSQL
+type PostgresBoostRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewPostgresBoostRepository создает новый экземпляр репозитория множителей наград
+func NewPostgresBoostRepository(db *sql.DB, logger *zap.Logger) *PostgresBoostRepository {
+	return &PostgresBoostRepository{db: db, logger: logger}
+}
+
+// CreateBoost сохраняет множитель наград
+func (r *PostgresBoostRepository) CreateBoost(ctx context.Context, boost *models.BoostCreate) (int64, error) {
+	var boostID int64
+	err := r.db.QueryRowContext(ctx, addBoostQuery, boost.Title, boost.MultiplierPercent, boost.TaskType, boost.MinLevel,
+		boost.StartsAt, boost.EndsAt, boost.AdminID).Scan(&boostID)
+	if err != nil {
+		r.logger.Error("Cannot create boost", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create boost", err)
+	}
+	return boostID, nil
+}
+
+// GetBoosts возвращает множители наград. При upcomingOnly закончившиеся множители не возвращаются.
+func (r *PostgresBoostRepository) GetBoosts(ctx context.Context, upcomingOnly bool) ([]models.Boost, error) {
+	rows, err := r.db.QueryContext(ctx, getBoostsQuery, upcomingOnly)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getBoostsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var boosts []models.Boost
+	for rows.Next() {
+		var boost models.Boost
+		if err := rows.Scan(&boost.BoostID, &boost.Title, &boost.MultiplierPercent, &boost.TaskType, &boost.MinLevel,
+			&boost.StartsAt, &boost.EndsAt, &boost.CreatedBy, &boost.CreatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		boosts = append(boosts, boost)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return boosts, nil
+}
@@ -0,0 +1,196 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	addCampaignQuery = `
+    INSERT INTO campaigns (title, description, budget, starts_at, ends_at, created_by)
+    VALUES ($1, NULLIF($2, ''), $3, $4, $5, $6) RETURNING campaign_id`
+	selectCampaignsQuery = `
+    SELECT campaign_id, title, COALESCE(description, ''), budget, spent, status, starts_at, ends_at, created_by, created_at
+    FROM campaigns`
+	getCampaignsQuery         = selectCampaignsQuery + ` ORDER BY starts_at DESC, campaign_id DESC`
+	getCampaignQuery          = selectCampaignsQuery + ` WHERE campaign_id = $1`
+	lockCampaignStatusQuery   = `SELECT status FROM campaigns WHERE campaign_id = $1 FOR UPDATE`
+	updateCampaignStatusQuery = `UPDATE campaigns SET status = $1 WHERE campaign_id = $2`
+	campaignParticipantsQuery = `
+    SELECT COUNT(DISTINCT tc.user_id), COUNT(tc.id)
+    FROM task_complete tc JOIN tasks t ON t.task_id = tc.task_id
+    WHERE t.campaign_id = $1 AND tc.revoked_at IS NULL`
+	campaignTaskStatsQuery = `
+    SELECT t.task_id, t.title, COUNT(tc.id), COUNT(DISTINCT tc.user_id)
+    FROM tasks t LEFT JOIN task_complete tc ON tc.task_id = t.task_id AND tc.revoked_at IS NULL
+    WHERE t.campaign_id = $1
+    GROUP BY t.task_id, t.title ORDER BY t.task_id`
+)
+
+// campaignTransitions допустимые переходы между статусами кампании
+var campaignTransitions = map[string]map[string]bool{
+	models.CampaignDraft: {
+		models.CampaignLive:  true,
+		models.CampaignEnded: true,
+	},
+	models.CampaignLive: {
+		models.CampaignPaused: true,
+		models.CampaignEnded:  true,
+	},
+	models.CampaignPaused: {
+		models.CampaignLive:  true,
+		models.CampaignEnded: true,
+	},
+}
+
+// PostgresCampaignRepository реализует репозиторий кампаний для PostgreSQL
+type PostgresCampaignRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewPostgresCampaignRepository создает новый экземпляр репозитория кампаний
+func NewPostgresCampaignRepository(db *sql.DB, logger *zap.Logger) *PostgresCampaignRepository {
+	return &PostgresCampaignRepository{db: db, logger: logger}
+}
+
+// CreateCampaign создает кампанию в статусе draft
+func (r *PostgresCampaignRepository) CreateCampaign(ctx context.Context, campaign *models.CampaignCreate) (int64, error) {
+	var campaignID int64
+	err := r.db.QueryRowContext(ctx, addCampaignQuery, campaign.Title, campaign.Description, campaign.Budget,
+		campaign.StartsAt, campaign.EndsAt, campaign.AdminID).Scan(&campaignID)
+	if err != nil {
+		r.logger.Error("Cannot create campaign", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create campaign", err)
+	}
+	return campaignID, nil
+}
+
+// GetCampaigns возвращает все кампании, начиная с последней
+func (r *PostgresCampaignRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	rows, err := r.db.QueryContext(ctx, getCampaignsQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getCampaignsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var campaigns []models.Campaign
+	for rows.Next() {
+		var campaign models.Campaign
+		if err := rows.Scan(&campaign.CampaignID, &campaign.Title, &campaign.Description, &campaign.Budget, &campaign.Spent,
+			&campaign.Status, &campaign.StartsAt, &campaign.EndsAt, &campaign.CreatedBy, &campaign.CreatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		campaigns = append(campaigns, campaign)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return campaigns, nil
+}
+
+// UpdateCampaignStatus переводит кампанию в новый статус
+func (r *PostgresCampaignRepository) UpdateCampaignStatus(ctx context.Context, campaignID int64, status string) (models.Campaign, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.Campaign{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var current string
+	err = tx.QueryRowContext(ctx, lockCampaignStatusQuery, campaignID).Scan(&current)
+	if err == sql.ErrNoRows {
+		r.logger.Info("campaign not found", zap.Int64("campaign_id", campaignID))
+		return models.Campaign{}, errors.NewNotFound(fmt.Sprintf("campaign with id %d not found", campaignID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock campaign", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.Campaign{}, errors.NewInternal("failed to lock campaign", err)
+	}
+
+	if !campaignTransitions[current][status] {
+		r.logger.Info("invalid campaign status transition",
+			zap.Int64("campaign_id", campaignID), zap.String("from", current), zap.String("to", status))
+		return models.Campaign{}, errors.NewValidation(
+			fmt.Sprintf("cannot change campaign status from %s to %s", current, status), nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, updateCampaignStatusQuery, status, campaignID); err != nil {
+		r.logger.Error("failed to update campaign status", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.Campaign{}, errors.NewInternal("failed to update campaign status", err)
+	}
+
+	campaign, err := r.getCampaign(ctx, tx, campaignID)
+	if err != nil {
+		return models.Campaign{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.Campaign{}, errors.NewInternal("failed to commit transaction", err)
+	}
+	return campaign, nil
+}
+
+// GetCampaignStats возвращает статистику кампании: участников, выполнения по заданиям и расход бюджета
+func (r *PostgresCampaignRepository) GetCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error) {
+	var stats models.CampaignStats
+	campaign, err := r.getCampaign(ctx, r.db, campaignID)
+	if err != nil {
+		return models.CampaignStats{}, err
+	}
+	stats.Campaign = campaign
+	stats.Remaining = max(campaign.Budget-campaign.Spent, 0)
+
+	if err := r.db.QueryRowContext(ctx, campaignParticipantsQuery, campaignID).Scan(&stats.Participants, &stats.Completions); err != nil {
+		r.logger.Error("failed to count campaign participants", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.CampaignStats{}, errors.NewInternal("failed to count campaign participants", err)
+	}
+
+	rows, err := r.db.QueryContext(ctx, campaignTaskStatsQuery, campaignID)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", campaignTaskStatsQuery), zap.Error(err))
+		return models.CampaignStats{}, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	stats.Tasks = []models.CampaignTaskStats{}
+	for rows.Next() {
+		var task models.CampaignTaskStats
+		if err := rows.Scan(&task.TaskID, &task.Title, &task.Completions, &task.Participants); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return models.CampaignStats{}, errors.NewInternal("Error scanning row", err)
+		}
+		stats.Tasks = append(stats.Tasks, task)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return models.CampaignStats{}, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return stats, nil
+}
+
+// getCampaign возвращает кампанию по ID
+func (r *PostgresCampaignRepository) getCampaign(ctx context.Context, q queryRower, campaignID int64) (models.Campaign, error) {
+	var campaign models.Campaign
+	err := q.QueryRowContext(ctx, getCampaignQuery, campaignID).Scan(&campaign.CampaignID, &campaign.Title, &campaign.Description,
+		&campaign.Budget, &campaign.Spent, &campaign.Status, &campaign.StartsAt, &campaign.EndsAt, &campaign.CreatedBy, &campaign.CreatedAt)
+	if err == sql.ErrNoRows {
+		r.logger.Info("campaign not found", zap.Int64("campaign_id", campaignID))
+		return models.Campaign{}, errors.NewNotFound(fmt.Sprintf("campaign with id %d not found", campaignID), err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch campaign", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.Campaign{}, errors.NewInternal("failed to fetch campaign", err)
+	}
+	return campaign, nil
+}
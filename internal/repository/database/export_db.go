@@ -0,0 +1,386 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"time"
+)
+
+// SQL-запросы
+const (
+	// Число выполнений заданий и операций с балансом пользователя, по нему оценивается размер выгрузки
+	countExportRecordsQuery = `
+    SELECT (SELECT COUNT(*) FROM task_complete WHERE user_id = $1) + (SELECT COUNT(*) FROM transactions WHERE user_id = $1)`
+	// Реферальный код пользователя и пригласивший его пользователь
+	getExportReferralQuery = `SELECT COALESCE(refer_code, ''), NULLIF(refer_from, '')::bigint FROM users WHERE user_id = $1`
+	// Пользователи, зарегистрированные по приглашению
+	getInvitedUsersQuery = `SELECT user_id FROM users WHERE refer_from = $1::text ORDER BY user_id`
+	// Состояние двухфакторной аутентификации без секрета
+	getExportTwoFactorQuery = `
+    SELECT totp_enabled_at IS NOT NULL,
+        (SELECT COUNT(*) FROM recovery_codes WHERE user_id = u.user_id AND used_at IS NULL)
+    FROM users u WHERE user_id = $1`
+	// Привязанные учетные записи внешних провайдеров
+	getExportIdentitiesQuery = `
+    SELECT provider, subject, COALESCE(email, ''), created_at FROM user_identities WHERE user_id = $1 ORDER BY created_at`
+	// Выданные одноразовые токены
+	getExportAuthTokensQuery = `
+    SELECT purpose, COALESCE(email, ''), created_at, expires_at, used_at FROM auth_tokens
+    WHERE user_id = $1 ORDER BY created_at`
+
+	selectDataExportQuery = `
+    SELECT export_id, user_id, format, status, created_at, completed_at, expires_at FROM data_exports`
+	// Выгрузка того же формата, которая еще готовится
+	getQueuedExportQuery = selectDataExportQuery + `
+    WHERE user_id = $1 AND format = $2 AND status IN ('pending', 'processing') ORDER BY created_at LIMIT 1`
+	createDataExportQuery = `
+    INSERT INTO data_exports (user_id, format) VALUES ($1, $2)
+    RETURNING export_id, user_id, format, status, created_at, completed_at, expires_at`
+	// Выгрузка пользователя, которая еще не удалена по сроку хранения
+	getDataExportQuery = selectDataExportQuery + `
+    WHERE export_id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > now())`
+	getDataExportContentQuery = `SELECT content FROM data_exports WHERE export_id = $1`
+	// Взятие в работу самой старой выгрузки из очереди. Выгрузка, которую начали готовить больше $1 секунд
+	// назад, считается брошенной (например, приложение перезапустилось) и берется снова
+	claimDataExportQuery = `
+    UPDATE data_exports SET status = 'processing', started_at = now()
+    WHERE export_id = (
+        SELECT export_id FROM data_exports
+        WHERE status = 'pending' OR (status = 'processing' AND started_at < now() - $1 * interval '1 second')
+        ORDER BY created_at LIMIT 1 FOR UPDATE SKIP LOCKED)
+    RETURNING export_id, user_id, format, status, created_at, completed_at, expires_at`
+	completeDataExportQuery = `
+    UPDATE data_exports SET status = 'ready', content = $2, completed_at = now(),
+        expires_at = now() + $3 * interval '1 second'
+    WHERE export_id = $1`
+	failDataExportQuery = `
+    UPDATE data_exports SET status = 'failed', error = $2, completed_at = now(),
+        expires_at = now() + $3 * interval '1 second'
+    WHERE export_id = $1`
+	deleteExpiredDataExportsQuery = `DELETE FROM data_exports WHERE expires_at <= now()`
+)
+
+// PostgresExportRepository реализует репозиторий выгрузок персональных данных для PostgreSQL
+type PostgresExportRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	ledger *Ledger
+}
+
+// NewPostgresExportRepository создает новый экземпляр репозитория выгрузок персональных данных
+func NewPostgresExportRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger) *PostgresExportRepository {
+	return &PostgresExportRepository{db: db, logger: logger, ledger: ledger}
+}
+
+// CountUserRecords возвращает число выполнений заданий и операций с балансом пользователя
+func (r *PostgresExportRepository) CountUserRecords(ctx context.Context, userID int64) (int, error) {
+	var count int
+	if err := r.db.QueryRowContext(ctx, countExportRecordsQuery, userID).Scan(&count); err != nil {
+		r.logger.Error("Failed to count user records", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to count user records", err)
+	}
+	return count, nil
+}
+
+// GetUserData собирает персональные данные пользователя.
+// Данные читаются в одной транзакции, чтобы разделы выгрузки были согласованы между собой.
+func (r *PostgresExportRepository) GetUserData(ctx context.Context, userID int64) (models.UserData, error) {
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.UserData{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	data := models.UserData{ExportedAt: time.Now().UTC()}
+	err = tx.QueryRowContext(ctx, GetProfileQuery, userID, models.TokenPurposeEmailChange, models.IdentityProviderTelegram).
+		Scan(&data.Profile.UserID, &data.Profile.Username, &data.Profile.DisplayName, &data.Profile.AvatarURL,
+			&data.Profile.Email, &data.Profile.EmailVerified, &data.Profile.PendingEmail, &data.Profile.TelegramID)
+	if err == sql.ErrNoRows {
+		return models.UserData{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch profile", zap.Int64("user_id", userID), zap.Error(err))
+		return models.UserData{}, errors.NewInternal("failed to fetch profile", err)
+	}
+
+	if data.Balances, err = r.getBalances(ctx, tx, userID); err != nil {
+		return models.UserData{}, err
+	}
+	if data.Completions, err = r.getCompletions(ctx, tx, userID); err != nil {
+		return models.UserData{}, err
+	}
+	if data.Transactions, err = r.ledger.queryTransactions(ctx, tx, getUserTransactionsQuery, userID); err != nil {
+		return models.UserData{}, err
+	}
+	if data.Transactions == nil {
+		data.Transactions = []models.Transaction{}
+	}
+	if data.Referrals, err = r.getReferrals(ctx, tx, userID); err != nil {
+		return models.UserData{}, err
+	}
+	if data.Sessions, err = r.getSessions(ctx, tx, userID); err != nil {
+		return models.UserData{}, err
+	}
+	return data, nil
+}
+
+// getBalances возвращает балансы пользователя по валютам
+func (r *PostgresExportRepository) getBalances(ctx context.Context, tx *sql.Tx, userID int64) (map[string]int, error) {
+	rows, err := tx.QueryContext(ctx, GetUserBalancesQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch balances", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch balances", err)
+	}
+	defer rows.Close()
+
+	balances := map[string]int{}
+	for rows.Next() {
+		var currency string
+		var balance int
+		if err := rows.Scan(&currency, &balance); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		balances[currency] = balance
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return balances, nil
+}
+
+// getCompletions возвращает выполненные пользователем задания, включая отмененные
+func (r *PostgresExportRepository) getCompletions(ctx context.Context, tx *sql.Tx, userID int64) ([]models.TaskCompletion, error) {
+	rows, err := tx.QueryContext(ctx, getUserCompletionsQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch completions", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch completions", err)
+	}
+	defer rows.Close()
+
+	completions := []models.TaskCompletion{}
+	for rows.Next() {
+		var completion models.TaskCompletion
+		if err := scanCompletion(rows, &completion); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		completions = append(completions, completion)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return completions, nil
+}
+
+// getReferrals возвращает реферальный код пользователя с воронкой, пригласившего и приглашенных
+func (r *PostgresExportRepository) getReferrals(ctx context.Context, tx *sql.Tx, userID int64) (models.ExportReferrals, error) {
+	referrals := models.ExportReferrals{InvitedUserIDs: []int64{}}
+	if err := tx.QueryRowContext(ctx, getExportReferralQuery, userID).Scan(&referrals.ReferCode, &referrals.ReferredBy); err != nil {
+		r.logger.Error("failed to fetch referral data", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportReferrals{}, errors.NewInternal("failed to fetch referral data", err)
+	}
+
+	if referrals.ReferCode != "" {
+		stats := models.ReferralStats{ReferCode: referrals.ReferCode}
+		err := tx.QueryRowContext(ctx, getReferralStatsQuery, referrals.ReferCode).
+			Scan(&stats.Clicks, &stats.Signups, &stats.FirstTaskCompletions)
+		if err != nil {
+			r.logger.Error("failed to fetch referral stats", zap.Int64("user_id", userID), zap.Error(err))
+			return models.ExportReferrals{}, errors.NewInternal("failed to fetch referral stats", err)
+		}
+		referrals.Stats = &stats
+	}
+
+	rows, err := tx.QueryContext(ctx, getInvitedUsersQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch invited users", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportReferrals{}, errors.NewInternal("failed to fetch invited users", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var invitedID int64
+		if err := rows.Scan(&invitedID); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return models.ExportReferrals{}, errors.NewInternal("Error scanning row", err)
+		}
+		referrals.InvitedUserIDs = append(referrals.InvitedUserIDs, invitedID)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return models.ExportReferrals{}, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return referrals, nil
+}
+
+// getSessions возвращает способы входа пользователя и выданные ему одноразовые токены
+func (r *PostgresExportRepository) getSessions(ctx context.Context, tx *sql.Tx, userID int64) (models.ExportSessions, error) {
+	sessions := models.ExportSessions{Identities: []models.ExportIdentity{}, Tokens: []models.ExportAuthToken{}}
+	err := tx.QueryRowContext(ctx, getExportTwoFactorQuery, userID).Scan(&sessions.TwoFactorEnabled, &sessions.RecoveryCodesLeft)
+	if err != nil {
+		r.logger.Error("failed to fetch two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportSessions{}, errors.NewInternal("failed to fetch two-factor state", err)
+	}
+
+	rows, err := tx.QueryContext(ctx, getExportIdentitiesQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch identities", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportSessions{}, errors.NewInternal("failed to fetch identities", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var identity models.ExportIdentity
+		if err := rows.Scan(&identity.Provider, &identity.Subject, &identity.Email, &identity.LinkedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return models.ExportSessions{}, errors.NewInternal("Error scanning row", err)
+		}
+		sessions.Identities = append(sessions.Identities, identity)
+	}
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return models.ExportSessions{}, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+
+	tokenRows, err := tx.QueryContext(ctx, getExportAuthTokensQuery, userID)
+	if err != nil {
+		r.logger.Error("failed to fetch auth tokens", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportSessions{}, errors.NewInternal("failed to fetch auth tokens", err)
+	}
+	defer tokenRows.Close()
+	for tokenRows.Next() {
+		var token models.ExportAuthToken
+		if err := tokenRows.Scan(&token.Purpose, &token.Email, &token.CreatedAt, &token.ExpiresAt, &token.UsedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return models.ExportSessions{}, errors.NewInternal("Error scanning row", err)
+		}
+		sessions.Tokens = append(sessions.Tokens, token)
+	}
+	if err := tokenRows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return models.ExportSessions{}, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return sessions, nil
+}
+
+// CreateExport ставит выгрузку в очередь. Если выгрузка того же формата уже готовится, возвращается она.
+func (r *PostgresExportRepository) CreateExport(ctx context.Context, userID int64, format string) (models.DataExport, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	// Блокировка пользователя не дает параллельным запросам поставить в очередь две одинаковые выгрузки
+	var id int64
+	if err := tx.QueryRowContext(ctx, lockTeamUserQuery, userID).Scan(&id); err == sql.ErrNoRows {
+		return models.DataExport{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock user", zap.Int64("user_id", userID), zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to lock user", err)
+	}
+
+	export, err := scanDataExport(tx.QueryRowContext(ctx, getQueuedExportQuery, userID, format))
+	if err == nil {
+		return export, nil
+	} else if err != sql.ErrNoRows {
+		r.logger.Error("failed to fetch queued export", zap.Int64("user_id", userID), zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to fetch queued export", err)
+	}
+
+	export, err = scanDataExport(tx.QueryRowContext(ctx, createDataExportQuery, userID, format))
+	if err != nil {
+		r.logger.Error("failed to create export", zap.Int64("user_id", userID), zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to create export", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to commit transaction", err)
+	}
+	return export, nil
+}
+
+// GetExport возвращает выгрузку пользователя; чужая и удаленная по сроку хранения выгрузки не находятся
+func (r *PostgresExportRepository) GetExport(ctx context.Context, userID, exportID int64) (models.DataExport, error) {
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, getDataExportQuery, exportID, userID))
+	if err == sql.ErrNoRows {
+		return models.DataExport{}, errors.NewNotFound(fmt.Sprintf("export with id %d not found", exportID), err)
+	} else if err != nil {
+		r.logger.Error("Failed to fetch export", zap.Int64("export_id", exportID), zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to fetch export", err)
+	}
+	return export, nil
+}
+
+// GetExportContent возвращает содержимое готовой выгрузки
+func (r *PostgresExportRepository) GetExportContent(ctx context.Context, exportID int64) ([]byte, error) {
+	var content []byte
+	err := r.db.QueryRowContext(ctx, getDataExportContentQuery, exportID).Scan(&content)
+	if err == sql.ErrNoRows {
+		return nil, errors.NewNotFound(fmt.Sprintf("export with id %d not found", exportID), err)
+	} else if err != nil {
+		r.logger.Error("Failed to fetch export content", zap.Int64("export_id", exportID), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch export content", err)
+	}
+	return content, nil
+}
+
+// ClaimExport берет в работу выгрузку из очереди. Если очередь пуста, возвращается NotFound.
+func (r *PostgresExportRepository) ClaimExport(ctx context.Context, staleAfter time.Duration) (models.DataExport, error) {
+	export, err := scanDataExport(r.db.QueryRowContext(ctx, claimDataExportQuery, staleAfter.Seconds()))
+	if err == sql.ErrNoRows {
+		return models.DataExport{}, errors.NewNotFound("no queued exports", err)
+	} else if err != nil {
+		r.logger.Error("Failed to claim export", zap.Error(err))
+		return models.DataExport{}, errors.NewInternal("failed to claim export", err)
+	}
+	return export, nil
+}
+
+// CompleteExport сохраняет готовую выгрузку на время ttl
+func (r *PostgresExportRepository) CompleteExport(ctx context.Context, exportID int64, content []byte, ttl time.Duration) error {
+	if _, err := r.db.ExecContext(ctx, completeDataExportQuery, exportID, content, ttl.Seconds()); err != nil {
+		r.logger.Error("Failed to complete export", zap.Int64("export_id", exportID), zap.Error(err))
+		return errors.NewInternal("failed to complete export", err)
+	}
+	return nil
+}
+
+// FailExport отмечает, что выгрузку не удалось подготовить
+func (r *PostgresExportRepository) FailExport(ctx context.Context, exportID int64, reason string, ttl time.Duration) error {
+	if _, err := r.db.ExecContext(ctx, failDataExportQuery, exportID, reason, ttl.Seconds()); err != nil {
+		r.logger.Error("Failed to mark export as failed", zap.Int64("export_id", exportID), zap.Error(err))
+		return errors.NewInternal("failed to mark export as failed", err)
+	}
+	return nil
+}
+
+// DeleteExpiredExports удаляет выгрузки с истекшим сроком хранения и возвращает их число
+func (r *PostgresExportRepository) DeleteExpiredExports(ctx context.Context) (int64, error) {
+	result, err := r.db.ExecContext(ctx, deleteExpiredDataExportsQuery)
+	if err != nil {
+		r.logger.Error("Failed to delete expired exports", zap.Error(err))
+		return 0, errors.NewInternal("failed to delete expired exports", err)
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("Failed to get rows affected", zap.Error(err))
+		return 0, errors.NewInternal("Failed to get rows affected", err)
+	}
+	return deleted, nil
+}
+
+// scanDataExport читает выгрузку из строки selectDataExportQuery
+func scanDataExport(row *sql.Row) (models.DataExport, error) {
+	var export models.DataExport
+	err := row.Scan(&export.ExportID, &export.UserID, &export.Format, &export.Status,
+		&export.CreatedAt, &export.CompletedAt, &export.ExpiresAt)
+	return export, err
+}
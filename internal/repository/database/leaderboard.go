@@ -0,0 +1,28 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"go.uber.org/zap"
+)
+
+// leaderboardOrder порядок строк таблицы лидеров: по убыванию очков,
+// при равенстве очков выше тот, у кого меньше ID, чтобы порядок был стабильным.
+func leaderboardOrder(scoreColumn, idColumn string) string {
+	return fmt.Sprintf(" ORDER BY %s DESC, %s", scoreColumn, idColumn)
+}
+
+// checkLeaderboardCurrency проверяет, что таблица лидеров запрошена в существующей валюте
+func checkLeaderboardCurrency(ctx context.Context, q queryRower, logger *zap.Logger, currency string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, checkCurrencyExistsQuery, currency).Scan(&exists); err != nil {
+		logger.Error("Failed to check currency existence", zap.String("currency", currency), zap.Error(err))
+		return errors.NewInternal("Failed to check currency existence", err)
+	}
+	if !exists {
+		logger.Info("Currency not found", zap.String("currency", currency))
+		return errors.NewNotFound(fmt.Sprintf("currency %q not found", currency), nil)
+	}
+	return nil
+}
@@ -0,0 +1,510 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/level"
+	"go.uber.org/zap"
+	"sort"
+	"time"
+)
+
+// SQL-запросы журнала операций
+const (
+	lockUserBalanceQuery = `SELECT balance FROM users WHERE user_id = $1 FOR UPDATE`
+	changeBalanceQuery   = `UPDATE users SET balance = balance + $1 WHERE user_id = $2 RETURNING balance`
+	// Баланс в coins хранится в users.balance и дублируется в user_balances для рейтингов по валютам
+	mirrorCoinsBalanceQuery = `
+    INSERT INTO user_balances (user_id, currency, balance) VALUES ($1, 'coins', $2)
+    ON CONFLICT (user_id, currency) DO UPDATE SET balance = EXCLUDED.balance`
+	changeCurrencyBalanceQuery = `
+    INSERT INTO user_balances (user_id, currency, balance) VALUES ($1, $2, $3)
+    ON CONFLICT (user_id, currency) DO UPDATE SET balance = user_balances.balance + EXCLUDED.balance
+    RETURNING balance`
+	checkUserExistsQuery     = `SELECT EXISTS(SELECT 1 FROM users WHERE user_id = $1)`
+	checkCurrencyExistsQuery = `SELECT EXISTS(SELECT 1 FROM currencies WHERE code = $1)`
+	addTransactionQuery      = `
+    INSERT INTO transactions (user_id, amount, currency, balance_after, kind, counterparty_id, reference, reason, created_by, season_id)
+    VALUES ($1, $2, $3, $4, $5, $6, NULLIF($7, ''), NULLIF($8, ''), $9, $10) RETURNING transaction_id, created_at`
+	selectTransactionsQuery = `
+    SELECT transaction_id, user_id, amount, currency, balance_after, kind, counterparty_id, COALESCE(reference, ''),
+        COALESCE(reason, ''), created_by, created_at
+    FROM transactions`
+
+	addCreditQuery = `
+    INSERT INTO point_credits (user_id, transaction_id, amount, remaining, expires_at) VALUES ($1, $2, $3, $4, $5)`
+	// Открытые начисления пользователя в порядке от самых старых
+	lockOpenCreditsQuery = `
+    SELECT credit_id, remaining, expires_at FROM point_credits
+    WHERE user_id = $1 AND remaining > 0 ORDER BY created_at, credit_id FOR UPDATE`
+	consumeCreditQuery  = `UPDATE point_credits SET remaining = remaining - $1 WHERE credit_id = $2`
+	addConsumptionQuery = `INSERT INTO credit_consumptions (transaction_id, credit_id, amount) VALUES ($1, $2, $3)`
+	findDebitQuery      = `
+    SELECT transaction_id FROM transactions WHERE user_id = $1 AND kind = $2 AND reference = $3
+    ORDER BY transaction_id DESC LIMIT 1`
+	// Погашенные списанием части начислений в порядке погашения
+	consumedCreditsQuery = `
+    SELECT c.amount, p.expires_at FROM credit_consumptions c JOIN point_credits p ON p.credit_id = c.credit_id
+    WHERE c.transaction_id = $1 ORDER BY p.created_at, p.credit_id`
+
+	changeLifetimeQuery = `
+    UPDATE users SET lifetime_points = GREATEST(lifetime_points + $1, 0) WHERE user_id = $2
+    RETURNING lifetime_points, level_reached`
+	setLevelReachedQuery = `UPDATE users SET level_reached = $1 WHERE user_id = $2`
+	openSeasonQuery      = `SELECT season_id FROM seasons WHERE status = 'open'`
+	// Очки сезона меняются, только пока он открыт: итоги закрытого сезона уже зафиксированы
+	addSeasonScoreQuery = `
+    INSERT INTO season_scores (season_id, user_id, score) SELECT season_id, $2, $3 FROM seasons WHERE season_id = $1 AND status = 'open'
+    ON CONFLICT (season_id, user_id) DO UPDATE SET score = season_scores.score + EXCLUDED.score`
+	addLevelUpQuery = `
+    INSERT INTO level_ups (user_id, level, bonus) VALUES ($1, $2, $3) ON CONFLICT (user_id, level) DO NOTHING`
+)
+
+// lifetimeKinds виды операций, которые входят в заработанные за все время баллы и в очки сезона.
+// Отмена начисления уменьшает заработанное, переводы, траты и бонусы за уровни не учитываются.
+var lifetimeKinds = map[string]bool{
+	models.TransactionTaskReward:       true,
+	models.TransactionReferralBonus:    true,
+	models.TransactionAchievementBonus: true,
+	models.TransactionReversal:         true,
+}
+
+// creditSlice часть начисления: число баллов и срок их действия (nil - бессрочно)
+type creditSlice struct {
+	amount    int
+	expiresAt *time.Time
+}
+
+// queryRower выполняет запрос, возвращающий одну строку; реализуется *sql.DB и *sql.Tx
+type queryRower interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// queryer выполняет запрос, возвращающий набор строк; реализуется *sql.DB и *sql.Tx
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// Ledger изменяет балансы пользователей и ведет журнал операций.
+// Все изменения баланса должны проходить через Ledger внутри транзакции вызывающего репозитория.
+// Каждое начисление сохраняется отдельной записью со сроком действия, списания погашают
+// самые старые начисления первыми.
+type Ledger struct {
+	logger    *zap.Logger
+	pointsTTL time.Duration
+	levels    models.LevelCurve
+}
+
+// NewLedger создает журнал операций с балансом.
+// Нулевой pointsTTL означает, что начисленные баллы не сгорают.
+func NewLedger(logger *zap.Logger, pointsTTL time.Duration, levels models.LevelCurve) *Ledger {
+	return &Ledger{logger: logger, pointsTTL: pointsTTL, levels: levels}
+}
+
+// lockBalance блокирует строку пользователя до конца транзакции и возвращает текущий баланс
+func (l *Ledger) lockBalance(ctx context.Context, tx *sql.Tx, userID int64) (int, error) {
+	var balance int
+	err := tx.QueryRowContext(ctx, lockUserBalanceQuery, userID).Scan(&balance)
+	if err == sql.ErrNoRows {
+		l.logger.Info("user not found", zap.Int64("user_id", userID))
+		return 0, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), err)
+	} else if err != nil {
+		l.logger.Error("failed to lock user balance", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, errors.NewInternal("failed to lock user balance", err)
+	}
+	return balance, nil
+}
+
+// lockBalances блокирует строки нескольких пользователей в порядке возрастания ID и возвращает их балансы.
+// Транзакции, которые меняют балансы нескольких пользователей, блокируют их только так,
+// иначе встречные операции между теми же пользователями взаимно блокируют друг друга.
+func (l *Ledger) lockBalances(ctx context.Context, tx *sql.Tx, userIDs ...int64) (map[int64]int, error) {
+	ordered := append([]int64(nil), userIDs...)
+	sort.Slice(ordered, func(i, j int) bool { return ordered[i] < ordered[j] })
+
+	balances := make(map[int64]int, len(ordered))
+	for _, userID := range ordered {
+		if _, ok := balances[userID]; ok {
+			continue
+		}
+		balance, err := l.lockBalance(ctx, tx, userID)
+		if err != nil {
+			return nil, err
+		}
+		balances[userID] = balance
+	}
+	return balances, nil
+}
+
+// checkCurrency проверяет, что валюта существует
+func (l *Ledger) checkCurrency(ctx context.Context, q queryRower, currency string) error {
+	var exists bool
+	if err := q.QueryRowContext(ctx, checkCurrencyExistsQuery, currency).Scan(&exists); err != nil {
+		l.logger.Error("failed to check currency existence", zap.String("currency", currency), zap.Error(err))
+		return errors.NewInternal("failed to check currency existence", err)
+	}
+	if !exists {
+		l.logger.Info("currency not found", zap.String("currency", currency))
+		return errors.NewValidation(fmt.Sprintf("unknown currency %q", currency), nil)
+	}
+	return nil
+}
+
+// apply изменяет баланс пользователя на entry.Amount в валюте entry.Currency и сохраняет запись в журнал.
+// Пустая валюта означает coins. Сроки действия начислений ведутся только для coins.
+// Заполняет BalanceAfter, TransactionID и CreatedAt записи.
+func (l *Ledger) apply(ctx context.Context, tx *sql.Tx, entry *models.Transaction) error {
+	_, err := l.post(ctx, tx, entry, nil)
+	return err
+}
+
+// transfer списывает баллы записью outgoing и начисляет их записью incoming.
+// Начисление получает сроки действия погашенных начислений отправителя,
+// поэтому перевод туда и обратно не продлевает срок действия баллов.
+func (l *Ledger) transfer(ctx context.Context, tx *sql.Tx, outgoing, incoming *models.Transaction) error {
+	consumed, err := l.post(ctx, tx, outgoing, nil)
+	if err != nil {
+		return err
+	}
+	_, err = l.post(ctx, tx, incoming, consumed)
+	return err
+}
+
+// refund возвращает баллы за списание вида debitKind с той же ссылкой, что у entry.
+// Возвращенные баллы получают сроки действия начислений, погашенных списанием.
+func (l *Ledger) refund(ctx context.Context, tx *sql.Tx, entry *models.Transaction, debitKind string) error {
+	var debitID int64
+	err := tx.QueryRowContext(ctx, findDebitQuery, entry.UserID, debitKind, entry.Reference).Scan(&debitID)
+	if err != nil && err != sql.ErrNoRows {
+		l.logger.Error("failed to find refunded debit", zap.Int64("user_id", entry.UserID), zap.String("reference", entry.Reference), zap.Error(err))
+		return errors.NewInternal("failed to find refunded debit", err)
+	}
+
+	// Для списаний, сделанных до учета погашений, начисление получает обычный срок действия
+	var consumed []creditSlice
+	if err == nil {
+		if consumed, err = l.consumedCredits(ctx, tx, debitID); err != nil {
+			return err
+		}
+	}
+	_, err = l.post(ctx, tx, entry, consumed)
+	return err
+}
+
+// post изменяет баланс и сохраняет запись в журнал, как apply.
+// Начисление сначала получает сроки действия из inherited, остаток - обычный срок.
+// Для списания возвращает погашенные части начислений.
+func (l *Ledger) post(ctx context.Context, tx *sql.Tx, entry *models.Transaction, inherited []creditSlice) ([]creditSlice, error) {
+	if entry.Currency == "" {
+		entry.Currency = models.CurrencyCoins
+	}
+	if entry.Currency == models.CurrencyCoins {
+		if err := l.changeCoinsBalance(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	} else if err := l.changeCurrencyBalance(ctx, tx, entry); err != nil {
+		return nil, err
+	}
+
+	// Отмена начисления относится к сезону исходного начисления, его передает вызывающий
+	seasonal := entry.Currency == models.CurrencyCoins && lifetimeKinds[entry.Kind]
+	if seasonal && entry.Kind != models.TransactionReversal {
+		if err := l.openSeason(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+
+	err := tx.QueryRowContext(ctx, addTransactionQuery, entry.UserID, entry.Amount, entry.Currency, entry.BalanceAfter,
+		entry.Kind, entry.CounterpartyID, entry.Reference, entry.Reason, entry.CreatedBy, entry.SeasonID).
+		Scan(&entry.TransactionID, &entry.CreatedAt)
+	if err != nil {
+		l.logger.Error("failed to write ledger entry", zap.Int64("user_id", entry.UserID), zap.String("kind", entry.Kind), zap.Error(err))
+		return nil, errors.NewInternal("failed to write ledger entry", err)
+	}
+
+	if entry.Currency != models.CurrencyCoins {
+		return nil, nil
+	}
+	if seasonal {
+		if entry.SeasonID != nil {
+			if _, err := tx.ExecContext(ctx, addSeasonScoreQuery, *entry.SeasonID, entry.UserID, entry.Amount); err != nil {
+				l.logger.Error("failed to update season score", zap.Int64("user_id", entry.UserID), zap.Error(err))
+				return nil, errors.NewInternal("failed to update season score", err)
+			}
+		}
+		if err := l.trackLifetime(ctx, tx, entry); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case entry.Amount > 0:
+		return nil, l.addCredit(ctx, tx, entry, inherited)
+	case entry.Amount < 0 && entry.Kind != models.TransactionExpiry:
+		// Сгорание само закрывает истекшие начисления, остальные списания погашают самые старые
+		return l.consumeCredits(ctx, tx, entry)
+	}
+	return nil, nil
+}
+
+// openSeason записывает в entry.SeasonID открытый сезон; если открытого сезона нет, SeasonID остается пустым
+func (l *Ledger) openSeason(ctx context.Context, tx *sql.Tx, entry *models.Transaction) error {
+	var seasonID int64
+	err := tx.QueryRowContext(ctx, openSeasonQuery).Scan(&seasonID)
+	if err == sql.ErrNoRows {
+		entry.SeasonID = nil
+		return nil
+	} else if err != nil {
+		l.logger.Error("failed to get open season", zap.Error(err))
+		return errors.NewInternal("failed to get open season", err)
+	}
+	entry.SeasonID = &seasonID
+	return nil
+}
+
+// trackLifetime учитывает запись в заработанных за все время баллах и выдает бонусы за новые уровни.
+// Уровень за отмененные начисления не понижается, а бонус за каждый уровень выдается один раз.
+func (l *Ledger) trackLifetime(ctx context.Context, tx *sql.Tx, entry *models.Transaction) error {
+	var lifetime int
+	var reached sql.NullInt64
+	if err := tx.QueryRowContext(ctx, changeLifetimeQuery, entry.Amount, entry.UserID).Scan(&lifetime, &reached); err != nil {
+		l.logger.Error("failed to update lifetime points", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return errors.NewInternal("failed to update lifetime points", err)
+	}
+
+	current := level.For(lifetime, l.levels)
+	if reached.Valid && current <= int(reached.Int64) {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, setLevelReachedQuery, current, entry.UserID); err != nil {
+		l.logger.Error("failed to update user level", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return errors.NewInternal("failed to update user level", err)
+	}
+	// Для пользователей, чей уровень еще не вычислялся, уровень фиксируется без бонусов
+	if !reached.Valid {
+		return nil
+	}
+
+	for next := int(reached.Int64) + 1; next <= current; next++ {
+		if _, err := tx.ExecContext(ctx, addLevelUpQuery, entry.UserID, next, l.levels.LevelUpBonus); err != nil {
+			l.logger.Error("failed to save level up", zap.Int64("user_id", entry.UserID), zap.Int("level", next), zap.Error(err))
+			return errors.NewInternal("failed to save level up", err)
+		}
+		l.logger.Info("User reached new level", zap.Int64("user_id", entry.UserID), zap.Int("level", next))
+
+		if l.levels.LevelUpBonus > 0 {
+			bonus := models.Transaction{
+				UserID:    entry.UserID,
+				Amount:    l.levels.LevelUpBonus,
+				Kind:      models.TransactionLevelBonus,
+				Reference: fmt.Sprintf("level:%d", next),
+			}
+			if err := l.apply(ctx, tx, &bonus); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// changeCoinsBalance изменяет основной баланс пользователя и его копию в user_balances
+func (l *Ledger) changeCoinsBalance(ctx context.Context, tx *sql.Tx, entry *models.Transaction) error {
+	err := tx.QueryRowContext(ctx, changeBalanceQuery, entry.Amount, entry.UserID).Scan(&entry.BalanceAfter)
+	if err == sql.ErrNoRows {
+		l.logger.Info("user not found", zap.Int64("user_id", entry.UserID))
+		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", entry.UserID), err)
+	} else if err != nil {
+		l.logger.Error("failed to update user balance", zap.Int64("user_id", entry.UserID), zap.Int("amount", entry.Amount), zap.Error(err))
+		return errors.NewInternal("failed to update user balance", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, mirrorCoinsBalanceQuery, entry.UserID, entry.BalanceAfter); err != nil {
+		l.logger.Error("failed to mirror coins balance", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return errors.NewInternal("failed to mirror coins balance", err)
+	}
+	return nil
+}
+
+// changeCurrencyBalance изменяет баланс пользователя в дополнительной валюте
+func (l *Ledger) changeCurrencyBalance(ctx context.Context, tx *sql.Tx, entry *models.Transaction) error {
+	var exists bool
+	if err := tx.QueryRowContext(ctx, checkUserExistsQuery, entry.UserID).Scan(&exists); err != nil {
+		l.logger.Error("failed to check user existence", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return errors.NewInternal("failed to check user existence", err)
+	}
+	if !exists {
+		l.logger.Info("user not found", zap.Int64("user_id", entry.UserID))
+		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", entry.UserID), nil)
+	}
+	if err := l.checkCurrency(ctx, tx, entry.Currency); err != nil {
+		return err
+	}
+
+	err := tx.QueryRowContext(ctx, changeCurrencyBalanceQuery, entry.UserID, entry.Currency, entry.Amount).Scan(&entry.BalanceAfter)
+	if err != nil {
+		l.logger.Error("failed to update user balance", zap.Int64("user_id", entry.UserID),
+			zap.String("currency", entry.Currency), zap.Int("amount", entry.Amount), zap.Error(err))
+		return errors.NewInternal("failed to update user balance", err)
+	}
+	return nil
+}
+
+// addCredit сохраняет начисление со сроком действия.
+// Если до начисления баланс был отрицательным, сначала гасится долг и в начисление попадает только остаток.
+// Части inherited сохраняются со своими сроками действия, долг гасится частями с ближайшим сроком;
+// баллы сверх inherited получают срок pointsTTL от даты начисления.
+func (l *Ledger) addCredit(ctx context.Context, tx *sql.Tx, entry *models.Transaction, inherited []creditSlice) error {
+	remaining := entry.Amount
+	if balanceBefore := entry.BalanceAfter - entry.Amount; balanceBefore < 0 {
+		remaining += balanceBefore
+	}
+	if remaining <= 0 {
+		return nil
+	}
+
+	debt := entry.Amount - remaining
+	for _, slice := range inherited {
+		if remaining == 0 {
+			return nil
+		}
+		paid := min(debt, slice.amount)
+		debt -= paid
+		amount := min(slice.amount-paid, remaining)
+		if amount == 0 {
+			continue
+		}
+		if err := l.saveCredit(ctx, tx, entry, amount, amount, slice.expiresAt); err != nil {
+			return err
+		}
+		remaining -= amount
+	}
+	if remaining == 0 {
+		return nil
+	}
+
+	var expiresAt *time.Time
+	if l.pointsTTL > 0 {
+		expiry := entry.CreatedAt.Add(l.pointsTTL)
+		expiresAt = &expiry
+	}
+	amount := entry.Amount
+	if len(inherited) > 0 {
+		amount = remaining
+	}
+	return l.saveCredit(ctx, tx, entry, amount, remaining, expiresAt)
+}
+
+// saveCredit сохраняет запись о начислении
+func (l *Ledger) saveCredit(ctx context.Context, tx *sql.Tx, entry *models.Transaction, amount, remaining int, expiresAt *time.Time) error {
+	if _, err := tx.ExecContext(ctx, addCreditQuery, entry.UserID, entry.TransactionID, amount, remaining, expiresAt); err != nil {
+		l.logger.Error("failed to save point credit", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return errors.NewInternal("failed to save point credit", err)
+	}
+	return nil
+}
+
+// consumeCredits погашает начисления пользователя на сумму списания, начиная с самых старых,
+// запоминает погашенные части и возвращает их
+func (l *Ledger) consumeCredits(ctx context.Context, tx *sql.Tx, entry *models.Transaction) ([]creditSlice, error) {
+	rows, err := tx.QueryContext(ctx, lockOpenCreditsQuery, entry.UserID)
+	if err != nil {
+		l.logger.Error("failed to lock point credits", zap.Int64("user_id", entry.UserID), zap.Error(err))
+		return nil, errors.NewInternal("failed to lock point credits", err)
+	}
+
+	type openCredit struct {
+		id        int64
+		remaining int
+		expiresAt *time.Time
+	}
+	var credits []openCredit
+	for rows.Next() {
+		var credit openCredit
+		if err := rows.Scan(&credit.id, &credit.remaining, &credit.expiresAt); err != nil {
+			rows.Close()
+			l.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		credits = append(credits, credit)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		l.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+
+	amount := -entry.Amount
+	var slices []creditSlice
+	for _, credit := range credits {
+		if amount == 0 {
+			break
+		}
+		consumed := min(credit.remaining, amount)
+		if _, err := tx.ExecContext(ctx, consumeCreditQuery, consumed, credit.id); err != nil {
+			l.logger.Error("failed to consume point credit", zap.Int64("credit_id", credit.id), zap.Error(err))
+			return nil, errors.NewInternal("failed to consume point credit", err)
+		}
+		if _, err := tx.ExecContext(ctx, addConsumptionQuery, entry.TransactionID, credit.id, consumed); err != nil {
+			l.logger.Error("failed to save credit consumption", zap.Int64("credit_id", credit.id), zap.Error(err))
+			return nil, errors.NewInternal("failed to save credit consumption", err)
+		}
+		slices = append(slices, creditSlice{amount: consumed, expiresAt: credit.expiresAt})
+		amount -= consumed
+	}
+	return slices, nil
+}
+
+// consumedCredits возвращает части начислений, погашенные записью журнала
+func (l *Ledger) consumedCredits(ctx context.Context, q queryer, transactionID int64) ([]creditSlice, error) {
+	rows, err := q.QueryContext(ctx, consumedCreditsQuery, transactionID)
+	if err != nil {
+		l.logger.Error("Failed to execute query", zap.String("query", consumedCreditsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var slices []creditSlice
+	for rows.Next() {
+		var slice creditSlice
+		if err := rows.Scan(&slice.amount, &slice.expiresAt); err != nil {
+			l.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		slices = append(slices, slice)
+	}
+
+	if err := rows.Err(); err != nil {
+		l.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return slices, nil
+}
+
+// queryTransactions выполняет запрос и сканирует записи журнала
+func (l *Ledger) queryTransactions(ctx context.Context, q queryer, query string, args ...interface{}) ([]models.Transaction, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		l.logger.Error("Failed to execute query", zap.String("query", query), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var transactions []models.Transaction
+	for rows.Next() {
+		var entry models.Transaction
+		if err := rows.Scan(&entry.TransactionID, &entry.UserID, &entry.Amount, &entry.Currency, &entry.BalanceAfter, &entry.Kind,
+			&entry.CounterpartyID, &entry.Reference, &entry.Reason, &entry.CreatedBy, &entry.CreatedAt); err != nil {
+			l.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		transactions = append(transactions, entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		l.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return transactions, nil
+}
@@ -0,0 +1,90 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	// Владелец реферального кода
+	getReferCodeOwnerQuery = `SELECT user_id FROM users WHERE refer_code = $1`
+
+	// Сохранение перехода по реферальной ссылке
+	addReferralClickQuery = `
+    INSERT INTO referral_clicks (refer_code, user_agent, referrer, token) VALUES ($1, $2, $3, $4)
+    RETURNING click_id, clicked_at`
+
+	// Воронка по реферальному коду
+	getReferralStatsQuery = `
+    SELECT
+        (SELECT COUNT(*) FROM referral_clicks WHERE refer_code = $1),
+        (SELECT COUNT(*) FROM users u JOIN referral_clicks c ON c.click_id = u.referral_click_id
+            WHERE c.refer_code = $1),
+        (SELECT COUNT(*) FROM users u JOIN referral_clicks c ON c.click_id = u.referral_click_id
+            WHERE c.refer_code = $1 AND EXISTS(SELECT 1 FROM task_complete tc
+                WHERE tc.user_id = u.user_id AND tc.revoked_at IS NULL))`
+)
+
+// PostgresReferralRepository реализует репозиторий реферальных ссылок для PostgreSQL
+type PostgresReferralRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewPostgresReferralRepository создает новый экземпляр репозитория реферальных ссылок
+func NewPostgresReferralRepository(db *sql.DB, logger *zap.Logger) *PostgresReferralRepository {
+	return &PostgresReferralRepository{db: db, logger: logger}
+}
+
+// getReferCodeOwner возвращает пользователя, которому принадлежит реферальный код
+func (r *PostgresReferralRepository) getReferCodeOwner(ctx context.Context, referCode string) (int64, error) {
+	var userID int64
+	err := r.db.QueryRowContext(ctx, getReferCodeOwnerQuery, referCode).Scan(&userID)
+	if err == sql.ErrNoRows {
+		r.logger.Info("User with refer_code not found", zap.String("refer_code", referCode))
+		return 0, errors.NewNotFound(fmt.Sprintf("user with refer_code \"%s\" not found", referCode), nil)
+	} else if err != nil {
+		r.logger.Error("Failed to check refer_code existence", zap.String("refer_code", referCode), zap.Error(err))
+		return 0, errors.NewInternal("failed to check refer_code existence", err)
+	}
+	return userID, nil
+}
+
+// RecordClick сохраняет переход по реферальной ссылке и возвращает его идентификатор
+func (r *PostgresReferralRepository) RecordClick(ctx context.Context, click *models.ReferralClick) (int64, error) {
+	if _, err := r.getReferCodeOwner(ctx, click.ReferCode); err != nil {
+		return 0, err
+	}
+
+	err := r.db.QueryRowContext(ctx, addReferralClickQuery, click.ReferCode, click.UserAgent, click.Referrer, click.Token).
+		Scan(&click.ClickID, &click.ClickedAt)
+	if err != nil {
+		r.logger.Error("Failed to record referral click", zap.String("refer_code", click.ReferCode), zap.Error(err))
+		return 0, errors.NewInternal("failed to record referral click", err)
+	}
+
+	r.logger.Info("Referral click recorded", zap.String("refer_code", click.ReferCode), zap.Int64("click_id", click.ClickID))
+	return click.ClickID, nil
+}
+
+// GetReferralStats возвращает воронку по реферальному коду
+func (r *PostgresReferralRepository) GetReferralStats(ctx context.Context, referCode string) (models.ReferralStats, error) {
+	userID, err := r.getReferCodeOwner(ctx, referCode)
+	if err != nil {
+		return models.ReferralStats{}, err
+	}
+
+	stats := models.ReferralStats{UserID: userID, ReferCode: referCode}
+	err = r.db.QueryRowContext(ctx, getReferralStatsQuery, referCode).
+		Scan(&stats.Clicks, &stats.Signups, &stats.FirstTaskCompletions)
+	if err != nil {
+		r.logger.Error("Failed to fetch referral stats", zap.String("refer_code", referCode), zap.Error(err))
+		return models.ReferralStats{}, errors.NewInternal("failed to fetch referral stats", err)
+	}
+	return stats, nil
+}
@@ -0,0 +1,286 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+const (
+	addRewardQuery = `
+    INSERT INTO rewards (title, description, cost, stock, per_user_limit) VALUES ($1, $2, $3, $4, $5) RETURNING reward_id`
+	getActiveRewardsQuery = `
+    SELECT reward_id, title, COALESCE(description, ''), cost, stock, per_user_limit, active, created_at
+    FROM rewards WHERE active ORDER BY cost, reward_id`
+	lockRewardQuery = `
+    SELECT reward_id, title, cost, stock, per_user_limit, active FROM rewards WHERE reward_id = $1 FOR UPDATE`
+	countUserRedemptionsQuery = `
+    SELECT COUNT(*) FROM redemptions WHERE reward_id = $1 AND user_id = $2 AND status <> 'cancelled'`
+	decrementStockQuery = `UPDATE rewards SET stock = stock - 1 WHERE reward_id = $1 AND stock IS NOT NULL`
+	restoreStockQuery   = `UPDATE rewards SET stock = stock + 1 WHERE reward_id = $1 AND stock IS NOT NULL`
+	addRedemptionQuery  = `
+    INSERT INTO redemptions (reward_id, user_id, cost) VALUES ($1, $2, $3)
+    RETURNING redemption_id, status, created_at, updated_at`
+	selectRedemptionsQuery = `
+    SELECT r.redemption_id, r.reward_id, w.title, r.user_id, r.cost, r.status, r.created_at, r.updated_at
+    FROM redemptions r JOIN rewards w ON w.reward_id = r.reward_id`
+	getUserRedemptionsQuery   = selectRedemptionsQuery + ` WHERE r.user_id = $1 ORDER BY r.created_at DESC`
+	getRedemptionsQuery       = selectRedemptionsQuery + ` WHERE ($1 = '' OR r.status = $1) ORDER BY r.created_at`
+	getRedemptionQuery        = selectRedemptionsQuery + ` WHERE r.redemption_id = $1`
+	lockRedemptionQuery       = `SELECT reward_id, user_id, cost, status FROM redemptions WHERE redemption_id = $1 FOR UPDATE`
+	updateRedemptionStatusSQL = `UPDATE redemptions SET status = $1, updated_at = now() WHERE redemption_id = $2`
+)
+
+// redemptionTransitions допустимые переходы между статусами выдачи награды
+var redemptionTransitions = map[string]map[string]bool{
+	models.RedemptionPending: {
+		models.RedemptionProcessing: true,
+		models.RedemptionFulfilled:  true,
+		models.RedemptionCancelled:  true,
+	},
+	models.RedemptionProcessing: {
+		models.RedemptionFulfilled: true,
+		models.RedemptionCancelled: true,
+	},
+}
+
+// PostgresRewardRepository реализует репозиторий каталога наград для PostgreSQL
+type PostgresRewardRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	ledger *Ledger
+}
+
+// NewPostgresRewardRepository создает новый экземпляр репозитория наград
+func NewPostgresRewardRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger) *PostgresRewardRepository {
+	return &PostgresRewardRepository{db: db, logger: logger, ledger: ledger}
+}
+
+// CreateReward добавляет награду в каталог
+func (r *PostgresRewardRepository) CreateReward(ctx context.Context, reward *models.RewardCreate) (int64, error) {
+	var rewardID int64
+	err := r.db.QueryRowContext(ctx, addRewardQuery, reward.Title, reward.Description, reward.Cost, reward.Stock, reward.PerUserLimit).Scan(&rewardID)
+	if err != nil {
+		r.logger.Error("Cannot create reward", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create reward", err)
+	}
+	return rewardID, nil
+}
+
+// GetRewards возвращает активные награды каталога
+func (r *PostgresRewardRepository) GetRewards(ctx context.Context) ([]models.Reward, error) {
+	rows, err := r.db.QueryContext(ctx, getActiveRewardsQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getActiveRewardsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var rewards []models.Reward
+	for rows.Next() {
+		var reward models.Reward
+		if err := rows.Scan(&reward.RewardID, &reward.Title, &reward.Description, &reward.Cost,
+			&reward.Stock, &reward.PerUserLimit, &reward.Active, &reward.CreatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		rewards = append(rewards, reward)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return rewards, nil
+}
+
+// RedeemReward списывает стоимость награды с баланса пользователя и создает заявку на выдачу.
+// Все проверки и списание выполняются в одной транзакции с блокировкой строк награды и пользователя.
+func (r *PostgresRewardRepository) RedeemReward(ctx context.Context, userID, rewardID int64) (models.Redemption, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var reward models.Reward
+	err = tx.QueryRowContext(ctx, lockRewardQuery, rewardID).
+		Scan(&reward.RewardID, &reward.Title, &reward.Cost, &reward.Stock, &reward.PerUserLimit, &reward.Active)
+	if err == sql.ErrNoRows || (err == nil && !reward.Active) {
+		r.logger.Info("reward not found", zap.Int64("reward_id", rewardID))
+		return models.Redemption{}, errors.NewNotFound(fmt.Sprintf("reward with id %d not found", rewardID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock reward", zap.Int64("reward_id", rewardID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to lock reward", err)
+	}
+
+	if reward.Stock != nil && *reward.Stock <= 0 {
+		r.logger.Info("reward is out of stock", zap.Int64("reward_id", rewardID))
+		return models.Redemption{}, errors.NewValidation("reward is out of stock", nil)
+	}
+
+	if reward.PerUserLimit != nil {
+		var redeemed int
+		if err := tx.QueryRowContext(ctx, countUserRedemptionsQuery, rewardID, userID).Scan(&redeemed); err != nil {
+			r.logger.Error("failed to count user redemptions", zap.Int64("user_id", userID), zap.Error(err))
+			return models.Redemption{}, errors.NewInternal("failed to count user redemptions", err)
+		}
+		if redeemed >= *reward.PerUserLimit {
+			r.logger.Info("per-user limit reached", zap.Int64("user_id", userID), zap.Int64("reward_id", rewardID))
+			return models.Redemption{}, errors.NewValidation("per-user limit for this reward is reached", nil)
+		}
+	}
+
+	balance, err := r.ledger.lockBalance(ctx, tx, userID)
+	if err != nil {
+		return models.Redemption{}, err
+	}
+	if balance < reward.Cost {
+		r.logger.Info("insufficient balance", zap.Int64("user_id", userID), zap.Int("balance", balance), zap.Int("cost", reward.Cost))
+		return models.Redemption{}, errors.NewValidation("insufficient balance", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, decrementStockQuery, rewardID); err != nil {
+		r.logger.Error("failed to decrement reward stock", zap.Int64("reward_id", rewardID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to decrement reward stock", err)
+	}
+
+	redemption := models.Redemption{RewardID: rewardID, RewardTitle: reward.Title, UserID: userID, Cost: reward.Cost}
+	err = tx.QueryRowContext(ctx, addRedemptionQuery, rewardID, userID, reward.Cost).
+		Scan(&redemption.RedemptionID, &redemption.Status, &redemption.CreatedAt, &redemption.UpdatedAt)
+	if err != nil {
+		r.logger.Error("failed to create redemption", zap.Int64("user_id", userID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to create redemption", err)
+	}
+
+	debit := models.Transaction{
+		UserID:    userID,
+		Amount:    -reward.Cost,
+		Kind:      models.TransactionRedemption,
+		Reference: redemptionReference(redemption.RedemptionID),
+	}
+	if err := r.ledger.apply(ctx, tx, &debit); err != nil {
+		return models.Redemption{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Reward redeemed", zap.Int64("user_id", userID), zap.Int64("reward_id", rewardID), zap.Int64("redemption_id", redemption.RedemptionID))
+	return redemption, nil
+}
+
+// GetUserRedemptions возвращает историю обменов пользователя
+func (r *PostgresRewardRepository) GetUserRedemptions(ctx context.Context, userID int64) ([]models.Redemption, error) {
+	return r.queryRedemptions(ctx, getUserRedemptionsQuery, userID)
+}
+
+// GetRedemptions возвращает заявки на выдачу наград, опционально отфильтрованные по статусу
+func (r *PostgresRewardRepository) GetRedemptions(ctx context.Context, status string) ([]models.Redemption, error) {
+	return r.queryRedemptions(ctx, getRedemptionsQuery, status)
+}
+
+// queryRedemptions выполняет запрос и сканирует список заявок на выдачу
+func (r *PostgresRewardRepository) queryRedemptions(ctx context.Context, query string, args ...interface{}) ([]models.Redemption, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", query), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var redemptions []models.Redemption
+	for rows.Next() {
+		var redemption models.Redemption
+		if err := rows.Scan(&redemption.RedemptionID, &redemption.RewardID, &redemption.RewardTitle, &redemption.UserID,
+			&redemption.Cost, &redemption.Status, &redemption.CreatedAt, &redemption.UpdatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		redemptions = append(redemptions, redemption)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return redemptions, nil
+}
+
+// UpdateRedemptionStatus переводит заявку на выдачу в новый статус.
+// При отмене стоимость возвращается на баланс пользователя, а остаток награды восстанавливается.
+func (r *PostgresRewardRepository) UpdateRedemptionStatus(ctx context.Context, redemptionID int64, status string) (models.Redemption, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var current models.Redemption
+	err = tx.QueryRowContext(ctx, lockRedemptionQuery, redemptionID).
+		Scan(&current.RewardID, &current.UserID, &current.Cost, &current.Status)
+	if err == sql.ErrNoRows {
+		r.logger.Info("redemption not found", zap.Int64("redemption_id", redemptionID))
+		return models.Redemption{}, errors.NewNotFound(fmt.Sprintf("redemption with id %d not found", redemptionID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock redemption", zap.Int64("redemption_id", redemptionID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to lock redemption", err)
+	}
+
+	if !redemptionTransitions[current.Status][status] {
+		r.logger.Info("invalid redemption status transition",
+			zap.Int64("redemption_id", redemptionID), zap.String("from", current.Status), zap.String("to", status))
+		return models.Redemption{}, errors.NewValidation(
+			fmt.Sprintf("cannot change redemption status from %s to %s", current.Status, status), nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, updateRedemptionStatusSQL, status, redemptionID); err != nil {
+		r.logger.Error("failed to update redemption status", zap.Int64("redemption_id", redemptionID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to update redemption status", err)
+	}
+
+	if status == models.RedemptionCancelled {
+		refund := models.Transaction{
+			UserID:    current.UserID,
+			Amount:    current.Cost,
+			Kind:      models.TransactionRedemptionRefund,
+			Reference: redemptionReference(redemptionID),
+		}
+		if err := r.ledger.refund(ctx, tx, &refund, models.TransactionRedemption); err != nil {
+			return models.Redemption{}, err
+		}
+		if _, err := tx.ExecContext(ctx, restoreStockQuery, current.RewardID); err != nil {
+			r.logger.Error("failed to restore reward stock", zap.Int64("reward_id", current.RewardID), zap.Error(err))
+			return models.Redemption{}, errors.NewInternal("failed to restore reward stock", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	var redemption models.Redemption
+	err = r.db.QueryRowContext(ctx, getRedemptionQuery, redemptionID).Scan(&redemption.RedemptionID, &redemption.RewardID,
+		&redemption.RewardTitle, &redemption.UserID, &redemption.Cost, &redemption.Status, &redemption.CreatedAt, &redemption.UpdatedAt)
+	if err != nil {
+		r.logger.Error("failed to fetch redemption", zap.Int64("redemption_id", redemptionID), zap.Error(err))
+		return models.Redemption{}, errors.NewInternal("failed to fetch redemption", err)
+	}
+
+	r.logger.Info("Redemption status updated", zap.Int64("redemption_id", redemptionID), zap.String("status", status))
+	return redemption, nil
+}
+
+// redemptionReference ссылка на заявку на выдачу награды для записи журнала
+func redemptionReference(redemptionID int64) string {
+	return fmt.Sprintf("redemption:%d", redemptionID)
+}
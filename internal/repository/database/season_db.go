@@ -0,0 +1,261 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+	"sort"
+)
+
+// uniqueViolationCode код ошибки PostgreSQL при нарушении уникального индекса
+const uniqueViolationCode = "23505"
+
+// SQL-запросы
+const (
+	checkOpenSeasonQuery = `SELECT EXISTS(SELECT 1 FROM seasons WHERE status = 'open')`
+	addSeasonQuery       = `INSERT INTO seasons (title, ends_at) VALUES ($1, $2) RETURNING season_id`
+	selectSeasonsQuery   = `
+    SELECT season_id, title, status, starts_at, ends_at, closed_at, closed_by, created_at FROM seasons`
+	getSeasonsQuery  = selectSeasonsQuery + ` ORDER BY starts_at DESC, season_id DESC`
+	getSeasonQuery   = selectSeasonsQuery + ` WHERE season_id = $1`
+	lockSeasonQuery  = selectSeasonsQuery + ` WHERE season_id = $1 FOR UPDATE`
+	closeSeasonQuery = `UPDATE seasons SET status = 'closed', closed_at = now(), closed_by = $1 WHERE season_id = $2`
+
+	// Текущие места в сезоне; участвуют только пользователи с положительными очками
+	liveSeasonStandingsQuery = `
+    SELECT ROW_NUMBER() OVER (ORDER BY s.score DESC, s.user_id) AS rank, u.user_id, u.username, s.score, 0
+    FROM season_scores s JOIN users u ON u.user_id = s.user_id
+    WHERE s.season_id = $1 AND s.score > 0 ORDER BY rank`
+	snapshotSeasonStandingsQuery = `
+    INSERT INTO season_standings (season_id, rank, user_id, username, score)
+    SELECT $1, ROW_NUMBER() OVER (ORDER BY s.score DESC, s.user_id), u.user_id, u.username, s.score
+    FROM season_scores s JOIN users u ON u.user_id = s.user_id
+    WHERE s.season_id = $1 AND s.score > 0`
+	setSeasonPrizeQuery = `
+    UPDATE season_standings SET prize = $1 WHERE season_id = $2 AND rank = $3 RETURNING user_id`
+	archivedSeasonStandingsQuery = `
+    SELECT rank, user_id, username, score, prize FROM season_standings WHERE season_id = $1 ORDER BY rank`
+)
+
+// PostgresSeasonRepository реализует репозиторий сезонов для PostgreSQL
+type PostgresSeasonRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+	ledger *Ledger
+}
+
+// NewPostgresSeasonRepository создает новый экземпляр репозитория сезонов
+func NewPostgresSeasonRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger) *PostgresSeasonRepository {
+	return &PostgresSeasonRepository{db: db, logger: logger, ledger: ledger}
+}
+
+// CreateSeason открывает новый сезон. Одновременно может быть открыт только один сезон.
+func (r *PostgresSeasonRepository) CreateSeason(ctx context.Context, season *models.SeasonCreate) (int64, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, checkOpenSeasonQuery).Scan(&exists); err != nil {
+		r.logger.Error("failed to check open season", zap.Error(err))
+		return 0, errors.NewInternal("failed to check open season", err)
+	}
+	if exists {
+		return 0, errors.NewAlreadyExists("another season is still open", nil)
+	}
+
+	var seasonID int64
+	if err := r.db.QueryRowContext(ctx, addSeasonQuery, season.Title, season.EndsAt).Scan(&seasonID); err != nil {
+		// Параллельный запрос мог открыть сезон между проверкой и вставкой, его остановит seasons_single_open_idx
+		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code == uniqueViolationCode {
+			return 0, errors.NewAlreadyExists("another season is still open", err)
+		}
+		r.logger.Error("Cannot create season", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create season", err)
+	}
+	return seasonID, nil
+}
+
+// GetSeasons возвращает все сезоны, начиная с последнего
+func (r *PostgresSeasonRepository) GetSeasons(ctx context.Context) ([]models.Season, error) {
+	rows, err := r.db.QueryContext(ctx, getSeasonsQuery)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getSeasonsQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var seasons []models.Season
+	for rows.Next() {
+		var season models.Season
+		if err := rows.Scan(&season.SeasonID, &season.Title, &season.Status, &season.StartsAt, &season.EndsAt,
+			&season.ClosedAt, &season.ClosedBy, &season.CreatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		seasons = append(seasons, season)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return seasons, nil
+}
+
+// GetSeasonStandings возвращает сезон и места участников: текущие для открытого сезона
+// и зафиксированные при закрытии для завершенного.
+func (r *PostgresSeasonRepository) GetSeasonStandings(ctx context.Context, seasonID int64) (models.SeasonResult, error) {
+	var result models.SeasonResult
+	if err := r.scanSeason(r.db.QueryRowContext(ctx, getSeasonQuery, seasonID), seasonID, &result.Season); err != nil {
+		return models.SeasonResult{}, err
+	}
+
+	query := liveSeasonStandingsQuery
+	if result.Season.Status == models.SeasonClosed {
+		query = archivedSeasonStandingsQuery
+	}
+	standings, err := r.queryStandings(ctx, r.db, query, seasonID)
+	if err != nil {
+		return models.SeasonResult{}, err
+	}
+	result.Standings = standings
+	return result, nil
+}
+
+// CloseSeason закрывает сезон, фиксирует итоговые места и начисляет призы первым местам.
+// Все изменения выполняются в одной транзакции: сезон не может быть закрыт дважды.
+func (r *PostgresSeasonRepository) CloseSeason(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.SeasonResult{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var season models.Season
+	if err := r.scanSeason(tx.QueryRowContext(ctx, lockSeasonQuery, closing.SeasonID), closing.SeasonID, &season); err != nil {
+		return models.SeasonResult{}, err
+	}
+	if season.Status == models.SeasonClosed {
+		r.logger.Info("season already closed", zap.Int64("season_id", closing.SeasonID))
+		return models.SeasonResult{}, errors.NewAlreadyExists("season is already closed", nil)
+	}
+
+	if _, err := tx.ExecContext(ctx, snapshotSeasonStandingsQuery, closing.SeasonID); err != nil {
+		r.logger.Error("failed to snapshot season standings", zap.Int64("season_id", closing.SeasonID), zap.Error(err))
+		return models.SeasonResult{}, errors.NewInternal("failed to snapshot season standings", err)
+	}
+
+	if err := r.payPrizes(ctx, tx, closing); err != nil {
+		return models.SeasonResult{}, err
+	}
+
+	if _, err := tx.ExecContext(ctx, closeSeasonQuery, closing.AdminID, closing.SeasonID); err != nil {
+		r.logger.Error("failed to close season", zap.Int64("season_id", closing.SeasonID), zap.Error(err))
+		return models.SeasonResult{}, errors.NewInternal("failed to close season", err)
+	}
+
+	result := models.SeasonResult{}
+	if err := r.scanSeason(tx.QueryRowContext(ctx, getSeasonQuery, closing.SeasonID), closing.SeasonID, &result.Season); err != nil {
+		return models.SeasonResult{}, err
+	}
+	result.Standings, err = r.queryStandings(ctx, tx, archivedSeasonStandingsQuery, closing.SeasonID)
+	if err != nil {
+		return models.SeasonResult{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.SeasonResult{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Season closed", zap.Int64("season_id", closing.SeasonID), zap.Int("participants", len(result.Standings)),
+		zap.Int("prizes", len(closing.Prizes)), zap.Int64("admin_id", closing.AdminID))
+	return result, nil
+}
+
+// payPrizes записывает призы в итоговые места и начисляет их победителям.
+// Балансы изменяются в порядке возрастания ID пользователей, как и в остальных операциях с несколькими пользователями.
+func (r *PostgresSeasonRepository) payPrizes(ctx context.Context, tx *sql.Tx, closing *models.SeasonClose) error {
+	type winner struct {
+		userID int64
+		rank   int
+		prize  int
+	}
+	var winners []winner
+	for i, prize := range closing.Prizes {
+		if prize == 0 {
+			continue
+		}
+		var userID sql.NullInt64
+		err := tx.QueryRowContext(ctx, setSeasonPrizeQuery, prize, closing.SeasonID, i+1).Scan(&userID)
+		if err == sql.ErrNoRows {
+			// Участников меньше, чем призовых мест
+			break
+		} else if err != nil {
+			r.logger.Error("failed to set season prize", zap.Int64("season_id", closing.SeasonID), zap.Int("rank", i+1), zap.Error(err))
+			return errors.NewInternal("failed to set season prize", err)
+		}
+		if userID.Valid {
+			winners = append(winners, winner{userID: userID.Int64, rank: i + 1, prize: prize})
+		}
+	}
+
+	sort.Slice(winners, func(i, j int) bool { return winners[i].userID < winners[j].userID })
+	for _, w := range winners {
+		entry := models.Transaction{
+			UserID:    w.userID,
+			Amount:    w.prize,
+			Kind:      models.TransactionSeasonPrize,
+			Reference: fmt.Sprintf("season:%d", closing.SeasonID),
+			CreatedBy: &closing.AdminID,
+		}
+		if err := r.ledger.apply(ctx, tx, &entry); err != nil {
+			return err
+		}
+		r.logger.Info("Season prize paid", zap.Int64("season_id", closing.SeasonID), zap.Int("rank", w.rank),
+			zap.Int64("user_id", w.userID), zap.Int("prize", w.prize))
+	}
+	return nil
+}
+
+// scanSeason сканирует строку сезона
+func (r *PostgresSeasonRepository) scanSeason(row *sql.Row, seasonID int64, season *models.Season) error {
+	err := row.Scan(&season.SeasonID, &season.Title, &season.Status, &season.StartsAt, &season.EndsAt,
+		&season.ClosedAt, &season.ClosedBy, &season.CreatedAt)
+	if err == sql.ErrNoRows {
+		r.logger.Info("season not found", zap.Int64("season_id", seasonID))
+		return errors.NewNotFound(fmt.Sprintf("season with id %d not found", seasonID), err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch season", zap.Int64("season_id", seasonID), zap.Error(err))
+		return errors.NewInternal("failed to fetch season", err)
+	}
+	return nil
+}
+
+// queryStandings выполняет запрос мест в сезоне
+func (r *PostgresSeasonRepository) queryStandings(ctx context.Context, q queryer, query string, seasonID int64) ([]models.SeasonStanding, error) {
+	rows, err := q.QueryContext(ctx, query, seasonID)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", query), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var standings []models.SeasonStanding
+	for rows.Next() {
+		var standing models.SeasonStanding
+		if err := rows.Scan(&standing.Rank, &standing.UserID, &standing.Username, &standing.Score, &standing.Prize); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		standings = append(standings, standing)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return standings, nil
+}
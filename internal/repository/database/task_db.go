@@ -3,32 +3,103 @@ package database
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/level"
 	"github.com/ZnNr/user-task-reward-controller/internal/service/refercode"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/streak"
+	"github.com/lib/pq"
 	"go.uber.org/zap"
+	"strings"
+	"time"
 )
 
 // SQL Queries
 const (
-	addTaskQuery            = `INSERT INTO tasks (title, description, price) VALUES ($1, $2, $3) RETURNING task_id`
+	addTaskQuery = `
+    INSERT INTO tasks (title, description, price, task_type, campaign_id, category_id)
+    VALUES ($1, $2, $3, $4, $5, $6) RETURNING task_id`
 	checkTaskDuplicateQuery = `SELECT COUNT(*) FROM tasks WHERE title = $1 AND description = $2 AND task_id <> $3`
-	completeTaskQuery       = `SELECT task_id, price FROM tasks WHERE task_id=$1`
-	userQuery               = `SELECT user_id, balance, refer_from FROM users WHERE user_id=$1`
-	completeQuery           = `INSERT INTO task_complete(user_id, task_id) VALUES ($1, $2)`
-	balanceToUserQuery      = `UPDATE users SET balance=balance+$1 WHERE user_id=$2`
+	addTaskPriceQuery       = `INSERT INTO task_prices (task_id, currency, amount) VALUES ($1, $2, $3)`
+	completeTaskQuery       = `SELECT task_id, price, task_type, campaign_id FROM tasks WHERE task_id=$1`
+	taskPricesQuery         = `SELECT currency, amount FROM task_prices WHERE task_id=$1 ORDER BY currency`
+	// Список заданий; вместо %s подставляются условия фильтра по таблице tasks t
+	selectTasksQuery = `
+    SELECT s.task_id, s.title, s.description, s.price, s.task_type, s.campaign_id, s.category, s.tags, s.prices, s.completions
+    FROM (
+        SELECT t.task_id, t.title, COALESCE(t.description, '') AS description, t.price, t.task_type, t.campaign_id,
+            COALESCE(c.code, '') AS category,
+            COALESCE((SELECT json_agg(tt.tag ORDER BY tt.tag) FROM task_tags tt WHERE tt.task_id = t.task_id), '[]') AS tags,
+            COALESCE((SELECT json_object_agg(p.currency, p.amount) FROM task_prices p WHERE p.task_id = t.task_id), '{}') AS prices,
+            (SELECT COUNT(*) FROM task_complete tc WHERE tc.task_id = t.task_id AND tc.revoked_at IS NULL) AS completions
+        FROM tasks t LEFT JOIN categories c ON c.category_id = t.category_id
+        WHERE %s
+    ) s`
+	addTaskTagQuery    = `INSERT INTO task_tags (task_id, tag) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+	categoryIDQuery    = `SELECT category_id FROM categories WHERE code = $1`
+	checkCategoryQuery = `SELECT EXISTS(SELECT 1 FROM categories WHERE code = $1)`
+	addCategoryQuery   = `INSERT INTO categories (code, title) VALUES ($1, $2) RETURNING category_id`
+	getCategoriesQuery = `SELECT category_id, code, title, created_at FROM categories ORDER BY title, category_id`
+	userQuery          = `SELECT user_id, balance, lifetime_points, refer_from FROM users WHERE user_id=$1 FOR UPDATE`
+	taskReferrerQuery  = `SELECT r.user_id FROM users u JOIN users r ON r.user_id::text = u.refer_from WHERE u.user_id = $1`
+	completeQuery      = `INSERT INTO task_complete(user_id, task_id) VALUES ($1, $2) RETURNING id, completed_at`
+	activeBoostQuery   = `
+    SELECT boost_id, multiplier_percent FROM boosts
+    WHERE starts_at <= $1 AND ends_at > $1 AND (task_type IS NULL OR task_type = $2) AND (min_level IS NULL OR min_level <= $3)
+    ORDER BY multiplier_percent DESC, boost_id LIMIT 1`
+	recordMultipliersQuery = `
+    UPDATE task_complete SET multiplier_percent = $1, boost_id = $2, streak_bonus_percent = $3 WHERE id = $4`
+	lockStreakQuery = `
+    SELECT current_streak, longest_streak, last_active_day, freezes_used FROM user_streaks WHERE user_id = $1 FOR UPDATE`
+	saveStreakQuery = `
+    INSERT INTO user_streaks (user_id, current_streak, longest_streak, last_active_day, freezes_used)
+    VALUES ($1, $2, $3, $4, $5)
+    ON CONFLICT (user_id) DO UPDATE SET current_streak = EXCLUDED.current_streak,
+        longest_streak = EXCLUDED.longest_streak, last_active_day = EXCLUDED.last_active_day,
+        freezes_used = EXCLUDED.freezes_used, updated_at = now()`
+
+	selectCompletionsQuery = `
+    SELECT tc.id, tc.user_id, tc.task_id, t.title, tc.completed_at, tc.revoked_at, tc.revoked_by, COALESCE(tc.revoke_reason, ''),
+        tc.multiplier_percent, tc.boost_id, tc.streak_bonus_percent
+    FROM task_complete tc JOIN tasks t ON t.task_id = tc.task_id`
+	getUserCompletionsQuery = selectCompletionsQuery + ` WHERE tc.user_id = $1 ORDER BY tc.completed_at DESC`
+	getCompletionQuery      = selectCompletionsQuery + ` WHERE tc.id = $1`
+	lockCompletionQuery     = `SELECT revoked_at FROM task_complete WHERE id = $1 FOR UPDATE`
+	revokeCompletionQuery   = `UPDATE task_complete SET revoked_at = now(), revoked_by = $1, revoke_reason = $2 WHERE id = $3`
+	// Начисления, сделанные при выполнении задания: награда исполнителю и бонус пригласившему
+	completionCreditsQuery = `
+    SELECT user_id, amount, currency, counterparty_id, season_id FROM transactions
+    WHERE reference = $1 AND kind IN ('task_reward', 'referral_bonus') ORDER BY user_id, currency`
+
+	campaignStatusQuery = `SELECT status FROM campaigns WHERE campaign_id = $1`
+	// Статус кампании и попадает ли текущий момент в период ее действия
+	lockCampaignQuery = `
+    SELECT status, starts_at <= now() AND ends_at > now() FROM campaigns WHERE campaign_id = $1 FOR UPDATE`
+	// Списание из бюджета кампании баллов в coins, выплаченных за выполнение; не выполняется при нехватке бюджета
+	chargeCampaignQuery = `
+    UPDATE campaigns c SET spent = c.spent + p.total
+    FROM (SELECT COALESCE(SUM(amount), 0) AS total FROM transactions
+        WHERE reference = $2 AND currency = 'coins' AND kind IN ('task_reward', 'referral_bonus')) p
+    WHERE c.campaign_id = $1 AND c.spent + p.total <= c.budget`
+	refundCampaignQuery = `
+    UPDATE campaigns c SET spent = GREATEST(c.spent - $1, 0)
+    FROM task_complete tc JOIN tasks t ON t.task_id = tc.task_id
+    WHERE tc.id = $2 AND c.campaign_id = t.campaign_id`
 )
 
 // TaskRepository для работы с задачами
 type PostgresTaskRepository struct {
-	db     *sql.DB
-	logger *zap.Logger
+	db          *sql.DB
+	logger      *zap.Logger
+	ledger      *Ledger
+	streakRules models.StreakRules
 }
 
 // NewPostgresTaskRepository создает новый экземпляр репозитория задач
-func NewPostgresTaskRepository(db *sql.DB, logger *zap.Logger) *PostgresTaskRepository {
-	return &PostgresTaskRepository{db: db, logger: logger}
+func NewPostgresTaskRepository(db *sql.DB, logger *zap.Logger, ledger *Ledger, streakRules models.StreakRules) *PostgresTaskRepository {
+	return &PostgresTaskRepository{db: db, logger: logger, ledger: ledger, streakRules: streakRules}
 }
 
 // executeQuery выполняет SQL-запрос и возвращает результат
@@ -62,7 +133,7 @@ func (r *PostgresTaskRepository) executeExec(ctx context.Context, query string,
 	return rowsAffected, nil
 }
 
-// CreateTask создает новую задачу
+// CreateTask создает новую задачу вместе со стоимостью по валютам
 func (r *PostgresTaskRepository) CreateTask(ctx context.Context, task *models.TaskCreate) (int64, error) {
 	// Проверяем на дубликаты
 	if isDuplicate, err := r.checkForDuplicateTask(ctx, task, 0); err != nil {
@@ -70,12 +141,59 @@ func (r *PostgresTaskRepository) CreateTask(ctx context.Context, task *models.Ta
 	} else if isDuplicate {
 		return 0, errors.NewAlreadyExists("task with the same title and description already exists", nil)
 	}
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
 	var lastID int64
-	err := r.db.QueryRowContext(ctx, addTaskQuery, task.Title, task.Description, task.Price).Scan(&lastID)
+	if task.CampaignID != nil {
+		if err := r.checkCampaignOpen(ctx, tx, *task.CampaignID); err != nil {
+			return 0, err
+		}
+	}
+
+	var categoryID sql.NullInt64
+	if task.Category != "" {
+		err := tx.QueryRowContext(ctx, categoryIDQuery, task.Category).Scan(&categoryID)
+		if err == sql.ErrNoRows {
+			return 0, errors.NewValidation(fmt.Sprintf("unknown category %q", task.Category), nil)
+		} else if err != nil {
+			r.logger.Error("failed to fetch category", zap.String("category", task.Category), zap.Error(err))
+			return 0, errors.NewInternal("failed to fetch category", err)
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, addTaskQuery, task.Title, task.Description, task.Price, task.Type, task.CampaignID, categoryID).Scan(&lastID)
 	if err != nil {
 		r.logger.Error("Cannot create task", zap.Error(err))
 		return 0, errors.NewInternal("Cannot create task", err)
 	}
+
+	for currency, amount := range task.Prices {
+		if err := r.ledger.checkCurrency(ctx, tx, currency); err != nil {
+			return 0, err
+		}
+		if _, err := tx.ExecContext(ctx, addTaskPriceQuery, lastID, currency, amount); err != nil {
+			r.logger.Error("Cannot save task price", zap.String("currency", currency), zap.Error(err))
+			return 0, errors.NewInternal("Cannot save task price", err)
+		}
+	}
+
+	for _, tag := range task.Tags {
+		if _, err := tx.ExecContext(ctx, addTaskTagQuery, lastID, tag); err != nil {
+			r.logger.Error("Cannot save task tag", zap.String("tag", tag), zap.Error(err))
+			return 0, errors.NewInternal("Cannot save task tag", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
 	return lastID, nil
 }
 
@@ -94,60 +212,303 @@ func (r *PostgresTaskRepository) checkForDuplicateTask(ctx context.Context, task
 func (r *PostgresTaskRepository) CompleteTask(ctx context.Context, userId, taskId int64) error {
 	// Проверяем, существует ли задача
 	var task models.Task
-	err := r.db.QueryRowContext(ctx, completeTaskQuery, taskId).Scan(&task.TaskID, &task.Price)
+	err := r.db.QueryRowContext(ctx, completeTaskQuery, taskId).Scan(&task.TaskID, &task.Price, &task.Type, &task.CampaignID)
 	if err != nil {
 		r.logger.Info("task not found", zap.Int64("task_id", taskId), zap.Error(err))
 		return errors.NewNotFound(fmt.Sprintf("task with id %d not found", taskId), err)
 	}
 
-	// Проверяем, существует ли пользователь
-	var user models.User
-	err = r.db.QueryRowContext(ctx, userQuery, userId).Scan(&user.ID, &user.Balance, &user.ReferFrom)
-	if err != nil {
-		r.logger.Info("user not found", zap.Int64("user_id", userId), zap.Error(err))
-		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", userId), err)
-	}
-
 	// Начинаем транзакцию
 	tx, err := r.db.BeginTx(ctx, nil)
 	if err != nil {
 		r.logger.Error("failed to begin transaction", zap.Error(err))
 		return errors.NewInternal("failed to begin transaction", err)
 	}
-	defer func() {
-		if err != nil {
-			tx.Rollback()
-			return
+	defer tx.Rollback()
+
+	// Пользователь и пригласивший его блокируются до конца транзакции в порядке ID, как в переводах
+	lockIDs := []int64{userId}
+	var referrerID int64
+	err = tx.QueryRowContext(ctx, taskReferrerQuery, userId).Scan(&referrerID)
+	if err == nil {
+		lockIDs = append(lockIDs, referrerID)
+	} else if err != sql.ErrNoRows {
+		r.logger.Error("failed to fetch referrer", zap.Int64("user_id", userId), zap.Error(err))
+		return errors.NewInternal("failed to fetch referrer", err)
+	}
+	if _, err := r.ledger.lockBalances(ctx, tx, lockIDs...); err != nil {
+		return err
+	}
+
+	var user models.User
+	err = tx.QueryRowContext(ctx, userQuery, userId).Scan(&user.ID, &user.Balance, &user.LifetimePoints, &user.ReferFrom)
+	if err != nil {
+		r.logger.Info("user not found", zap.Int64("user_id", userId), zap.Error(err))
+		return errors.NewNotFound(fmt.Sprintf("user with id %d not found", userId), err)
+	}
+
+	// Задание кампании можно выполнить, только пока кампания идет; строка кампании блокируется
+	// до конца транзакции, чтобы параллельные выполнения не превысили ее бюджет
+	if task.CampaignID != nil {
+		if err := r.lockRunningCampaign(ctx, tx, *task.CampaignID); err != nil {
+			return err
 		}
-		err = tx.Commit()
-	}()
+	}
 
 	// Выполняем запись о завершении задачи
-	rowsAffected, err := r.executeExec(ctx, completeQuery, userId, taskId)
-	if err != nil || rowsAffected == 0 {
+	var completionID int64
+	var completedAt time.Time
+	if err := tx.QueryRowContext(ctx, completeQuery, userId, taskId).Scan(&completionID, &completedAt); err != nil {
 		r.logger.Error("failed to complete task", zap.Int64("user_id", userId), zap.Int64("task_id", taskId), zap.Error(err))
 		return errors.NewInternal("failed to complete task", err)
 	}
 
-	// Обновляем баланс пользователя
-	rowsAffected, err = r.executeExec(ctx, balanceToUserQuery, task.Price, userId)
-	if err != nil || rowsAffected == 0 {
-		r.logger.Error("failed to update user balance", zap.Int64("user_id", userId), zap.Int("price", int(task.Price)), zap.Error(err))
-		return errors.NewInternal("failed to update user balance", err)
+	userStreak, err := r.advanceStreak(ctx, tx, userId, completedAt)
+	if err != nil {
+		return err
+	}
+
+	boost, err := r.activeBoost(ctx, tx, task.Type, level.For(user.LifetimePoints, r.ledger.levels), completedAt)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, recordMultipliersQuery, boost.MultiplierPercent, boost.BoostID,
+		userStreak.BonusPercent, completionID); err != nil {
+		r.logger.Error("failed to record reward multipliers", zap.Int64("completion_id", completionID), zap.Error(err))
+		return errors.NewInternal("failed to record reward multipliers", err)
+	}
+
+	prices, err := r.taskPrices(ctx, tx, taskId)
+	if err != nil {
+		return err
+	}
+
+	// Начисляем награду пользователю в каждой валюте задания с множителем акции и надбавкой за серию.
+	// Реферальный бонус считается от базовой стоимости задания.
+	for _, price := range prices {
+		reward := models.Transaction{
+			UserID:    userId,
+			Amount:    streak.Apply(price.Amount*boost.MultiplierPercent/100, userStreak.BonusPercent),
+			Currency:  price.Currency,
+			Kind:      models.TransactionTaskReward,
+			Reference: completionReference(completionID),
+		}
+		if err := r.ledger.apply(ctx, tx, &reward); err != nil {
+			r.logger.Error("failed to update user balance", zap.Int64("user_id", userId),
+				zap.String("currency", price.Currency), zap.Int("price", price.Amount), zap.Error(err))
+			return err
+		}
 	}
 
 	// Если у пользователя есть реферал, выплачиваем бонус
 	if user.ReferFrom != nil {
-		if err := r.referralReward(user.ReferFrom, int(task.Price)); err != nil {
+		if err := r.referralReward(ctx, tx, userId, *user.ReferFrom, prices, completionID); err != nil {
 			r.logger.Error("failed to process referral reward", zap.Int("refer_id", *user.ReferFrom), zap.Error(err))
+			return err
+		}
+	}
+
+	if task.CampaignID != nil {
+		if err := r.chargeCampaign(ctx, tx, *task.CampaignID, completionID); err != nil {
+			return err
 		}
 	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
+
+// checkCampaignOpen проверяет, что в кампанию можно добавить задание
+func (r *PostgresTaskRepository) checkCampaignOpen(ctx context.Context, tx *sql.Tx, campaignID int64) error {
+	var status string
+	err := tx.QueryRowContext(ctx, campaignStatusQuery, campaignID).Scan(&status)
+	if err == sql.ErrNoRows {
+		r.logger.Info("campaign not found", zap.Int64("campaign_id", campaignID))
+		return errors.NewNotFound(fmt.Sprintf("campaign with id %d not found", campaignID), err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch campaign", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return errors.NewInternal("failed to fetch campaign", err)
+	}
+	if status == models.CampaignEnded {
+		return errors.NewValidation("cannot add tasks to an ended campaign", nil)
+	}
 	return nil
 }
 
-// GetAllTasks возвращает все задачи
-func (r *PostgresTaskRepository) GetAllTasks(ctx context.Context) ([]models.Task, error) {
-	rows, err := r.executeQuery(ctx, "SELECT task_id, title, description FROM tasks")
+// lockRunningCampaign блокирует строку кампании и проверяет, что кампания запущена и идет период ее действия
+func (r *PostgresTaskRepository) lockRunningCampaign(ctx context.Context, tx *sql.Tx, campaignID int64) error {
+	var status string
+	var inPeriod bool
+	err := tx.QueryRowContext(ctx, lockCampaignQuery, campaignID).Scan(&status, &inPeriod)
+	if err != nil {
+		r.logger.Error("failed to lock campaign", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return errors.NewInternal("failed to lock campaign", err)
+	}
+	if status != models.CampaignLive || !inPeriod {
+		r.logger.Info("campaign is not running", zap.Int64("campaign_id", campaignID), zap.String("status", status))
+		return errors.NewValidation("campaign is not running", nil)
+	}
+	return nil
+}
+
+// chargeCampaign списывает из бюджета кампании баллы, выплаченные за выполнение задания.
+// Если бюджета не хватает, выполнение отклоняется вместе со всеми начислениями.
+func (r *PostgresTaskRepository) chargeCampaign(ctx context.Context, tx *sql.Tx, campaignID, completionID int64) error {
+	result, err := tx.ExecContext(ctx, chargeCampaignQuery, campaignID, completionReference(completionID))
+	if err != nil {
+		r.logger.Error("failed to charge campaign budget", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return errors.NewInternal("failed to charge campaign budget", err)
+	}
+	charged, err := result.RowsAffected()
+	if err != nil {
+		r.logger.Error("failed to get affected rows", zap.Error(err))
+		return errors.NewInternal("failed to get affected rows", err)
+	}
+	if charged == 0 {
+		r.logger.Info("campaign budget exhausted", zap.Int64("campaign_id", campaignID), zap.Int64("completion_id", completionID))
+		return errors.NewValidation("campaign budget exhausted", nil)
+	}
+	return nil
+}
+
+// appliedBoost множитель награды, выбранный для выполнения задания
+type appliedBoost struct {
+	BoostID           *int64
+	MultiplierPercent int
+}
+
+// activeBoost выбирает действующий в момент at множитель с наибольшим значением.
+// Множители не суммируются: применяется только самый выгодный для пользователя.
+func (r *PostgresTaskRepository) activeBoost(ctx context.Context, tx *sql.Tx, taskType string, userLevel int, at time.Time) (appliedBoost, error) {
+	boost := appliedBoost{MultiplierPercent: 100}
+	var boostID int64
+	err := tx.QueryRowContext(ctx, activeBoostQuery, at, taskType, userLevel).Scan(&boostID, &boost.MultiplierPercent)
+	if err == sql.ErrNoRows {
+		return boost, nil
+	} else if err != nil {
+		r.logger.Error("failed to fetch active boost", zap.String("task_type", taskType), zap.Error(err))
+		return appliedBoost{}, errors.NewInternal("failed to fetch active boost", err)
+	}
+	boost.BoostID = &boostID
+	return boost, nil
+}
+
+// advanceStreak продлевает серию пользователя выполнением задания и сохраняет ее
+func (r *PostgresTaskRepository) advanceStreak(ctx context.Context, tx *sql.Tx, userId int64, completedAt time.Time) (models.Streak, error) {
+	var current models.Streak
+	err := tx.QueryRowContext(ctx, lockStreakQuery, userId).
+		Scan(&current.Current, &current.Longest, &current.LastActiveDay, &current.FreezesUsed)
+	if err != nil && err != sql.ErrNoRows {
+		r.logger.Error("failed to lock user streak", zap.Int64("user_id", userId), zap.Error(err))
+		return models.Streak{}, errors.NewInternal("failed to lock user streak", err)
+	}
+
+	next := streak.Advance(current, completedAt, r.streakRules)
+	if _, err := tx.ExecContext(ctx, saveStreakQuery, userId, next.Current, next.Longest, next.LastActiveDay, next.FreezesUsed); err != nil {
+		r.logger.Error("failed to save user streak", zap.Int64("user_id", userId), zap.Error(err))
+		return models.Streak{}, errors.NewInternal("failed to save user streak", err)
+	}
+	return next, nil
+}
+
+// taskPrice стоимость задания в одной валюте
+type taskPrice struct {
+	Currency string
+	Amount   int
+}
+
+// taskPrices возвращает стоимость задания по валютам
+func (r *PostgresTaskRepository) taskPrices(ctx context.Context, tx *sql.Tx, taskId int64) ([]taskPrice, error) {
+	rows, err := tx.QueryContext(ctx, taskPricesQuery, taskId)
+	if err != nil {
+		r.logger.Error("failed to fetch task prices", zap.Int64("task_id", taskId), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch task prices", err)
+	}
+	defer rows.Close()
+
+	var prices []taskPrice
+	for rows.Next() {
+		var price taskPrice
+		if err := rows.Scan(&price.Currency, &price.Amount); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		prices = append(prices, price)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return prices, nil
+}
+
+// taskSort порядок списка заданий и условие продолжения после курсора.
+// В after %[1]s - параметр значения ключа сортировки, %[2]s - параметр ID последнего задания.
+type taskSort struct {
+	order string
+	after string
+	// keyed сортировка использует значение ключа, а не только ID задания
+	keyed bool
+}
+
+// taskSorts поддерживаемые сортировки списка заданий.
+// При равных значениях ключа задания упорядочены по ID, поэтому курсор однозначен.
+var taskSorts = map[string]taskSort{
+	models.TaskSortID:     {order: "s.task_id", after: "s.task_id > %[2]s"},
+	models.TaskSortNewest: {order: "s.task_id DESC", after: "s.task_id < %[2]s"},
+	models.TaskSortPriceAsc: {order: "s.price, s.task_id", keyed: true,
+		after: "(s.price > %[1]s OR (s.price = %[1]s AND s.task_id > %[2]s))"},
+	models.TaskSortPriceDesc: {order: "s.price DESC, s.task_id", keyed: true,
+		after: "(s.price < %[1]s OR (s.price = %[1]s AND s.task_id > %[2]s))"},
+	models.TaskSortPopular: {order: "s.completions DESC, s.task_id", keyed: true,
+		after: "(s.completions < %[1]s OR (s.completions = %[1]s AND s.task_id > %[2]s))"},
+}
+
+// GetAllTasks возвращает страницу заданий с учетом поиска, фильтров и сортировки.
+// Возвращается не больше filter.Limit заданий (0 - без ограничения), начиная с позиции filter.After.
+func (r *PostgresTaskRepository) GetAllTasks(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+	sort, ok := taskSorts[filter.Sort]
+	if !ok {
+		return nil, errors.NewValidation(fmt.Sprintf("unknown sort %q", filter.Sort), nil)
+	}
+
+	var args []interface{}
+	param := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conditions := []string{"TRUE"}
+	if filter.Query != "" {
+		conditions = append(conditions, "t.search_vector @@ websearch_to_tsquery('simple', "+param(filter.Query)+")")
+	}
+	if filter.Category != "" {
+		conditions = append(conditions, "c.code = "+param(filter.Category))
+	}
+	if len(filter.Tags) > 0 {
+		conditions = append(conditions, fmt.Sprintf(
+			"(SELECT COUNT(*) FROM task_tags tt WHERE tt.task_id = t.task_id AND tt.tag = ANY(%s)) = %s",
+			param(pq.Array(filter.Tags)), param(len(filter.Tags))))
+	}
+
+	query := fmt.Sprintf(selectTasksQuery, strings.Join(conditions, " AND "))
+	if filter.After != nil {
+		var value string
+		if sort.keyed {
+			value = param(filter.After.Value)
+		}
+		query += " WHERE " + fmt.Sprintf(sort.after, value, param(filter.After.TaskID))
+	}
+	query += " ORDER BY " + sort.order
+	if filter.Limit > 0 {
+		query += " LIMIT " + param(filter.Limit)
+	}
+
+	rows, err := r.executeQuery(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -156,10 +517,20 @@ func (r *PostgresTaskRepository) GetAllTasks(ctx context.Context) ([]models.Task
 	var tasks []models.Task
 	for rows.Next() {
 		var task models.Task
-		if err := rows.Scan(&task.TaskID, &task.Title, &task.Description); err != nil {
+		var tags, prices []byte
+		if err := rows.Scan(&task.TaskID, &task.Title, &task.Description, &task.Price, &task.Type, &task.CampaignID,
+			&task.Category, &tags, &prices, &task.Completions); err != nil {
 			r.logger.Error("Error scanning row", zap.Error(err))
 			return nil, errors.NewInternal("Error scanning row", err)
 		}
+		if err := json.Unmarshal(tags, &task.Tags); err != nil {
+			r.logger.Error("Error decoding task tags", zap.Int64("task_id", task.TaskID), zap.Error(err))
+			return nil, errors.NewInternal("Error decoding task tags", err)
+		}
+		if err := json.Unmarshal(prices, &task.Prices); err != nil {
+			r.logger.Error("Error decoding task prices", zap.Int64("task_id", task.TaskID), zap.Error(err))
+			return nil, errors.NewInternal("Error decoding task prices", err)
+		}
 		tasks = append(tasks, task)
 	}
 
@@ -170,19 +541,233 @@ func (r *PostgresTaskRepository) GetAllTasks(ctx context.Context) ([]models.Task
 	return tasks, nil
 }
 
-// referralReward выплачивает бонус за реферальную программу
-func (r *PostgresTaskRepository) referralReward(refer_id *int, price int) error {
-	var refId int
-	if err := r.db.QueryRow("SELECT user_id FROM users WHERE user_id=$1", *refer_id).Scan(&refId); err != nil {
-		r.logger.Error("user not found for referral reward", zap.Int("refer_id", *refer_id), zap.Error(err))
-		return errors.NewNotFound(fmt.Sprintf("user with id \"%d\" not found", *refer_id), err)
+// CreateCategory добавляет категорию заданий
+func (r *PostgresTaskRepository) CreateCategory(ctx context.Context, category *models.CategoryCreate) (int64, error) {
+	var exists bool
+	if err := r.db.QueryRowContext(ctx, checkCategoryQuery, category.Code).Scan(&exists); err != nil {
+		r.logger.Error("failed to check category code", zap.String("code", category.Code), zap.Error(err))
+		return 0, errors.NewInternal("failed to check category code", err)
+	}
+	if exists {
+		return 0, errors.NewAlreadyExists(fmt.Sprintf("category with code %q already exists", category.Code), nil)
+	}
+
+	var categoryID int64
+	if err := r.db.QueryRowContext(ctx, addCategoryQuery, category.Code, category.Title).Scan(&categoryID); err != nil {
+		r.logger.Error("Cannot create category", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create category", err)
 	}
-	rewardCount := refercode.Reward(price)
-	_, err := r.db.Exec("UPDATE users SET balance=balance+$1 WHERE user_id=$2", rewardCount, *refer_id)
+	return categoryID, nil
+}
+
+// GetCategories возвращает категории заданий
+func (r *PostgresTaskRepository) GetCategories(ctx context.Context) ([]models.Category, error) {
+	rows, err := r.executeQuery(ctx, getCategoriesQuery)
 	if err != nil {
-		r.logger.Error("failed to update referrer balance", zap.Int("refer_id", refId), zap.Error(err))
-		return errors.NewInternal("failed to update referrer balance", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []models.Category
+	for rows.Next() {
+		var category models.Category
+		if err := rows.Scan(&category.CategoryID, &category.Code, &category.Title, &category.CreatedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		categories = append(categories, category)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return categories, nil
+}
+
+// referralReward выплачивает бонус за реферальную программу в каждой валюте задания.
+// Отсутствие пригласившего пользователя не считается ошибкой: бонус просто не начисляется.
+func (r *PostgresTaskRepository) referralReward(ctx context.Context, tx *sql.Tx, userId int64, referId int, prices []taskPrice, completionID int64) error {
+	var refId int64
+	err := tx.QueryRowContext(ctx, "SELECT user_id FROM users WHERE user_id=$1", referId).Scan(&refId)
+	if err == sql.ErrNoRows {
+		r.logger.Warn("user not found for referral reward", zap.Int("refer_id", referId))
+		return nil
+	} else if err != nil {
+		r.logger.Error("failed to fetch referrer", zap.Int("refer_id", referId), zap.Error(err))
+		return errors.NewInternal("failed to fetch referrer", err)
+	}
+
+	for _, price := range prices {
+		bonus := models.Transaction{
+			UserID:         refId,
+			Amount:         refercode.Reward(price.Amount),
+			Currency:       price.Currency,
+			Kind:           models.TransactionReferralBonus,
+			CounterpartyID: &userId,
+			Reference:      completionReference(completionID),
+		}
+		if err := r.ledger.apply(ctx, tx, &bonus); err != nil {
+			r.logger.Error("failed to update referrer balance", zap.Int64("refer_id", refId), zap.String("currency", price.Currency), zap.Error(err))
+			return err
+		}
+		r.logger.Info("Referral reward processed", zap.Int64("refer_id", refId),
+			zap.String("currency", price.Currency), zap.Int("reward", bonus.Amount))
 	}
-	r.logger.Info("Referral reward processed", zap.Int("refer_id", refId), zap.Int("reward", rewardCount))
 	return nil
 }
+
+// completionReference ссылка на выполнение задания для записи журнала
+func completionReference(completionID int64) string {
+	return fmt.Sprintf("completion:%d", completionID)
+}
+
+// GetUserCompletions возвращает выполненные пользователем задания, включая отмененные
+func (r *PostgresTaskRepository) GetUserCompletions(ctx context.Context, userId int64) ([]models.TaskCompletion, error) {
+	rows, err := r.executeQuery(ctx, getUserCompletionsQuery, userId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var completions []models.TaskCompletion
+	for rows.Next() {
+		var completion models.TaskCompletion
+		if err := scanCompletion(rows, &completion); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		completions = append(completions, completion)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return completions, nil
+}
+
+// scanCompletion читает выполнение задания из строки selectCompletionsQuery
+func scanCompletion(rows *sql.Rows, completion *models.TaskCompletion) error {
+	return rows.Scan(&completion.CompletionID, &completion.UserID, &completion.TaskID, &completion.TaskTitle,
+		&completion.CompletedAt, &completion.RevokedAt, &completion.RevokedBy, &completion.RevokeReason,
+		&completion.MultiplierPercent, &completion.BoostID, &completion.StreakBonusPercent)
+}
+
+// RevokeCompletion отменяет выполнение задания и списывает начисленные за него баллы
+// компенсирующими записями журнала. История не удаляется: запись о выполнении помечается отмененной.
+// Если баллы уже потрачены, баланс уходит в минус, а недостача возвращается в результате.
+func (r *PostgresTaskRepository) RevokeCompletion(ctx context.Context, revocation *models.Revocation) (models.RevocationResult, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	var revokedAt sql.NullTime
+	err = tx.QueryRowContext(ctx, lockCompletionQuery, revocation.CompletionID).Scan(&revokedAt)
+	if err == sql.ErrNoRows {
+		r.logger.Info("completion not found", zap.Int64("completion_id", revocation.CompletionID))
+		return models.RevocationResult{}, errors.NewNotFound(fmt.Sprintf("completion with id %d not found", revocation.CompletionID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock completion", zap.Int64("completion_id", revocation.CompletionID), zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to lock completion", err)
+	}
+	if revokedAt.Valid {
+		r.logger.Info("completion already revoked", zap.Int64("completion_id", revocation.CompletionID))
+		return models.RevocationResult{}, errors.NewAlreadyExists("completion is already revoked", nil)
+	}
+
+	credits, err := r.completionCredits(ctx, tx, revocation.CompletionID)
+	if err != nil {
+		return models.RevocationResult{}, err
+	}
+
+	// Строки пользователей блокируются в порядке возрастания ID (запрос отсортирован по user_id).
+	// Недостача считается только по coins: остальные валюты не тратятся.
+	result := models.RevocationResult{Clawbacks: make([]models.Transaction, 0, len(credits))}
+	refunded := 0
+	for _, credit := range credits {
+		balance, err := r.ledger.lockBalance(ctx, tx, credit.UserID)
+		if err != nil {
+			return models.RevocationResult{}, err
+		}
+		if available := max(balance, 0); credit.Currency == models.CurrencyCoins && credit.Amount > available {
+			result.Shortfall += credit.Amount - available
+		}
+
+		clawback := models.Transaction{
+			UserID:         credit.UserID,
+			Amount:         -credit.Amount,
+			Currency:       credit.Currency,
+			Kind:           models.TransactionReversal,
+			CounterpartyID: credit.CounterpartyID,
+			Reference:      completionReference(revocation.CompletionID),
+			CreatedBy:      &revocation.AdminID,
+			SeasonID:       credit.SeasonID,
+		}
+		if err := r.ledger.apply(ctx, tx, &clawback); err != nil {
+			return models.RevocationResult{}, err
+		}
+		result.Clawbacks = append(result.Clawbacks, clawback)
+		if credit.Currency == models.CurrencyCoins {
+			refunded += credit.Amount
+		}
+	}
+
+	// Отозванные баллы возвращаются в бюджет кампании
+	if _, err := tx.ExecContext(ctx, refundCampaignQuery, refunded, revocation.CompletionID); err != nil {
+		r.logger.Error("failed to refund campaign budget", zap.Int64("completion_id", revocation.CompletionID), zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to refund campaign budget", err)
+	}
+
+	if _, err := tx.ExecContext(ctx, revokeCompletionQuery, revocation.AdminID, revocation.Reason, revocation.CompletionID); err != nil {
+		r.logger.Error("failed to revoke completion", zap.Int64("completion_id", revocation.CompletionID), zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to revoke completion", err)
+	}
+
+	err = tx.QueryRowContext(ctx, getCompletionQuery, revocation.CompletionID).Scan(&result.Completion.CompletionID,
+		&result.Completion.UserID, &result.Completion.TaskID, &result.Completion.TaskTitle, &result.Completion.CompletedAt,
+		&result.Completion.RevokedAt, &result.Completion.RevokedBy, &result.Completion.RevokeReason,
+		&result.Completion.MultiplierPercent, &result.Completion.BoostID, &result.Completion.StreakBonusPercent)
+	if err != nil {
+		r.logger.Error("failed to fetch completion", zap.Int64("completion_id", revocation.CompletionID), zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to fetch completion", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.RevocationResult{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Completion revoked", zap.Int64("completion_id", revocation.CompletionID),
+		zap.Int("clawbacks", len(result.Clawbacks)), zap.Int("shortfall", result.Shortfall))
+	return result, nil
+}
+
+// completionCredits возвращает начисления, сделанные при выполнении задания
+func (r *PostgresTaskRepository) completionCredits(ctx context.Context, tx *sql.Tx, completionID int64) ([]models.Transaction, error) {
+	rows, err := tx.QueryContext(ctx, completionCreditsQuery, completionReference(completionID))
+	if err != nil {
+		r.logger.Error("failed to fetch completion credits", zap.Int64("completion_id", completionID), zap.Error(err))
+		return nil, errors.NewInternal("failed to fetch completion credits", err)
+	}
+	defer rows.Close()
+
+	var credits []models.Transaction
+	for rows.Next() {
+		var credit models.Transaction
+		if err := rows.Scan(&credit.UserID, &credit.Amount, &credit.Currency, &credit.CounterpartyID, &credit.SeasonID); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return nil, errors.NewInternal("Error scanning row", err)
+		}
+		credits = append(credits, credit)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return nil, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return credits, nil
+}
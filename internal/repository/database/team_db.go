@@ -0,0 +1,462 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/refercode"
+	"go.uber.org/zap"
+)
+
+// SQL-запросы
+var (
+	// Таблица лидеров команд. В очки команды входят награды участников в валюте $1
+	// за задания, выполненные после вступления в команду и не отмененные.
+	getTeamsLeaderboardQuery = `
+    SELECT t.team_id, t.name, COUNT(DISTINCT m.user_id) AS members, COALESCE(SUM(tx.amount), 0) AS score
+    FROM teams t
+    JOIN team_members m ON m.team_id = t.team_id
+    LEFT JOIN task_complete tc ON tc.user_id = m.user_id AND tc.revoked_at IS NULL AND tc.completed_at >= m.joined_at
+    LEFT JOIN transactions tx ON tx.reference = 'completion:' || tc.id AND tx.user_id = tc.user_id
+        AND tx.kind = 'task_reward' AND tx.currency = $1
+    GROUP BY t.team_id, t.name` +
+		leaderboardOrder("score", "t.team_id")
+)
+
+const (
+	lockTeamUserQuery      = `SELECT user_id FROM users WHERE user_id = $1 FOR UPDATE`
+	checkTeamMemberQuery   = `SELECT EXISTS(SELECT 1 FROM team_members WHERE user_id = $1)`
+	checkTeamNameQuery     = `SELECT EXISTS(SELECT 1 FROM teams WHERE lower(name) = lower($1))`
+	addTeamQuery           = `INSERT INTO teams (name, invite_code) VALUES ($1, $2) RETURNING team_id`
+	addTeamMemberQuery     = `INSERT INTO team_members (user_id, team_id, role) VALUES ($1, $2, $3)`
+	getTeamQuery           = `SELECT team_id, name, invite_code, created_at FROM teams WHERE team_id = $1`
+	lockTeamByCodeQuery    = `SELECT team_id FROM teams WHERE invite_code = $1 FOR UPDATE`
+	lockTeamQuery          = `SELECT team_id FROM teams WHERE team_id = $1 FOR UPDATE`
+	countTeamMembersQuery  = `SELECT COUNT(*) FROM team_members WHERE team_id = $1`
+	getMembershipQuery     = `SELECT team_id, role FROM team_members WHERE user_id = $1`
+	deleteTeamMemberQuery  = `DELETE FROM team_members WHERE user_id = $1 AND team_id = $2`
+	deleteTeamQuery        = `DELETE FROM teams WHERE team_id = $1`
+	setTeamMemberRoleQuery = `UPDATE team_members SET role = $1 WHERE user_id = $2 AND team_id = $3`
+	// Следующий капитан - участник, который дольше всех состоит в команде
+	nextCaptainQuery = `
+    SELECT user_id FROM team_members WHERE team_id = $1 ORDER BY joined_at, user_id LIMIT 1`
+	getTeamMembersQuery = `
+    SELECT m.user_id, u.username, m.role, m.joined_at
+    FROM team_members m JOIN users u ON u.user_id = m.user_id
+    WHERE m.team_id = $1 ORDER BY m.role = 'captain' DESC, m.joined_at, m.user_id`
+)
+
+// PostgresTeamRepository реализует репозиторий команд для PostgreSQL
+type PostgresTeamRepository struct {
+	db     *sql.DB
+	logger *zap.Logger
+}
+
+// NewPostgresTeamRepository создает новый экземпляр репозитория команд
+func NewPostgresTeamRepository(db *sql.DB, logger *zap.Logger) *PostgresTeamRepository {
+	return &PostgresTeamRepository{db: db, logger: logger}
+}
+
+// CreateTeam создает команду, в которой создатель становится капитаном.
+// Пользователь, уже состоящий в команде, не может создать новую.
+func (r *PostgresTeamRepository) CreateTeam(ctx context.Context, team *models.TeamCreate) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lockFreeUser(ctx, tx, team.UserID); err != nil {
+		return 0, err
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, checkTeamNameQuery, team.Name).Scan(&exists); err != nil {
+		r.logger.Error("failed to check team name", zap.String("name", team.Name), zap.Error(err))
+		return 0, errors.NewInternal("failed to check team name", err)
+	}
+	if exists {
+		return 0, errors.NewAlreadyExists(fmt.Sprintf("team with name %q already exists", team.Name), nil)
+	}
+
+	inviteCode, err := refercode.SecureStringBytes()
+	if err != nil {
+		r.logger.Error("failed to generate invite code", zap.Error(err))
+		return 0, errors.NewInternal("failed to generate invite code", err)
+	}
+
+	var teamID int64
+	if err := tx.QueryRowContext(ctx, addTeamQuery, team.Name, inviteCode).Scan(&teamID); err != nil {
+		r.logger.Error("Cannot create team", zap.Error(err))
+		return 0, errors.NewInternal("Cannot create team", err)
+	}
+	if _, err := tx.ExecContext(ctx, addTeamMemberQuery, team.UserID, teamID, models.TeamRoleCaptain); err != nil {
+		r.logger.Error("failed to add team captain", zap.Int64("team_id", teamID), zap.Error(err))
+		return 0, errors.NewInternal("failed to add team captain", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return 0, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Team created", zap.Int64("team_id", teamID), zap.Int64("captain_id", team.UserID))
+	return teamID, nil
+}
+
+// JoinTeam добавляет пользователя в команду по коду приглашения.
+// maxMembers ограничивает размер команды (0 - без ограничения).
+func (r *PostgresTeamRepository) JoinTeam(ctx context.Context, userID int64, inviteCode string, maxMembers int) (models.Team, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return models.Team{}, errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.lockFreeUser(ctx, tx, userID); err != nil {
+		return models.Team{}, err
+	}
+
+	// Блокировка команды не дает параллельным вступлениям превысить размер команды
+	var teamID int64
+	err = tx.QueryRowContext(ctx, lockTeamByCodeQuery, inviteCode).Scan(&teamID)
+	if err == sql.ErrNoRows {
+		r.logger.Info("team invite code not found", zap.Int64("user_id", userID))
+		return models.Team{}, errors.NewNotFound("team with this invite code not found", err)
+	} else if err != nil {
+		r.logger.Error("failed to lock team", zap.Error(err))
+		return models.Team{}, errors.NewInternal("failed to lock team", err)
+	}
+
+	if maxMembers > 0 {
+		var members int
+		if err := tx.QueryRowContext(ctx, countTeamMembersQuery, teamID).Scan(&members); err != nil {
+			r.logger.Error("failed to count team members", zap.Int64("team_id", teamID), zap.Error(err))
+			return models.Team{}, errors.NewInternal("failed to count team members", err)
+		}
+		if members >= maxMembers {
+			r.logger.Info("team is full", zap.Int64("team_id", teamID), zap.Int("members", members))
+			return models.Team{}, errors.NewValidation(fmt.Sprintf("team is full: at most %d members allowed", maxMembers), nil)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, addTeamMemberQuery, userID, teamID, models.TeamRoleMember); err != nil {
+		r.logger.Error("failed to add team member", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Error(err))
+		return models.Team{}, errors.NewInternal("failed to add team member", err)
+	}
+
+	team, err := r.getTeam(ctx, tx, teamID)
+	if err != nil {
+		return models.Team{}, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return models.Team{}, errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("User joined team", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	return team, nil
+}
+
+// LeaveTeam исключает пользователя из его команды.
+// Если уходит капитан, капитаном становится участник, который дольше всех состоит в команде;
+// команда без участников удаляется.
+func (r *PostgresTeamRepository) LeaveTeam(ctx context.Context, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.leaveTeam(ctx, tx, userID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+	return nil
+}
+
+// leaveTeam исключает пользователя из его команды в рамках транзакции tx по правилам LeaveTeam
+func (r *PostgresTeamRepository) leaveTeam(ctx context.Context, tx *sql.Tx, userID int64) error {
+	teamID, role, err := r.membership(ctx, tx, userID)
+	if err != nil {
+		return err
+	}
+	if err := r.lockTeam(ctx, tx, teamID); err != nil {
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, deleteTeamMemberQuery, userID, teamID); err != nil {
+		r.logger.Error("failed to remove team member", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to remove team member", err)
+	}
+
+	if role == models.TeamRoleCaptain {
+		var nextCaptainID int64
+		err := tx.QueryRowContext(ctx, nextCaptainQuery, teamID).Scan(&nextCaptainID)
+		switch {
+		case err == sql.ErrNoRows:
+			if _, err := tx.ExecContext(ctx, deleteTeamQuery, teamID); err != nil {
+				r.logger.Error("failed to delete empty team", zap.Int64("team_id", teamID), zap.Error(err))
+				return errors.NewInternal("failed to delete empty team", err)
+			}
+			r.logger.Info("Empty team deleted", zap.Int64("team_id", teamID))
+		case err != nil:
+			r.logger.Error("failed to find next captain", zap.Int64("team_id", teamID), zap.Error(err))
+			return errors.NewInternal("failed to find next captain", err)
+		default:
+			if _, err := tx.ExecContext(ctx, setTeamMemberRoleQuery, models.TeamRoleCaptain, nextCaptainID, teamID); err != nil {
+				r.logger.Error("failed to assign captain", zap.Int64("team_id", teamID), zap.Error(err))
+				return errors.NewInternal("failed to assign captain", err)
+			}
+			r.logger.Info("Team captain changed", zap.Int64("team_id", teamID), zap.Int64("captain_id", nextCaptainID))
+		}
+	}
+
+	r.logger.Info("User left team", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	return nil
+}
+
+// SetCaptain передает роль капитана другому участнику команды.
+// Передать роль может только текущий капитан.
+func (r *PostgresTeamRepository) SetCaptain(ctx context.Context, captainID, teamID, newCaptainID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.checkCaptain(ctx, tx, captainID, teamID); err != nil {
+		return err
+	}
+	memberTeamID, _, err := r.membership(ctx, tx, newCaptainID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err != nil || memberTeamID != teamID {
+		return errors.NewNotFound(fmt.Sprintf("user %d is not a member of team %d", newCaptainID, teamID), nil)
+	}
+
+	// Сначала снимаем роль с текущего капитана: у команды не может быть двух капитанов
+	if _, err := tx.ExecContext(ctx, setTeamMemberRoleQuery, models.TeamRoleMember, captainID, teamID); err != nil {
+		r.logger.Error("failed to demote captain", zap.Int64("team_id", teamID), zap.Error(err))
+		return errors.NewInternal("failed to demote captain", err)
+	}
+	if _, err := tx.ExecContext(ctx, setTeamMemberRoleQuery, models.TeamRoleCaptain, newCaptainID, teamID); err != nil {
+		r.logger.Error("failed to assign captain", zap.Int64("team_id", teamID), zap.Error(err))
+		return errors.NewInternal("failed to assign captain", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Team captain changed", zap.Int64("team_id", teamID), zap.Int64("captain_id", newCaptainID))
+	return nil
+}
+
+// RemoveMember исключает участника из команды по решению капитана
+func (r *PostgresTeamRepository) RemoveMember(ctx context.Context, captainID, teamID, userID int64) error {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		r.logger.Error("failed to begin transaction", zap.Error(err))
+		return errors.NewInternal("failed to begin transaction", err)
+	}
+	defer tx.Rollback()
+
+	if err := r.checkCaptain(ctx, tx, captainID, teamID); err != nil {
+		return err
+	}
+
+	result, err := tx.ExecContext(ctx, deleteTeamMemberQuery, userID, teamID)
+	if err != nil {
+		r.logger.Error("failed to remove team member", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to remove team member", err)
+	}
+	if removed, err := result.RowsAffected(); err != nil {
+		r.logger.Error("failed to get affected rows", zap.Error(err))
+		return errors.NewInternal("failed to get affected rows", err)
+	} else if removed == 0 {
+		return errors.NewNotFound(fmt.Sprintf("user %d is not a member of team %d", userID, teamID), nil)
+	}
+
+	if err := tx.Commit(); err != nil {
+		r.logger.Error("failed to commit transaction", zap.Error(err))
+		return errors.NewInternal("failed to commit transaction", err)
+	}
+
+	r.logger.Info("Team member removed", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Int64("captain_id", captainID))
+	return nil
+}
+
+// GetTeam возвращает команду с участниками без кода приглашения
+func (r *PostgresTeamRepository) GetTeam(ctx context.Context, teamID int64) (models.Team, error) {
+	team, err := r.getTeam(ctx, r.db, teamID)
+	if err != nil {
+		return models.Team{}, err
+	}
+	team.InviteCode = ""
+	return team, nil
+}
+
+// GetUserTeam возвращает команду пользователя вместе с кодом приглашения
+func (r *PostgresTeamRepository) GetUserTeam(ctx context.Context, userID int64) (models.Team, error) {
+	teamID, _, err := r.membership(ctx, r.db, userID)
+	if err != nil {
+		return models.Team{}, err
+	}
+	return r.getTeam(ctx, r.db, teamID)
+}
+
+// GetTeamsLeaderboard возвращает команды, отсортированные по очкам в указанной валюте,
+// в том же порядке, что и таблица лидеров пользователей.
+func (r *PostgresTeamRepository) GetTeamsLeaderboard(ctx context.Context, currency string) ([]models.TeamStanding, error) {
+	if err := checkLeaderboardCurrency(ctx, r.db, r.logger, currency); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.db.QueryContext(ctx, getTeamsLeaderboardQuery, currency)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getTeamsLeaderboardQuery), zap.Error(err))
+		return nil, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	var standings []models.TeamStanding
+	for rows.Next() {
+		var standing models.TeamStanding
+		if err := rows.Scan(&standing.TeamID, &standing.Name, &standing.Members, &standing.Score); err != nil {
+			r.logger.Error("Failed to scan leaderboard row", zap.Error(err))
+			return nil, errors.NewInternal("Failed to scan leaderboard row", err)
+		}
+		standings = append(standings, standing)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error iterating leaderboard rows", zap.Error(err))
+		return nil, errors.NewInternal("Error iterating leaderboard rows", err)
+	}
+	return standings, nil
+}
+
+// lockFreeUser блокирует строку пользователя и проверяет, что он не состоит в команде.
+// Блокировка не дает одному пользователю параллельно вступить в несколько команд.
+func (r *PostgresTeamRepository) lockFreeUser(ctx context.Context, tx *sql.Tx, userID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, lockTeamUserQuery, userID).Scan(&id)
+	if err == sql.ErrNoRows {
+		r.logger.Info("User not found", zap.Int64("user_id", userID))
+		return errors.NewNotFound("User not found", err)
+	} else if err != nil {
+		r.logger.Error("failed to lock user", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to lock user", err)
+	}
+
+	var exists bool
+	if err := tx.QueryRowContext(ctx, checkTeamMemberQuery, userID).Scan(&exists); err != nil {
+		r.logger.Error("failed to check team membership", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("failed to check team membership", err)
+	}
+	if exists {
+		r.logger.Info("user is already in a team", zap.Int64("user_id", userID))
+		return errors.NewAlreadyExists("user is already in a team", nil)
+	}
+	return nil
+}
+
+// lockTeam блокирует строку команды
+func (r *PostgresTeamRepository) lockTeam(ctx context.Context, tx *sql.Tx, teamID int64) error {
+	var id int64
+	err := tx.QueryRowContext(ctx, lockTeamQuery, teamID).Scan(&id)
+	if err == sql.ErrNoRows {
+		r.logger.Info("team not found", zap.Int64("team_id", teamID))
+		return errors.NewNotFound(fmt.Sprintf("team with id %d not found", teamID), err)
+	} else if err != nil {
+		r.logger.Error("failed to lock team", zap.Int64("team_id", teamID), zap.Error(err))
+		return errors.NewInternal("failed to lock team", err)
+	}
+	return nil
+}
+
+// checkCaptain блокирует команду и проверяет, что пользователь ее капитан
+func (r *PostgresTeamRepository) checkCaptain(ctx context.Context, tx *sql.Tx, userID, teamID int64) error {
+	if err := r.lockTeam(ctx, tx, teamID); err != nil {
+		return err
+	}
+	memberTeamID, role, err := r.membership(ctx, tx, userID)
+	if err != nil && !errors.IsNotFound(err) {
+		return err
+	}
+	if err != nil || memberTeamID != teamID || role != models.TeamRoleCaptain {
+		r.logger.Info("user is not the team captain", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+		return errors.NewForbidden("only the team captain can manage the team", nil)
+	}
+	return nil
+}
+
+// membership возвращает команду и роль пользователя
+func (r *PostgresTeamRepository) membership(ctx context.Context, q queryRower, userID int64) (int64, string, error) {
+	var teamID int64
+	var role string
+	err := q.QueryRowContext(ctx, getMembershipQuery, userID).Scan(&teamID, &role)
+	if err == sql.ErrNoRows {
+		r.logger.Info("user is not in a team", zap.Int64("user_id", userID))
+		return 0, "", errors.NewNotFound("user is not in a team", err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch team membership", zap.Int64("user_id", userID), zap.Error(err))
+		return 0, "", errors.NewInternal("failed to fetch team membership", err)
+	}
+	return teamID, role, nil
+}
+
+// teamQueryer выполняет запросы в рамках *sql.DB или *sql.Tx
+type teamQueryer interface {
+	queryRower
+	queryer
+}
+
+// getTeam возвращает команду с участниками
+func (r *PostgresTeamRepository) getTeam(ctx context.Context, q teamQueryer, teamID int64) (models.Team, error) {
+	var team models.Team
+	err := q.QueryRowContext(ctx, getTeamQuery, teamID).Scan(&team.TeamID, &team.Name, &team.InviteCode, &team.CreatedAt)
+	if err == sql.ErrNoRows {
+		r.logger.Info("team not found", zap.Int64("team_id", teamID))
+		return models.Team{}, errors.NewNotFound(fmt.Sprintf("team with id %d not found", teamID), err)
+	} else if err != nil {
+		r.logger.Error("failed to fetch team", zap.Int64("team_id", teamID), zap.Error(err))
+		return models.Team{}, errors.NewInternal("failed to fetch team", err)
+	}
+
+	rows, err := q.QueryContext(ctx, getTeamMembersQuery, teamID)
+	if err != nil {
+		r.logger.Error("Failed to execute query", zap.String("query", getTeamMembersQuery), zap.Error(err))
+		return models.Team{}, errors.NewInternal("Failed to execute query", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var member models.TeamMember
+		if err := rows.Scan(&member.UserID, &member.Username, &member.Role, &member.JoinedAt); err != nil {
+			r.logger.Error("Error scanning row", zap.Error(err))
+			return models.Team{}, errors.NewInternal("Error scanning row", err)
+		}
+		team.Members = append(team.Members, member)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error encountered during rows iteration", zap.Error(err))
+		return models.Team{}, errors.NewInternal("Error encountered during rows iteration", err)
+	}
+	return team, nil
+}
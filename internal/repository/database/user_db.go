@@ -7,18 +7,47 @@ import (
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"go.uber.org/zap"
+	"time"
 )
 
 // SQL-запросы
+var (
+	// Получение таблицы лидеров по балансу в валюте
+	GetLeaderboardByBalanceQuery = `
+    SELECT u.user_id, u.username, u.balance, u.lifetime_points, COALESCE(b.balance, 0) AS currency_balance,
+        u.refer_code, u.refer_from
+    FROM users u LEFT JOIN user_balances b ON b.user_id = u.user_id AND b.currency = $1
+    WHERE u.deleted_at IS NULL` +
+		leaderboardOrder("currency_balance", "u.user_id")
+)
+
 const (
-	// Получение таблицы лидеров по балансу
-	GetLeaderboardByBalanceQuery = `SELECT user_id, username, balance, refer_code, refer_from FROM users ORDER BY balance DESC`
+	// Серия пользователя и текущий день по часам базы данных
+	GetUserStreakQuery = `
+    SELECT COALESCE(s.current_streak, 0), COALESCE(s.longest_streak, 0), s.last_active_day,
+        COALESCE(s.freezes_used, 0), current_date
+    FROM (SELECT 1) one LEFT JOIN user_streaks s ON s.user_id = $1`
+
+	// Балансы пользователя по валютам
+	GetUserBalancesQuery = `SELECT currency, balance FROM user_balances WHERE user_id = $1`
 
 	// Получение информации о пользователе по ID
-	GetUserByIDQuery = `SELECT user_id, username, email, balance, refer_code, refer_from FROM users WHERE user_id = $1`
+	GetUserByIDQuery = `
+    SELECT user_id, username, email, email_verified_at IS NOT NULL, balance, lifetime_points, refer_code, refer_from,
+        (SELECT subject::bigint FROM user_identities WHERE user_id = users.user_id AND provider = $2)
+    FROM users WHERE user_id = $1`
 
 	// Получить ID пользователя по имени пользователя или email
-	GetUserIDQuery = `SELECT user_id FROM users WHERE username = $1 OR email = $2`
+	GetUserIDQuery = `SELECT user_id FROM users WHERE username = $1 OR lower(email) = lower($2)`
+
+	// Проверка роли администратора
+	IsAdminQuery = `SELECT is_admin FROM users WHERE user_id = $1`
+
+	// Ближайшие сгорания баллов по дням
+	GetUpcomingExpirationsQuery = `
+    SELECT SUM(remaining), date_trunc('day', expires_at) AS day FROM point_credits
+    WHERE user_id = $1 AND remaining > 0 AND expires_at > now()
+    GROUP BY day ORDER BY day LIMIT $2`
 )
 
 // PostgresUserRepository реализует репозиторий пользователей для PostgreSQL
@@ -48,9 +77,14 @@ func (r *PostgresUserRepository) executeQueryRow(ctx context.Context, query stri
 	return row.Err()
 }
 
-// GetUsersLeaderboard возвращает список пользователей, отсортированный по балансу
-func (r *PostgresUserRepository) GetUsersLeaderboard(ctx context.Context) ([]models.User, error) {
-	rows, err := r.executeQuery(ctx, GetLeaderboardByBalanceQuery)
+// GetUsersLeaderboard возвращает список пользователей, отсортированный по балансу в указанной валюте.
+// Баланс в этой валюте возвращается в Balances.
+func (r *PostgresUserRepository) GetUsersLeaderboard(ctx context.Context, currency string) ([]models.User, error) {
+	if err := checkLeaderboardCurrency(ctx, r.db, r.logger, currency); err != nil {
+		return nil, err
+	}
+
+	rows, err := r.executeQuery(ctx, GetLeaderboardByBalanceQuery, currency)
 	if err != nil {
 		return nil, err
 	}
@@ -59,11 +93,13 @@ func (r *PostgresUserRepository) GetUsersLeaderboard(ctx context.Context) ([]mod
 	var users []models.User
 	for rows.Next() {
 		var user models.User
-		err = rows.Scan(&user.ID, &user.Username, &user.Balance, &user.ReferCode, &user.ReferFrom)
+		var balance int
+		err = rows.Scan(&user.ID, &user.Username, &user.Balance, &user.LifetimePoints, &balance, &user.ReferCode, &user.ReferFrom)
 		if err != nil {
 			r.logger.Error("Failed to scan leaderboard row", zap.Error(err))
 			return nil, errors.NewInternal("Failed to scan leaderboard row", err)
 		}
+		user.Balances = map[string]int{currency: balance}
 		users = append(users, user)
 	}
 
@@ -78,7 +114,7 @@ func (r *PostgresUserRepository) GetUsersLeaderboard(ctx context.Context) ([]mod
 // GetUserInfo возвращает информацию о пользователе по ID
 func (r *PostgresUserRepository) GetUserInfo(ctx context.Context, userID int64) (models.User, error) {
 	var user models.User
-	err := r.db.QueryRowContext(ctx, GetUserByIDQuery, userID).Scan(&user.ID, &user.Username, &user.Email, &user.Balance, &user.ReferCode, &user.ReferFrom)
+	err := r.db.QueryRowContext(ctx, GetUserByIDQuery, userID, models.IdentityProviderTelegram).Scan(&user.ID, &user.Username, &user.Email, &user.EmailVerified, &user.Balance, &user.LifetimePoints, &user.ReferCode, &user.ReferFrom, &user.TelegramID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			r.logger.Info("User not found", zap.Int64("user_id", userID))
@@ -105,16 +141,16 @@ func (r *PostgresUserRepository) GetUserID(ctx context.Context, usernameOrEmail
 	return userID, nil
 }
 
-// ReferrerCode сохраняет реферальный код для пользователя
-func (r *PostgresUserRepository) ReferrerCode(ctx context.Context, userId int64, referCode string) error {
+// ReferrerCode сохраняет реферальный код для пользователя и возвращает ID пригласившего
+func (r *PostgresUserRepository) ReferrerCode(ctx context.Context, userId int64, referCode string) (int64, error) {
 	var refId int
 	err := r.db.QueryRowContext(ctx, "SELECT user_id FROM users WHERE refer_code=$1", referCode).Scan(&refId)
 	if err == sql.ErrNoRows {
 		r.logger.Info("User with refer_code not found", zap.String("refer_code", referCode))
-		return errors.NewNotFound(fmt.Sprintf("user with refer_code \"%s\" not found", referCode), err)
+		return 0, errors.NewNotFound(fmt.Sprintf("user with refer_code \"%s\" not found", referCode), err)
 	} else if err != nil {
 		r.logger.Error("Error querying for refer_code", zap.String("refer_code", referCode), zap.Error(err))
-		return errors.NewInternal("failed to query user by refer_code", err)
+		return 0, errors.NewInternal("failed to query user by refer_code", err)
 	}
 
 	r.logger.Info("Found user with refer_code", zap.String("refer_code", referCode), zap.Int("user_id", refId))
@@ -122,19 +158,98 @@ func (r *PostgresUserRepository) ReferrerCode(ctx context.Context, userId int64,
 	result, err := r.db.ExecContext(ctx, "UPDATE users SET refer_from=$1 WHERE user_id=$2", refId, userId)
 	if err != nil {
 		r.logger.Error("Error updating refer_from", zap.Int64("user_id", userId), zap.Error(err))
-		return errors.NewInternal("failed to set referrer code", err)
+		return 0, errors.NewInternal("failed to set referrer code", err)
 	}
 
 	rowsAffected, err := result.RowsAffected()
 	if err != nil {
 		r.logger.Error("Error getting rows affected", zap.Int64("user_id", userId), zap.Error(err))
-		return errors.NewInternal("failed to get rows affected", err)
+		return 0, errors.NewInternal("failed to get rows affected", err)
 	}
 	if rowsAffected == 0 {
 		r.logger.Info("No rows were updated", zap.Int64("user_id", userId))
-		return errors.NewNotFound("no rows were updated", nil)
+		return 0, errors.NewNotFound("no rows were updated", nil)
 	}
 
 	r.logger.Info("Successfully set refer_from", zap.Int64("user_id", userId), zap.Int("refer_id", refId))
-	return nil
+	return int64(refId), nil
+}
+
+// IsAdmin проверяет, является ли пользователь администратором
+func (r *PostgresUserRepository) IsAdmin(ctx context.Context, userID int64) (bool, error) {
+	var isAdmin bool
+	err := r.db.QueryRowContext(ctx, IsAdminQuery, userID).Scan(&isAdmin)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.logger.Info("User not found", zap.Int64("user_id", userID))
+			return false, errors.NewNotFound("User not found", err)
+		}
+		r.logger.Error("Failed to check admin role", zap.Int64("user_id", userID), zap.Error(err))
+		return false, errors.NewInternal("Failed to check admin role", err)
+	}
+	return isAdmin, nil
+}
+
+// GetUpcomingExpirations возвращает ближайшие сгорания баллов пользователя, сгруппированные по дням
+func (r *PostgresUserRepository) GetUpcomingExpirations(ctx context.Context, userID int64, limit int) ([]models.PointExpiration, error) {
+	rows, err := r.executeQuery(ctx, GetUpcomingExpirationsQuery, userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var expirations []models.PointExpiration
+	for rows.Next() {
+		var expiration models.PointExpiration
+		if err := rows.Scan(&expiration.Amount, &expiration.ExpiresAt); err != nil {
+			r.logger.Error("Failed to scan expiration row", zap.Error(err))
+			return nil, errors.NewInternal("Failed to scan expiration row", err)
+		}
+		expirations = append(expirations, expiration)
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error iterating expiration rows", zap.Error(err))
+		return nil, errors.NewInternal("Error iterating expiration rows", err)
+	}
+	return expirations, nil
+}
+
+// GetUserBalances возвращает балансы пользователя по валютам
+func (r *PostgresUserRepository) GetUserBalances(ctx context.Context, userID int64) (map[string]int, error) {
+	rows, err := r.executeQuery(ctx, GetUserBalancesQuery, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[string]int)
+	for rows.Next() {
+		var currency string
+		var balance int
+		if err := rows.Scan(&currency, &balance); err != nil {
+			r.logger.Error("Failed to scan balance row", zap.Error(err))
+			return nil, errors.NewInternal("Failed to scan balance row", err)
+		}
+		balances[currency] = balance
+	}
+
+	if err := rows.Err(); err != nil {
+		r.logger.Error("Error iterating balance rows", zap.Error(err))
+		return nil, errors.NewInternal("Error iterating balance rows", err)
+	}
+	return balances, nil
+}
+
+// GetUserStreak возвращает сохраненную серию пользователя и текущий день
+func (r *PostgresUserRepository) GetUserStreak(ctx context.Context, userID int64) (models.Streak, time.Time, error) {
+	var userStreak models.Streak
+	var today time.Time
+	err := r.db.QueryRowContext(ctx, GetUserStreakQuery, userID).Scan(&userStreak.Current, &userStreak.Longest,
+		&userStreak.LastActiveDay, &userStreak.FreezesUsed, &today)
+	if err != nil {
+		r.logger.Error("Failed to fetch user streak", zap.Int64("user_id", userID), zap.Error(err))
+		return models.Streak{}, time.Time{}, errors.NewInternal("Failed to fetch user streak", err)
+	}
+	return userStreak, today, nil
 }
@@ -6,6 +6,7 @@ import (
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository/database"
 	"go.uber.org/zap"
+	"time"
 )
 
 // AuthRepository интерфейс для работы с аутентификацией
@@ -13,21 +14,150 @@ type AuthRepository interface {
 	CreateUser(ctx context.Context, user *models.CreateUser) (int64, error)
 	GetUser(ctx context.Context, req *models.SignIn) (*models.User, error)
 	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	GetUserByID(ctx context.Context, userID int64) (*models.User, error)
+	SaveAuthToken(ctx context.Context, token *models.AuthToken) error
+	VerifyEmail(ctx context.Context, tokenID string) (int64, error)
+	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
+	GetTokenVersion(ctx context.Context, userID int64) (int, error)
+	ResetPassword(ctx context.Context, tokenID, passwordHash string) (int64, error)
+	ChangePassword(ctx context.Context, userID int64, passwordHash string) (int, error)
+	UpdatePasswordHash(ctx context.Context, userID int64, passwordHash string) error
+	RecordLoginFailure(ctx context.Context, scope, key string, window time.Duration) (int, error)
+	BlockLogin(ctx context.Context, scope, key string, duration time.Duration) error
+	GetLoginBlock(ctx context.Context, username, ip string) (time.Duration, error)
+	ResetLoginFailures(ctx context.Context, scope, key string) error
+	UseAuthToken(ctx context.Context, tokenID, purpose string) (models.AuthToken, error)
+	GetTwoFactor(ctx context.Context, userID int64) (models.TwoFactor, error)
+	SetTOTPSecret(ctx context.Context, userID int64, secret string) error
+	EnableTwoFactor(ctx context.Context, userID, step int64, codeHashes []string) error
+	DisableTwoFactor(ctx context.Context, userID int64) error
+	UseTOTPStep(ctx context.Context, userID, step int64) error
+	UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error
+	UseLoginChallenge(ctx context.Context, tokenID string, userID int64, factor models.SecondFactor) (bool, error)
+	ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error
+	GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error)
+	LinkIdentity(ctx context.Context, provider, subject string, userID int64, email string) error
+	CreateExternalUser(ctx context.Context, user *models.ExternalUserCreate) (int64, error)
+	SaveOIDCState(ctx context.Context, state *models.OIDCState) error
+	UseOIDCState(ctx context.Context, state string) (models.OIDCState, error)
+	RevokeAuthTokens(ctx context.Context, userID int64, purpose string) error
+	GetProfile(ctx context.Context, userID int64) (models.Profile, error)
+	UpdateProfile(ctx context.Context, userID int64, update *models.ProfileUpdate) error
+	ChangeEmail(ctx context.Context, tokenID string) (int64, error)
+	DeleteAccount(ctx context.Context, userID int64) error
 }
 
 // UserRepository интерфейс для работы с пользователями
 type UserRepository interface {
 	GetUserInfo(ctx context.Context, userID int64) (models.User, error)
-	GetUsersLeaderboard(ctx context.Context) ([]models.User, error)
+	GetUsersLeaderboard(ctx context.Context, currency string) ([]models.User, error)
 	GetUserID(ctx context.Context, usernameOrEmail string) (int64, error)
-	ReferrerCode(ctx context.Context, userId int64, refCode string) error
+	ReferrerCode(ctx context.Context, userId int64, refCode string) (int64, error)
+	IsAdmin(ctx context.Context, userID int64) (bool, error)
+	GetUpcomingExpirations(ctx context.Context, userID int64, limit int) ([]models.PointExpiration, error)
+	GetUserBalances(ctx context.Context, userID int64) (map[string]int, error)
+	GetUserStreak(ctx context.Context, userID int64) (models.Streak, time.Time, error)
 }
 
 // TaskRepository интерфейс для работы с задачами
 type TaskRepository interface {
 	CreateTask(ctx context.Context, req *models.TaskCreate) (int64, error)
 	CompleteTask(ctx context.Context, userId, taskId int64) error
-	GetAllTasks(ctx context.Context) ([]models.Task, error)
+	GetAllTasks(ctx context.Context, filter models.TaskFilter) ([]models.Task, error)
+	GetUserCompletions(ctx context.Context, userId int64) ([]models.TaskCompletion, error)
+	RevokeCompletion(ctx context.Context, revocation *models.Revocation) (models.RevocationResult, error)
+	CreateCategory(ctx context.Context, category *models.CategoryCreate) (int64, error)
+	GetCategories(ctx context.Context) ([]models.Category, error)
+}
+
+// ReferralRepository интерфейс для работы с реферальными ссылками
+type ReferralRepository interface {
+	RecordClick(ctx context.Context, click *models.ReferralClick) (int64, error)
+	GetReferralStats(ctx context.Context, referCode string) (models.ReferralStats, error)
+}
+
+// RewardRepository интерфейс для работы с каталогом наград
+type RewardRepository interface {
+	CreateReward(ctx context.Context, reward *models.RewardCreate) (int64, error)
+	GetRewards(ctx context.Context) ([]models.Reward, error)
+	RedeemReward(ctx context.Context, userID, rewardID int64) (models.Redemption, error)
+	GetUserRedemptions(ctx context.Context, userID int64) ([]models.Redemption, error)
+	GetRedemptions(ctx context.Context, status string) ([]models.Redemption, error)
+	UpdateRedemptionStatus(ctx context.Context, redemptionID int64, status string) (models.Redemption, error)
+}
+
+// BalanceRepository интерфейс для операций с балансом пользователей
+type BalanceRepository interface {
+	Transfer(ctx context.Context, transfer *models.Transfer, limits models.TransferLimits) (models.TransferResult, error)
+	GetUserTransactions(ctx context.Context, userID int64) ([]models.Transaction, error)
+	AdjustBalance(ctx context.Context, adjustment *models.Adjustment) (models.Transaction, error)
+	GetUsersWithExpiredPoints(ctx context.Context) ([]int64, error)
+	ExpireUserPoints(ctx context.Context, userID int64) (int, error)
+	GetCurrencies(ctx context.Context) ([]models.Currency, error)
+}
+
+// AchievementRepository интерфейс для работы с достижениями
+type AchievementRepository interface {
+	CreateAchievement(ctx context.Context, achievement *models.AchievementCreate) (int64, error)
+	GetAchievements(ctx context.Context) ([]models.Achievement, error)
+	GetUserAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+	AwardAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+}
+
+// BoostRepository интерфейс для работы с множителями наград
+type BoostRepository interface {
+	CreateBoost(ctx context.Context, boost *models.BoostCreate) (int64, error)
+	GetBoosts(ctx context.Context, upcomingOnly bool) ([]models.Boost, error)
+}
+
+// SeasonRepository интерфейс для работы с сезонами
+type SeasonRepository interface {
+	CreateSeason(ctx context.Context, season *models.SeasonCreate) (int64, error)
+	GetSeasons(ctx context.Context) ([]models.Season, error)
+	GetSeasonStandings(ctx context.Context, seasonID int64) (models.SeasonResult, error)
+	CloseSeason(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error)
+}
+
+// TeamRepository интерфейс для работы с командами
+type TeamRepository interface {
+	CreateTeam(ctx context.Context, team *models.TeamCreate) (int64, error)
+	JoinTeam(ctx context.Context, userID int64, inviteCode string, maxMembers int) (models.Team, error)
+	LeaveTeam(ctx context.Context, userID int64) error
+	SetCaptain(ctx context.Context, captainID, teamID, newCaptainID int64) error
+	RemoveMember(ctx context.Context, captainID, teamID, userID int64) error
+	GetTeam(ctx context.Context, teamID int64) (models.Team, error)
+	GetUserTeam(ctx context.Context, userID int64) (models.Team, error)
+	GetTeamsLeaderboard(ctx context.Context, currency string) ([]models.TeamStanding, error)
+}
+
+// CampaignRepository интерфейс для работы с кампаниями
+type CampaignRepository interface {
+	CreateCampaign(ctx context.Context, campaign *models.CampaignCreate) (int64, error)
+	GetCampaigns(ctx context.Context) ([]models.Campaign, error)
+	UpdateCampaignStatus(ctx context.Context, campaignID int64, status string) (models.Campaign, error)
+	GetCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error)
+}
+
+// APIKeyRepository интерфейс для работы с API-ключами
+type APIKeyRepository interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKeyCreate) (models.APIKey, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyID int64) error
+	TouchAPIKey(ctx context.Context, apiKeyID int64) error
+}
+
+// ExportRepository интерфейс для выгрузки персональных данных
+type ExportRepository interface {
+	CountUserRecords(ctx context.Context, userID int64) (int, error)
+	GetUserData(ctx context.Context, userID int64) (models.UserData, error)
+	CreateExport(ctx context.Context, userID int64, format string) (models.DataExport, error)
+	GetExport(ctx context.Context, userID, exportID int64) (models.DataExport, error)
+	GetExportContent(ctx context.Context, exportID int64) ([]byte, error)
+	ClaimExport(ctx context.Context, staleAfter time.Duration) (models.DataExport, error)
+	CompleteExport(ctx context.Context, exportID int64, content []byte, ttl time.Duration) error
+	FailExport(ctx context.Context, exportID int64, reason string, ttl time.Duration) error
+	DeleteExpiredExports(ctx context.Context) (int64, error)
 }
 
 // Repository структура для объединения всех репозиториев
@@ -35,13 +165,44 @@ type Repository struct {
 	AuthRepository
 	UserRepository
 	TaskRepository
+	ReferralRepository
+	RewardRepository
+	BalanceRepository
+	AchievementRepository
+	BoostRepository
+	SeasonRepository
+	TeamRepository
+	CampaignRepository
+	APIKeyRepository
+	ExportRepository
+}
+
+// Options параметры бизнес-правил, которые применяются на уровне хранилища
+type Options struct {
+	// PointsTTL срок действия начисленных баллов (0 - баллы не сгорают)
+	PointsTTL time.Duration
+	// Streak настройки бонуса за серию дней с выполненными заданиями
+	Streak models.StreakRules
+	// Levels кривая уровней по заработанным за все время баллам
+	Levels models.LevelCurve
 }
 
 // NewRepositories создает новый экземпляр Repository с логированием
-func NewRepositories(db *sql.DB, logger *zap.Logger) *Repository {
+func NewRepositories(db *sql.DB, logger *zap.Logger, opts Options) *Repository {
+	ledger := database.NewLedger(logger, opts.PointsTTL, opts.Levels)
 	return &Repository{
-		AuthRepository: database.NewPostgresAuthRepository(db, logger),
-		UserRepository: database.NewPostgresUserRepository(db, logger),
-		TaskRepository: database.NewPostgresTaskRepository(db, logger),
+		AuthRepository:        database.NewPostgresAuthRepository(db, logger),
+		UserRepository:        database.NewPostgresUserRepository(db, logger),
+		TaskRepository:        database.NewPostgresTaskRepository(db, logger, ledger, opts.Streak),
+		ReferralRepository:    database.NewPostgresReferralRepository(db, logger),
+		RewardRepository:      database.NewPostgresRewardRepository(db, logger, ledger),
+		BalanceRepository:     database.NewPostgresBalanceRepository(db, logger, ledger),
+		AchievementRepository: database.NewPostgresAchievementRepository(db, logger, ledger),
+		BoostRepository:       database.NewPostgresBoostRepository(db, logger),
+		SeasonRepository:      database.NewPostgresSeasonRepository(db, logger, ledger),
+		TeamRepository:        database.NewPostgresTeamRepository(db, logger),
+		CampaignRepository:    database.NewPostgresCampaignRepository(db, logger),
+		APIKeyRepository:      database.NewPostgresAPIKeyRepository(db, logger),
+		ExportRepository:      database.NewPostgresExportRepository(db, logger, ledger),
 	}
 }
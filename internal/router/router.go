@@ -25,14 +25,25 @@ func NewRouter(
 	// Настраиваем маршруты для аутентификации
 	setupAuthRoutes(authRouter, handler)
 
+	// Реферальные ссылки открываются в браузере без авторизации
+	//curl -i -X GET "http://localhost:8080/r/ABC123"
+	r.HandleFunc("/r/{code}", handler.ReferralLink).Methods("GET")
+
 	// Настраиваем маршруты для API и добавляем middleware для авторизации к маршрутам
 	setupAPIRoutes(apiRouter, handler)
 
-	// Применяем JWT Middleware только к защищенным маршрутам
+	// Применяем JWT Middleware только к защищенным маршрутам.
+	// Интеграции вместо JWT передают API-ключ в заголовке X-API-Key, он дает доступ только к части маршрутов.
 	protectedAPIRouter := apiRouter.PathPrefix("").Subrouter()
+	protectedAPIRouter.Use(handlers.APIKeyMiddleware(handler.Services.APIKey, logger))
 	protectedAPIRouter.Use(handlers.JWTMiddleware(handler.Services.Auth, logger))
 	setupProtectedAPIRoutes(protectedAPIRouter, handler)
 
+	// Административные маршруты доступны только пользователям с ролью администратора
+	adminRouter := protectedAPIRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(handlers.AdminMiddleware(handler.Services.User, logger))
+	setupAdminAPIRoutes(adminRouter, handler)
+
 	return r
 }
 
@@ -76,6 +87,71 @@ func setupAuthRoutes(router *mux.Router, handler *handlers.Handler) {
 
 	router.HandleFunc("/login", handler.LoginHandler).Methods("POST")
 
+	/*
+		если у пользователя включена двухфакторная аутентификация, /auth/login вместо токена возвращает
+		{"message": "two-factor code required", "two_factor_required": true, "challenge_token": "eyJqdGkiOi...Jt0"},
+		токен обменивается на JWT вместе с кодом из приложения или резервным кодом:
+		curl -X POST "http://localhost:8080/auth/login/2fa" \
+		-H "Content-Type: application/json" \
+		-d '{"challenge_token": "eyJqdGkiOi...Jt0", "code": "492039"}'
+	*/
+	router.HandleFunc("/login/2fa", handler.LoginTwoFactorHandler).Methods("POST")
+
+	/*
+		ссылка из письма после регистрации:
+		curl -X GET "http://localhost:8080/auth/email/verify?token=eyJqdGkiOi...Jt0"
+
+		curl -X POST "http://localhost:8080/auth/email/verify" \
+		-H "Content-Type: application/json" \
+		-d '{"token": "eyJqdGkiOi...Jt0"}'
+	*/
+	router.HandleFunc("/email/verify", handler.VerifyEmailHandler).Methods("GET", "POST")
+
+	/*
+		ссылка из письма, отправленного на новый адрес после PATCH /api/users/me:
+		curl -X GET "http://localhost:8080/auth/email/change?token=eyJqdGkiOi...Jt0"
+
+		curl -X POST "http://localhost:8080/auth/email/change" \
+		-H "Content-Type: application/json" \
+		-d '{"token": "eyJqdGkiOi...Jt0"}'
+	*/
+	router.HandleFunc("/email/change", handler.ConfirmEmailChangeHandler).Methods("GET", "POST")
+
+	/*
+		curl -X POST "http://localhost:8080/auth/password/forgot" \
+		-H "Content-Type: application/json" \
+		-d '{"email": "john.doe@example.com"}'
+	*/
+	router.HandleFunc("/password/forgot", handler.ForgotPasswordHandler).Methods("POST")
+	/*
+		после смены пароля все выданные ранее JWT перестают действовать:
+		curl -X POST "http://localhost:8080/auth/password/reset" \
+		-H "Content-Type: application/json" \
+		-d '{"token": "eyJqdGkiOi...Jt0", "password": "new-password"}'
+	*/
+	router.HandleFunc("/password/reset", handler.ResetPasswordHandler).Methods("POST")
+
+	/*
+		вход через провайдера OpenID Connect (если задан OIDC_ISSUER) открывается в браузере,
+		сервис перенаправляет на страницу входа провайдера:
+		curl -i -X GET "http://localhost:8080/auth/oidc/login"
+
+		провайдер возвращает пользователя на адрес возврата, в ответе JWT как у /auth/login:
+		curl -X GET "http://localhost:8080/auth/oidc/callback?code=SplxlOBeZQQYbYS6WxSbIA&state=af0ifjsldkj" \
+		--cookie "oidc_state=af0ifjsldkj"
+	*/
+	router.HandleFunc("/oidc/login", handler.OIDCLoginHandler).Methods("GET")
+	router.HandleFunc("/oidc/callback", handler.OIDCCallbackHandler).Methods("GET")
+
+	/*
+		вход через виджет Telegram Login (если задан TELEGRAM_BOT_TOKEN): данные, которые виджет передает в onauth,
+		отправляются как есть; ответ как у /auth/login, в том числе с challenge_token при включенной 2FA
+		curl -X POST "http://localhost:8080/auth/telegram" \
+		-H "Content-Type: application/json" \
+		-d '{"id": 123456789, "first_name": "John", "username": "john_doe", "auth_date": 1700000000, "hash": "c0ffee...e1"}'
+	*/
+	router.HandleFunc("/telegram", handler.TelegramLoginHandler).Methods("POST")
+
 }
 
 // setupAPIRoutes настраивает общие маршруты для API
@@ -96,11 +172,27 @@ func setupProtectedAPIRoutes(router *mux.Router, handler *handlers.Handler) {
 				"description": "This is a new task description.",
 				"price": 50
 		}'
+
+			цена в нескольких валютах и тип задания для множителей наград:
+			-d '{"title": "Quiz", "type": "quiz", "prices": {"coins": 20, "xp": 100}}'
+
+			категория и метки:
+			-d '{"title": "Share a post", "price": 30, "category": "social", "tags": ["twitter", "share"]}'
+
+			задание кампании (только для администратора, иначе 403):
+			-d '{"title": "Launch quiz", "price": 40, "campaign_id": 1}'
 	*/
 
 	router.HandleFunc("/task/create", handler.TaskCreate).Methods("POST")
-	//curl -X GET "http://localhost:8080/api/task/all"
+	/*
+		curl -X GET "http://localhost:8080/api/task/all?q=share%20post&category=social&tags=twitter,share&sort=popular&limit=20"
+		sort: id (по умолчанию), newest, price_asc, price_desc, popular
+		без limit и cursor возвращаются все задания; с limit ответ содержит next_cursor, пока есть следующая страница
+		следующая страница: ?sort=popular&limit=20&cursor=<next_cursor из предыдущего ответа>
+	*/
 	router.HandleFunc("/task/all", handler.TaskGetAll).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/categories"
+	router.HandleFunc("/categories", handler.CategoryGetAll).Methods("GET")
 	/*
 			curl -X POST "http://localhost:8080/api/task/123/complete" \
 			-H "Content-Type: application/json" \
@@ -124,9 +216,173 @@ func setupProtectedAPIRoutes(router *mux.Router, handler *handlers.Handler) {
 
 	//curl -X GET "http://localhost:8080/api/users/123/status"
 	router.HandleFunc("/users/{user_id}/status", handler.UserInfo).Methods("GET")
-	//curl -X GET "http://localhost:8080/api/users/leaderboard"
+	//curl -X GET "http://localhost:8080/api/users/me"
+	//пример ответа {"user_id": 123, "username": "john_doe", "display_name": "John", "avatar_url": "https://example.com/a.png",
+	//"email": "john@example.com", "email_verified": true, "pending_email": "new@example.com"}
+	router.HandleFunc("/users/me", handler.GetProfile).Methods("GET")
+	/*
+		поля без значения не меняются, пустая строка очищает display_name и avatar_url;
+		новый email начинает действовать после перехода по ссылке из письма, отправленного на него
+		curl -X PATCH "http://localhost:8080/api/users/me" \
+		-H "Content-Type: application/json" \
+		-d '{"display_name": "John", "avatar_url": "https://example.com/a.png", "email": "new@example.com"}'
+	*/
+	router.HandleFunc("/users/me", handler.UpdateProfile).Methods("PATCH")
+	/*
+		удаление аккаунта: персональные данные стираются, история начислений сохраняется;
+		confirm - имя пользователя
+		curl -X DELETE "http://localhost:8080/api/users/me" \
+		-H "Content-Type: application/json" \
+		-d '{"confirm": "john_doe"}'
+	*/
+	router.HandleFunc("/users/me", handler.DeleteAccount).Methods("DELETE")
+	//curl -X POST "http://localhost:8080/api/users/me/email/verification"
+	router.HandleFunc("/users/me/email/verification", handler.ResendEmailVerification).Methods("POST")
+	/*
+		остальные сессии пользователя завершаются, в ответе новый токен:
+		curl -X POST "http://localhost:8080/api/users/me/password" \
+		-H "Content-Type: application/json" \
+		-d '{"old_password": "securepassword123", "new_password": "correct-horse-battery"}'
+	*/
+	router.HandleFunc("/users/me/password", handler.ChangePasswordHandler).Methods("POST")
+
+	//curl -X GET "http://localhost:8080/api/users/me/2fa"
+	//пример ответа {"enabled": true, "recovery_codes_left": 9}
+	router.HandleFunc("/users/me/2fa", handler.TwoFactorStatus).Methods("GET")
+	/*
+		подключение двухфакторной аутентификации: секрет и otpauth-ссылка для приложения-аутентификатора,
+		затем подтверждение кодом из приложения; в ответе резервные коды, они показываются один раз
+		curl -X POST "http://localhost:8080/api/users/me/2fa/setup"
+		curl -X POST "http://localhost:8080/api/users/me/2fa/enable" \
+		-H "Content-Type: application/json" \
+		-d '{"code": "492039"}'
+	*/
+	router.HandleFunc("/users/me/2fa/setup", handler.TwoFactorSetup).Methods("POST")
+	router.HandleFunc("/users/me/2fa/enable", handler.TwoFactorEnable).Methods("POST")
+	/*
+		code - код из приложения или резервный код
+		curl -X POST "http://localhost:8080/api/users/me/2fa/disable" \
+		-H "Content-Type: application/json" \
+		-d '{"password": "securepassword123", "code": "492039"}'
+
+		curl -X POST "http://localhost:8080/api/users/me/2fa/recovery-codes" \
+		-H "Content-Type: application/json" \
+		-d '{"code": "492039"}'
+	*/
+	router.HandleFunc("/users/me/2fa/disable", handler.TwoFactorDisable).Methods("POST")
+	router.HandleFunc("/users/me/2fa/recovery-codes", handler.TwoFactorRecoveryCodes).Methods("POST")
+	/*
+		привязка аккаунта Telegram к текущему пользователю по данным виджета Telegram Login;
+		ID аккаунта появляется в /api/users/{user_id}/status как telegram_id
+		curl -X POST "http://localhost:8080/api/users/me/telegram" \
+		-H "Content-Type: application/json" \
+		-d '{"id": 123456789, "first_name": "John", "username": "john_doe", "auth_date": 1700000000, "hash": "c0ffee...e1"}'
+	*/
+	router.HandleFunc("/users/me/telegram", handler.LinkTelegram).Methods("POST")
+	/*
+		выгрузка персональных данных: профиль, выполненные задания, операции с балансом, рефералы и сессии;
+		format - json (по умолчанию) или zip, в архиве каждый раздел лежит в отдельном файле
+		curl -X GET "http://localhost:8080/api/users/me/export?format=zip" -o export.zip
+
+		для большого аккаунта выгрузка готовится в фоне, ответ 202:
+		{"export_id": 7, "format": "zip", "status": "pending", "created_at": "2024-03-01T12:00:00Z",
+		"status_url": "http://localhost:8080/api/users/me/exports/7"}
+		когда выгрузка готова, на подтвержденный адрес приходит письмо, в состоянии появляется download_url
+		curl -X GET "http://localhost:8080/api/users/me/exports/7"
+		curl -X GET "http://localhost:8080/api/users/me/exports/7/download" -o export.zip
+	*/
+	router.HandleFunc("/users/me/export", handler.ExportUserData).Methods("GET")
+	router.HandleFunc("/users/me/exports/{export_id}", handler.GetExport).Methods("GET")
+	router.HandleFunc("/users/me/exports/{export_id}/download", handler.DownloadExport).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/users/leaderboard?currency=xp"
 	router.HandleFunc("/users/leaderboard", handler.UsersLeaderboard).Methods("GET")
 
+	//доступно владельцу реферального кода и администраторам
+	//curl -X GET "http://localhost:8080/api/referrals/ABC123/stats"
+	//пример ответа {"refer_code": "ABC123", "clicks": 10, "signups": 3, "first_task_completions": 1}
+	router.HandleFunc("/referrals/{code}/stats", handler.ReferralStats).Methods("GET")
+
+	// Регистрируем маршруты каталога наград
+
+	//curl -X GET "http://localhost:8080/api/rewards"
+	router.HandleFunc("/rewards", handler.RewardGetAll).Methods("GET")
+	//curl -X POST "http://localhost:8080/api/rewards/7/redeem"
+	router.HandleFunc("/rewards/{reward_id}/redeem", handler.RewardRedeem).Methods("POST")
+	//доступно самому пользователю и администраторам
+	//curl -X GET "http://localhost:8080/api/users/123/redemptions"
+	router.HandleFunc("/users/{user_id}/redemptions", handler.UserRedemptions).Methods("GET")
+
+	// Регистрируем маршруты операций с балансом
+
+	/*
+		curl -X POST "http://localhost:8080/api/users/123/transfer" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "to_user_id": 456,
+		  "amount": 25
+		}'
+	*/
+	router.HandleFunc("/users/{user_id}/transfer", handler.UserTransfer).Methods("POST")
+	//доступно самому пользователю и администраторам
+	//curl -X GET "http://localhost:8080/api/users/123/transactions"
+	router.HandleFunc("/users/{user_id}/transactions", handler.UserTransactions).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/currencies"
+	router.HandleFunc("/currencies", handler.Currencies).Methods("GET")
+
+	//curl -X GET "http://localhost:8080/api/boosts"
+	router.HandleFunc("/boosts", handler.BoostGetAll).Methods("GET")
+
+	// Регистрируем маршруты сезонов
+
+	//curl -X GET "http://localhost:8080/api/seasons"
+	router.HandleFunc("/seasons", handler.SeasonGetAll).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/seasons/3/standings"
+	router.HandleFunc("/seasons/{season_id}/standings", handler.SeasonStandings).Methods("GET")
+
+	// Регистрируем маршруты команд
+
+	/*
+		curl -X POST "http://localhost:8080/api/teams" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "name": "Night Owls"
+		}'
+	*/
+	router.HandleFunc("/teams", handler.TeamCreate).Methods("POST")
+	//curl -X GET "http://localhost:8080/api/teams/leaderboard?currency=xp"
+	router.HandleFunc("/teams/leaderboard", handler.TeamsLeaderboard).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/teams/me"
+	router.HandleFunc("/teams/me", handler.TeamMine).Methods("GET")
+	/*
+		curl -X POST "http://localhost:8080/api/teams/join" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "invite_code": "AbCdEfGhIjKlMnO"
+		}'
+	*/
+	router.HandleFunc("/teams/join", handler.TeamJoin).Methods("POST")
+	//curl -X POST "http://localhost:8080/api/teams/leave"
+	router.HandleFunc("/teams/leave", handler.TeamLeave).Methods("POST")
+	//curl -X GET "http://localhost:8080/api/teams/5"
+	router.HandleFunc("/teams/{team_id:[0-9]+}", handler.TeamGet).Methods("GET")
+	/*
+		curl -X POST "http://localhost:8080/api/teams/5/captain" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "user_id": 456
+		}'
+	*/
+	router.HandleFunc("/teams/{team_id}/captain", handler.TeamSetCaptain).Methods("POST")
+	//curl -X DELETE "http://localhost:8080/api/teams/5/members/456"
+	router.HandleFunc("/teams/{team_id}/members/{user_id}", handler.TeamRemoveMember).Methods("DELETE")
+
+	// Регистрируем маршруты достижений
+
+	//curl -X GET "http://localhost:8080/api/achievements"
+	router.HandleFunc("/achievements", handler.AchievementGetAll).Methods("GET")
+	//curl -X GET "http://localhost:8080/api/users/123/achievements"
+	router.HandleFunc("/users/{user_id}/achievements", handler.UserAchievements).Methods("GET")
+
 	//примеры запросов
 	//curl -X GET "http://localhost:8080/api/users/john_doe"
 	//curl -X GET "http://localhost:8080/api/users/example@example.com"
@@ -134,3 +390,167 @@ func setupProtectedAPIRoutes(router *mux.Router, handler *handlers.Handler) {
 	//пример ответа {"user_id": 123 }
 	router.HandleFunc("/users/{username_or_email}", handler.GetUserIDbyUsernameOrEmailHandler).Methods("GET")
 }
+
+// setupAdminAPIRoutes настраивает административные маршруты для API
+func setupAdminAPIRoutes(router *mux.Router, handler *handlers.Handler) {
+	/*
+		curl -X POST "http://localhost:8080/api/admin/categories" \
+		-H "Content-Type: application/json" \
+		-d '{"code": "social", "title": "Social networks"}'
+	*/
+	router.HandleFunc("/categories", handler.CategoryCreate).Methods("POST")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/rewards" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "title": "Branded T-shirt",
+		  "description": "Size M",
+		  "cost": 500,
+		  "stock": 20,
+		  "per_user_limit": 1
+		}'
+	*/
+	router.HandleFunc("/rewards", handler.RewardCreate).Methods("POST")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/achievements" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "code": "fifty_tasks",
+		  "title": "50 tasks",
+		  "metric": "completed_tasks",
+		  "threshold": 50,
+		  "bonus": 100
+		}'
+	*/
+	router.HandleFunc("/achievements", handler.AchievementCreate).Methods("POST")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/boosts" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "title": "Double points weekend",
+		  "multiplier_percent": 200,
+		  "starts_at": "2024-06-01T00:00:00Z",
+		  "ends_at": "2024-06-03T00:00:00Z"
+		}'
+		необязательные ограничения: "task_type": "quiz", "min_level": 3
+	*/
+	router.HandleFunc("/boosts", handler.AdminBoostCreate).Methods("POST")
+	//curl -X GET "http://localhost:8080/api/admin/boosts"
+	router.HandleFunc("/boosts", handler.AdminBoostGetAll).Methods("GET")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/seasons" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "title": "Summer 2024",
+		  "ends_at": "2024-09-01T00:00:00Z"
+		}'
+	*/
+	router.HandleFunc("/seasons", handler.AdminSeasonCreate).Methods("POST")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/seasons/3/close" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "prizes": [1000, 500, 250]
+		}'
+	*/
+	router.HandleFunc("/seasons/{season_id}/close", handler.AdminSeasonClose).Methods("POST")
+
+	/*
+		curl -X POST "http://localhost:8080/api/admin/campaigns" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "title": "Spring onboarding",
+		  "budget": 50000,
+		  "starts_at": "2024-03-01T00:00:00Z",
+		  "ends_at": "2024-04-01T00:00:00Z"
+		}'
+		задания добавляются в кампанию полем "campaign_id" при создании задания
+	*/
+	router.HandleFunc("/campaigns", handler.AdminCampaignCreate).Methods("POST")
+	//curl -X GET "http://localhost:8080/api/admin/campaigns"
+	router.HandleFunc("/campaigns", handler.AdminCampaigns).Methods("GET")
+	/*
+		curl -X PATCH "http://localhost:8080/api/admin/campaigns/4" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "status": "live"
+		}'
+	*/
+	router.HandleFunc("/campaigns/{campaign_id}", handler.AdminCampaignStatus).Methods("PATCH")
+	//curl -X GET "http://localhost:8080/api/admin/campaigns/4/stats"
+	router.HandleFunc("/campaigns/{campaign_id}/stats", handler.AdminCampaignStats).Methods("GET")
+
+	//curl -X GET "http://localhost:8080/api/admin/redemptions?status=pending"
+	router.HandleFunc("/redemptions", handler.AdminRedemptions).Methods("GET")
+
+	/*
+		curl -X PATCH "http://localhost:8080/api/admin/redemptions/15" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "status": "fulfilled"
+		}'
+	*/
+	router.HandleFunc("/redemptions/{redemption_id}", handler.AdminRedemptionStatus).Methods("PATCH")
+
+	// Ручная корректировка баланса: положительная сумма начисляет баллы, отрицательная - списывает.
+	// Коды причин: goodwill, correction, compensation, fraud, promotion, other
+	/*
+		curl -X POST "http://localhost:8080/api/admin/users/123/adjustments" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "amount": -40,
+		  "reason": "correction",
+		  "reference": "SUPPORT-1024"
+		}'
+	*/
+	router.HandleFunc("/users/{user_id}/adjustments", handler.AdminAdjustBalance).Methods("POST")
+
+	// Снятие блокировки входа после неудачных попыток; ip необязателен
+	/*
+		curl -X POST "http://localhost:8080/api/admin/users/123/unlock" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "ip": "203.0.113.7"
+		}'
+	*/
+	router.HandleFunc("/users/{user_id}/unlock", handler.AdminUnlockLogin).Methods("POST")
+
+	// API-ключи интеграций; права: tasks:complete, users:read, transactions:read. Ключ показывается только в ответе на создание
+	/*
+		curl -X POST "http://localhost:8080/api/admin/api-keys" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "name": "telegram-activity-bot",
+		  "scopes": ["tasks:complete", "users:read"]
+		}'
+
+		запрос с ключом:
+		curl -X POST "http://localhost:8080/api/task/123/complete" \
+		-H "X-API-Key: utr_3f9c...e1" \
+		-H "Content-Type: application/json" \
+		-d '{"task_id": 456}'
+	*/
+	router.HandleFunc("/api-keys", handler.AdminAPIKeyCreate).Methods("POST")
+	//curl -X GET "http://localhost:8080/api/admin/api-keys"
+	router.HandleFunc("/api-keys", handler.AdminAPIKeys).Methods("GET")
+	//curl -X DELETE "http://localhost:8080/api/admin/api-keys/3"
+	router.HandleFunc("/api-keys/{api_key_id}", handler.AdminAPIKeyRevoke).Methods("DELETE")
+
+	//curl -X GET "http://localhost:8080/api/admin/users/123/completions"
+	router.HandleFunc("/users/{user_id}/completions", handler.AdminUserCompletions).Methods("GET")
+
+	// Отмена выполнения задания: баллы исполнителя и реферальный бонус списываются компенсирующими записями
+	/*
+		curl -X POST "http://localhost:8080/api/admin/completions/42/revoke" \
+		-H "Content-Type: application/json" \
+		-d '{
+		  "reason": "Fake channel subscription"
+		}'
+	*/
+	router.HandleFunc("/completions/{completion_id}/revoke", handler.AdminRevokeCompletion).Methods("POST")
+}
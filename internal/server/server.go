@@ -8,11 +8,16 @@ import (
 	"fmt"
 	"github.com/ZnNr/user-task-reward-controller/internal/config"
 	"github.com/ZnNr/user-task-reward-controller/internal/handlers"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository"
 	"github.com/ZnNr/user-task-reward-controller/internal/router"
 	"github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/password"
 	"go.uber.org/zap"
 	"net/http"
+	"sync"
 	"time"
 
 	"github.com/golang-migrate/migrate/v4/database/postgres"
@@ -23,6 +28,8 @@ import (
 const (
 	jwtSignKey = "joiQWRtaW4iLCJJc3N1ZXIiOiJJc3N1ZXIiLCJVc2VybmFtZSI"
 	tokenTTL   = 120 * time.Minute
+	// oidcRequestTimeout время ожидания ответа провайдера OpenID Connect
+	oidcRequestTimeout = 10 * time.Second
 )
 
 // App структура приложения
@@ -30,7 +37,11 @@ type App struct {
 	config     *config.Config
 	logger     *zap.Logger
 	db         *sql.DB
+	services   *service.Service
 	httpServer *http.Server
+
+	stopJobs context.CancelFunc
+	jobs     sync.WaitGroup
 }
 
 // New конструктор нового экземпляра приложения
@@ -118,7 +129,31 @@ func (a *App) initHTTPServer() error {
 	logger := a.logger.With(zap.String("op", op))
 
 	// Инициализируем репозитории
-	repos := repository.NewRepositories(a.db, a.logger)
+	repos := repository.NewRepositories(a.db, a.logger, repository.Options{
+		PointsTTL: a.config.PointsTTL,
+		Streak:    a.config.StreakRules(),
+		Levels:    a.config.LevelCurve(),
+	})
+
+	// Инициализируем отправку писем
+	mailer, err := mail.NewSender(a.config.MailSender, a.config.MailFrom, a.config.MailDir, a.logger)
+	if err != nil {
+		logger.Error("Failed to initialize mail sender", zap.Error(err))
+		return fmt.Errorf("failed to initialize mail sender: %w", err)
+	}
+
+	// Загружаем политику паролей
+	passwordPolicy, err := password.LoadPolicy(a.config.PasswordMinLength, a.config.PasswordBreachedList)
+	if err != nil {
+		logger.Error("Failed to load password policy", zap.Error(err))
+		return fmt.Errorf("failed to load password policy: %w", err)
+	}
+
+	// Вход через провайдера OpenID Connect; настройки провайдера запрашиваются при первом входе
+	var oidcProvider *oidc.Provider
+	if a.config.OIDCEnabled() {
+		oidcProvider = oidc.NewProvider(a.config.OIDC(), &http.Client{Timeout: oidcRequestTimeout})
+	}
 
 	// Инициализируем сервисы
 	services := service.NewService(service.ServicesDependencies{
@@ -126,10 +161,37 @@ func (a *App) initHTTPServer() error {
 		Logger:   a.logger,
 		SignKey:  jwtSignKey,
 		TokenTTL: tokenTTL,
+
+		Mailer:               mailer,
+		PublicURL:            a.config.PublicURL,
+		EmailVerificationTTL: a.config.EmailVerificationTTL,
+		PasswordResetURL:     a.config.PasswordResetURL,
+		PasswordResetTTL:     a.config.PasswordResetTTL,
+		PasswordPolicy:       passwordPolicy,
+		BcryptCost:           a.config.BcryptCost,
+		LoginThrottle:        a.config.LoginThrottleRules(),
+		TOTPIssuer:           a.config.TOTPIssuer,
+		TwoFactorTTL:         a.config.TwoFactorTTL,
+		OIDC:                 oidcProvider,
+		TelegramBotToken:     a.config.TelegramBotToken,
+		TelegramAuthMaxAge:   a.config.TelegramAuthMaxAge,
+
+		ReferralLandingURL: a.config.ReferralLandingURL,
+		TransferLimits: models.TransferLimits{
+			DailyLimit: a.config.TransferDailyLimit,
+			MinBalance: a.config.TransferMinBalance,
+		},
+		StreakRules:    a.config.StreakRules(),
+		Levels:         a.config.LevelCurve(),
+		TeamMaxMembers: a.config.TeamMaxMembers,
+		ExportRules:    a.config.ExportRules(),
 	})
 
+	a.services = services
+
 	// Создаем обработчики
 	handler := handlers.NewHandler(services, a.logger)
+	handler.TrustProxyHeaders = a.config.TrustProxyHeaders
 
 	// Создаем роутер и добавляем маршруты для всех обработчиков
 	router := router.NewRouter(handler, a.logger)
@@ -152,6 +214,8 @@ func (a *App) Run() error {
 	const op = "server.App.Run"
 	logger := a.logger.With(zap.String("op", op))
 
+	a.startBackgroundJobs()
+
 	logger.Info("Starting server", zap.String("port", a.config.ServerPort))
 	if err := a.httpServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
 		logger.Error("Failed to start server", zap.Error(err))
@@ -172,6 +236,8 @@ func (a *App) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
 
+	a.stopBackgroundJobs()
+
 	if err := a.db.Close(); err != nil {
 		logger.Error("Failed to close database connection", zap.Error(err))
 		return fmt.Errorf("failed to close database connection: %w", err)
@@ -180,3 +246,59 @@ func (a *App) Shutdown(ctx context.Context) error {
 	logger.Info("Server stopped gracefully")
 	return nil
 }
+
+// startBackgroundJobs запускает фоновые задачи приложения
+func (a *App) startBackgroundJobs() {
+	const op = "server.App.startBackgroundJobs"
+	logger := a.logger.With(zap.String("op", op))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopJobs = cancel
+
+	if a.config.PointsTTL > 0 {
+		a.runPeriodically(ctx, "points_expiry", a.config.PointsExpiryInterval, func(ctx context.Context) error {
+			_, err := a.services.Balance.ExpirePoints(ctx)
+			return err
+		})
+		logger.Info("Points expiry job started", zap.Duration("points_ttl", a.config.PointsTTL),
+			zap.Duration("interval", a.config.PointsExpiryInterval))
+	}
+
+	a.runPeriodically(ctx, "data_exports", a.config.ExportInterval, func(ctx context.Context) error {
+		_, err := a.services.Export.ProcessExports(ctx)
+		return err
+	})
+	logger.Info("Data export job started", zap.Duration("interval", a.config.ExportInterval))
+}
+
+// runPeriodically выполняет задачу сразу и затем с указанным интервалом до отмены контекста
+func (a *App) runPeriodically(ctx context.Context, name string, interval time.Duration, job func(ctx context.Context) error) {
+	logger := a.logger.With(zap.String("job", name))
+
+	a.jobs.Add(1)
+	go func() {
+		defer a.jobs.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			if err := job(ctx); err != nil && ctx.Err() == nil {
+				logger.Error("Background job failed", zap.Error(err))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// stopBackgroundJobs останавливает фоновые задачи и дожидается их завершения
+func (a *App) stopBackgroundJobs() {
+	if a.stopJobs == nil {
+		return
+	}
+	a.stopJobs()
+	a.jobs.Wait()
+}
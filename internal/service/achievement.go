@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+)
+
+// AchievementService служба достижений пользователей
+type AchievementService struct {
+	repo   repository.AchievementRepository
+	logger *zap.Logger
+}
+
+// NewAchievementService создает новый экземпляр AchievementService
+func NewAchievementService(repo repository.AchievementRepository, logger *zap.Logger) *AchievementService {
+	return &AchievementService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// achievementEvaluator проверяет условия достижений после действий пользователя
+type achievementEvaluator interface {
+	EvaluateAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+}
+
+// evaluateAchievements выдает пользователю достижения, условия которых выполнены.
+// Ошибка проверки не отменяет основное действие: она только записывается в журнал,
+// а недостающие достижения будут выданы при следующей проверке.
+func evaluateAchievements(ctx context.Context, evaluator achievementEvaluator, userID int64, logger *zap.Logger) {
+	if evaluator == nil {
+		return
+	}
+	if _, err := evaluator.EvaluateAchievements(ctx, userID); err != nil {
+		logger.Error("Failed to evaluate achievements", zap.Int64("user_id", userID), zap.Error(err))
+	}
+}
+
+// CreateAchievement добавляет описание достижения
+func (s *AchievementService) CreateAchievement(ctx context.Context, req *models.AchievementCreate) (int64, error) {
+	const op = "service.Achievement.CreateAchievement"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateAchievementRequest(req); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return 0, err
+	}
+
+	achievementID, err := s.repo.CreateAchievement(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create achievement", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Achievement created successfully", zap.Int64("achievement_id", achievementID), zap.String("code", req.Code))
+	return achievementID, nil
+}
+
+// validateAchievementRequest выполняет проверку валидности запроса на создание достижения.
+func validateAchievementRequest(req *models.AchievementCreate) error {
+	if req.Code == "" {
+		return errors.NewValidation("achievement code cannot be empty", nil)
+	}
+	if len(req.Code) > 64 {
+		return errors.NewValidation("achievement code cannot be longer than 64 characters", nil)
+	}
+	if req.Title == "" {
+		return errors.NewValidation("achievement title cannot be empty", nil)
+	}
+	switch req.Metric {
+	case models.AchievementMetricCompletedTasks, models.AchievementMetricReferrals, models.AchievementMetricLongestStreak:
+	case "":
+		return errors.NewValidation("metric is required", nil)
+	default:
+		return errors.NewValidation("unknown metric", nil)
+	}
+	if req.Threshold < 1 {
+		return errors.NewValidation("minimum value for the Threshold field is 1", nil)
+	}
+	if req.Bonus < 0 {
+		return errors.NewValidation("bonus cannot be negative", nil)
+	}
+	if req.BonusCurrency == "" {
+		req.BonusCurrency = models.CurrencyCoins
+	}
+	return nil
+}
+
+// GetAchievements возвращает активные достижения
+func (s *AchievementService) GetAchievements(ctx context.Context) ([]models.Achievement, error) {
+	const op = "service.Achievement.GetAchievements"
+	logger := s.logger.With(zap.String("op", op))
+
+	achievements, err := s.repo.GetAchievements(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch achievements", zap.Error(err))
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// GetUserAchievements возвращает достижения, выданные пользователю
+func (s *AchievementService) GetUserAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	const op = "service.Achievement.GetUserAchievements"
+	logger := s.logger.With(zap.String("op", op))
+
+	achievements, err := s.repo.GetUserAchievements(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to fetch user achievements", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return achievements, nil
+}
+
+// EvaluateAchievements проверяет условия достижений пользователя и выдает выполненные
+func (s *AchievementService) EvaluateAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	const op = "service.Achievement.EvaluateAchievements"
+	logger := s.logger.With(zap.String("op", op))
+
+	awarded, err := s.repo.AwardAchievements(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to award achievements", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	if len(awarded) > 0 {
+		logger.Info("Achievements awarded", zap.Int64("user_id", userID), zap.Int("awarded", len(awarded)))
+	}
+	return awarded, nil
+}
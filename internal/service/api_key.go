@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// apiKeyPrefix начало всех API-ключей, по нему ключ легко найти в логах и утечках
+	apiKeyPrefix = "utr_"
+	// apiKeySecretBytes число случайных байт в ключе
+	apiKeySecretBytes = 24
+	// apiKeyShownLength длина начала ключа, которое хранится для отображения
+	apiKeyShownLength = len(apiKeyPrefix) + 8
+	// maxAPIKeyNameLength наибольшая длина названия ключа
+	maxAPIKeyNameLength = 100
+)
+
+// apiKeyScopes права, которые можно выдать API-ключу
+var apiKeyScopes = map[string]bool{
+	models.ScopeTasksComplete:    true,
+	models.ScopeUsersRead:        true,
+	models.ScopeTransactionsRead: true,
+}
+
+// APIKeyService служба API-ключей для интеграций
+type APIKeyService struct {
+	repo   repository.APIKeyRepository
+	logger *zap.Logger
+}
+
+// NewAPIKeyService создает новый экземпляр APIKeyService
+func NewAPIKeyService(repo repository.APIKeyRepository, logger *zap.Logger) *APIKeyService {
+	return &APIKeyService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateAPIKey выпускает API-ключ. Ключ возвращается только здесь, сохраняется лишь его хэш.
+func (s *APIKeyService) CreateAPIKey(ctx context.Context, req *models.APIKeyCreate) (models.APIKeyCreated, error) {
+	const op = "service.APIKey.CreateAPIKey"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateAPIKeyRequest(req); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return models.APIKeyCreated{}, err
+	}
+
+	secret := make([]byte, apiKeySecretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		logger.Error("Cannot generate api key", zap.Error(err))
+		return models.APIKeyCreated{}, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	key := apiKeyPrefix + hex.EncodeToString(secret)
+	req.Prefix = key[:apiKeyShownLength]
+	req.KeyHash = hashAPIKey(key)
+
+	apiKey, err := s.repo.CreateAPIKey(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create api key", zap.Error(err))
+		return models.APIKeyCreated{}, err
+	}
+
+	logger.Info("API key created successfully", zap.Int64("api_key_id", apiKey.APIKeyID), zap.String("name", apiKey.Name),
+		zap.Strings("scopes", apiKey.Scopes), zap.Int64("admin_id", req.AdminID))
+	return models.APIKeyCreated{APIKey: apiKey, Key: key}, nil
+}
+
+// validateAPIKeyRequest проверяет запрос на создание API-ключа и приводит права к нижнему регистру без повторов
+func validateAPIKeyRequest(req *models.APIKeyCreate) error {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return errors.NewValidation("api key name cannot be empty", nil)
+	}
+	if utf8.RuneCountInString(req.Name) > maxAPIKeyNameLength {
+		return errors.NewValidation(fmt.Sprintf("api key name cannot be longer than %d characters", maxAPIKeyNameLength), nil)
+	}
+
+	seen := make(map[string]bool, len(req.Scopes))
+	scopes := make([]string, 0, len(req.Scopes))
+	for _, scope := range req.Scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if !apiKeyScopes[scope] {
+			return errors.NewValidation(fmt.Sprintf("unknown scope %q", scope), nil)
+		}
+		if !seen[scope] {
+			seen[scope] = true
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return errors.NewValidation("at least one scope is required", nil)
+	}
+	req.Scopes = scopes
+	return nil
+}
+
+// GetAPIKeys возвращает все API-ключи, включая отозванные
+func (s *APIKeyService) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	const op = "service.APIKey.GetAPIKeys"
+	logger := s.logger.With(zap.String("op", op))
+
+	keys, err := s.repo.GetAPIKeys(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch api keys", zap.Error(err))
+		return nil, err
+	}
+	if keys == nil {
+		keys = []models.APIKey{}
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey отзывает API-ключ; запросы с ним сразу перестают проходить
+func (s *APIKeyService) RevokeAPIKey(ctx context.Context, apiKeyID int64) error {
+	const op = "service.APIKey.RevokeAPIKey"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := s.repo.RevokeAPIKey(ctx, apiKeyID); err != nil {
+		logger.Error("Failed to revoke api key", zap.Int64("api_key_id", apiKeyID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("API key revoked", zap.Int64("api_key_id", apiKeyID))
+	return nil
+}
+
+// AuthenticateAPIKey проверяет API-ключ и отмечает время его использования.
+// Неизвестный и отозванный ключи не различаются.
+func (s *APIKeyService) AuthenticateAPIKey(ctx context.Context, key string) (models.APIKey, error) {
+	const op = "service.APIKey.AuthenticateAPIKey"
+	logger := s.logger.With(zap.String("op", op))
+
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return models.APIKey{}, errors.NewUnauthorized("invalid api key", nil)
+	}
+	apiKey, err := s.repo.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("Unknown api key")
+			return models.APIKey{}, errors.NewUnauthorized("invalid api key", nil)
+		}
+		logger.Error("Failed to fetch api key", zap.Error(err))
+		return models.APIKey{}, err
+	}
+	if apiKey.RevokedAt != nil {
+		logger.Info("Revoked api key used", zap.Int64("api_key_id", apiKey.APIKeyID))
+		return models.APIKey{}, errors.NewUnauthorized("invalid api key", nil)
+	}
+
+	// Ошибка учета использования не мешает запросу
+	if err := s.repo.TouchAPIKey(ctx, apiKey.APIKeyID); err != nil {
+		logger.Error("Failed to update api key last use", zap.Int64("api_key_id", apiKey.APIKeyID), zap.Error(err))
+	}
+	return apiKey, nil
+}
+
+// hashAPIKey возвращает хэш API-ключа для хранения.
+// Ключ случайный и длинный, поэтому медленный хэш для него не нужен.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -2,77 +2,305 @@ package service
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/authtoken"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/password"
 	"github.com/golang-jwt/jwt/v5"
 	"go.uber.org/zap"
 	"golang.org/x/crypto/bcrypt"
+	netmail "net/mail"
+	"net/url"
+	"strings"
 	"time"
 )
 
+const (
+	// maxEmailLength наибольшая длина адреса электронной почты
+	maxEmailLength = 255
+	// defaultPasswordMinLength минимальная длина пароля, если политика не задана
+	defaultPasswordMinLength = 8
+)
+
 // AuthService структура для работы с аутентификацией и регистрацией пользователей
 type AuthService struct {
 	repo     repository.AuthRepository
 	logger   *zap.Logger
 	SignKey  string
 	TokenTTL time.Duration
+
+	mailer               mail.Sender
+	tokens               *authtoken.Signer
+	publicURL            string
+	emailVerificationTTL time.Duration
+	passwordResetURL     string
+	passwordResetTTL     time.Duration
+	passwordPolicy       *password.Policy
+	bcryptCost           int
+	loginThrottle        models.LoginThrottleRules
+	totpIssuer           string
+	twoFactorTTL         time.Duration
+	oidc                 *oidc.Provider
+	telegramBotToken     string
+	telegramAuthMaxAge   time.Duration
+	// dummyHash хэш для сравнения при входе несуществующего пользователя
+	dummyHash string
 }
 
 // AuthDependencies зависимости для создания AuthService
 type AuthDependencies struct {
-	authRepo repository.AuthRepository
-	logger   *zap.Logger
-	signKey  string
-	tokenTTL time.Duration
+	AuthRepo repository.AuthRepository
+	Logger   *zap.Logger
+	SignKey  string
+	TokenTTL time.Duration
+	// Mailer отправляет письма со ссылками подтверждения
+	Mailer mail.Sender
+	// PublicURL адрес сервиса, из которого строятся ссылки в письмах
+	PublicURL string
+	// EmailVerificationTTL срок действия ссылки подтверждения адреса
+	EmailVerificationTTL time.Duration
+	// PasswordResetURL страница установки нового пароля, к ней добавляется параметр token
+	PasswordResetURL string
+	// PasswordResetTTL срок действия ссылки сброса пароля
+	PasswordResetTTL time.Duration
+	// PasswordPolicy политика новых паролей; nil - только минимальная длина по умолчанию
+	PasswordPolicy *password.Policy
+	// BcryptCost стоимость хэширования паролей; 0 - bcrypt.DefaultCost.
+	// Хэши с меньшей стоимостью пересчитываются при входе пользователя.
+	BcryptCost int
+	// LoginThrottle ограничения на неудачные попытки входа
+	LoginThrottle models.LoginThrottleRules
+	// TOTPIssuer название сервиса в приложении-аутентификаторе
+	TOTPIssuer string
+	// TwoFactorTTL время на ввод кода после проверки пароля
+	TwoFactorTTL time.Duration
+	// OIDC провайдер OpenID Connect для входа; nil - вход через провайдера отключен
+	OIDC *oidc.Provider
+	// TelegramBotToken токен бота, от имени которого работает виджет Telegram Login; пусто - вход отключен
+	TelegramBotToken string
+	// TelegramAuthMaxAge наибольший возраст данных виджета; 0 - без ограничения
+	TelegramAuthMaxAge time.Duration
 }
 
 // NewAuthService создает новый экземпляр AuthService
 func NewAuthService(deps AuthDependencies) *AuthService {
+	if deps.PasswordPolicy == nil {
+		deps.PasswordPolicy = password.NewPolicy(defaultPasswordMinLength, nil)
+	}
+	if deps.BcryptCost == 0 {
+		deps.BcryptCost = bcrypt.DefaultCost
+	}
+	// Ошибка здесь возможна только при недопустимой стоимости, тогда сравнение с пустым хэшем просто вернет false
+	dummyHash, _ := bcrypt.GenerateFromPassword([]byte("dummy password"), deps.BcryptCost)
 	return &AuthService{
-		repo:     deps.authRepo,
-		logger:   deps.logger,
-		SignKey:  deps.signKey,
-		TokenTTL: deps.tokenTTL,
+		repo:     deps.AuthRepo,
+		logger:   deps.Logger,
+		SignKey:  deps.SignKey,
+		TokenTTL: deps.TokenTTL,
+
+		mailer:               deps.Mailer,
+		tokens:               authtoken.NewSigner(deps.SignKey),
+		publicURL:            strings.TrimRight(deps.PublicURL, "/"),
+		emailVerificationTTL: deps.EmailVerificationTTL,
+		passwordResetURL:     deps.PasswordResetURL,
+		passwordResetTTL:     deps.PasswordResetTTL,
+		passwordPolicy:       deps.PasswordPolicy,
+		bcryptCost:           deps.BcryptCost,
+		loginThrottle:        deps.LoginThrottle,
+		totpIssuer:           deps.TOTPIssuer,
+		twoFactorTTL:         deps.TwoFactorTTL,
+		oidc:                 deps.OIDC,
+		telegramBotToken:     deps.TelegramBotToken,
+		telegramAuthMaxAge:   deps.TelegramAuthMaxAge,
+		dummyHash:            string(dummyHash),
 	}
 }
 
-// Login выполняет вход пользователя и возвращает токен
-func (s *AuthService) Login(ctx context.Context, login *models.SignIn) (string, error) {
+// Login выполняет вход пользователя и возвращает токен.
+// Если у пользователя включена двухфакторная аутентификация, вместо JWT возвращается токен второго шага,
+// который обменивается на JWT вместе с кодом в LoginTwoFactor.
+// После серии неудачных попыток по имени пользователя или с одного IP-адреса вход временно блокируется.
+func (s *AuthService) Login(ctx context.Context, login *models.SignIn) (*models.LoginResult, error) {
 	const op = "service.Auth.Login"
 	logger := s.logger.With(zap.String("op", op))
 	if login.Username == "" {
 		logger.Error("username is required")
-		return "", errors.NewBadRequest(errors.ErrorMessage[errors.BadRequest], nil)
+		return nil, errors.NewBadRequest(errors.ErrorMessage[errors.BadRequest], nil)
 	}
 	if login.Password == "" {
 		logger.Error("password is required")
-		return "", errors.NewBadRequest(errors.ErrorMessage[errors.BadRequest], nil)
+		return nil, errors.NewBadRequest(errors.ErrorMessage[errors.BadRequest], nil)
+	}
+	if err := s.checkLoginBlock(ctx, login); err != nil {
+		logger.Warn("login blocked", zap.String("Username", login.Username), zap.String("ip", login.IP))
+		return nil, err
 	}
+
 	user, err := s.repo.GetUserByUsername(ctx, login.Username)
 	if err != nil {
 		if errors.IsNotFound(err) {
 			logger.Error("user not found", zap.String("Username", login.Username))
-			return "", errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
+			// Сравниваем с фиктивным хэшем, чтобы по времени ответа нельзя было отличить несуществующего пользователя
+			CheckPasswordHash(login.Password, s.dummyHash)
+			s.recordLoginFailure(ctx, login)
+			return nil, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
 		}
 		logger.Error("cannot get user", zap.String("Username", login.Username), zap.Error(err))
-		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
 	}
 	// Сравниваем хэш пароля
 	if !CheckPasswordHash(login.Password, user.Password) {
 		logger.Error("invalid password", zap.String("Username", login.Username))
-		return "", errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
+		s.recordLoginFailure(ctx, login)
+		return nil, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil)
+	}
+	s.rehashPassword(ctx, user, login.Password)
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", user.ID), zap.Error(err))
+		return nil, err
+	}
+	if tf.Enabled {
+		// Неудачные попытки сбрасываются только после второго шага, иначе знание пароля позволило бы перебирать коды
+		challenge, err := s.issueMailToken(ctx, models.TokenPurposeLoginChallenge, user.ID, "", s.twoFactorTTL)
+		if err != nil {
+			logger.Error("cannot issue login challenge", zap.Int64("user_id", user.ID), zap.Error(err))
+			return nil, err
+		}
+		logger.Info("Password accepted, two-factor code required", zap.Int64("user_id", user.ID))
+		return &models.LoginResult{TwoFactorRequired: true, ChallengeToken: challenge}, nil
 	}
+
+	s.resetLoginFailures(ctx, login)
 	// Генерируем токен
 	token, err := s.generateToken(*user)
 	if err != nil {
 		logger.Error("cannot generate token", zap.String("Username", login.Username), zap.Error(err))
-		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
 	}
 
 	logger.Info("User logged in successfully", zap.String("Username", login.Username))
-	return token, nil
+	return &models.LoginResult{Token: token}, nil
+}
+
+// loginKey ключ учета попыток входа по имени пользователя
+func loginKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// checkLoginBlock возвращает ошибку, если вход по имени пользователя или с IP-адреса заблокирован
+func (s *AuthService) checkLoginBlock(ctx context.Context, login *models.SignIn) error {
+	if s.loginThrottle.Window <= 0 {
+		return nil
+	}
+	remaining, err := s.repo.GetLoginBlock(ctx, loginKey(login.Username), login.IP)
+	if err != nil {
+		return errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	if remaining <= 0 {
+		return nil
+	}
+	seconds := int64((remaining + time.Second - 1) / time.Second)
+	return errors.NewTooManyRequests(fmt.Sprintf("too many failed login attempts, try again in %d seconds", seconds), nil)
+}
+
+// recordLoginFailure учитывает неудачную попытку входа и при необходимости блокирует вход.
+// Ошибки учета не влияют на ответ пользователю.
+func (s *AuthService) recordLoginFailure(ctx context.Context, login *models.SignIn) {
+	if s.loginThrottle.Window <= 0 {
+		return
+	}
+	logger := s.logger.With(zap.String("op", "service.Auth.recordLoginFailure"))
+
+	keys := map[string]string{models.LoginScopeUsername: loginKey(login.Username)}
+	if login.IP != "" {
+		keys[models.LoginScopeIP] = login.IP
+	}
+	for scope, key := range keys {
+		failures, err := s.repo.RecordLoginFailure(ctx, scope, key, s.loginThrottle.Window)
+		if err != nil {
+			logger.Error("cannot record login failure", zap.String("scope", scope), zap.Error(err))
+			continue
+		}
+		block := loginBlockDuration(s.loginThrottle, scope, failures)
+		if block <= 0 {
+			continue
+		}
+		if err := s.repo.BlockLogin(ctx, scope, key, block); err != nil {
+			logger.Error("cannot block login", zap.String("scope", scope), zap.Error(err))
+			continue
+		}
+		logger.Warn("login blocked after failed attempts", zap.String("scope", scope), zap.String("key", key),
+			zap.Int("failures", failures), zap.Duration("duration", block))
+	}
+}
+
+// loginBlockDuration возвращает срок блокировки входа после failures неудачных попыток подряд.
+// По имени пользователя задержка растет экспоненциально, затем вход блокируется на LockoutDuration;
+// по IP-адресу вход блокируется только после IPLockoutAfter попыток.
+func loginBlockDuration(rules models.LoginThrottleRules, scope string, failures int) time.Duration {
+	if scope == models.LoginScopeIP {
+		if rules.IPLockoutAfter > 0 && failures >= rules.IPLockoutAfter {
+			return rules.LockoutDuration
+		}
+		return 0
+	}
+	if rules.LockoutAfter > 0 && failures >= rules.LockoutAfter {
+		return rules.LockoutDuration
+	}
+	if rules.BackoffAfter <= 0 || failures < rules.BackoffAfter || rules.BackoffBase <= 0 {
+		return 0
+	}
+	delay := rules.BackoffBase
+	for i := rules.BackoffAfter; i < failures; i++ {
+		delay *= 2
+		if rules.BackoffMax > 0 && delay >= rules.BackoffMax {
+			return rules.BackoffMax
+		}
+	}
+	return delay
+}
+
+// resetLoginFailures сбрасывает неудачные попытки по имени пользователя после успешного входа.
+// Счетчик по IP-адресу не сбрасывается, чтобы вход в свою учетную запись не позволял продолжать перебор чужих.
+func (s *AuthService) resetLoginFailures(ctx context.Context, login *models.SignIn) {
+	if s.loginThrottle.Window <= 0 {
+		return
+	}
+	if err := s.repo.ResetLoginFailures(ctx, models.LoginScopeUsername, loginKey(login.Username)); err != nil {
+		s.logger.Error("cannot reset login failures", zap.String("Username", login.Username), zap.Error(err))
+	}
+}
+
+// UnlockLogin снимает блокировку входа пользователя и, если указан, IP-адреса
+func (s *AuthService) UnlockLogin(ctx context.Context, userID int64, ip string) error {
+	const op = "service.Auth.UnlockLogin"
+	logger := s.logger.With(zap.String("op", op))
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if err := s.repo.ResetLoginFailures(ctx, models.LoginScopeUsername, loginKey(user.Username)); err != nil {
+		logger.Error("cannot unlock user login", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if ip = strings.TrimSpace(ip); ip != "" {
+		if err := s.repo.ResetLoginFailures(ctx, models.LoginScopeIP, ip); err != nil {
+			logger.Error("cannot unlock ip login", zap.String("ip", ip), zap.Error(err))
+			return err
+		}
+	}
+
+	logger.Info("Login unlocked", zap.Int64("user_id", userID), zap.String("ip", ip))
+	return nil
 }
 
 // Register регистрирует нового пользователя
@@ -87,7 +315,25 @@ func (s *AuthService) Register(ctx context.Context, signUp *models.CreateUser) (
 		logger.Error("password is required")
 		return 0, errors.NewBadRequest(errors.ErrorMessage[errors.BadRequest], nil)
 	}
-	signUp.Password, _ = s.generatePasswordHash(signUp.Password)
+	if isReservedUsername(signUp.Username) {
+		logger.Info("reserved username", zap.String("username", signUp.Username))
+		return 0, errors.NewValidation("username is reserved", nil)
+	}
+	email, err := normalizeEmail(signUp.Email)
+	if err != nil {
+		logger.Error("invalid email", zap.String("username", signUp.Username), zap.Error(err))
+		return 0, err
+	}
+	signUp.Email = email
+	if err := s.passwordPolicy.Validate(signUp.Password, signUp.Username); err != nil {
+		logger.Info("password rejected by policy", zap.String("username", signUp.Username), zap.Error(err))
+		return 0, err
+	}
+	signUp.Password, err = s.generatePasswordHash(signUp.Password)
+	if err != nil {
+		logger.Error("cannot hash password", zap.String("username", signUp.Username), zap.Error(err))
+		return 0, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
 	userId, err := s.repo.CreateUser(ctx, signUp)
 	if err != nil {
 		if errors.IsAlreadyExists(err) {
@@ -99,9 +345,297 @@ func (s *AuthService) Register(ctx context.Context, signUp *models.CreateUser) (
 	}
 
 	logger.Info("User registered successfully", zap.Int64("user_id", userId))
+
+	// Регистрация не откатывается из-за письма: пользователь может запросить его повторно
+	if err := s.sendEmailVerification(ctx, userId, signUp.Email); err != nil {
+		logger.Error("cannot send email verification", zap.Int64("user_id", userId), zap.Error(err))
+	}
 	return userId, nil
 }
 
+// normalizeEmail приводит адрес к нижнему регистру без пробелов по краям и проверяет его формат
+func normalizeEmail(email string) (string, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return "", errors.NewValidation("email is required", nil)
+	}
+	if len(email) > maxEmailLength {
+		return "", errors.NewValidation(fmt.Sprintf("email cannot be longer than %d characters", maxEmailLength), nil)
+	}
+	// Адрес с отображаемым именем или комментарием ParseAddress тоже примет, поэтому сравниваем результат с исходной строкой
+	addr, err := netmail.ParseAddress(email)
+	if err != nil || addr.Address != email || !strings.Contains(email[strings.LastIndex(email, "@")+1:], ".") {
+		return "", errors.NewValidation("invalid email format", err)
+	}
+	return email, nil
+}
+
+// VerifyEmail подтверждает адрес пользователя по токену из письма
+func (s *AuthService) VerifyEmail(ctx context.Context, token string) error {
+	const op = "service.Auth.VerifyEmail"
+	logger := s.logger.With(zap.String("op", op))
+
+	claims, err := s.parseMailToken(models.TokenPurposeEmailVerification, token)
+	if err != nil {
+		logger.Info("invalid verification token", zap.Error(err))
+		return err
+	}
+
+	userID, err := s.repo.VerifyEmail(ctx, claims.TokenID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.NewValidation("invalid token", err)
+		}
+		logger.Error("cannot verify email", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Email verified successfully", zap.Int64("user_id", userID))
+	return nil
+}
+
+// ResendEmailVerification повторно отправляет письмо для подтверждения адреса
+func (s *AuthService) ResendEmailVerification(ctx context.Context, userID int64) error {
+	const op = "service.Auth.ResendEmailVerification"
+	logger := s.logger.With(zap.String("op", op))
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if !user.Email.Valid || user.Email.String == "" {
+		return errors.NewValidation("user has no email", nil)
+	}
+	if user.EmailVerified {
+		return errors.NewValidation("email is already verified", nil)
+	}
+
+	if err := s.sendEmailVerification(ctx, userID, user.Email.String); err != nil {
+		logger.Error("cannot send email verification", zap.Int64("user_id", userID), zap.Error(err))
+		return errors.NewInternal("cannot send email verification", err)
+	}
+	logger.Info("Email verification sent", zap.Int64("user_id", userID))
+	return nil
+}
+
+// ForgotPassword отправляет на адрес пользователя ссылку для сброса пароля.
+// Для неизвестного адреса ошибка не возвращается, чтобы по ответу нельзя было узнать, зарегистрирован ли адрес.
+func (s *AuthService) ForgotPassword(ctx context.Context, email string) error {
+	const op = "service.Auth.ForgotPassword"
+	logger := s.logger.With(zap.String("op", op))
+
+	email, err := normalizeEmail(email)
+	if err != nil {
+		logger.Info("invalid email", zap.Error(err))
+		return err
+	}
+
+	user, err := s.repo.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("password reset requested for unknown email")
+			return s.sendUnknownAccountEmail(ctx, email)
+		}
+		logger.Error("cannot get user", zap.Error(err))
+		return err
+	}
+
+	token, err := s.issueMailToken(ctx, models.TokenPurposePasswordReset, user.ID, email, s.passwordResetTTL)
+	if err != nil {
+		logger.Error("cannot issue reset token", zap.Int64("user_id", user.ID), zap.Error(err))
+		return err
+	}
+	err = s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Open the link to set a new password:\n\n%s?token=%s\n\n"+
+			"The link is valid for %s. If you did not request a password reset, ignore this email.\n",
+			s.passwordResetURL, url.QueryEscape(token), s.passwordResetTTL),
+	})
+	if err != nil {
+		logger.Error("cannot send reset email", zap.Int64("user_id", user.ID), zap.Error(err))
+		return errors.NewInternal("cannot send reset email", err)
+	}
+
+	logger.Info("Password reset email sent", zap.Int64("user_id", user.ID))
+	return nil
+}
+
+// sendUnknownAccountEmail отвечает на запрос сброса пароля для адреса без аккаунта.
+// Подписывается токен и отправляется письмо, как для существующего аккаунта,
+// чтобы по времени ответа нельзя было узнать, зарегистрирован ли адрес.
+func (s *AuthService) sendUnknownAccountEmail(ctx context.Context, email string) error {
+	if _, _, err := s.tokens.Issue(models.TokenPurposePasswordReset, 0, email, s.passwordResetTTL); err != nil {
+		s.logger.Error("cannot issue reset token", zap.Error(err))
+		return err
+	}
+	err := s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Reset your password",
+		Body: "Someone requested a password reset for this email address, but it is not linked to any account.\n\n" +
+			"If you did not request a password reset, ignore this email.\n",
+	})
+	if err != nil {
+		s.logger.Error("cannot send reset email", zap.Error(err))
+		return errors.NewInternal("cannot send reset email", err)
+	}
+	return nil
+}
+
+// ResetPassword устанавливает новый пароль по токену из письма и отзывает все сессии пользователя
+func (s *AuthService) ResetPassword(ctx context.Context, req *models.PasswordResetRequest) error {
+	const op = "service.Auth.ResetPassword"
+	logger := s.logger.With(zap.String("op", op))
+
+	claims, err := s.parseMailToken(models.TokenPurposePasswordReset, req.Token)
+	if err != nil {
+		logger.Info("invalid reset token", zap.Error(err))
+		return err
+	}
+	if req.Password == "" {
+		logger.Error("password is required")
+		return errors.NewBadRequest("password is required", nil)
+	}
+	if err := s.passwordPolicy.Validate(req.Password, ""); err != nil {
+		logger.Info("password rejected by policy", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		return err
+	}
+
+	hash, err := s.generatePasswordHash(req.Password)
+	if err != nil {
+		logger.Error("cannot hash password", zap.Error(err))
+		return errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+
+	userID, err := s.repo.ResetPassword(ctx, claims.TokenID, hash)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.NewValidation("invalid token", err)
+		}
+		logger.Error("cannot reset password", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Password reset successfully, sessions revoked", zap.Int64("user_id", userID))
+	return nil
+}
+
+// ChangePassword меняет пароль пользователя после проверки текущего.
+// Все выданные ранее JWT отзываются, взамен возвращается новый токен для текущей сессии.
+func (s *AuthService) ChangePassword(ctx context.Context, userID int64, req *models.PasswordChangeRequest) (string, error) {
+	const op = "service.Auth.ChangePassword"
+	logger := s.logger.With(zap.String("op", op))
+
+	if req.OldPassword == "" || req.NewPassword == "" {
+		logger.Error("old and new passwords are required")
+		return "", errors.NewBadRequest("old_password and new_password are required", nil)
+	}
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", userID), zap.Error(err))
+		return "", err
+	}
+	if !CheckPasswordHash(req.OldPassword, user.Password) {
+		logger.Info("invalid current password", zap.Int64("user_id", userID))
+		return "", errors.NewForbidden("current password is incorrect", nil)
+	}
+	if req.NewPassword == req.OldPassword {
+		return "", errors.NewValidation("new password must differ from the current one", nil)
+	}
+	if err := s.passwordPolicy.Validate(req.NewPassword, user.Username); err != nil {
+		logger.Info("password rejected by policy", zap.Int64("user_id", userID), zap.Error(err))
+		return "", err
+	}
+
+	hash, err := s.generatePasswordHash(req.NewPassword)
+	if err != nil {
+		logger.Error("cannot hash password", zap.Error(err))
+		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	user.TokenVersion, err = s.repo.ChangePassword(ctx, userID, hash)
+	if err != nil {
+		logger.Error("cannot change password", zap.Int64("user_id", userID), zap.Error(err))
+		return "", err
+	}
+
+	token, err := s.generateToken(*user)
+	if err != nil {
+		logger.Error("cannot generate token", zap.Int64("user_id", userID), zap.Error(err))
+		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+
+	logger.Info("Password changed successfully, other sessions revoked", zap.Int64("user_id", userID))
+	return token, nil
+}
+
+// rehashPassword пересчитывает хэш пароля, если он получен с меньшей стоимостью, чем настроенная.
+// Ошибка не мешает входу: хэш будет пересчитан при следующем входе.
+func (s *AuthService) rehashPassword(ctx context.Context, user *models.User, plain string) {
+	cost, err := bcrypt.Cost([]byte(user.Password))
+	if err != nil || cost >= s.bcryptCost {
+		return
+	}
+	hash, err := s.generatePasswordHash(plain)
+	if err != nil {
+		s.logger.Error("cannot rehash password", zap.Int64("user_id", user.ID), zap.Error(err))
+		return
+	}
+	if err := s.repo.UpdatePasswordHash(ctx, user.ID, hash); err != nil {
+		s.logger.Error("cannot save rehashed password", zap.Int64("user_id", user.ID), zap.Error(err))
+		return
+	}
+	s.logger.Info("Password rehashed", zap.Int64("user_id", user.ID), zap.Int("old_cost", cost), zap.Int("new_cost", s.bcryptCost))
+}
+
+// sendEmailVerification выпускает токен подтверждения адреса и отправляет ссылку с ним на этот адрес
+func (s *AuthService) sendEmailVerification(ctx context.Context, userID int64, email string) error {
+	token, err := s.issueMailToken(ctx, models.TokenPurposeEmailVerification, userID, email, s.emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your email",
+		Body: fmt.Sprintf("Open the link to confirm your email:\n\n%s/auth/email/verify?token=%s\n\nThe link is valid for %s.\n",
+			s.publicURL, url.QueryEscape(token), s.emailVerificationTTL),
+	})
+}
+
+// issueMailToken выпускает подписанный одноразовый токен и сохраняет его идентификатор
+func (s *AuthService) issueMailToken(ctx context.Context, purpose string, userID int64, email string, ttl time.Duration) (string, error) {
+	token, claims, err := s.tokens.Issue(purpose, userID, email, ttl)
+	if err != nil {
+		return "", err
+	}
+	err = s.repo.SaveAuthToken(ctx, &models.AuthToken{
+		TokenID:   claims.TokenID,
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	})
+	if err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// parseMailToken проверяет подпись, назначение и срок действия токена из письма
+func (s *AuthService) parseMailToken(purpose, token string) (authtoken.Claims, error) {
+	if token == "" {
+		return authtoken.Claims{}, errors.NewBadRequest("token is required", nil)
+	}
+	claims, err := s.tokens.Parse(purpose, token)
+	if stderrors.Is(err, authtoken.ErrExpired) {
+		return authtoken.Claims{}, errors.NewValidation("token has expired", err)
+	} else if err != nil {
+		return authtoken.Claims{}, errors.NewValidation("invalid token", err)
+	}
+	return claims, nil
+}
+
 // GetUser получает пользователя по имени и паролю
 func (s *AuthService) GetUser(ctx context.Context, up *models.SignIn) (*models.User, error) {
 	const op = "service.Auth.GetUser"
@@ -135,6 +669,8 @@ func (s *AuthService) generateToken(user models.User) (string, error) {
 		"expires_at": time.Now().Add(s.TokenTTL).Unix(),
 		"issued_at":  time.Now().Unix(),
 		"user_id":    user.ID,
+		// Смена пароля увеличивает версию и тем самым отзывает выданные токены
+		"token_version": user.TokenVersion,
 	})
 	tokenString, err := token.SignedString([]byte(s.SignKey))
 	if err != nil {
@@ -145,8 +681,9 @@ func (s *AuthService) generateToken(user models.User) (string, error) {
 	return tokenString, nil
 }
 
-// ParseToken разбирает JWT токен и возвращает ID пользователя
-func (s *AuthService) ParseToken(accessToken string) (int64, error) {
+// ParseToken разбирает JWT токен и возвращает ID пользователя.
+// Токен, выданный до отзыва сессий пользователя, недействителен.
+func (s *AuthService) ParseToken(ctx context.Context, accessToken string) (int64, error) {
 	const op = "service.Auth.ParseToken"
 	logger := s.logger.With(zap.String("op", op))
 	token, err := jwt.Parse(accessToken, func(token *jwt.Token) (interface{}, error) {
@@ -171,13 +708,29 @@ func (s *AuthService) ParseToken(accessToken string) (int64, error) {
 		return 0, errors.NewInvalidToken(errors.ErrorMessage[errors.InvalidToken], nil)
 	}
 
+	// Токены, выданные до появления версии сессий, считаются выданными для версии 0
+	tokenVersion, _ := claims["token_version"].(float64)
+	currentVersion, err := s.repo.GetTokenVersion(ctx, int64(userId))
+	if err != nil {
+		if errors.IsNotFound(err) {
+			logger.Info("token user not found", zap.Float64("user_id", userId))
+			return 0, errors.NewInvalidToken(errors.ErrorMessage[errors.InvalidToken], err)
+		}
+		logger.Error("cannot get token version", zap.Float64("user_id", userId), zap.Error(err))
+		return 0, err
+	}
+	if int(tokenVersion) != currentVersion {
+		logger.Info("token has been revoked", zap.Float64("user_id", userId))
+		return 0, errors.NewInvalidToken(errors.ErrorMessage[errors.InvalidToken], nil)
+	}
+
 	logger.Info("Token parsed successfully", zap.Float64("user_id", userId))
 	return int64(userId), nil
 }
 
 // generatePasswordHash генерирует хэш пароля
 func (s *AuthService) generatePasswordHash(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), s.bcryptCost)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,99 @@
+// Package authtoken выпускает и проверяет подписанные одноразовые токены,
+// которые отправляются пользователю по почте (подтверждение адреса, сброс пароля) или выдаются на второй шаг входа.
+//
+// Токен имеет вид base64url(claims).base64url(HMAC-SHA256(purpose.claims)).
+// Подпись защищает от подделки, а одноразовость обеспечивается хранилищем по Claims.TokenID.
+package authtoken
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalid токен поврежден, подписан другим ключом или выпущен для другой цели
+	ErrInvalid = errors.New("invalid token")
+	// ErrExpired срок действия токена истек
+	ErrExpired = errors.New("token expired")
+)
+
+// Claims содержимое токена
+type Claims struct {
+	TokenID   string `json:"jti"`
+	UserID    int64  `json:"uid"`
+	Purpose   string `json:"pur"`
+	Email     string `json:"email,omitempty"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// Signer подписывает и проверяет токены
+type Signer struct {
+	key []byte
+	now func() time.Time
+}
+
+// NewSigner создает Signer с ключом подписи
+func NewSigner(key string) *Signer {
+	return &Signer{key: []byte(key), now: time.Now}
+}
+
+// Issue выпускает токен для пользователя со сроком действия ttl
+func (s *Signer) Issue(purpose string, userID int64, email string, ttl time.Duration) (string, Claims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", Claims{}, err
+	}
+	claims := Claims{
+		TokenID:   hex.EncodeToString(id),
+		UserID:    userID,
+		Purpose:   purpose,
+		Email:     email,
+		ExpiresAt: s.now().Add(ttl).Unix(),
+	}
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", Claims{}, err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(s.sign(purpose, encoded)), claims, nil
+}
+
+// Parse проверяет подпись, цель и срок действия токена и возвращает его содержимое
+func (s *Signer) Parse(purpose, token string) (Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return Claims{}, ErrInvalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, s.sign(purpose, encoded)) {
+		return Claims{}, ErrInvalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Claims{}, ErrInvalid
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Purpose != purpose || claims.TokenID == "" {
+		return Claims{}, ErrInvalid
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return Claims{}, ErrExpired
+	}
+	return claims, nil
+}
+
+// sign вычисляет подпись; цель входит в подпись, чтобы токен одного назначения нельзя было применить для другого
+func (s *Signer) sign(purpose, encoded string) []byte {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(purpose))
+	mac.Write([]byte{'.'})
+	mac.Write([]byte(encoded))
+	return mac.Sum(nil)
+}
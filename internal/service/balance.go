@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+)
+
+// BalanceService служба операций с балансом пользователей
+type BalanceService struct {
+	repo           repository.BalanceRepository
+	logger         *zap.Logger
+	transferLimits models.TransferLimits
+}
+
+// NewBalanceService создает новый экземпляр BalanceService
+func NewBalanceService(repo repository.BalanceRepository, logger *zap.Logger, transferLimits models.TransferLimits) *BalanceService {
+	return &BalanceService{
+		repo:           repo,
+		logger:         logger,
+		transferLimits: transferLimits,
+	}
+}
+
+// Transfer переводит баллы от одного пользователя другому.
+// Переводить баллы может только сам владелец баланса.
+func (s *BalanceService) Transfer(ctx context.Context, actorID int64, transfer *models.Transfer) (models.TransferResult, error) {
+	const op = "service.Balance.Transfer"
+	logger := s.logger.With(zap.String("op", op))
+
+	if actorID != transfer.FromUserID {
+		logger.Warn("transfer from another user's balance", zap.Int64("actor_id", actorID), zap.Int64("from_user_id", transfer.FromUserID))
+		return models.TransferResult{}, errors.NewForbidden("cannot transfer points from another user's balance", nil)
+	}
+	if err := validateTransfer(transfer); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return models.TransferResult{}, err
+	}
+
+	logger.Info("Transferring points", zap.Int64("from_user_id", transfer.FromUserID),
+		zap.Int64("to_user_id", transfer.ToUserID), zap.Int("amount", transfer.Amount))
+
+	result, err := s.repo.Transfer(ctx, transfer, s.transferLimits)
+	if err != nil {
+		logger.Error("Failed to transfer points", zap.Error(err))
+		return models.TransferResult{}, err
+	}
+
+	logger.Info("Points transferred successfully", zap.Int64("from_user_id", transfer.FromUserID), zap.Int64("to_user_id", transfer.ToUserID))
+	return result, nil
+}
+
+// validateTransfer выполняет проверку валидности запроса на перевод.
+func validateTransfer(transfer *models.Transfer) error {
+	if transfer.ToUserID == 0 {
+		return errors.NewValidation("recipient is required", nil)
+	}
+	if transfer.ToUserID == transfer.FromUserID {
+		return errors.NewValidation("cannot transfer points to yourself", nil)
+	}
+	if transfer.Amount < 1 {
+		return errors.NewValidation("minimum value for the Amount field is 1", nil)
+	}
+	return nil
+}
+
+// GetUserTransactions возвращает историю операций с балансом пользователя
+func (s *BalanceService) GetUserTransactions(ctx context.Context, userID int64) ([]models.Transaction, error) {
+	const op = "service.Balance.GetUserTransactions"
+	logger := s.logger.With(zap.String("op", op))
+
+	transactions, err := s.repo.GetUserTransactions(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to fetch user transactions", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return transactions, nil
+}
+
+// AdjustBalance начисляет или списывает баллы пользователя по решению администратора
+func (s *BalanceService) AdjustBalance(ctx context.Context, adjustment *models.Adjustment) (models.Transaction, error) {
+	const op = "service.Balance.AdjustBalance"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateAdjustment(adjustment); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return models.Transaction{}, err
+	}
+
+	logger.Info("Adjusting balance", zap.Int64("user_id", adjustment.UserID), zap.Int("amount", adjustment.Amount),
+		zap.String("reason", adjustment.Reason), zap.Int64("admin_id", adjustment.AdminID))
+
+	entry, err := s.repo.AdjustBalance(ctx, adjustment)
+	if err != nil {
+		logger.Error("Failed to adjust balance", zap.Error(err))
+		return models.Transaction{}, err
+	}
+
+	logger.Info("Balance adjusted successfully", zap.Int64("transaction_id", entry.TransactionID))
+	return entry, nil
+}
+
+// validateAdjustment выполняет проверку валидности ручной корректировки баланса.
+func validateAdjustment(adjustment *models.Adjustment) error {
+	if adjustment.Amount == 0 {
+		return errors.NewValidation("amount cannot be zero", nil)
+	}
+	switch adjustment.Reason {
+	case models.AdjustmentGoodwill, models.AdjustmentCorrection, models.AdjustmentCompensation,
+		models.AdjustmentFraud, models.AdjustmentPromotion, models.AdjustmentOther:
+	case "":
+		return errors.NewValidation("reason is required", nil)
+	default:
+		return errors.NewValidation("unknown reason code", nil)
+	}
+	if len(adjustment.Reference) > 255 {
+		return errors.NewValidation("reference cannot be longer than 255 characters", nil)
+	}
+	return nil
+}
+
+// ExpirePoints списывает баллы с истекшим сроком действия и возвращает общее количество сгоревших баллов.
+// Каждый пользователь обрабатывается отдельно: ошибка у одного пользователя не мешает списанию у остальных.
+func (s *BalanceService) ExpirePoints(ctx context.Context) (int, error) {
+	const op = "service.Balance.ExpirePoints"
+	logger := s.logger.With(zap.String("op", op))
+
+	userIDs, err := s.repo.GetUsersWithExpiredPoints(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch users with expired points", zap.Error(err))
+		return 0, err
+	}
+
+	expired, failed := 0, 0
+	for _, userID := range userIDs {
+		if ctx.Err() != nil {
+			return expired, ctx.Err()
+		}
+		amount, err := s.repo.ExpireUserPoints(ctx, userID)
+		if err != nil {
+			logger.Error("Failed to expire user points", zap.Int64("user_id", userID), zap.Error(err))
+			failed++
+			continue
+		}
+		expired += amount
+	}
+
+	if expired > 0 {
+		logger.Info("Points expired", zap.Int("expired", expired), zap.Int("users", len(userIDs)-failed))
+	}
+	if failed > 0 {
+		return expired, errors.NewInternal(fmt.Sprintf("failed to expire points for %d of %d users", failed, len(userIDs)), nil)
+	}
+	return expired, nil
+}
+
+// GetCurrencies возвращает список валют
+func (s *BalanceService) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+	const op = "service.Balance.GetCurrencies"
+	logger := s.logger.With(zap.String("op", op))
+
+	currencies, err := s.repo.GetCurrencies(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch currencies", zap.Error(err))
+		return nil, err
+	}
+	return currencies, nil
+}
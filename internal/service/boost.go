@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+)
+
+// maxBoostMultiplierPercent наибольший допустимый множитель наград
+const maxBoostMultiplierPercent = 1000
+
+// BoostService служба временных множителей наград
+type BoostService struct {
+	repo   repository.BoostRepository
+	logger *zap.Logger
+}
+
+// NewBoostService создает новый экземпляр BoostService
+func NewBoostService(repo repository.BoostRepository, logger *zap.Logger) *BoostService {
+	return &BoostService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateBoost создает множитель наград
+func (s *BoostService) CreateBoost(ctx context.Context, req *models.BoostCreate) (int64, error) {
+	const op = "service.Boost.CreateBoost"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateBoostRequest(req); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return 0, err
+	}
+
+	boostID, err := s.repo.CreateBoost(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create boost", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Boost created successfully", zap.Int64("boost_id", boostID), zap.Int("multiplier_percent", req.MultiplierPercent),
+		zap.Time("starts_at", req.StartsAt), zap.Time("ends_at", req.EndsAt), zap.Int64("admin_id", req.AdminID))
+	return boostID, nil
+}
+
+// validateBoostRequest выполняет проверку валидности запроса на создание множителя наград.
+func validateBoostRequest(req *models.BoostCreate) error {
+	if req.Title == "" {
+		return errors.NewValidation("boost title cannot be empty", nil)
+	}
+	if req.MultiplierPercent <= 100 || req.MultiplierPercent > maxBoostMultiplierPercent {
+		return errors.NewValidation("multiplier_percent must be greater than 100 and not greater than 1000", nil)
+	}
+	if req.TaskType != nil && *req.TaskType == "" {
+		req.TaskType = nil
+	}
+	if req.MinLevel != nil && *req.MinLevel < 1 {
+		return errors.NewValidation("minimum value for the MinLevel field is 1", nil)
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return errors.NewValidation("starts_at and ends_at are required", nil)
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return errors.NewValidation("ends_at must be after starts_at", nil)
+	}
+	return nil
+}
+
+// GetBoosts возвращает множители наград. При upcomingOnly закончившиеся множители не возвращаются.
+func (s *BoostService) GetBoosts(ctx context.Context, upcomingOnly bool) ([]models.Boost, error) {
+	const op = "service.Boost.GetBoosts"
+	logger := s.logger.With(zap.String("op", op))
+
+	boosts, err := s.repo.GetBoosts(ctx, upcomingOnly)
+	if err != nil {
+		logger.Error("Failed to fetch boosts", zap.Error(err))
+		return nil, err
+	}
+	return boosts, nil
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+)
+
+// CampaignService служба маркетинговых кампаний
+type CampaignService struct {
+	repo   repository.CampaignRepository
+	logger *zap.Logger
+}
+
+// NewCampaignService создает новый экземпляр CampaignService
+func NewCampaignService(repo repository.CampaignRepository, logger *zap.Logger) *CampaignService {
+	return &CampaignService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateCampaign создает кампанию в статусе draft
+func (s *CampaignService) CreateCampaign(ctx context.Context, req *models.CampaignCreate) (int64, error) {
+	const op = "service.Campaign.CreateCampaign"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateCampaignRequest(req); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return 0, err
+	}
+
+	campaignID, err := s.repo.CreateCampaign(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create campaign", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Campaign created successfully", zap.Int64("campaign_id", campaignID), zap.Int("budget", req.Budget),
+		zap.Time("starts_at", req.StartsAt), zap.Time("ends_at", req.EndsAt), zap.Int64("admin_id", req.AdminID))
+	return campaignID, nil
+}
+
+// validateCampaignRequest выполняет проверку валидности запроса на создание кампании.
+func validateCampaignRequest(req *models.CampaignCreate) error {
+	if req.Title == "" {
+		return errors.NewValidation("campaign title cannot be empty", nil)
+	}
+	if len(req.Description) > 255 {
+		return errors.NewValidation("description cannot be longer than 255 characters", nil)
+	}
+	if req.Budget < 1 {
+		return errors.NewValidation("minimum value for the Budget field is 1", nil)
+	}
+	if req.StartsAt.IsZero() || req.EndsAt.IsZero() {
+		return errors.NewValidation("starts_at and ends_at are required", nil)
+	}
+	if !req.EndsAt.After(req.StartsAt) {
+		return errors.NewValidation("ends_at must be after starts_at", nil)
+	}
+	return nil
+}
+
+// GetCampaigns возвращает все кампании
+func (s *CampaignService) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	const op = "service.Campaign.GetCampaigns"
+	logger := s.logger.With(zap.String("op", op))
+
+	campaigns, err := s.repo.GetCampaigns(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch campaigns", zap.Error(err))
+		return nil, err
+	}
+	return campaigns, nil
+}
+
+// UpdateCampaignStatus переводит кампанию в новый статус
+func (s *CampaignService) UpdateCampaignStatus(ctx context.Context, campaignID int64, status string) (models.Campaign, error) {
+	const op = "service.Campaign.UpdateCampaignStatus"
+	logger := s.logger.With(zap.String("op", op))
+
+	if !isCampaignStatus(status) {
+		logger.Error("Unknown campaign status", zap.String("status", status))
+		return models.Campaign{}, errors.NewBadRequest("unknown campaign status", nil)
+	}
+
+	campaign, err := s.repo.UpdateCampaignStatus(ctx, campaignID, status)
+	if err != nil {
+		logger.Error("Failed to update campaign status", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.Campaign{}, err
+	}
+
+	logger.Info("Campaign status updated", zap.Int64("campaign_id", campaignID), zap.String("status", status))
+	return campaign, nil
+}
+
+// isCampaignStatus проверяет, что статус кампании известен
+func isCampaignStatus(status string) bool {
+	switch status {
+	case models.CampaignDraft, models.CampaignLive, models.CampaignPaused, models.CampaignEnded:
+		return true
+	}
+	return false
+}
+
+// GetCampaignStats возвращает статистику кампании
+func (s *CampaignService) GetCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error) {
+	const op = "service.Campaign.GetCampaignStats"
+	logger := s.logger.With(zap.String("op", op))
+
+	stats, err := s.repo.GetCampaignStats(ctx, campaignID)
+	if err != nil {
+		logger.Error("Failed to fetch campaign stats", zap.Int64("campaign_id", campaignID), zap.Error(err))
+		return models.CampaignStats{}, err
+	}
+	return stats, nil
+}
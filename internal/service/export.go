@@ -0,0 +1,293 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+const (
+	// exportStaleAfter время, после которого незавершенная выгрузка считается брошенной и готовится заново
+	exportStaleAfter = 30 * time.Minute
+	// exportBatchSize наибольшее число выгрузок, которое готовится за один запуск фоновой задачи
+	exportBatchSize = 10
+)
+
+// ExportService служба выгрузки персональных данных
+type ExportService struct {
+	repo      repository.ExportRepository
+	logger    *zap.Logger
+	mailer    mail.Sender
+	publicURL string
+	rules     models.ExportRules
+}
+
+// NewExportService создает новый экземпляр ExportService
+func NewExportService(repo repository.ExportRepository, logger *zap.Logger, mailer mail.Sender, publicURL string,
+	rules models.ExportRules) *ExportService {
+	return &ExportService{
+		repo:      repo,
+		logger:    logger,
+		mailer:    mailer,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		rules:     rules,
+	}
+}
+
+// ExportUserData выгружает персональные данные пользователя в формате JSON или ZIP.
+// Данные небольшого аккаунта возвращаются сразу; для большого выгрузка ставится в очередь,
+// готовый архив скачивается по ссылке из ответа.
+func (s *ExportService) ExportUserData(ctx context.Context, userID int64, format string) (models.ExportResult, error) {
+	const op = "service.Export.ExportUserData"
+	logger := s.logger.With(zap.String("op", op))
+
+	format, err := normalizeExportFormat(format)
+	if err != nil {
+		return models.ExportResult{}, err
+	}
+
+	records, err := s.repo.CountUserRecords(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to count user records", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportResult{}, err
+	}
+
+	if records <= s.rules.SyncMaxRecords {
+		data, err := s.repo.GetUserData(ctx, userID)
+		if err != nil {
+			logger.Error("Failed to fetch user data", zap.Int64("user_id", userID), zap.Error(err))
+			return models.ExportResult{}, err
+		}
+		file, err := buildExportFile(&data, format)
+		if err != nil {
+			logger.Error("Failed to build export", zap.Int64("user_id", userID), zap.Error(err))
+			return models.ExportResult{}, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+		}
+		logger.Info("User data exported", zap.Int64("user_id", userID), zap.String("format", format))
+		return models.ExportResult{File: &file}, nil
+	}
+
+	export, err := s.repo.CreateExport(ctx, userID, format)
+	if err != nil {
+		logger.Error("Failed to queue export", zap.Int64("user_id", userID), zap.Error(err))
+		return models.ExportResult{}, err
+	}
+	s.setExportURLs(&export)
+
+	logger.Info("User data export queued", zap.Int64("user_id", userID), zap.Int64("export_id", export.ExportID),
+		zap.Int("records", records))
+	return models.ExportResult{Export: &export}, nil
+}
+
+// GetExport возвращает состояние выгрузки пользователя
+func (s *ExportService) GetExport(ctx context.Context, userID, exportID int64) (models.DataExport, error) {
+	const op = "service.Export.GetExport"
+	logger := s.logger.With(zap.String("op", op))
+
+	export, err := s.repo.GetExport(ctx, userID, exportID)
+	if err != nil {
+		logger.Info("Failed to fetch export", zap.Int64("user_id", userID), zap.Int64("export_id", exportID), zap.Error(err))
+		return models.DataExport{}, err
+	}
+	s.setExportURLs(&export)
+	return export, nil
+}
+
+// DownloadExport возвращает файл готовой выгрузки пользователя
+func (s *ExportService) DownloadExport(ctx context.Context, userID, exportID int64) (models.ExportFile, error) {
+	const op = "service.Export.DownloadExport"
+	logger := s.logger.With(zap.String("op", op))
+
+	export, err := s.repo.GetExport(ctx, userID, exportID)
+	if err != nil {
+		logger.Info("Failed to fetch export", zap.Int64("user_id", userID), zap.Int64("export_id", exportID), zap.Error(err))
+		return models.ExportFile{}, err
+	}
+	switch export.Status {
+	case models.ExportStatusReady:
+	case models.ExportStatusFailed:
+		return models.ExportFile{}, errors.NewValidation("export failed, request a new one", nil)
+	default:
+		return models.ExportFile{}, errors.NewValidation("export is not ready yet", nil)
+	}
+
+	content, err := s.repo.GetExportContent(ctx, exportID)
+	if err != nil {
+		logger.Error("Failed to fetch export content", zap.Int64("export_id", exportID), zap.Error(err))
+		return models.ExportFile{}, err
+	}
+	exportedAt := export.CreatedAt
+	if export.CompletedAt != nil {
+		exportedAt = *export.CompletedAt
+	}
+	return models.ExportFile{
+		Name:        exportFileName(export.UserID, export.Format, exportedAt),
+		ContentType: exportContentType(export.Format),
+		Content:     content,
+	}, nil
+}
+
+// ProcessExports удаляет выгрузки с истекшим сроком хранения и готовит выгрузки из очереди.
+// Возвращает число подготовленных выгрузок, включая неудавшиеся.
+func (s *ExportService) ProcessExports(ctx context.Context) (int, error) {
+	const op = "service.Export.ProcessExports"
+	logger := s.logger.With(zap.String("op", op))
+
+	deleted, err := s.repo.DeleteExpiredExports(ctx)
+	if err != nil {
+		logger.Error("Failed to delete expired exports", zap.Error(err))
+		return 0, err
+	}
+	if deleted > 0 {
+		logger.Info("Expired exports deleted", zap.Int64("deleted", deleted))
+	}
+
+	processed := 0
+	for processed < exportBatchSize && ctx.Err() == nil {
+		export, err := s.repo.ClaimExport(ctx, exportStaleAfter)
+		if errors.IsNotFound(err) {
+			break
+		} else if err != nil {
+			logger.Error("Failed to claim export", zap.Error(err))
+			return processed, err
+		}
+		// Ошибка одной выгрузки не останавливает остальные
+		if err := s.processExport(ctx, export); err != nil {
+			logger.Error("Failed to process export", zap.Int64("export_id", export.ExportID), zap.Error(err))
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+// processExport готовит выгрузку и сообщает пользователю, что ее можно скачать
+func (s *ExportService) processExport(ctx context.Context, export models.DataExport) error {
+	data, err := s.repo.GetUserData(ctx, export.UserID)
+	var file models.ExportFile
+	if err == nil {
+		file, err = buildExportFile(&data, export.Format)
+	}
+	if err != nil {
+		if failErr := s.repo.FailExport(ctx, export.ExportID, err.Error(), s.rules.TTL); failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	if err := s.repo.CompleteExport(ctx, export.ExportID, file.Content, s.rules.TTL); err != nil {
+		return err
+	}
+	export.Status = models.ExportStatusReady
+	s.logger.Info("User data export ready", zap.Int64("user_id", export.UserID), zap.Int64("export_id", export.ExportID),
+		zap.Int("size", len(file.Content)))
+
+	// Письмо только уведомляет: ссылка работает лишь для вошедшего пользователя, и выгрузка уже готова
+	if data.Profile.Email != "" && data.Profile.EmailVerified {
+		s.setExportURLs(&export)
+		err := s.mailer.Send(ctx, mail.Message{
+			To:      data.Profile.Email,
+			Subject: "Your data export is ready",
+			Body: fmt.Sprintf("The export of your data is ready. Sign in and download it here:\n\n%s\n\n"+
+				"The file is kept for %s.\n", export.DownloadURL, s.rules.TTL),
+		})
+		if err != nil {
+			s.logger.Error("Failed to send export notification", zap.Int64("export_id", export.ExportID), zap.Error(err))
+		}
+	}
+	return nil
+}
+
+// setExportURLs заполняет ссылки на состояние выгрузки и на ее файл
+func (s *ExportService) setExportURLs(export *models.DataExport) {
+	export.StatusURL = fmt.Sprintf("%s/api/users/me/exports/%d", s.publicURL, export.ExportID)
+	if export.Status == models.ExportStatusReady {
+		export.DownloadURL = export.StatusURL + "/download"
+	}
+}
+
+// normalizeExportFormat проверяет формат выгрузки; по умолчанию JSON
+func normalizeExportFormat(format string) (string, error) {
+	switch format = strings.ToLower(strings.TrimSpace(format)); format {
+	case "":
+		return models.ExportFormatJSON, nil
+	case models.ExportFormatJSON, models.ExportFormatZIP:
+		return format, nil
+	default:
+		return "", errors.NewValidation(fmt.Sprintf("unknown export format %q", format), nil)
+	}
+}
+
+// buildExportFile собирает файл выгрузки. JSON содержит все данные одним документом,
+// в ZIP каждый раздел лежит в отдельном файле.
+func buildExportFile(data *models.UserData, format string) (models.ExportFile, error) {
+	file := models.ExportFile{
+		Name:        exportFileName(data.Profile.UserID, format, data.ExportedAt),
+		ContentType: exportContentType(format),
+	}
+	if format == models.ExportFormatJSON {
+		content, err := json.MarshalIndent(data, "", "  ")
+		if err != nil {
+			return models.ExportFile{}, err
+		}
+		file.Content = content
+		return file, nil
+	}
+
+	sections := []struct {
+		name    string
+		payload interface{}
+	}{
+		{"profile.json", struct {
+			ExportedAt time.Time      `json:"exported_at"`
+			Profile    models.Profile `json:"profile"`
+			Balances   map[string]int `json:"balances"`
+		}{data.ExportedAt, data.Profile, data.Balances}},
+		{"completions.json", data.Completions},
+		{"transactions.json", data.Transactions},
+		{"referrals.json", data.Referrals},
+		{"sessions.json", data.Sessions},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+	for _, section := range sections {
+		content, err := json.MarshalIndent(section.payload, "", "  ")
+		if err != nil {
+			return models.ExportFile{}, err
+		}
+		w, err := archive.CreateHeader(&zip.FileHeader{Name: section.name, Method: zip.Deflate, Modified: data.ExportedAt})
+		if err != nil {
+			return models.ExportFile{}, err
+		}
+		if _, err := w.Write(content); err != nil {
+			return models.ExportFile{}, err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return models.ExportFile{}, err
+	}
+	file.Content = buf.Bytes()
+	return file, nil
+}
+
+// exportFileName имя файла выгрузки
+func exportFileName(userID int64, format string, exportedAt time.Time) string {
+	return fmt.Sprintf("user-%d-data-%s.%s", userID, exportedAt.UTC().Format("20060102"), format)
+}
+
+// exportContentType тип содержимого файла выгрузки
+func exportContentType(format string) string {
+	if format == models.ExportFormatZIP {
+		return "application/zip"
+	}
+	return "application/json"
+}
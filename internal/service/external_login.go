@@ -0,0 +1,138 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"math/big"
+	"strings"
+	"unicode"
+)
+
+const (
+	// maxExternalUsernameLength наибольшая длина имени, подобранного по данным провайдера, без суффикса
+	maxExternalUsernameLength = 50
+	// externalUsernameAttempts число попыток подобрать свободное имя со случайным суффиксом
+	externalUsernameAttempts = 5
+)
+
+// externalLoginResult завершает вход через внешнего провайдера.
+// Если у пользователя включена двухфакторная аутентификация, вместо JWT выдается токен второго шага,
+// как при входе по паролю: вход через провайдер не должен обходить второй фактор.
+func (s *AuthService) externalLoginResult(ctx context.Context, user *models.User) (*models.LoginResult, error) {
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if tf.Enabled {
+		challenge, err := s.issueMailToken(ctx, models.TokenPurposeLoginChallenge, user.ID, "", s.twoFactorTTL)
+		if err != nil {
+			return nil, err
+		}
+		return &models.LoginResult{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+	}
+
+	token, err := s.generateToken(*user)
+	if err != nil {
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	return &models.LoginResult{Token: token}, nil
+}
+
+// createExternalUser создает пользователя для учетной записи внешнего провайдера.
+// Имя подбирается по вариантам usernames; email сохраняется подтвержденным, поэтому передавать можно
+// только адрес, проверенный провайдером.
+func (s *AuthService) createExternalUser(ctx context.Context, provider, subject, email string, usernames ...string) (*models.User, error) {
+	username, err := s.externalUsername(ctx, usernames...)
+	if err != nil {
+		return nil, err
+	}
+	password, err := s.randomPasswordHash()
+	if err != nil {
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	userID, err := s.repo.CreateExternalUser(ctx, &models.ExternalUserCreate{
+		Username:      username,
+		Password:      password,
+		Email:         email,
+		EmailVerified: email != "",
+		Provider:      provider,
+		Subject:       subject,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetUserByID(ctx, userID)
+}
+
+// externalUsername подбирает свободное имя пользователя по данным внешнего провайдера.
+// Используется первый непустой вариант; если имя занято, к нему добавляется случайный суффикс.
+func (s *AuthService) externalUsername(ctx context.Context, candidates ...string) (string, error) {
+	base := "user"
+	for _, candidate := range candidates {
+		if candidate = sanitizeUsername(candidate); candidate != "" && !isReservedUsername(candidate) {
+			base = candidate
+			break
+		}
+	}
+
+	username := base
+	for attempt := 0; attempt <= externalUsernameAttempts; attempt++ {
+		if attempt > 0 {
+			suffix, err := rand.Int(rand.Reader, big.NewInt(10000))
+			if err != nil {
+				return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+			}
+			username = fmt.Sprintf("%s_%04d", base, suffix.Int64())
+		}
+		_, err := s.repo.GetUserByUsername(ctx, username)
+		if errors.IsNotFound(err) {
+			return username, nil
+		} else if err != nil {
+			return "", err
+		}
+	}
+	return "", errors.NewAlreadyExists("cannot pick a free username", nil)
+}
+
+// sanitizeUsername оставляет в имени буквы, цифры, точку, дефис и подчеркивание; пробелы заменяются подчеркиванием
+func sanitizeUsername(name string) string {
+	var b strings.Builder
+	count := 0
+	for _, r := range strings.TrimSpace(name) {
+		if count == maxExternalUsernameLength {
+			break
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '.' || r == '-' || r == '_':
+			b.WriteRune(r)
+		case unicode.IsSpace(r):
+			b.WriteRune('_')
+		default:
+			continue
+		}
+		count++
+	}
+	return strings.Trim(b.String(), "._-")
+}
+
+// localPart возвращает часть адреса до @
+func localPart(email string) string {
+	if i := strings.LastIndex(email, "@"); i > 0 {
+		return email[:i]
+	}
+	return ""
+}
+
+// randomPasswordHash возвращает хэш случайного пароля для пользователя, созданного внешним провайдером.
+// Пароль никому не известен: войти по паролю можно только после его сброса.
+func (s *AuthService) randomPasswordHash() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return s.generatePasswordHash(hex.EncodeToString(buf))
+}
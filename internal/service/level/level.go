@@ -0,0 +1,26 @@
+package level
+
+import "github.com/ZnNr/user-task-reward-controller/internal/models"
+
+// For возвращает уровень пользователя, заработавшего lifetime баллов за все время
+func For(lifetime int, curve models.LevelCurve) int {
+	level := 1
+	for _, threshold := range curve.Thresholds {
+		if lifetime < threshold {
+			break
+		}
+		level++
+	}
+	return level
+}
+
+// NextAt возвращает количество баллов за все время, необходимое для следующего уровня,
+// или nil, если достигнут последний уровень кривой
+func NextAt(lifetime int, curve models.LevelCurve) *int {
+	for _, threshold := range curve.Thresholds {
+		if lifetime < threshold {
+			return &threshold
+		}
+	}
+	return nil
+}
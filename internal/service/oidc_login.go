@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"go.uber.org/zap"
+	"time"
+)
+
+// oidcStateTTL время на вход у провайдера OpenID Connect
+const oidcStateTTL = 10 * time.Minute
+
+// OIDCLogin начинает вход через провайдера OpenID Connect и возвращает ссылку на страницу входа провайдера.
+// state, nonce и code_verifier PKCE сохраняются до возврата пользователя от провайдера.
+func (s *AuthService) OIDCLogin(ctx context.Context) (*models.OIDCLogin, error) {
+	const op = "service.Auth.OIDCLogin"
+	logger := s.logger.With(zap.String("op", op))
+
+	if s.oidc == nil {
+		return nil, errors.NewNotFound("oidc login is not configured", nil)
+	}
+
+	var values [3]string
+	for i := range values {
+		value, err := oidc.NewVerifier()
+		if err != nil {
+			logger.Error("cannot generate oidc state", zap.Error(err))
+			return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+		}
+		values[i] = value
+	}
+	state := &models.OIDCState{
+		State:        values[0],
+		Nonce:        values[1],
+		CodeVerifier: values[2],
+		ExpiresAt:    time.Now().Add(oidcStateTTL),
+	}
+
+	authURL, err := s.oidc.AuthCodeURL(ctx, state.State, state.Nonce, oidc.Challenge(state.CodeVerifier))
+	if err != nil {
+		logger.Error("cannot build provider login url", zap.Error(err))
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	if err := s.repo.SaveOIDCState(ctx, state); err != nil {
+		logger.Error("cannot save oidc state", zap.Error(err))
+		return nil, err
+	}
+	return &models.OIDCLogin{URL: authURL, State: state.State}, nil
+}
+
+// OIDCCallback завершает вход через провайдера OpenID Connect и возвращает JWT.
+// Учетная запись провайдера ищется среди привязанных, затем по подтвержденному адресу;
+// если пользователя нет, он создается. Если у пользователя включена двухфакторная аутентификация,
+// вместо JWT возвращается токен второго шага, вход завершается через LoginTwoFactor.
+func (s *AuthService) OIDCCallback(ctx context.Context, req *models.OIDCCallback) (*models.LoginResult, error) {
+	const op = "service.Auth.OIDCCallback"
+	logger := s.logger.With(zap.String("op", op))
+
+	if s.oidc == nil {
+		return nil, errors.NewNotFound("oidc login is not configured", nil)
+	}
+	if req.Error != "" {
+		logger.Info("provider returned an error", zap.String("error", req.Error), zap.String("description", req.ErrorDescription))
+		return nil, errors.NewUnauthorized("login was rejected by the identity provider", nil)
+	}
+	if req.Code == "" || req.State == "" {
+		return nil, errors.NewBadRequest("code and state are required", nil)
+	}
+	// state из cookie защищает от подстановки чужого ответа провайдера в браузер пользователя
+	if req.CookieState != req.State {
+		logger.Info("oidc state does not match cookie")
+		return nil, errors.NewValidation("invalid login state", nil)
+	}
+
+	state, err := s.repo.UseOIDCState(ctx, req.State)
+	if err != nil {
+		if errors.IsNotFound(err) || errors.IsValidation(err) {
+			return nil, errors.NewValidation("invalid login state", err)
+		}
+		logger.Error("cannot use oidc state", zap.Error(err))
+		return nil, err
+	}
+
+	rawToken, err := s.oidc.Exchange(ctx, req.Code, state.CodeVerifier)
+	if err != nil {
+		logger.Warn("cannot exchange authorization code", zap.Error(err))
+		return nil, errors.NewUnauthorized("cannot complete login with the identity provider", err)
+	}
+	claims, err := s.oidc.VerifyIDToken(ctx, rawToken, state.Nonce)
+	if err != nil {
+		logger.Warn("invalid id token", zap.Error(err))
+		return nil, errors.NewUnauthorized("cannot complete login with the identity provider", err)
+	}
+
+	user, err := s.resolveOIDCUser(ctx, claims)
+	if err != nil {
+		logger.Error("cannot resolve user", zap.String("subject", claims.Subject), zap.Error(err))
+		return nil, err
+	}
+
+	result, err := s.externalLoginResult(ctx, user)
+	if err != nil {
+		logger.Error("cannot complete login", zap.Int64("user_id", user.ID), zap.Error(err))
+		return nil, err
+	}
+	if result.TwoFactorRequired {
+		logger.Info("OIDC login accepted, two-factor code required", zap.Int64("user_id", user.ID))
+		return result, nil
+	}
+
+	logger.Info("User logged in successfully with oidc", zap.Int64("user_id", user.ID))
+	return result, nil
+}
+
+// resolveOIDCUser находит или создает пользователя для учетной записи провайдера.
+// К существующему пользователю учетная запись привязывается, только если адрес подтвержден и провайдером, и сервисом:
+// иначе чужой аккаунт, зарегистрированный на этот адрес, получил бы доступ владельца адреса.
+func (s *AuthService) resolveOIDCUser(ctx context.Context, claims *oidc.Claims) (*models.User, error) {
+	user, err := s.repo.GetUserByIdentity(ctx, models.IdentityProviderOIDC, claims.Subject)
+	if err == nil {
+		return user, nil
+	} else if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	email := ""
+	if claims.EmailVerified {
+		// Адрес в неподходящем формате просто не сохраняется
+		email, _ = normalizeEmail(claims.Email)
+	}
+	if email != "" {
+		existing, err := s.repo.GetUserByEmail(ctx, email)
+		switch {
+		case err == nil && existing.EmailVerified:
+			if err := s.repo.LinkIdentity(ctx, models.IdentityProviderOIDC, claims.Subject, existing.ID, email); err != nil {
+				return nil, err
+			}
+			s.logger.Info("OIDC identity linked by email", zap.Int64("user_id", existing.ID))
+			return existing, nil
+		case err == nil:
+			// Адрес занят неподтвержденным аккаунтом, новый пользователь создается без адреса
+			email = ""
+		case !errors.IsNotFound(err):
+			return nil, err
+		}
+	}
+
+	user, err = s.createExternalUser(ctx, models.IdentityProviderOIDC, claims.Subject, email,
+		claims.PreferredUsername, localPart(email), claims.Name)
+	if err != nil {
+		return nil, err
+	}
+	s.logger.Info("User registered with oidc", zap.Int64("user_id", user.ID))
+	return user, nil
+}
@@ -0,0 +1,74 @@
+// Package password проверяет новые пароли на соответствие политике:
+// длина и отсутствие в списке утекших паролей.
+package password
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// MaxBytes наибольшая длина пароля в байтах: bcrypt учитывает только первые 72 байта
+const MaxBytes = 72
+
+// Policy политика паролей
+type Policy struct {
+	minLength int
+	// breached утекшие пароли в нижнем регистре
+	breached map[string]struct{}
+}
+
+// NewPolicy создает политику с минимальной длиной пароля в символах и списком утекших паролей
+func NewPolicy(minLength int, breached []string) *Policy {
+	p := &Policy{minLength: minLength, breached: make(map[string]struct{}, len(breached))}
+	for _, password := range breached {
+		if password = strings.TrimSpace(password); password != "" {
+			p.breached[strings.ToLower(password)] = struct{}{}
+		}
+	}
+	return p
+}
+
+// LoadPolicy создает политику, читая утекшие пароли из файла по одному в строке.
+// Пустой путь означает, что список утекших паролей не используется.
+func LoadPolicy(minLength int, breachedFile string) (*Policy, error) {
+	if breachedFile == "" {
+		return NewPolicy(minLength, nil), nil
+	}
+	file, err := os.Open(breachedFile)
+	if err != nil {
+		return nil, fmt.Errorf("cannot open breached passwords list: %w", err)
+	}
+	defer file.Close()
+
+	var breached []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		breached = append(breached, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot read breached passwords list: %w", err)
+	}
+	return NewPolicy(minLength, breached), nil
+}
+
+// Validate проверяет пароль пользователя username на соответствие политике
+func (p *Policy) Validate(password, username string) error {
+	if utf8.RuneCountInString(password) < p.minLength {
+		return errors.NewValidation(fmt.Sprintf("password must be at least %d characters long", p.minLength), nil)
+	}
+	if len(password) > MaxBytes {
+		return errors.NewValidation(fmt.Sprintf("password cannot be longer than %d bytes", MaxBytes), nil)
+	}
+	lower := strings.ToLower(password)
+	if username != "" && lower == strings.ToLower(username) {
+		return errors.NewValidation("password cannot be the same as the username", nil)
+	}
+	if _, ok := p.breached[lower]; ok {
+		return errors.NewValidation("password is too common, choose another one", nil)
+	}
+	return nil
+}
@@ -0,0 +1,194 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"go.uber.org/zap"
+	"net/url"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// maxDisplayNameLength наибольшая длина отображаемого имени
+	maxDisplayNameLength = 100
+	// maxAvatarURLLength наибольшая длина ссылки на аватар
+	maxAvatarURLLength = 2048
+)
+
+// GetProfile возвращает профиль пользователя
+func (s *AuthService) GetProfile(ctx context.Context, userID int64) (*models.Profile, error) {
+	const op = "service.Auth.GetProfile"
+	logger := s.logger.With(zap.String("op", op))
+
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get profile", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return &profile, nil
+}
+
+// UpdateProfile меняет отображаемое имя и аватар пользователя и возвращает обновленный профиль.
+// Новый адрес не заменяет текущий сразу: на него отправляется ссылка, и адрес меняется после перехода по ней.
+func (s *AuthService) UpdateProfile(ctx context.Context, userID int64, req *models.ProfileUpdate) (*models.Profile, error) {
+	const op = "service.Auth.UpdateProfile"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateProfileUpdate(req); err != nil {
+		logger.Info("Validation failed", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get profile", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	// Адрес проверяется до сохранения остальных полей, чтобы отклоненный запрос ничего не менял
+	newEmail := ""
+	if req.Email != nil && *req.Email != profile.Email {
+		existing, err := s.repo.GetUserByEmail(ctx, *req.Email)
+		if err == nil && existing.ID != userID {
+			logger.Info("email is already in use", zap.Int64("user_id", userID))
+			return nil, errors.NewAlreadyExists("email is already in use", nil)
+		} else if err != nil && !errors.IsNotFound(err) {
+			logger.Error("cannot check email", zap.Int64("user_id", userID), zap.Error(err))
+			return nil, err
+		}
+		newEmail = *req.Email
+	}
+
+	if req.DisplayName != nil || req.AvatarURL != nil {
+		if err := s.repo.UpdateProfile(ctx, userID, req); err != nil {
+			logger.Error("cannot update profile", zap.Int64("user_id", userID), zap.Error(err))
+			return nil, err
+		}
+	}
+
+	if newEmail != "" {
+		if err := s.sendEmailChange(ctx, userID, newEmail); err != nil {
+			logger.Error("cannot send email change confirmation", zap.Int64("user_id", userID), zap.Error(err))
+			return nil, errors.NewInternal("cannot send email change confirmation", err)
+		}
+		logger.Info("Email change requested", zap.Int64("user_id", userID))
+	}
+
+	logger.Info("Profile updated", zap.Int64("user_id", userID))
+	return s.GetProfile(ctx, userID)
+}
+
+// validateProfileUpdate проверяет изменение профиля и приводит поля к каноническому виду
+func validateProfileUpdate(req *models.ProfileUpdate) error {
+	if req.DisplayName == nil && req.AvatarURL == nil && req.Email == nil {
+		return errors.NewBadRequest("nothing to update", nil)
+	}
+	if req.DisplayName != nil {
+		name := strings.TrimSpace(*req.DisplayName)
+		if utf8.RuneCountInString(name) > maxDisplayNameLength {
+			return errors.NewValidation(fmt.Sprintf("display name cannot be longer than %d characters", maxDisplayNameLength), nil)
+		}
+		if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+			return errors.NewValidation("display name contains invalid characters", nil)
+		}
+		req.DisplayName = &name
+	}
+	if req.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*req.AvatarURL)
+		if avatarURL != "" {
+			if len(avatarURL) > maxAvatarURLLength {
+				return errors.NewValidation(fmt.Sprintf("avatar url cannot be longer than %d characters", maxAvatarURLLength), nil)
+			}
+			u, err := url.Parse(avatarURL)
+			if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+				return errors.NewValidation("avatar url must be an absolute http or https url", err)
+			}
+		}
+		req.AvatarURL = &avatarURL
+	}
+	if req.Email != nil {
+		email, err := normalizeEmail(*req.Email)
+		if err != nil {
+			return err
+		}
+		req.Email = &email
+	}
+	return nil
+}
+
+// sendEmailChange отправляет на новый адрес ссылку для его подтверждения.
+// Ссылки, отправленные ранее на другие адреса, перестают действовать.
+func (s *AuthService) sendEmailChange(ctx context.Context, userID int64, email string) error {
+	if err := s.repo.RevokeAuthTokens(ctx, userID, models.TokenPurposeEmailChange); err != nil {
+		return err
+	}
+	token, err := s.issueMailToken(ctx, models.TokenPurposeEmailChange, userID, email, s.emailVerificationTTL)
+	if err != nil {
+		return err
+	}
+	return s.mailer.Send(ctx, mail.Message{
+		To:      email,
+		Subject: "Confirm your new email",
+		Body: fmt.Sprintf("Open the link to use this address for your account:\n\n%s/auth/email/change?token=%s\n\n"+
+			"The link is valid for %s. If you did not request the change, ignore this email.\n",
+			s.publicURL, url.QueryEscape(token), s.emailVerificationTTL),
+	})
+}
+
+// ConfirmEmailChange заменяет адрес пользователя новым по токену из письма
+func (s *AuthService) ConfirmEmailChange(ctx context.Context, token string) error {
+	const op = "service.Auth.ConfirmEmailChange"
+	logger := s.logger.With(zap.String("op", op))
+
+	claims, err := s.parseMailToken(models.TokenPurposeEmailChange, token)
+	if err != nil {
+		logger.Info("invalid email change token", zap.Error(err))
+		return err
+	}
+
+	userID, err := s.repo.ChangeEmail(ctx, claims.TokenID)
+	if err != nil {
+		if errors.IsNotFound(err) {
+			return errors.NewValidation("invalid token", err)
+		}
+		logger.Error("cannot change email", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Email changed successfully", zap.Int64("user_id", userID))
+	return nil
+}
+
+// DeleteAccount удаляет аккаунт пользователя. Для подтверждения нужно указать имя пользователя.
+// Персональные данные стираются, а история начислений остается, чтобы не изменились награды и статистика пригласивших.
+func (s *AuthService) DeleteAccount(ctx context.Context, userID int64, req *models.AccountDeleteRequest) error {
+	const op = "service.Auth.DeleteAccount"
+	logger := s.logger.With(zap.String("op", op))
+
+	profile, err := s.repo.GetProfile(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get profile", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if req.Confirm != profile.Username {
+		return errors.NewValidation("confirm must match the username", nil)
+	}
+
+	if err := s.repo.DeleteAccount(ctx, userID); err != nil {
+		logger.Error("cannot delete account", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Account deleted", zap.Int64("user_id", userID))
+	return nil
+}
+
+// isReservedUsername сообщает, что имя зарезервировано для удаленных пользователей
+func isReservedUsername(username string) bool {
+	return strings.HasPrefix(strings.ToLower(username), models.DeletedUsernamePrefix)
+}
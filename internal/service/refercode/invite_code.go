@@ -0,0 +1,22 @@
+package refercode
+
+import (
+	"crypto/rand"
+	"math/big"
+)
+
+// SecureStringBytes генерирует код из тех же символов, что и RandStringBytes, но на crypto/rand.
+// Используется для кодов, знание которых дает доступ, например приглашений в команду.
+func SecureStringBytes() (string, error) {
+	n := 15
+	b := make([]byte, n)
+	size := big.NewInt(int64(len(letterBytes)))
+	for i := range b {
+		idx, err := rand.Int(rand.Reader, size)
+		if err != nil {
+			return "", err
+		}
+		b[i] = letterBytes[idx.Int64()]
+	}
+	return string(b), nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+	"net/url"
+)
+
+// referralTokenBytes число случайных байт в идентификаторе перехода
+const referralTokenBytes = 16
+
+// ReferralService служба учета переходов по реферальным ссылкам
+type ReferralService struct {
+	repo       repository.ReferralRepository
+	logger     *zap.Logger
+	landingURL string
+}
+
+// NewReferralService создает новый экземпляр ReferralService
+func NewReferralService(repo repository.ReferralRepository, logger *zap.Logger, landingURL string) *ReferralService {
+	return &ReferralService{
+		repo:       repo,
+		logger:     logger,
+		landingURL: landingURL,
+	}
+}
+
+// TrackClick сохраняет переход по реферальной ссылке и возвращает адрес для перенаправления.
+// Переходу выдается случайный идентификатор click.Token, по которому к нему привязывается регистрация.
+func (s *ReferralService) TrackClick(ctx context.Context, click *models.ReferralClick) (string, error) {
+	const op = "service.Referral.TrackClick"
+	logger := s.logger.With(zap.String("op", op))
+
+	if click.ReferCode == "" {
+		logger.Error("refer_code is required")
+		return "", errors.NewBadRequest("refer_code is required", nil)
+	}
+
+	token := make([]byte, referralTokenBytes)
+	if _, err := rand.Read(token); err != nil {
+		logger.Error("Cannot generate referral click token", zap.Error(err))
+		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	click.Token = hex.EncodeToString(token)
+
+	clickID, err := s.repo.RecordClick(ctx, click)
+	if err != nil {
+		logger.Error("Failed to record referral click", zap.String("refer_code", click.ReferCode), zap.Error(err))
+		return "", err
+	}
+
+	logger.Info("Referral click tracked", zap.String("refer_code", click.ReferCode), zap.Int64("click_id", clickID))
+	return s.landingLocation(click.ReferCode), nil
+}
+
+// landingLocation добавляет реферальный код к адресу целевой страницы
+func (s *ReferralService) landingLocation(referCode string) string {
+	location, err := url.Parse(s.landingURL)
+	if err != nil {
+		s.logger.Warn("Invalid referral landing URL, falling back to root", zap.String("landing_url", s.landingURL), zap.Error(err))
+		return "/"
+	}
+	query := location.Query()
+	query.Set("ref", referCode)
+	location.RawQuery = query.Encode()
+	return location.String()
+}
+
+// GetReferralStats возвращает воронку по реферальному коду
+func (s *ReferralService) GetReferralStats(ctx context.Context, referCode string) (models.ReferralStats, error) {
+	const op = "service.Referral.GetReferralStats"
+	logger := s.logger.With(zap.String("op", op))
+
+	logger.Debug("Fetching referral stats", zap.String("refer_code", referCode))
+	stats, err := s.repo.GetReferralStats(ctx, referCode)
+	if err != nil {
+		logger.Error("Failed to fetch referral stats", zap.Error(err))
+		return models.ReferralStats{}, err
+	}
+	logger.Info("Referral stats fetched successfully", zap.String("refer_code", referCode))
+	return stats, nil
+}
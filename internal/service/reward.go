@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+)
+
+// RewardService служба каталога наград и обмена баллов
+type RewardService struct {
+	repo   repository.RewardRepository
+	logger *zap.Logger
+}
+
+// NewRewardService создает новый экземпляр RewardService
+func NewRewardService(repo repository.RewardRepository, logger *zap.Logger) *RewardService {
+	return &RewardService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateReward добавляет награду в каталог
+func (s *RewardService) CreateReward(ctx context.Context, req *models.RewardCreate) (int64, error) {
+	const op = "service.Reward.CreateReward"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := validateRewardRequest(req); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return 0, err
+	}
+
+	rewardID, err := s.repo.CreateReward(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create reward", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Reward created successfully", zap.Int64("reward_id", rewardID), zap.String("title", req.Title))
+	return rewardID, nil
+}
+
+// validateRewardRequest выполняет проверку валидности запроса на создание награды.
+func validateRewardRequest(req *models.RewardCreate) error {
+	if req.Title == "" {
+		return errors.NewValidation("reward title cannot be empty", nil)
+	}
+	if req.Cost < 1 {
+		return errors.NewValidation("minimum value for the Cost field is 1", nil)
+	}
+	if req.Stock != nil && *req.Stock < 0 {
+		return errors.NewValidation("stock cannot be negative", nil)
+	}
+	if req.PerUserLimit != nil && *req.PerUserLimit < 1 {
+		return errors.NewValidation("minimum value for the PerUserLimit field is 1", nil)
+	}
+	return nil
+}
+
+// GetRewards возвращает активные награды каталога
+func (s *RewardService) GetRewards(ctx context.Context) ([]models.Reward, error) {
+	const op = "service.Reward.GetRewards"
+	logger := s.logger.With(zap.String("op", op))
+
+	rewards, err := s.repo.GetRewards(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch rewards", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Rewards fetched successfully", zap.Int("rewards_count", len(rewards)))
+	return rewards, nil
+}
+
+// RedeemReward обменивает баллы пользователя на награду
+func (s *RewardService) RedeemReward(ctx context.Context, userID, rewardID int64) (models.Redemption, error) {
+	const op = "service.Reward.RedeemReward"
+	logger := s.logger.With(zap.String("op", op))
+
+	logger.Info("Redeeming reward", zap.Int64("user_id", userID), zap.Int64("reward_id", rewardID))
+
+	redemption, err := s.repo.RedeemReward(ctx, userID, rewardID)
+	if err != nil {
+		logger.Error("Failed to redeem reward", zap.Error(err))
+		return models.Redemption{}, err
+	}
+
+	logger.Info("Reward redeemed successfully", zap.Int64("redemption_id", redemption.RedemptionID))
+	return redemption, nil
+}
+
+// GetUserRedemptions возвращает историю обменов пользователя
+func (s *RewardService) GetUserRedemptions(ctx context.Context, userID int64) ([]models.Redemption, error) {
+	const op = "service.Reward.GetUserRedemptions"
+	logger := s.logger.With(zap.String("op", op))
+
+	redemptions, err := s.repo.GetUserRedemptions(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to fetch user redemptions", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// GetRedemptions возвращает заявки на выдачу наград для администратора
+func (s *RewardService) GetRedemptions(ctx context.Context, status string) ([]models.Redemption, error) {
+	const op = "service.Reward.GetRedemptions"
+	logger := s.logger.With(zap.String("op", op))
+
+	if status != "" && !isRedemptionStatus(status) {
+		logger.Error("Unknown redemption status", zap.String("status", status))
+		return nil, errors.NewBadRequest("unknown redemption status", nil)
+	}
+
+	redemptions, err := s.repo.GetRedemptions(ctx, status)
+	if err != nil {
+		logger.Error("Failed to fetch redemptions", zap.Error(err))
+		return nil, err
+	}
+	return redemptions, nil
+}
+
+// UpdateRedemptionStatus переводит заявку на выдачу в новый статус
+func (s *RewardService) UpdateRedemptionStatus(ctx context.Context, redemptionID int64, status string) (models.Redemption, error) {
+	const op = "service.Reward.UpdateRedemptionStatus"
+	logger := s.logger.With(zap.String("op", op))
+
+	if !isRedemptionStatus(status) {
+		logger.Error("Unknown redemption status", zap.String("status", status))
+		return models.Redemption{}, errors.NewBadRequest("unknown redemption status", nil)
+	}
+
+	redemption, err := s.repo.UpdateRedemptionStatus(ctx, redemptionID, status)
+	if err != nil {
+		logger.Error("Failed to update redemption status", zap.Int64("redemption_id", redemptionID), zap.Error(err))
+		return models.Redemption{}, err
+	}
+
+	logger.Info("Redemption status updated", zap.Int64("redemption_id", redemptionID), zap.String("status", status))
+	return redemption, nil
+}
+
+// isRedemptionStatus проверяет, что статус выдачи награды известен
+func isRedemptionStatus(status string) bool {
+	switch status {
+	case models.RedemptionPending, models.RedemptionProcessing, models.RedemptionFulfilled, models.RedemptionCancelled:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+	"time"
+)
+
+// maxSeasonPrizes наибольшее количество призовых мест при закрытии сезона
+const maxSeasonPrizes = 100
+
+// SeasonService служба соревновательных сезонов
+type SeasonService struct {
+	repo   repository.SeasonRepository
+	logger *zap.Logger
+}
+
+// NewSeasonService создает новый экземпляр SeasonService
+func NewSeasonService(repo repository.SeasonRepository, logger *zap.Logger) *SeasonService {
+	return &SeasonService{
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// CreateSeason открывает новый сезон
+func (s *SeasonService) CreateSeason(ctx context.Context, req *models.SeasonCreate) (int64, error) {
+	const op = "service.Season.CreateSeason"
+	logger := s.logger.With(zap.String("op", op))
+
+	if req.Title == "" {
+		logger.Error("Validation failed: title is required")
+		return 0, errors.NewValidation("season title cannot be empty", nil)
+	}
+	if req.EndsAt != nil && !req.EndsAt.After(time.Now()) {
+		logger.Error("Validation failed: ends_at is in the past")
+		return 0, errors.NewValidation("ends_at must be in the future", nil)
+	}
+
+	seasonID, err := s.repo.CreateSeason(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create season", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Season created successfully", zap.Int64("season_id", seasonID), zap.String("title", req.Title))
+	return seasonID, nil
+}
+
+// GetSeasons возвращает текущий и прошедшие сезоны
+func (s *SeasonService) GetSeasons(ctx context.Context) ([]models.Season, error) {
+	const op = "service.Season.GetSeasons"
+	logger := s.logger.With(zap.String("op", op))
+
+	seasons, err := s.repo.GetSeasons(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch seasons", zap.Error(err))
+		return nil, err
+	}
+	return seasons, nil
+}
+
+// GetSeasonStandings возвращает места участников сезона
+func (s *SeasonService) GetSeasonStandings(ctx context.Context, seasonID int64) (models.SeasonResult, error) {
+	const op = "service.Season.GetSeasonStandings"
+	logger := s.logger.With(zap.String("op", op))
+
+	result, err := s.repo.GetSeasonStandings(ctx, seasonID)
+	if err != nil {
+		logger.Error("Failed to fetch season standings", zap.Int64("season_id", seasonID), zap.Error(err))
+		return models.SeasonResult{}, err
+	}
+	return result, nil
+}
+
+// CloseSeason закрывает сезон, фиксирует итоговые места и начисляет призы
+func (s *SeasonService) CloseSeason(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error) {
+	const op = "service.Season.CloseSeason"
+	logger := s.logger.With(zap.String("op", op))
+
+	if len(closing.Prizes) > maxSeasonPrizes {
+		logger.Error("Validation failed: too many prizes", zap.Int("prizes", len(closing.Prizes)))
+		return models.SeasonResult{}, errors.NewValidation(fmt.Sprintf("cannot award more than %d prizes", maxSeasonPrizes), nil)
+	}
+	for i, prize := range closing.Prizes {
+		if prize < 0 {
+			logger.Error("Validation failed: negative prize", zap.Int("rank", i+1))
+			return models.SeasonResult{}, errors.NewValidation(fmt.Sprintf("prize for rank %d cannot be negative", i+1), nil)
+		}
+	}
+
+	logger.Info("Closing season", zap.Int64("season_id", closing.SeasonID), zap.Ints("prizes", closing.Prizes),
+		zap.Int64("admin_id", closing.AdminID))
+
+	result, err := s.repo.CloseSeason(ctx, closing)
+	if err != nil {
+		logger.Error("Failed to close season", zap.Error(err))
+		return models.SeasonResult{}, err
+	}
+
+	logger.Info("Season closed successfully", zap.Int64("season_id", closing.SeasonID), zap.Int("participants", len(result.Standings)))
+	return result, nil
+}
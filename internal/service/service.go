@@ -2,33 +2,144 @@ package service
 
 import (
 	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/password"
 	"go.uber.org/zap"
 	"time"
 )
 
 // Auth интерфейс для аутентификации
 type Auth interface {
-	Login(ctx context.Context, credentials *models.SignIn) (string, error)
+	Login(ctx context.Context, credentials *models.SignIn) (*models.LoginResult, error)
+	LoginTwoFactor(ctx context.Context, req *models.TwoFactorLoginRequest) (string, error)
 	Register(ctx context.Context, userInfo *models.CreateUser) (int64, error)
-	ParseToken(token string) (int64, error)
+	ParseToken(ctx context.Context, token string) (int64, error)
 	GetUser(ctx context.Context, up *models.SignIn) (*models.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	ResendEmailVerification(ctx context.Context, userID int64) error
+	ForgotPassword(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, req *models.PasswordResetRequest) error
+	ChangePassword(ctx context.Context, userID int64, req *models.PasswordChangeRequest) (string, error)
+	UnlockLogin(ctx context.Context, userID int64, ip string) error
+	GetTwoFactorStatus(ctx context.Context, userID int64) (*models.TwoFactorStatus, error)
+	SetupTwoFactor(ctx context.Context, userID int64) (*models.TwoFactorSetup, error)
+	EnableTwoFactor(ctx context.Context, userID int64, code string) (*models.RecoveryCodes, error)
+	DisableTwoFactor(ctx context.Context, userID int64, req *models.TwoFactorDisableRequest) error
+	RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*models.RecoveryCodes, error)
+	OIDCLogin(ctx context.Context) (*models.OIDCLogin, error)
+	OIDCCallback(ctx context.Context, req *models.OIDCCallback) (*models.LoginResult, error)
+	TelegramLogin(ctx context.Context, data *models.TelegramLogin) (*models.LoginResult, error)
+	LinkTelegram(ctx context.Context, userID int64, data *models.TelegramLogin) error
+	GetProfile(ctx context.Context, userID int64) (*models.Profile, error)
+	UpdateProfile(ctx context.Context, userID int64, req *models.ProfileUpdate) (*models.Profile, error)
+	ConfirmEmailChange(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, userID int64, req *models.AccountDeleteRequest) error
 }
 
 // User интерфейс для работы с пользователями
 type User interface {
 	GetUserInfo(ctx context.Context, userId int64) (models.User, error)
-	GetUsersLeaderboard(ctx context.Context) ([]models.User, error)
+	GetUsersLeaderboard(ctx context.Context, currency string) ([]models.User, error)
 	GetUserID(ctx context.Context, usernameOrEmail string) (int64, error)
 	ReferrerCode(ctx context.Context, userId int64, refCode string) error
+	IsAdmin(ctx context.Context, userId int64) (bool, error)
 }
 
 // Task интерфейс для работы с задачами
 type Task interface {
 	CreateTask(ctx context.Context, req *models.TaskCreate) (int64, error)
 	CompleteTask(tx context.Context, userId, taskId int64) error
-	GetAllTasks(ctx context.Context) ([]models.Task, error)
+	GetAllTasks(ctx context.Context, filter models.TaskFilter) (models.TaskPage, error)
+	GetUserCompletions(ctx context.Context, userId int64) ([]models.TaskCompletion, error)
+	RevokeCompletion(ctx context.Context, revocation *models.Revocation) (models.RevocationResult, error)
+	CreateCategory(ctx context.Context, req *models.CategoryCreate) (int64, error)
+	GetCategories(ctx context.Context) ([]models.Category, error)
+}
+
+// Referral интерфейс для работы с реферальными ссылками
+type Referral interface {
+	TrackClick(ctx context.Context, click *models.ReferralClick) (string, error)
+	GetReferralStats(ctx context.Context, referCode string) (models.ReferralStats, error)
+}
+
+// Reward интерфейс для работы с каталогом наград
+type Reward interface {
+	CreateReward(ctx context.Context, req *models.RewardCreate) (int64, error)
+	GetRewards(ctx context.Context) ([]models.Reward, error)
+	RedeemReward(ctx context.Context, userID, rewardID int64) (models.Redemption, error)
+	GetUserRedemptions(ctx context.Context, userID int64) ([]models.Redemption, error)
+	GetRedemptions(ctx context.Context, status string) ([]models.Redemption, error)
+	UpdateRedemptionStatus(ctx context.Context, redemptionID int64, status string) (models.Redemption, error)
+}
+
+// Balance интерфейс для операций с балансом пользователей
+type Balance interface {
+	Transfer(ctx context.Context, actorID int64, transfer *models.Transfer) (models.TransferResult, error)
+	GetUserTransactions(ctx context.Context, userID int64) ([]models.Transaction, error)
+	AdjustBalance(ctx context.Context, adjustment *models.Adjustment) (models.Transaction, error)
+	ExpirePoints(ctx context.Context) (int, error)
+	GetCurrencies(ctx context.Context) ([]models.Currency, error)
+}
+
+// Achievement интерфейс для работы с достижениями
+type Achievement interface {
+	CreateAchievement(ctx context.Context, req *models.AchievementCreate) (int64, error)
+	GetAchievements(ctx context.Context) ([]models.Achievement, error)
+	GetUserAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+	EvaluateAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+}
+
+// Boost интерфейс для работы с множителями наград
+type Boost interface {
+	CreateBoost(ctx context.Context, req *models.BoostCreate) (int64, error)
+	GetBoosts(ctx context.Context, upcomingOnly bool) ([]models.Boost, error)
+}
+
+// Season интерфейс для работы с сезонами
+type Season interface {
+	CreateSeason(ctx context.Context, req *models.SeasonCreate) (int64, error)
+	GetSeasons(ctx context.Context) ([]models.Season, error)
+	GetSeasonStandings(ctx context.Context, seasonID int64) (models.SeasonResult, error)
+	CloseSeason(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error)
+}
+
+// Team интерфейс для работы с командами
+type Team interface {
+	CreateTeam(ctx context.Context, req *models.TeamCreate) (int64, error)
+	JoinTeam(ctx context.Context, userID int64, inviteCode string) (models.Team, error)
+	LeaveTeam(ctx context.Context, userID int64) error
+	SetCaptain(ctx context.Context, captainID, teamID, newCaptainID int64) error
+	RemoveMember(ctx context.Context, captainID, teamID, userID int64) error
+	GetTeam(ctx context.Context, teamID int64) (models.Team, error)
+	GetUserTeam(ctx context.Context, userID int64) (models.Team, error)
+	GetTeamsLeaderboard(ctx context.Context, currency string) ([]models.TeamStanding, error)
+}
+
+// Campaign интерфейс для работы с кампаниями
+type Campaign interface {
+	CreateCampaign(ctx context.Context, req *models.CampaignCreate) (int64, error)
+	GetCampaigns(ctx context.Context) ([]models.Campaign, error)
+	UpdateCampaignStatus(ctx context.Context, campaignID int64, status string) (models.Campaign, error)
+	GetCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error)
+}
+
+// APIKey интерфейс для работы с API-ключами интеграций
+type APIKey interface {
+	CreateAPIKey(ctx context.Context, req *models.APIKeyCreate) (models.APIKeyCreated, error)
+	GetAPIKeys(ctx context.Context) ([]models.APIKey, error)
+	RevokeAPIKey(ctx context.Context, apiKeyID int64) error
+	AuthenticateAPIKey(ctx context.Context, key string) (models.APIKey, error)
+}
+
+// Export интерфейс для выгрузки персональных данных пользователя
+type Export interface {
+	ExportUserData(ctx context.Context, userID int64, format string) (models.ExportResult, error)
+	GetExport(ctx context.Context, userID, exportID int64) (models.DataExport, error)
+	DownloadExport(ctx context.Context, userID, exportID int64) (models.ExportFile, error)
+	ProcessExports(ctx context.Context) (int, error)
 }
 
 // Service структура для объединения всех сервисов
@@ -36,6 +147,16 @@ type Service struct {
 	Auth
 	User
 	Task
+	Referral
+	Reward
+	Balance
+	Achievement
+	Boost
+	Season
+	Team
+	Campaign
+	APIKey
+	Export
 }
 
 // ServicesDependencies зависимости для создания Service
@@ -44,18 +165,80 @@ type ServicesDependencies struct {
 	Logger   *zap.Logger
 	SignKey  string
 	TokenTTL time.Duration
+	// Mailer отправляет письма пользователям
+	Mailer mail.Sender
+	// PublicURL адрес сервиса, из которого строятся ссылки в письмах
+	PublicURL string
+	// EmailVerificationTTL срок действия ссылки подтверждения адреса
+	EmailVerificationTTL time.Duration
+	// PasswordResetURL страница установки нового пароля
+	PasswordResetURL string
+	// PasswordResetTTL срок действия ссылки сброса пароля
+	PasswordResetTTL time.Duration
+	// PasswordPolicy политика новых паролей
+	PasswordPolicy *password.Policy
+	// BcryptCost стоимость хэширования паролей
+	BcryptCost int
+	// LoginThrottle ограничения на неудачные попытки входа
+	LoginThrottle models.LoginThrottleRules
+	// TOTPIssuer название сервиса в приложении-аутентификаторе
+	TOTPIssuer string
+	// TwoFactorTTL время на ввод кода после проверки пароля
+	TwoFactorTTL time.Duration
+	// OIDC провайдер OpenID Connect для входа; nil - вход через провайдера отключен
+	OIDC *oidc.Provider
+	// TelegramBotToken токен бота виджета Telegram Login; пусто - вход отключен
+	TelegramBotToken string
+	// TelegramAuthMaxAge наибольший возраст данных виджета Telegram Login
+	TelegramAuthMaxAge time.Duration
+	// ReferralLandingURL страница, на которую перенаправляется переход по реферальной ссылке
+	ReferralLandingURL string
+	// TransferLimits ограничения на переводы баллов между пользователями
+	TransferLimits models.TransferLimits
+	// StreakRules настройки бонуса за серию дней с выполненными заданиями
+	StreakRules models.StreakRules
+	// Levels кривая уровней по заработанным за все время баллам
+	Levels models.LevelCurve
+	// TeamMaxMembers наибольший размер команды (0 - без ограничения)
+	TeamMaxMembers int
+	// ExportRules настройки выгрузки персональных данных
+	ExportRules models.ExportRules
 }
 
 // NewService создает новый экземпляр Service
 func NewService(deps ServicesDependencies) *Service {
+	achievements := NewAchievementService(deps.Repos.AchievementRepository, deps.Logger)
 	return &Service{
 		Auth: NewAuthService(AuthDependencies{
-			authRepo: deps.Repos.AuthRepository,
-			logger:   deps.Logger,
-			signKey:  deps.SignKey,
-			tokenTTL: deps.TokenTTL,
+			AuthRepo:             deps.Repos.AuthRepository,
+			Logger:               deps.Logger,
+			SignKey:              deps.SignKey,
+			TokenTTL:             deps.TokenTTL,
+			Mailer:               deps.Mailer,
+			PublicURL:            deps.PublicURL,
+			EmailVerificationTTL: deps.EmailVerificationTTL,
+			PasswordResetURL:     deps.PasswordResetURL,
+			PasswordResetTTL:     deps.PasswordResetTTL,
+			PasswordPolicy:       deps.PasswordPolicy,
+			BcryptCost:           deps.BcryptCost,
+			LoginThrottle:        deps.LoginThrottle,
+			TOTPIssuer:           deps.TOTPIssuer,
+			TwoFactorTTL:         deps.TwoFactorTTL,
+			OIDC:                 deps.OIDC,
+			TelegramBotToken:     deps.TelegramBotToken,
+			TelegramAuthMaxAge:   deps.TelegramAuthMaxAge,
 		}),
-		Task: NewTaskService(deps.Repos.TaskRepository, deps.Logger),
-		User: NewUserService(deps.Repos.UserRepository, deps.Logger),
+		Task:        NewTaskService(deps.Repos.TaskRepository, deps.Logger, achievements),
+		User:        NewUserService(deps.Repos.UserRepository, deps.Logger, achievements, deps.StreakRules, deps.Levels),
+		Referral:    NewReferralService(deps.Repos.ReferralRepository, deps.Logger, deps.ReferralLandingURL),
+		Reward:      NewRewardService(deps.Repos.RewardRepository, deps.Logger),
+		Balance:     NewBalanceService(deps.Repos.BalanceRepository, deps.Logger, deps.TransferLimits),
+		Achievement: achievements,
+		Boost:       NewBoostService(deps.Repos.BoostRepository, deps.Logger),
+		Season:      NewSeasonService(deps.Repos.SeasonRepository, deps.Logger),
+		Team:        NewTeamService(deps.Repos.TeamRepository, deps.Logger, deps.TeamMaxMembers),
+		Campaign:    NewCampaignService(deps.Repos.CampaignRepository, deps.Logger),
+		APIKey:      NewAPIKeyService(deps.Repos.APIKeyRepository, deps.Logger),
+		Export:      NewExportService(deps.Repos.ExportRepository, deps.Logger, deps.Mailer, deps.PublicURL, deps.ExportRules),
 	}
 }
@@ -0,0 +1,87 @@
+package streak
+
+import (
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"time"
+)
+
+// freezeRestoreDays каждые столько дней непрерывной серии пользователю возвращается одна заморозка
+const freezeRestoreDays = 7
+
+// Day возвращает календарный день момента времени в его собственной зоне.
+// День хранится как полночь UTC, чтобы дни можно было сравнивать между собой.
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// daysBetween количество календарных дней между двумя днями
+func daysBetween(from, to time.Time) int {
+	return int(Day(to).Sub(Day(from)).Hours() / 24)
+}
+
+// Advance продлевает серию выполнением задания в день day.
+// Повторное выполнение в тот же день серию не меняет. Пропущенные дни закрываются заморозками,
+// если их хватает на весь пропуск, иначе серия начинается заново.
+func Advance(s models.Streak, day time.Time, rules models.StreakRules) models.Streak {
+	day = Day(day)
+	switch {
+	case s.LastActiveDay == nil || s.Current == 0:
+		s.Current = 1
+	default:
+		gap := daysBetween(*s.LastActiveDay, day)
+		if gap <= 0 {
+			return withDerived(s, rules)
+		}
+		missed := gap - 1
+		if missed > 0 && missed <= freezesLeft(s, rules) {
+			s.FreezesUsed += missed
+			missed = 0
+		}
+		if missed == 0 {
+			s.Current++
+		} else {
+			s.Current = 1
+		}
+	}
+
+	if s.Current%freezeRestoreDays == 0 && s.FreezesUsed > 0 {
+		s.FreezesUsed--
+	}
+	s.Longest = max(s.Longest, s.Current)
+	s.LastActiveDay = &day
+	return withDerived(s, rules)
+}
+
+// Current возвращает состояние серии на день today: если пропуск уже нельзя закрыть заморозками,
+// текущая серия обнуляется.
+func Current(s models.Streak, today time.Time, rules models.StreakRules) models.Streak {
+	if s.LastActiveDay != nil && s.Current > 0 {
+		if missed := daysBetween(*s.LastActiveDay, today) - 1; missed > freezesLeft(s, rules) {
+			s.Current = 0
+		}
+	}
+	return withDerived(s, rules)
+}
+
+// BonusPercent надбавка к награде за задание при серии длиной current дней
+func BonusPercent(current int, rules models.StreakRules) int {
+	if current <= 1 || rules.BonusPercent <= 0 {
+		return 0
+	}
+	return min((current-1)*rules.BonusPercent, rules.MaxBonusPercent)
+}
+
+// Apply начисляет надбавку к награде, округляя вниз
+func Apply(amount, bonusPercent int) int {
+	return amount + amount*bonusPercent/100
+}
+
+func freezesLeft(s models.Streak, rules models.StreakRules) int {
+	return max(rules.Freezes-s.FreezesUsed, 0)
+}
+
+func withDerived(s models.Streak, rules models.StreakRules) models.Streak {
+	s.FreezesLeft = freezesLeft(s, rules)
+	s.BonusPercent = BonusPercent(s.Current, rules)
+	return s
+}
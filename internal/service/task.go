@@ -2,25 +2,45 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository"
 	"go.uber.org/zap"
+	"strings"
+)
+
+const (
+	// defaultTasksPageSize размер страницы заданий, если передан cursor без limit
+	defaultTasksPageSize = 50
+	// maxTasksPageSize наибольший допустимый размер страницы заданий
+	maxTasksPageSize = 100
+	// maxTaskTags наибольшее число меток у задания
+	maxTaskTags = 10
+	// maxTaskTagLength наибольшая длина метки
+	maxTaskTagLength = 32
 )
 
 type TaskService struct {
-	repo   repository.TaskRepository
-	logger *zap.Logger
+	repo         repository.TaskRepository
+	logger       *zap.Logger
+	achievements achievementEvaluator
 }
 
-func NewTaskService(repo repository.TaskRepository, logger *zap.Logger) *TaskService {
+// NewTaskService создает новый экземпляр TaskService.
+// achievements может быть nil, тогда достижения после выполнения задания не проверяются.
+func NewTaskService(repo repository.TaskRepository, logger *zap.Logger, achievements achievementEvaluator) *TaskService {
 	return &TaskService{
-		repo:   repo,
-		logger: logger,
+		repo:         repo,
+		logger:       logger,
+		achievements: achievements,
 	}
 }
 
 // CreateTask создает новую задачу.
+// Задание с кампанией может создать только администратор (req.ByAdmin), иначе возвращается Forbidden.
 func (s *TaskService) CreateTask(ctx context.Context, req *models.TaskCreate) (int64, error) {
 	const op = "service.Task.CreateTask"
 	logger := s.logger.With(zap.String("op", op))
@@ -30,6 +50,12 @@ func (s *TaskService) CreateTask(ctx context.Context, req *models.TaskCreate) (i
 		zap.String("description", req.Description),
 		zap.Int("price", req.Price))
 
+	// Задание кампании оплачивается из ее бюджета, поэтому привязать его может только администратор
+	if req.CampaignID != nil && !req.ByAdmin {
+		logger.Warn("Campaign task creation by non-admin", zap.Int64("campaign_id", *req.CampaignID))
+		return 0, errors.NewForbidden("only administrators can add tasks to a campaign", nil)
+	}
+
 	// Валидация запроса
 	if err := validateTaskRequest(req); err != nil {
 		logger.Error("Validation failed", zap.Error(err))
@@ -47,13 +73,46 @@ func (s *TaskService) CreateTask(ctx context.Context, req *models.TaskCreate) (i
 }
 
 // validateTaskRequest выполняет проверку валидности запроса на создание или обновление задачи.
+// Приводит Price и Prices к согласованному виду: Price всегда равен стоимости в coins.
 func validateTaskRequest(req *models.TaskCreate) error {
 	if req.Title == "" {
 		return errors.NewValidation("task title cannot be empty", nil)
 	}
-	if req.Price < 1 {
-		return errors.NewValidation("minimum value for the Price field is 1", nil)
+	if req.Type == "" {
+		req.Type = models.TaskTypeGeneral
+	}
+	if len(req.Type) > 32 {
+		return errors.NewValidation("task type cannot be longer than 32 characters", nil)
+	}
+	if req.CampaignID != nil && *req.CampaignID < 1 {
+		return errors.NewValidation("invalid campaign_id", nil)
 	}
+	req.Category = strings.ToLower(strings.TrimSpace(req.Category))
+	tags, err := normalizeTags(req.Tags)
+	if err != nil {
+		return err
+	}
+	req.Tags = tags
+	if len(req.Prices) == 0 {
+		if req.Price < 1 {
+			return errors.NewValidation("minimum value for the Price field is 1", nil)
+		}
+		req.Prices = map[string]int{models.CurrencyCoins: req.Price}
+		return nil
+	}
+
+	for currency, amount := range req.Prices {
+		if currency == "" {
+			return errors.NewValidation("currency code cannot be empty", nil)
+		}
+		if amount < 1 {
+			return errors.NewValidation(fmt.Sprintf("minimum price in currency %q is 1", currency), nil)
+		}
+	}
+	if req.Price != 0 && req.Price != req.Prices[models.CurrencyCoins] {
+		return errors.NewValidation("price must match prices.coins", nil)
+	}
+	req.Price = req.Prices[models.CurrencyCoins]
 	return nil
 }
 
@@ -71,22 +130,214 @@ func (s *TaskService) CompleteTask(ctx context.Context, userId, taskId int64) er
 	}
 
 	logger.Info("Task completed successfully", zap.Int64("user_id", userId), zap.Int64("task_id", taskId))
+	evaluateAchievements(ctx, s.achievements, userId, logger)
 	return nil
 }
 
-// GetAllTasks возвращает все задачи.
-func (s *TaskService) GetAllTasks(ctx context.Context) ([]models.Task, error) {
+// normalizeTags приводит метки к нижнему регистру, убирает пробелы, пустые метки и повторы.
+func normalizeTags(tags []string) ([]string, error) {
+	var normalized []string
+	seen := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > maxTaskTagLength {
+			return nil, errors.NewValidation(fmt.Sprintf("tag cannot be longer than %d characters", maxTaskTagLength), nil)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	if len(normalized) > maxTaskTags {
+		return nil, errors.NewValidation(fmt.Sprintf("task cannot have more than %d tags", maxTaskTags), nil)
+	}
+	return normalized, nil
+}
+
+// GetAllTasks возвращает страницу заданий с учетом поиска, фильтров и сортировки.
+// Без limit и cursor возвращаются все задания одной страницей, как до появления пагинации.
+// Курсор следующей страницы возвращается, только если после нее остались задания.
+func (s *TaskService) GetAllTasks(ctx context.Context, filter models.TaskFilter) (models.TaskPage, error) {
 	const op = "service.Task.GetAllTasks"
 	logger := s.logger.With(zap.String("op", op))
 
-	logger.Info("Fetching all tasks")
+	if err := validateTaskFilter(&filter); err != nil {
+		logger.Error("Validation failed", zap.Error(err))
+		return models.TaskPage{}, err
+	}
+
+	logger.Info("Fetching tasks", zap.String("query", filter.Query), zap.String("category", filter.Category),
+		zap.Strings("tags", filter.Tags), zap.String("sort", filter.Sort), zap.Int("limit", filter.Limit))
+
+	// Запрашиваем на одно задание больше, чтобы узнать, есть ли следующая страница
+	limit := filter.Limit
+	if limit > 0 {
+		filter.Limit++
+	}
+	tasks, err := s.repo.GetAllTasks(ctx, filter)
+	if err != nil {
+		logger.Error("Failed to fetch tasks", zap.Error(err))
+		return models.TaskPage{}, err
+	}
+
+	page := models.TaskPage{Tasks: tasks}
+	if limit > 0 && len(tasks) > limit {
+		page.Tasks = tasks[:limit]
+		page.NextCursor = encodeTaskCursor(filter.Sort, page.Tasks[limit-1])
+	}
+	if page.Tasks == nil {
+		page.Tasks = []models.Task{}
+	}
+
+	logger.Info("Tasks fetched successfully", zap.Int("tasks_count", len(page.Tasks)))
+	return page, nil
+}
+
+// validateTaskFilter проверяет параметры списка заданий, подставляет значения по умолчанию и разбирает курсор.
+func validateTaskFilter(filter *models.TaskFilter) error {
+	filter.Query = strings.TrimSpace(filter.Query)
+	filter.Category = strings.ToLower(strings.TrimSpace(filter.Category))
+	tags, err := normalizeTags(filter.Tags)
+	if err != nil {
+		return err
+	}
+	filter.Tags = tags
+
+	switch filter.Sort {
+	case "":
+		filter.Sort = models.TaskSortID
+	case models.TaskSortID, models.TaskSortNewest, models.TaskSortPriceAsc, models.TaskSortPriceDesc, models.TaskSortPopular:
+	default:
+		return errors.NewValidation(fmt.Sprintf("unknown sort %q", filter.Sort), nil)
+	}
+
+	// Без limit и cursor список не ограничивается, чтобы не обрезать ответ старым клиентам
+	if filter.Limit == 0 && filter.Cursor == "" {
+		return nil
+	}
+	if filter.Limit == 0 {
+		filter.Limit = defaultTasksPageSize
+	}
+	if filter.Limit < 1 || filter.Limit > maxTasksPageSize {
+		return errors.NewValidation(fmt.Sprintf("limit must be between 1 and %d", maxTasksPageSize), nil)
+	}
+
+	if filter.Cursor != "" {
+		cursor, err := decodeTaskCursor(filter.Cursor)
+		if err != nil {
+			return err
+		}
+		if cursor.Sort != filter.Sort {
+			return errors.NewValidation("cursor does not match sort order", nil)
+		}
+		filter.After = &cursor
+	}
+	return nil
+}
+
+// encodeTaskCursor кодирует позицию задания в списке в непрозрачную строку
+func encodeTaskCursor(sort string, task models.Task) string {
+	cursor := models.TaskCursor{Sort: sort, TaskID: task.TaskID}
+	switch sort {
+	case models.TaskSortPriceAsc, models.TaskSortPriceDesc:
+		cursor.Value = int64(task.Price)
+	case models.TaskSortPopular:
+		cursor.Value = int64(task.Completions)
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeTaskCursor разбирает курсор, выданный encodeTaskCursor
+func decodeTaskCursor(value string) (models.TaskCursor, error) {
+	var cursor models.TaskCursor
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return models.TaskCursor{}, errors.NewValidation("invalid cursor", err)
+	}
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.TaskID < 1 {
+		return models.TaskCursor{}, errors.NewValidation("invalid cursor", err)
+	}
+	return cursor, nil
+}
+
+// CreateCategory создает категорию заданий.
+func (s *TaskService) CreateCategory(ctx context.Context, req *models.CategoryCreate) (int64, error) {
+	const op = "service.Task.CreateCategory"
+	logger := s.logger.With(zap.String("op", op))
+
+	req.Code = strings.ToLower(strings.TrimSpace(req.Code))
+	req.Title = strings.TrimSpace(req.Title)
+	if req.Code == "" || req.Title == "" {
+		logger.Error("Validation failed", zap.String("code", req.Code))
+		return 0, errors.NewValidation("category code and title cannot be empty", nil)
+	}
+	if len(req.Code) > 64 {
+		return 0, errors.NewValidation("category code cannot be longer than 64 characters", nil)
+	}
 
-	tasks, err := s.repo.GetAllTasks(ctx)
+	categoryID, err := s.repo.CreateCategory(ctx, req)
 	if err != nil {
-		logger.Error("Failed to fetch all tasks", zap.Error(err))
+		logger.Error("Failed to create category", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Category created successfully", zap.Int64("category_id", categoryID), zap.String("code", req.Code))
+	return categoryID, nil
+}
+
+// GetCategories возвращает категории заданий.
+func (s *TaskService) GetCategories(ctx context.Context) ([]models.Category, error) {
+	const op = "service.Task.GetCategories"
+	logger := s.logger.With(zap.String("op", op))
+
+	categories, err := s.repo.GetCategories(ctx)
+	if err != nil {
+		logger.Error("Failed to fetch categories", zap.Error(err))
+		return nil, err
+	}
+	return categories, nil
+}
+
+// GetUserCompletions возвращает выполненные пользователем задания.
+func (s *TaskService) GetUserCompletions(ctx context.Context, userId int64) ([]models.TaskCompletion, error) {
+	const op = "service.Task.GetUserCompletions"
+	logger := s.logger.With(zap.String("op", op))
+
+	completions, err := s.repo.GetUserCompletions(ctx, userId)
+	if err != nil {
+		logger.Error("Failed to fetch user completions", zap.Int64("user_id", userId), zap.Error(err))
 		return nil, err
 	}
+	return completions, nil
+}
+
+// RevokeCompletion отменяет выполнение задания и забирает начисленные за него баллы.
+func (s *TaskService) RevokeCompletion(ctx context.Context, revocation *models.Revocation) (models.RevocationResult, error) {
+	const op = "service.Task.RevokeCompletion"
+	logger := s.logger.With(zap.String("op", op))
+
+	if revocation.Reason == "" {
+		logger.Error("Validation failed: reason is required")
+		return models.RevocationResult{}, errors.NewValidation("reason is required", nil)
+	}
+	if len(revocation.Reason) > 255 {
+		logger.Error("Validation failed: reason is too long")
+		return models.RevocationResult{}, errors.NewValidation("reason cannot be longer than 255 characters", nil)
+	}
 
-	logger.Info("All tasks fetched successfully", zap.Int("tasks_count", len(tasks)))
-	return tasks, nil
+	logger.Info("Revoking task completion", zap.Int64("completion_id", revocation.CompletionID), zap.Int64("admin_id", revocation.AdminID))
+
+	result, err := s.repo.RevokeCompletion(ctx, revocation)
+	if err != nil {
+		logger.Error("Failed to revoke task completion", zap.Error(err))
+		return models.RevocationResult{}, err
+	}
+
+	if result.Shortfall > 0 {
+		logger.Warn("Clawback exceeded available balance", zap.Int64("completion_id", revocation.CompletionID), zap.Int("shortfall", result.Shortfall))
+	}
+	logger.Info("Task completion revoked successfully", zap.Int64("completion_id", revocation.CompletionID))
+	return result, nil
 }
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"go.uber.org/zap"
+	"strings"
+)
+
+// TeamService служба команд пользователей
+type TeamService struct {
+	repo       repository.TeamRepository
+	logger     *zap.Logger
+	maxMembers int
+}
+
+// NewTeamService создает новый экземпляр TeamService.
+// maxMembers ограничивает размер команды (0 - без ограничения).
+func NewTeamService(repo repository.TeamRepository, logger *zap.Logger, maxMembers int) *TeamService {
+	return &TeamService{
+		repo:       repo,
+		logger:     logger,
+		maxMembers: maxMembers,
+	}
+}
+
+// CreateTeam создает команду, капитаном которой становится создатель
+func (s *TeamService) CreateTeam(ctx context.Context, req *models.TeamCreate) (int64, error) {
+	const op = "service.Team.CreateTeam"
+	logger := s.logger.With(zap.String("op", op))
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		logger.Error("Validation failed: name is required")
+		return 0, errors.NewValidation("team name cannot be empty", nil)
+	}
+	if len(req.Name) > 64 {
+		logger.Error("Validation failed: name is too long")
+		return 0, errors.NewValidation("team name cannot be longer than 64 characters", nil)
+	}
+
+	teamID, err := s.repo.CreateTeam(ctx, req)
+	if err != nil {
+		logger.Error("Failed to create team", zap.Error(err))
+		return 0, err
+	}
+
+	logger.Info("Team created successfully", zap.Int64("team_id", teamID), zap.String("name", req.Name), zap.Int64("captain_id", req.UserID))
+	return teamID, nil
+}
+
+// JoinTeam добавляет пользователя в команду по коду приглашения
+func (s *TeamService) JoinTeam(ctx context.Context, userID int64, inviteCode string) (models.Team, error) {
+	const op = "service.Team.JoinTeam"
+	logger := s.logger.With(zap.String("op", op))
+
+	if inviteCode == "" {
+		logger.Error("Validation failed: invite code is required")
+		return models.Team{}, errors.NewValidation("invite code is required", nil)
+	}
+
+	team, err := s.repo.JoinTeam(ctx, userID, inviteCode, s.maxMembers)
+	if err != nil {
+		logger.Error("Failed to join team", zap.Int64("user_id", userID), zap.Error(err))
+		return models.Team{}, err
+	}
+
+	logger.Info("User joined team successfully", zap.Int64("user_id", userID), zap.Int64("team_id", team.TeamID))
+	return team, nil
+}
+
+// LeaveTeam исключает пользователя из его команды
+func (s *TeamService) LeaveTeam(ctx context.Context, userID int64) error {
+	const op = "service.Team.LeaveTeam"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := s.repo.LeaveTeam(ctx, userID); err != nil {
+		logger.Error("Failed to leave team", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("User left team successfully", zap.Int64("user_id", userID))
+	return nil
+}
+
+// SetCaptain передает роль капитана другому участнику команды
+func (s *TeamService) SetCaptain(ctx context.Context, captainID, teamID, newCaptainID int64) error {
+	const op = "service.Team.SetCaptain"
+	logger := s.logger.With(zap.String("op", op))
+
+	if newCaptainID == captainID {
+		logger.Error("Validation failed: user is already the captain")
+		return errors.NewValidation("user is already the team captain", nil)
+	}
+
+	if err := s.repo.SetCaptain(ctx, captainID, teamID, newCaptainID); err != nil {
+		logger.Error("Failed to change team captain", zap.Int64("team_id", teamID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Team captain changed successfully", zap.Int64("team_id", teamID), zap.Int64("captain_id", newCaptainID))
+	return nil
+}
+
+// RemoveMember исключает участника из команды по решению капитана
+func (s *TeamService) RemoveMember(ctx context.Context, captainID, teamID, userID int64) error {
+	const op = "service.Team.RemoveMember"
+	logger := s.logger.With(zap.String("op", op))
+
+	if userID == captainID {
+		logger.Error("Validation failed: captain cannot remove themselves")
+		return errors.NewValidation("captain cannot remove themselves, leave the team instead", nil)
+	}
+
+	if err := s.repo.RemoveMember(ctx, captainID, teamID, userID); err != nil {
+		logger.Error("Failed to remove team member", zap.Int64("team_id", teamID), zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Team member removed successfully", zap.Int64("team_id", teamID), zap.Int64("user_id", userID))
+	return nil
+}
+
+// GetTeam возвращает команду с участниками
+func (s *TeamService) GetTeam(ctx context.Context, teamID int64) (models.Team, error) {
+	const op = "service.Team.GetTeam"
+	logger := s.logger.With(zap.String("op", op))
+
+	team, err := s.repo.GetTeam(ctx, teamID)
+	if err != nil {
+		logger.Error("Failed to fetch team", zap.Int64("team_id", teamID), zap.Error(err))
+		return models.Team{}, err
+	}
+	return team, nil
+}
+
+// GetUserTeam возвращает команду пользователя вместе с кодом приглашения
+func (s *TeamService) GetUserTeam(ctx context.Context, userID int64) (models.Team, error) {
+	const op = "service.Team.GetUserTeam"
+	logger := s.logger.With(zap.String("op", op))
+
+	team, err := s.repo.GetUserTeam(ctx, userID)
+	if err != nil {
+		logger.Error("Failed to fetch user team", zap.Int64("user_id", userID), zap.Error(err))
+		return models.Team{}, err
+	}
+	return team, nil
+}
+
+// GetTeamsLeaderboard возвращает команды, отсортированные по очкам в указанной валюте.
+// Пустая валюта означает coins.
+func (s *TeamService) GetTeamsLeaderboard(ctx context.Context, currency string) ([]models.TeamStanding, error) {
+	const op = "service.Team.GetTeamsLeaderboard"
+	logger := s.logger.With(zap.String("op", op))
+
+	currency = leaderboardCurrency(currency)
+
+	logger.Debug("Fetching teams leaderboard", zap.String("currency", currency))
+	standings, err := s.repo.GetTeamsLeaderboard(ctx, currency)
+	if err != nil {
+		logger.Error("Failed to fetch teams leaderboard", zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Teams leaderboard fetched successfully", zap.Int("teams_count", len(standings)))
+	return standings, nil
+}
@@ -0,0 +1,78 @@
+// Package telegram проверяет данные, которые виджет Telegram Login передает сайту после входа пользователя.
+//
+// Подпись - HMAC-SHA256 строки "ключ=значение" по всем полям, кроме hash, отсортированным по ключу
+// и разделенным переводом строки; ключ HMAC - SHA-256 токена бота.
+// См. https://core.telegram.org/widgets/login#checking-authorization
+package telegram
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clockSkew допустимое опережение auth_date относительно часов сервиса
+const clockSkew = time.Minute
+
+var (
+	// ErrInvalidHash данные не подписаны ботом с этим токеном или изменены
+	ErrInvalidHash = errors.New("invalid telegram login hash")
+	// ErrExpired данные входа устарели
+	ErrExpired = errors.New("telegram login data expired")
+)
+
+// CheckAuthorization проверяет подпись полей виджета и время входа auth_date.
+// fields содержит все полученные поля, включая hash; maxAge 0 - время входа не ограничено.
+func CheckAuthorization(botToken string, fields map[string]string, now time.Time, maxAge time.Duration) error {
+	expected, err := hex.DecodeString(fields["hash"])
+	if err != nil || len(expected) != sha256.Size {
+		return ErrInvalidHash
+	}
+	if !hmac.Equal(sign(botToken, fields), expected) {
+		return ErrInvalidHash
+	}
+
+	authDate, err := strconv.ParseInt(fields["auth_date"], 10, 64)
+	if err != nil {
+		return ErrInvalidHash
+	}
+	issued := time.Unix(authDate, 0)
+	if issued.After(now.Add(clockSkew)) || (maxAge > 0 && now.Sub(issued) > maxAge) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// Sign возвращает подпись полей в том виде, в каком ее передает виджет
+func Sign(botToken string, fields map[string]string) string {
+	return hex.EncodeToString(sign(botToken, fields))
+}
+
+// sign вычисляет HMAC строки проверки данных
+func sign(botToken string, fields map[string]string) []byte {
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(dataCheckString(fields)))
+	return mac.Sum(nil)
+}
+
+// dataCheckString собирает строку проверки данных из всех полей, кроме hash
+func dataCheckString(fields map[string]string) string {
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		if key != "hash" {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	lines := make([]string, len(keys))
+	for i, key := range keys {
+		lines[i] = key + "=" + fields[key]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/telegram"
+	"go.uber.org/zap"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TelegramLogin выполняет вход по данным виджета Telegram Login и возвращает JWT.
+// Если аккаунт Telegram не привязан ни к одному пользователю, создается новый пользователь.
+// Если у пользователя включена двухфакторная аутентификация, вместо JWT возвращается токен второго шага,
+// вход завершается через LoginTwoFactor.
+func (s *AuthService) TelegramLogin(ctx context.Context, data *models.TelegramLogin) (*models.LoginResult, error) {
+	const op = "service.Auth.TelegramLogin"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := s.checkTelegramLogin(data); err != nil {
+		logger.Info("invalid telegram login data", zap.Int64("telegram_id", data.ID), zap.Error(err))
+		return nil, err
+	}
+
+	subject := strconv.FormatInt(data.ID, 10)
+	user, err := s.repo.GetUserByIdentity(ctx, models.IdentityProviderTelegram, subject)
+	if errors.IsNotFound(err) {
+		name := strings.TrimSpace(data.FirstName + " " + data.LastName)
+		user, err = s.createExternalUser(ctx, models.IdentityProviderTelegram, subject, "", data.Username, name, "tg_"+subject)
+		if err == nil {
+			logger.Info("User registered with telegram", zap.Int64("user_id", user.ID))
+		}
+	}
+	if err != nil {
+		logger.Error("cannot resolve user", zap.Int64("telegram_id", data.ID), zap.Error(err))
+		return nil, err
+	}
+
+	result, err := s.externalLoginResult(ctx, user)
+	if err != nil {
+		logger.Error("cannot complete login", zap.Int64("user_id", user.ID), zap.Error(err))
+		return nil, err
+	}
+	if result.TwoFactorRequired {
+		logger.Info("Telegram login accepted, two-factor code required", zap.Int64("user_id", user.ID))
+		return result, nil
+	}
+
+	logger.Info("User logged in successfully with telegram", zap.Int64("user_id", user.ID))
+	return result, nil
+}
+
+// LinkTelegram привязывает аккаунт Telegram к пользователю по данным виджета Telegram Login.
+// Повторная привязка того же аккаунта не считается ошибкой.
+func (s *AuthService) LinkTelegram(ctx context.Context, userID int64, data *models.TelegramLogin) error {
+	const op = "service.Auth.LinkTelegram"
+	logger := s.logger.With(zap.String("op", op))
+
+	if err := s.checkTelegramLogin(data); err != nil {
+		logger.Info("invalid telegram login data", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	subject := strconv.FormatInt(data.ID, 10)
+	linked, err := s.repo.GetUserByIdentity(ctx, models.IdentityProviderTelegram, subject)
+	if err == nil {
+		if linked.ID == userID {
+			return nil
+		}
+		logger.Info("telegram account is linked to another user", zap.Int64("user_id", userID), zap.Int64("telegram_id", data.ID))
+		return errors.NewAlreadyExists("telegram account is linked to another user", nil)
+	} else if !errors.IsNotFound(err) {
+		logger.Error("cannot get user by telegram account", zap.Int64("telegram_id", data.ID), zap.Error(err))
+		return err
+	}
+
+	if err := s.repo.LinkIdentity(ctx, models.IdentityProviderTelegram, subject, userID, ""); err != nil {
+		if errors.IsAlreadyExists(err) {
+			return errors.NewAlreadyExists("another telegram account is already linked", err)
+		}
+		logger.Error("cannot link telegram account", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+
+	logger.Info("Telegram account linked", zap.Int64("user_id", userID), zap.Int64("telegram_id", data.ID))
+	return nil
+}
+
+// checkTelegramLogin проверяет подпись и срок данных виджета Telegram Login
+func (s *AuthService) checkTelegramLogin(data *models.TelegramLogin) error {
+	if s.telegramBotToken == "" {
+		return errors.NewNotFound("telegram login is not configured", nil)
+	}
+	if data.ID <= 0 || data.Hash == "" {
+		return errors.NewBadRequest("id and hash are required", nil)
+	}
+	if err := telegram.CheckAuthorization(s.telegramBotToken, data.Fields(), time.Now(), s.telegramAuthMaxAge); err != nil {
+		return errors.NewUnauthorized("invalid telegram login data", err)
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockAchievementRepository реализует интерфейс repository.AchievementRepository для тестирования.
+type MockAchievementRepository struct {
+	createAchievementFunc func(ctx context.Context, achievement *models.AchievementCreate) (int64, error)
+	awardAchievementsFunc func(ctx context.Context, userID int64) ([]models.UserAchievement, error)
+}
+
+func (m *MockAchievementRepository) CreateAchievement(ctx context.Context, achievement *models.AchievementCreate) (int64, error) {
+	return m.createAchievementFunc(ctx, achievement)
+}
+
+func (m *MockAchievementRepository) GetAchievements(ctx context.Context) ([]models.Achievement, error) {
+	return nil, nil
+}
+
+func (m *MockAchievementRepository) GetUserAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	return nil, nil
+}
+
+func (m *MockAchievementRepository) AwardAchievements(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+	return m.awardAchievementsFunc(ctx, userID)
+}
+
+func TestCreateAchievement(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		repo          *MockAchievementRepository
+		req           *models.AchievementCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success with default bonus currency",
+			repo: &MockAchievementRepository{
+				createAchievementFunc: func(ctx context.Context, achievement *models.AchievementCreate) (int64, error) {
+					assert.Equal(t, models.CurrencyCoins, achievement.BonusCurrency)
+					return 3, nil
+				},
+			},
+			req: &models.AchievementCreate{Code: "fifty_tasks", Title: "50 tasks",
+				Metric: models.AchievementMetricCompletedTasks, Threshold: 50, Bonus: 100},
+			expectedID:    3,
+			expectedError: nil,
+		},
+		{
+			name:          "unknown metric",
+			repo:          &MockAchievementRepository{},
+			req:           &models.AchievementCreate{Code: "rich", Title: "Rich", Metric: "balance", Threshold: 1},
+			expectedID:    0,
+			expectedError: errors.NewValidation("unknown metric", nil),
+		},
+		{
+			name:          "zero threshold",
+			repo:          &MockAchievementRepository{},
+			req:           &models.AchievementCreate{Code: "none", Title: "None", Metric: models.AchievementMetricReferrals},
+			expectedID:    0,
+			expectedError: errors.NewValidation("minimum value for the Threshold field is 1", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewAchievementService(tt.repo, logger)
+			id, err := service.CreateAchievement(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestCompleteTaskEvaluatesAchievements(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	var evaluated []int64
+	achievements := service2.NewAchievementService(&MockAchievementRepository{
+		awardAchievementsFunc: func(ctx context.Context, userID int64) ([]models.UserAchievement, error) {
+			evaluated = append(evaluated, userID)
+			return nil, errors.NewInternal("repo error", nil)
+		},
+	}, logger)
+	tasks := &MockRepository{
+		completeTaskFunc: func(ctx context.Context, userId, taskId int64) error {
+			return nil
+		},
+	}
+
+	service := service2.NewTaskService(tasks, logger, achievements)
+	// Ошибка выдачи достижений не отменяет выполнение задания
+	assert.NoError(t, service.CompleteTask(ctx, 7, 1))
+	assert.Equal(t, []int64{7}, evaluated)
+}
@@ -0,0 +1,218 @@
+package tests
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/handlers"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/gorilla/mux"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockAPIKeyRepository хранит API-ключи в памяти для тестирования.
+type MockAPIKeyRepository struct {
+	keys    map[string]*models.APIKey
+	touched []int64
+}
+
+func (m *MockAPIKeyRepository) CreateAPIKey(ctx context.Context, key *models.APIKeyCreate) (models.APIKey, error) {
+	if m.keys == nil {
+		m.keys = map[string]*models.APIKey{}
+	}
+	adminID := key.AdminID
+	apiKey := &models.APIKey{
+		APIKeyID:  int64(len(m.keys) + 1),
+		Name:      key.Name,
+		Prefix:    key.Prefix,
+		Scopes:    key.Scopes,
+		CreatedBy: &adminID,
+		CreatedAt: time.Now(),
+	}
+	m.keys[key.KeyHash] = apiKey
+	return *apiKey, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeys(ctx context.Context) ([]models.APIKey, error) {
+	var keys []models.APIKey
+	for _, key := range m.keys {
+		keys = append(keys, *key)
+	}
+	return keys, nil
+}
+
+func (m *MockAPIKeyRepository) GetAPIKeyByHash(ctx context.Context, keyHash string) (models.APIKey, error) {
+	if key, ok := m.keys[keyHash]; ok {
+		return *key, nil
+	}
+	return models.APIKey{}, errors.NewNotFound("api key not found", nil)
+}
+
+func (m *MockAPIKeyRepository) RevokeAPIKey(ctx context.Context, apiKeyID int64) error {
+	for _, key := range m.keys {
+		if key.APIKeyID == apiKeyID {
+			now := time.Now()
+			key.RevokedAt = &now
+			return nil
+		}
+	}
+	return errors.NewNotFound("api key not found", nil)
+}
+
+func (m *MockAPIKeyRepository) TouchAPIKey(ctx context.Context, apiKeyID int64) error {
+	m.touched = append(m.touched, apiKeyID)
+	return nil
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name           string
+		req            *models.APIKeyCreate
+		expectedScopes []string
+		expectedError  error
+	}{
+		{
+			name:           "scopes are normalised",
+			req:            &models.APIKeyCreate{Name: " telegram bot ", Scopes: []string{"tasks:complete", " Users:Read", "tasks:complete"}},
+			expectedScopes: []string{models.ScopeTasksComplete, models.ScopeUsersRead},
+		},
+		{
+			name:          "empty name",
+			req:           &models.APIKeyCreate{Name: " ", Scopes: []string{"users:read"}},
+			expectedError: errors.NewValidation("api key name cannot be empty", nil),
+		},
+		{
+			name:          "unknown scope",
+			req:           &models.APIKeyCreate{Name: "bot", Scopes: []string{"users:write"}},
+			expectedError: errors.NewValidation(`unknown scope "users:write"`, nil),
+		},
+		{
+			name:          "no scopes",
+			req:           &models.APIKeyCreate{Name: "bot"},
+			expectedError: errors.NewValidation("at least one scope is required", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockAPIKeyRepository{}
+			service := service2.NewAPIKeyService(repo, logger)
+			created, err := service.CreateAPIKey(ctx, tt.req)
+			assert.Equal(t, tt.expectedError, err)
+			if err != nil {
+				assert.Empty(t, repo.keys)
+				return
+			}
+			assert.Equal(t, "telegram bot", created.Name)
+			assert.Equal(t, tt.expectedScopes, created.Scopes)
+			assert.True(t, strings.HasPrefix(created.Key, "utr_"))
+			assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+			assert.Len(t, created.Prefix, 12)
+
+			// в хранилище попадает только хэш ключа
+			sum := sha256.Sum256([]byte(created.Key))
+			assert.Contains(t, repo.keys, hex.EncodeToString(sum[:]))
+		})
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	repo := &MockAPIKeyRepository{}
+	service := service2.NewAPIKeyService(repo, logger)
+
+	created, err := service.CreateAPIKey(ctx, &models.APIKeyCreate{Name: "bot", Scopes: []string{"tasks:complete"}, AdminID: 1})
+	assert.NoError(t, err)
+
+	apiKey, err := service.AuthenticateAPIKey(ctx, created.Key)
+	assert.NoError(t, err)
+	assert.Equal(t, created.APIKeyID, apiKey.APIKeyID)
+	assert.True(t, apiKey.HasScope(models.ScopeTasksComplete))
+	assert.False(t, apiKey.HasScope(models.ScopeUsersRead))
+	assert.Equal(t, []int64{created.APIKeyID}, repo.touched)
+
+	invalid := errors.NewUnauthorized("invalid api key", nil)
+	_, err = service.AuthenticateAPIKey(ctx, created.Key+"0")
+	assert.Equal(t, invalid, err)
+	_, err = service.AuthenticateAPIKey(ctx, strings.TrimPrefix(created.Key, "utr_"))
+	assert.Equal(t, invalid, err)
+
+	assert.NoError(t, service.RevokeAPIKey(ctx, created.APIKeyID))
+	_, err = service.AuthenticateAPIKey(ctx, created.Key)
+	assert.Equal(t, invalid, err)
+	assert.Len(t, repo.touched, 1)
+
+	assert.True(t, errors.IsNotFound(service.RevokeAPIKey(ctx, 42)))
+}
+
+func TestAPIKeyMiddlewareScopes(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	service := service2.NewAPIKeyService(&MockAPIKeyRepository{}, logger)
+
+	readUsers, err := service.CreateAPIKey(ctx, &models.APIKeyCreate{Name: "bot", Scopes: []string{models.ScopeUsersRead}, AdminID: 1})
+	assert.NoError(t, err)
+	readTransactions, err := service.CreateAPIKey(ctx, &models.APIKeyCreate{Name: "bot", Scopes: []string{models.ScopeTransactionsRead}, AdminID: 1})
+	assert.NoError(t, err)
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+	router := mux.NewRouter()
+	router.Use(handlers.APIKeyMiddleware(service, logger))
+	router.Handle("/api/users/{user_id}/status", ok).Methods(http.MethodGet)
+	router.Handle("/api/users/{user_id}/transactions", ok).Methods(http.MethodGet)
+
+	tests := []struct {
+		name           string
+		key            string
+		path           string
+		expectedStatus int
+	}{
+		{
+			name:           "users:read opens status",
+			key:            readUsers.Key,
+			path:           "/api/users/42/status",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "users:read does not open transactions",
+			key:            readUsers.Key,
+			path:           "/api/users/42/transactions",
+			expectedStatus: http.StatusForbidden,
+		},
+		{
+			name:           "transactions:read opens transactions",
+			key:            readTransactions.Key,
+			path:           "/api/users/42/transactions",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "transactions:read does not open status",
+			key:            readTransactions.Key,
+			path:           "/api/users/42/status",
+			expectedStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("X-API-Key", tt.key)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+			assert.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,758 @@
+package tests
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/mail"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/authtoken"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/password"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MockAuthRepository реализует интерфейс repository.AuthRepository для тестирования.
+type MockAuthRepository struct {
+	createUserFunc  func(ctx context.Context, user *models.CreateUser) (int64, error)
+	getUserByIDFunc func(ctx context.Context, userID int64) (*models.User, error)
+	verifyEmailFunc func(ctx context.Context, tokenID string) (int64, error)
+	// users пользователи по имени и по адресу электронной почты
+	users         map[string]*models.User
+	tokenVersion  int
+	resetPassword func(ctx context.Context, tokenID, passwordHash string) (int64, error)
+	// passwordHashes хэши, сохраненные ChangePassword и UpdatePasswordHash
+	passwordHashes []string
+	tokens         []models.AuthToken
+	// loginFailures и loginBlocks неудачные попытки и блокировки входа по ключу "scope/key"
+	loginFailures map[string]int
+	loginBlocks   map[string]time.Duration
+	// usedTokens идентификаторы использованных одноразовых токенов
+	usedTokens map[string]bool
+	twoFactor  models.TwoFactor
+	// recoveryCodes хэши резервных кодов и признак использования
+	recoveryCodes map[string]bool
+	// identities привязанные учетные записи внешних провайдеров: "provider/subject" -> ID пользователя
+	identities    map[string]int64
+	externalUsers []models.ExternalUserCreate
+	oidcStates    map[string]models.OIDCState
+	// profiles профили по ID пользователя; удаленные аккаунты убираются из profiles в deletedUsers
+	profiles     map[int64]*models.Profile
+	deletedUsers []int64
+}
+
+func (m *MockAuthRepository) CreateUser(ctx context.Context, user *models.CreateUser) (int64, error) {
+	return m.createUserFunc(ctx, user)
+}
+
+func (m *MockAuthRepository) GetUser(ctx context.Context, req *models.SignIn) (*models.User, error) {
+	return nil, nil
+}
+
+func (m *MockAuthRepository) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	if user, ok := m.users[username]; ok {
+		return user, nil
+	}
+	return nil, errors.NewNotFound("User not found", nil)
+}
+
+func (m *MockAuthRepository) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	return m.GetUserByUsername(ctx, email)
+}
+
+func (m *MockAuthRepository) GetTokenVersion(ctx context.Context, userID int64) (int, error) {
+	return m.tokenVersion, nil
+}
+
+func (m *MockAuthRepository) ChangePassword(ctx context.Context, userID int64, passwordHash string) (int, error) {
+	m.passwordHashes = append(m.passwordHashes, passwordHash)
+	m.tokenVersion++
+	return m.tokenVersion, nil
+}
+
+func (m *MockAuthRepository) UpdatePasswordHash(ctx context.Context, userID int64, passwordHash string) error {
+	m.passwordHashes = append(m.passwordHashes, passwordHash)
+	return nil
+}
+
+func (m *MockAuthRepository) ResetPassword(ctx context.Context, tokenID, passwordHash string) (int64, error) {
+	return m.resetPassword(ctx, tokenID, passwordHash)
+}
+
+func (m *MockAuthRepository) GetUserByID(ctx context.Context, userID int64) (*models.User, error) {
+	return m.getUserByIDFunc(ctx, userID)
+}
+
+func (m *MockAuthRepository) SaveAuthToken(ctx context.Context, token *models.AuthToken) error {
+	m.tokens = append(m.tokens, *token)
+	return nil
+}
+
+func (m *MockAuthRepository) VerifyEmail(ctx context.Context, tokenID string) (int64, error) {
+	return m.verifyEmailFunc(ctx, tokenID)
+}
+
+func (m *MockAuthRepository) RecordLoginFailure(ctx context.Context, scope, key string, window time.Duration) (int, error) {
+	if m.loginFailures == nil {
+		m.loginFailures = map[string]int{}
+	}
+	m.loginFailures[scope+"/"+key]++
+	return m.loginFailures[scope+"/"+key], nil
+}
+
+func (m *MockAuthRepository) BlockLogin(ctx context.Context, scope, key string, duration time.Duration) error {
+	if m.loginBlocks == nil {
+		m.loginBlocks = map[string]time.Duration{}
+	}
+	m.loginBlocks[scope+"/"+key] = duration
+	return nil
+}
+
+func (m *MockAuthRepository) GetLoginBlock(ctx context.Context, username, ip string) (time.Duration, error) {
+	remaining := m.loginBlocks[models.LoginScopeUsername+"/"+username]
+	if byIP := m.loginBlocks[models.LoginScopeIP+"/"+ip]; byIP > remaining {
+		remaining = byIP
+	}
+	return remaining, nil
+}
+
+func (m *MockAuthRepository) ResetLoginFailures(ctx context.Context, scope, key string) error {
+	delete(m.loginFailures, scope+"/"+key)
+	delete(m.loginBlocks, scope+"/"+key)
+	return nil
+}
+
+func (m *MockAuthRepository) UseAuthToken(ctx context.Context, tokenID, purpose string) (models.AuthToken, error) {
+	for _, token := range m.tokens {
+		if token.TokenID != tokenID || token.Purpose != purpose {
+			continue
+		}
+		if m.usedTokens[tokenID] {
+			return models.AuthToken{}, errors.NewValidation("token has already been used", nil)
+		}
+		if m.usedTokens == nil {
+			m.usedTokens = map[string]bool{}
+		}
+		m.usedTokens[tokenID] = true
+		return token, nil
+	}
+	return models.AuthToken{}, errors.NewNotFound("token not found", nil)
+}
+
+func (m *MockAuthRepository) GetTwoFactor(ctx context.Context, userID int64) (models.TwoFactor, error) {
+	tf := m.twoFactor
+	for _, used := range m.recoveryCodes {
+		if !used {
+			tf.RecoveryCodesLeft++
+		}
+	}
+	return tf, nil
+}
+
+func (m *MockAuthRepository) SetTOTPSecret(ctx context.Context, userID int64, secret string) error {
+	if m.twoFactor.Enabled {
+		return errors.NewValidation("two-factor authentication is already enabled", nil)
+	}
+	m.twoFactor.Secret = secret
+	return nil
+}
+
+func (m *MockAuthRepository) EnableTwoFactor(ctx context.Context, userID, step int64, codeHashes []string) error {
+	m.twoFactor.Enabled = true
+	m.twoFactor.LastStep = step
+	return m.ReplaceRecoveryCodes(ctx, userID, codeHashes)
+}
+
+func (m *MockAuthRepository) DisableTwoFactor(ctx context.Context, userID int64) error {
+	m.twoFactor = models.TwoFactor{}
+	m.recoveryCodes = nil
+	return nil
+}
+
+func (m *MockAuthRepository) UseTOTPStep(ctx context.Context, userID, step int64) error {
+	if step <= m.twoFactor.LastStep {
+		return errors.NewValidation("code has already been used", nil)
+	}
+	m.twoFactor.LastStep = step
+	return nil
+}
+
+func (m *MockAuthRepository) UseRecoveryCode(ctx context.Context, userID int64, codeHash string) error {
+	if used, ok := m.recoveryCodes[codeHash]; !ok || used {
+		return errors.NewNotFound("recovery code not found", nil)
+	}
+	m.recoveryCodes[codeHash] = true
+	return nil
+}
+
+func (m *MockAuthRepository) UseLoginChallenge(ctx context.Context, tokenID string, userID int64, factor models.SecondFactor) (bool, error) {
+	found := false
+	for _, token := range m.tokens {
+		if token.TokenID == tokenID && token.Purpose == models.TokenPurposeLoginChallenge {
+			found = true
+		}
+	}
+	if !found {
+		return false, errors.NewNotFound("token not found", nil)
+	}
+	if m.usedTokens[tokenID] {
+		return false, errors.NewValidation("token has already been used", nil)
+	}
+
+	if factor.RecoveryCodeHash != "" {
+		if used, ok := m.recoveryCodes[factor.RecoveryCodeHash]; !ok || used {
+			return false, nil
+		}
+		m.recoveryCodes[factor.RecoveryCodeHash] = true
+	} else {
+		if factor.Step <= m.twoFactor.LastStep {
+			return false, nil
+		}
+		m.twoFactor.LastStep = factor.Step
+	}
+	_, err := m.UseAuthToken(ctx, tokenID, models.TokenPurposeLoginChallenge)
+	return true, err
+}
+
+func (m *MockAuthRepository) ReplaceRecoveryCodes(ctx context.Context, userID int64, codeHashes []string) error {
+	m.recoveryCodes = make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		m.recoveryCodes[hash] = false
+	}
+	return nil
+}
+
+func (m *MockAuthRepository) GetUserByIdentity(ctx context.Context, provider, subject string) (*models.User, error) {
+	userID, ok := m.identities[provider+"/"+subject]
+	if !ok {
+		return nil, errors.NewNotFound("User not found", nil)
+	}
+	return m.GetUserByID(ctx, userID)
+}
+
+func (m *MockAuthRepository) LinkIdentity(ctx context.Context, provider, subject string, userID int64, email string) error {
+	if _, ok := m.identities[provider+"/"+subject]; ok {
+		return errors.NewAlreadyExists("identity is already linked", nil)
+	}
+	for key, linkedID := range m.identities {
+		if linkedID == userID && strings.HasPrefix(key, provider+"/") {
+			return errors.NewAlreadyExists("identity is already linked", nil)
+		}
+	}
+	if m.identities == nil {
+		m.identities = map[string]int64{}
+	}
+	m.identities[provider+"/"+subject] = userID
+	return nil
+}
+
+func (m *MockAuthRepository) CreateExternalUser(ctx context.Context, user *models.ExternalUserCreate) (int64, error) {
+	if m.users == nil {
+		m.users = map[string]*models.User{}
+	}
+	userID := int64(100 + len(m.externalUsers))
+	created := &models.User{ID: userID, Username: user.Username, Password: user.Password, EmailVerified: user.EmailVerified}
+	m.users[user.Username] = created
+	if user.Email != "" {
+		created.Email = sql.NullString{String: user.Email, Valid: true}
+		m.users[user.Email] = created
+	}
+	m.externalUsers = append(m.externalUsers, *user)
+	return userID, m.LinkIdentity(ctx, user.Provider, user.Subject, userID, user.Email)
+}
+
+func (m *MockAuthRepository) SaveOIDCState(ctx context.Context, state *models.OIDCState) error {
+	if m.oidcStates == nil {
+		m.oidcStates = map[string]models.OIDCState{}
+	}
+	m.oidcStates[state.State] = *state
+	return nil
+}
+
+func (m *MockAuthRepository) UseOIDCState(ctx context.Context, state string) (models.OIDCState, error) {
+	st, ok := m.oidcStates[state]
+	if !ok {
+		return models.OIDCState{}, errors.NewNotFound("login state not found", nil)
+	}
+	delete(m.oidcStates, state)
+	return st, nil
+}
+
+func (m *MockAuthRepository) RevokeAuthTokens(ctx context.Context, userID int64, purpose string) error {
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.Purpose == purpose {
+			if m.usedTokens == nil {
+				m.usedTokens = map[string]bool{}
+			}
+			m.usedTokens[token.TokenID] = true
+		}
+	}
+	return nil
+}
+
+func (m *MockAuthRepository) GetProfile(ctx context.Context, userID int64) (models.Profile, error) {
+	profile, ok := m.profiles[userID]
+	if !ok {
+		return models.Profile{}, errors.NewNotFound("user not found", nil)
+	}
+	result := *profile
+	for _, token := range m.tokens {
+		if token.UserID == userID && token.Purpose == models.TokenPurposeEmailChange && !m.usedTokens[token.TokenID] {
+			result.PendingEmail = token.Email
+		}
+	}
+	return result, nil
+}
+
+func (m *MockAuthRepository) UpdateProfile(ctx context.Context, userID int64, update *models.ProfileUpdate) error {
+	profile, ok := m.profiles[userID]
+	if !ok {
+		return errors.NewNotFound("user not found", nil)
+	}
+	if update.DisplayName != nil {
+		profile.DisplayName = *update.DisplayName
+	}
+	if update.AvatarURL != nil {
+		profile.AvatarURL = *update.AvatarURL
+	}
+	return nil
+}
+
+func (m *MockAuthRepository) ChangeEmail(ctx context.Context, tokenID string) (int64, error) {
+	token, err := m.UseAuthToken(ctx, tokenID, models.TokenPurposeEmailChange)
+	if err != nil {
+		return 0, err
+	}
+	if user, ok := m.users[token.Email]; ok && user.ID != token.UserID {
+		return 0, errors.NewAlreadyExists("email is already in use", nil)
+	}
+	profile, ok := m.profiles[token.UserID]
+	if !ok {
+		return 0, errors.NewNotFound("user not found", nil)
+	}
+	profile.Email = token.Email
+	profile.EmailVerified = true
+	return token.UserID, nil
+}
+
+func (m *MockAuthRepository) DeleteAccount(ctx context.Context, userID int64) error {
+	if _, ok := m.profiles[userID]; !ok {
+		return errors.NewNotFound("user not found", nil)
+	}
+	delete(m.profiles, userID)
+	m.deletedUsers = append(m.deletedUsers, userID)
+	return nil
+}
+
+// MockMailSender запоминает отправленные письма.
+type MockMailSender struct {
+	sent []mail.Message
+}
+
+func (m *MockMailSender) Send(ctx context.Context, msg mail.Message) error {
+	m.sent = append(m.sent, msg)
+	return nil
+}
+
+// mailToken извлекает токен из ссылки в письме
+func mailToken(t *testing.T, msg mail.Message) string {
+	match := regexp.MustCompile(`token=(\S+)`).FindStringSubmatch(msg.Body)
+	if !assert.Len(t, match, 2) {
+		return ""
+	}
+	token, err := url.QueryUnescape(match[1])
+	assert.NoError(t, err)
+	return token
+}
+
+func newAuthService(repo *MockAuthRepository, mailer *MockMailSender, verificationTTL time.Duration) *service2.AuthService {
+	return newAuthServiceWith(repo, mailer, verificationTTL, nil, bcrypt.MinCost)
+}
+
+func newAuthServiceWith(repo *MockAuthRepository, mailer *MockMailSender, verificationTTL time.Duration,
+	policy *password.Policy, bcryptCost int) *service2.AuthService {
+	deps := authDependencies(repo, mailer, verificationTTL)
+	deps.PasswordPolicy = policy
+	deps.BcryptCost = bcryptCost
+	return service2.NewAuthService(deps)
+}
+
+func authDependencies(repo *MockAuthRepository, mailer *MockMailSender, verificationTTL time.Duration) service2.AuthDependencies {
+	logger, _ := zap.NewDevelopment()
+	return service2.AuthDependencies{
+		AuthRepo:             repo,
+		Logger:               logger,
+		SignKey:              "test-key",
+		TokenTTL:             time.Hour,
+		Mailer:               mailer,
+		PublicURL:            "http://localhost:8080/",
+		EmailVerificationTTL: verificationTTL,
+		PasswordResetURL:     "http://localhost:3000/reset-password",
+		PasswordResetTTL:     time.Hour,
+		BcryptCost:           bcrypt.MinCost,
+		TOTPIssuer:           "UserTaskReward",
+		TwoFactorTTL:         5 * time.Minute,
+	}
+}
+
+func TestRegisterEmail(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		email         string
+		expectedEmail string
+		expectedError error
+	}{
+		{
+			name:          "email is normalised",
+			email:         "  John.Doe@Example.COM ",
+			expectedEmail: "john.doe@example.com",
+		},
+		{
+			name:          "missing email",
+			email:         " ",
+			expectedError: errors.NewValidation("email is required", nil),
+		},
+		{
+			name:          "display name is not accepted",
+			email:         "John <john@example.com>",
+			expectedError: errors.NewValidation("invalid email format", nil),
+		},
+		{
+			name:          "domain without dot",
+			email:         "john@example",
+			expectedError: errors.NewValidation("invalid email format", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockAuthRepository{
+				createUserFunc: func(ctx context.Context, user *models.CreateUser) (int64, error) {
+					assert.Equal(t, tt.expectedEmail, user.Email)
+					return 7, nil
+				},
+			}
+			mailer := &MockMailSender{}
+			service := newAuthService(repo, mailer, time.Hour)
+
+			userID, err := service.Register(ctx, &models.CreateUser{Username: "john", Password: "secret-password", Email: tt.email})
+			if tt.expectedError != nil {
+				assert.Equal(t, int64(0), userID)
+				assert.True(t, errors.IsValidation(err))
+				assert.Equal(t, tt.expectedError.Error(), err.Error())
+				assert.Empty(t, mailer.sent)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, int64(7), userID)
+			if assert.Len(t, mailer.sent, 1) {
+				assert.Equal(t, tt.expectedEmail, mailer.sent[0].To)
+				assert.Contains(t, mailer.sent[0].Body, "http://localhost:8080/auth/email/verify?token=")
+			}
+			if assert.Len(t, repo.tokens, 1) {
+				assert.Equal(t, models.TokenPurposeEmailVerification, repo.tokens[0].Purpose)
+				assert.Equal(t, tt.expectedEmail, repo.tokens[0].Email)
+			}
+		})
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &MockAuthRepository{
+		createUserFunc: func(ctx context.Context, user *models.CreateUser) (int64, error) {
+			return 7, nil
+		},
+	}
+	repo.verifyEmailFunc = func(ctx context.Context, tokenID string) (int64, error) {
+		assert.Equal(t, repo.tokens[0].TokenID, tokenID)
+		return 7, nil
+	}
+	mailer := &MockMailSender{}
+	service := newAuthService(repo, mailer, time.Hour)
+
+	_, err := service.Register(ctx, &models.CreateUser{Username: "john", Password: "secret-password", Email: "john@example.com"})
+	assert.NoError(t, err)
+	token := mailToken(t, mailer.sent[0])
+
+	assert.NoError(t, service.VerifyEmail(ctx, token))
+
+	// подделанный токен не принимается
+	tampered := token[:len(token)-2] + "xx"
+	assert.Equal(t, errors.NewValidation("invalid token", authtoken.ErrInvalid), service.VerifyEmail(ctx, tampered))
+
+	assert.True(t, errors.IsBadRequest(service.VerifyEmail(ctx, "")))
+}
+
+func TestVerifyEmailExpired(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &MockAuthRepository{
+		createUserFunc: func(ctx context.Context, user *models.CreateUser) (int64, error) {
+			return 7, nil
+		},
+		verifyEmailFunc: func(ctx context.Context, tokenID string) (int64, error) {
+			t.Fatal("expired token must not reach the repository")
+			return 0, nil
+		},
+	}
+	mailer := &MockMailSender{}
+	service := newAuthService(repo, mailer, -time.Minute)
+
+	_, err := service.Register(ctx, &models.CreateUser{Username: "john", Password: "secret-password", Email: "john@example.com"})
+	assert.NoError(t, err)
+
+	err = service.VerifyEmail(ctx, mailToken(t, mailer.sent[0]))
+	assert.Equal(t, errors.NewValidation("token has expired", authtoken.ErrExpired), err)
+}
+
+func TestResendEmailVerification(t *testing.T) {
+	ctx := context.Background()
+
+	verified := false
+	repo := &MockAuthRepository{
+		getUserByIDFunc: func(ctx context.Context, userID int64) (*models.User, error) {
+			return &models.User{ID: userID, Email: sql.NullString{String: "john@example.com", Valid: true}, EmailVerified: verified}, nil
+		},
+	}
+	mailer := &MockMailSender{}
+	service := newAuthService(repo, mailer, time.Hour)
+
+	assert.NoError(t, service.ResendEmailVerification(ctx, 7))
+	assert.Len(t, mailer.sent, 1)
+
+	verified = true
+	assert.Equal(t, errors.NewValidation("email is already verified", nil), service.ResendEmailVerification(ctx, 7))
+}
+
+func TestForgotPasswordUnknownEmail(t *testing.T) {
+	ctx := context.Background()
+
+	mailer := &MockMailSender{}
+	service := newAuthService(&MockAuthRepository{}, mailer, time.Hour)
+
+	// ответ для незарегистрированного адреса не отличается от ответа для зарегистрированного,
+	// письмо тоже отправляется, но без ссылки на сброс
+	assert.NoError(t, service.ForgotPassword(ctx, "nobody@example.com"))
+	if assert.Len(t, mailer.sent, 1) {
+		assert.Equal(t, "nobody@example.com", mailer.sent[0].To)
+		assert.NotContains(t, mailer.sent[0].Body, "token=")
+	}
+
+	assert.True(t, errors.IsValidation(service.ForgotPassword(ctx, "not an email")))
+}
+
+func TestResetPasswordRevokesSessions(t *testing.T) {
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: 7, Username: "john", Password: string(hash),
+		Email: sql.NullString{String: "john@example.com", Valid: true}}
+	repo := &MockAuthRepository{users: map[string]*models.User{"john": user, "john@example.com": user}}
+	repo.resetPassword = func(ctx context.Context, tokenID, passwordHash string) (int64, error) {
+		assert.Equal(t, repo.tokens[0].TokenID, tokenID)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(passwordHash), []byte("new-password")))
+		user.Password = passwordHash
+		repo.tokenVersion++
+		return user.ID, nil
+	}
+	mailer := &MockMailSender{}
+	service := newAuthService(repo, mailer, time.Hour)
+
+	login, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "old-password"})
+	assert.NoError(t, err)
+	jwtToken := login.Token
+	userID, err := service.ParseToken(ctx, jwtToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+
+	assert.NoError(t, service.ForgotPassword(ctx, " John@Example.com"))
+	if !assert.Len(t, mailer.sent, 1) {
+		return
+	}
+	assert.Contains(t, mailer.sent[0].Body, "http://localhost:3000/reset-password?token=")
+	resetToken := mailToken(t, mailer.sent[0])
+
+	// токен подтверждения адреса не подходит для сброса пароля и наоборот
+	assert.Equal(t, errors.NewValidation("invalid token", authtoken.ErrInvalid), service.VerifyEmail(ctx, resetToken))
+
+	assert.NoError(t, service.ResetPassword(ctx, &models.PasswordResetRequest{Token: resetToken, Password: "new-password"}))
+
+	_, err = service.ParseToken(ctx, jwtToken)
+	assert.True(t, errors.IsInvalidToken(err))
+
+	_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "new-password"})
+	assert.NoError(t, err)
+}
+
+func TestRegisterPasswordPolicy(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &MockAuthRepository{
+		createUserFunc: func(ctx context.Context, user *models.CreateUser) (int64, error) {
+			t.Fatal("rejected password must not reach the repository")
+			return 0, nil
+		},
+	}
+	policy := password.NewPolicy(10, []string{"Password123"})
+	service := newAuthServiceWith(repo, &MockMailSender{}, time.Hour, policy, bcrypt.MinCost)
+
+	_, err := service.Register(ctx, &models.CreateUser{Username: "john", Password: "short", Email: "john@example.com"})
+	assert.Equal(t, errors.NewValidation("password must be at least 10 characters long", nil), err)
+
+	_, err = service.Register(ctx, &models.CreateUser{Username: "john", Password: "password123", Email: "john@example.com"})
+	assert.Equal(t, errors.NewValidation("password is too common, choose another one", nil), err)
+
+	_, err = service.Register(ctx, &models.CreateUser{Username: "john_doe_1", Password: "JOHN_DOE_1", Email: "john@example.com"})
+	assert.Equal(t, errors.NewValidation("password cannot be the same as the username", nil), err)
+}
+
+func TestChangePassword(t *testing.T) {
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: 7, Username: "john", Password: string(hash)}
+	repo := &MockAuthRepository{
+		users: map[string]*models.User{"john": user},
+		getUserByIDFunc: func(ctx context.Context, userID int64) (*models.User, error) {
+			copied := *user
+			return &copied, nil
+		},
+	}
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+
+	login, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "old-password"})
+	assert.NoError(t, err)
+	oldToken := login.Token
+
+	_, err = service.ChangePassword(ctx, 7, &models.PasswordChangeRequest{OldPassword: "wrong-password", NewPassword: "new-password"})
+	assert.Equal(t, errors.NewForbidden("current password is incorrect", nil), err)
+
+	_, err = service.ChangePassword(ctx, 7, &models.PasswordChangeRequest{OldPassword: "old-password", NewPassword: "short"})
+	assert.True(t, errors.IsValidation(err))
+	assert.Empty(t, repo.passwordHashes)
+
+	newToken, err := service.ChangePassword(ctx, 7, &models.PasswordChangeRequest{OldPassword: "old-password", NewPassword: "new-password"})
+	assert.NoError(t, err)
+	if assert.Len(t, repo.passwordHashes, 1) {
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(repo.passwordHashes[0]), []byte("new-password")))
+	}
+
+	// старый токен отозван, новый действует
+	_, err = service.ParseToken(ctx, oldToken)
+	assert.True(t, errors.IsInvalidToken(err))
+	userID, err := service.ParseToken(ctx, newToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+}
+
+func TestLoginRehashesPassword(t *testing.T) {
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	repo := &MockAuthRepository{users: map[string]*models.User{"john": {ID: 7, Username: "john", Password: string(hash)}}}
+
+	// хэш уже с нужной стоимостью не пересчитывается
+	service := newAuthServiceWith(repo, &MockMailSender{}, time.Hour, nil, bcrypt.MinCost)
+	_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "old-password"})
+	assert.NoError(t, err)
+	assert.Empty(t, repo.passwordHashes)
+
+	service = newAuthServiceWith(repo, &MockMailSender{}, time.Hour, nil, bcrypt.MinCost+1)
+	_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "old-password"})
+	assert.NoError(t, err)
+	if assert.Len(t, repo.passwordHashes, 1) {
+		cost, err := bcrypt.Cost([]byte(repo.passwordHashes[0]))
+		assert.NoError(t, err)
+		assert.Equal(t, bcrypt.MinCost+1, cost)
+	}
+}
+
+func TestLoginThrottle(t *testing.T) {
+	ctx := context.Background()
+
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	repo := &MockAuthRepository{users: map[string]*models.User{"john": {ID: 7, Username: "john", Password: string(hash)}}}
+	deps := authDependencies(repo, &MockMailSender{}, time.Hour)
+	deps.LoginThrottle = models.LoginThrottleRules{
+		Window:          time.Hour,
+		BackoffAfter:    2,
+		BackoffBase:     time.Second,
+		BackoffMax:      3 * time.Second,
+		LockoutAfter:    5,
+		LockoutDuration: 30 * time.Minute,
+		IPLockoutAfter:  100,
+	}
+	service := service2.NewAuthService(deps)
+	login := func(password string) error {
+		_, err := service.Login(ctx, &models.SignIn{Username: "john", Password: password, IP: "203.0.113.7"})
+		// снимаем задержку, чтобы проверить следующую попытку
+		delete(repo.loginBlocks, models.LoginScopeUsername+"/john")
+		return err
+	}
+
+	assert.True(t, errors.IsUnauthorized(login("wrong")))
+	assert.Empty(t, repo.loginBlocks)
+	assert.True(t, errors.IsUnauthorized(login("wrong")))
+	// задержка удваивается с каждой попыткой и ограничена BackoffMax
+	for _, expected := range []time.Duration{2 * time.Second, 3 * time.Second} {
+		_, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "wrong"})
+		assert.True(t, errors.IsUnauthorized(err))
+		assert.Equal(t, expected, repo.loginBlocks[models.LoginScopeUsername+"/john"])
+
+		// пока действует задержка, вход невозможен даже с верным паролем
+		_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+		assert.Equal(t, errors.NewTooManyRequests(fmt.Sprintf("too many failed login attempts, try again in %d seconds", expected/time.Second), nil), err)
+		delete(repo.loginBlocks, models.LoginScopeUsername+"/john")
+	}
+
+	_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "wrong"})
+	assert.True(t, errors.IsUnauthorized(err))
+	assert.Equal(t, 30*time.Minute, repo.loginBlocks[models.LoginScopeUsername+"/john"])
+	_, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.True(t, errors.IsTooManyRequests(err))
+
+	repo.getUserByIDFunc = func(ctx context.Context, userID int64) (*models.User, error) {
+		return repo.users["john"], nil
+	}
+	assert.NoError(t, service.UnlockLogin(ctx, 7, "203.0.113.7"))
+	assert.Empty(t, repo.loginBlocks)
+	assert.NoError(t, login("secret-password"))
+}
+
+func TestLoginThrottleUnknownUser(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &MockAuthRepository{}
+	deps := authDependencies(repo, &MockMailSender{}, time.Hour)
+	deps.LoginThrottle = models.LoginThrottleRules{Window: time.Hour, LockoutAfter: 10, LockoutDuration: time.Minute, IPLockoutAfter: 2}
+	service := service2.NewAuthService(deps)
+
+	// несуществующее имя учитывается так же, как неверный пароль
+	for _, username := range []string{"ghost", "nobody"} {
+		_, err := service.Login(ctx, &models.SignIn{Username: username, Password: "secret-password", IP: "203.0.113.7"})
+		assert.Equal(t, errors.NewUnauthorized(errors.ErrorMessage[errors.Unauthorized], nil), err)
+		assert.Equal(t, 1, repo.loginFailures[models.LoginScopeUsername+"/"+username])
+	}
+
+	// после IPLockoutAfter попыток с одного адреса вход с него блокируется для любых имен
+	assert.Equal(t, time.Minute, repo.loginBlocks[models.LoginScopeIP+"/203.0.113.7"])
+	_, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password", IP: "203.0.113.7"})
+	assert.True(t, errors.IsTooManyRequests(err))
+}
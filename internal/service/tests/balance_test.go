@@ -0,0 +1,292 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockBalanceRepository хранит балансы и журнал операций в памяти для тестирования.
+type MockBalanceRepository struct {
+	balances     map[int64]int
+	transactions []models.Transaction
+	expired      map[int64]int
+	broken       map[int64]bool
+}
+
+func (m *MockBalanceRepository) apply(entry models.Transaction) models.Transaction {
+	m.balances[entry.UserID] += entry.Amount
+	entry.TransactionID = int64(len(m.transactions) + 1)
+	entry.BalanceAfter = m.balances[entry.UserID]
+	m.transactions = append(m.transactions, entry)
+	return entry
+}
+
+func (m *MockBalanceRepository) Transfer(ctx context.Context, transfer *models.Transfer, limits models.TransferLimits) (models.TransferResult, error) {
+	for _, userID := range []int64{transfer.FromUserID, transfer.ToUserID} {
+		if _, ok := m.balances[userID]; !ok {
+			return models.TransferResult{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), nil)
+		}
+	}
+	if m.balances[transfer.FromUserID]-transfer.Amount < limits.MinBalance {
+		return models.TransferResult{}, errors.NewValidation(
+			fmt.Sprintf("insufficient balance: at least %d points must remain after transfer", limits.MinBalance), nil)
+	}
+	return models.TransferResult{
+		Outgoing: m.apply(models.Transaction{UserID: transfer.FromUserID, Amount: -transfer.Amount,
+			Kind: models.TransactionTransferOut, CounterpartyID: &transfer.ToUserID}),
+		Incoming: m.apply(models.Transaction{UserID: transfer.ToUserID, Amount: transfer.Amount,
+			Kind: models.TransactionTransferIn, CounterpartyID: &transfer.FromUserID}),
+	}, nil
+}
+
+func (m *MockBalanceRepository) GetUserTransactions(ctx context.Context, userID int64) ([]models.Transaction, error) {
+	var transactions []models.Transaction
+	for _, entry := range m.transactions {
+		if entry.UserID == userID {
+			transactions = append(transactions, entry)
+		}
+	}
+	return transactions, nil
+}
+
+func (m *MockBalanceRepository) AdjustBalance(ctx context.Context, adjustment *models.Adjustment) (models.Transaction, error) {
+	if _, ok := m.balances[adjustment.UserID]; !ok {
+		return models.Transaction{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", adjustment.UserID), nil)
+	}
+	if balance := m.balances[adjustment.UserID]; balance+adjustment.Amount < 0 {
+		return models.Transaction{}, errors.NewValidation(
+			fmt.Sprintf("insufficient balance: cannot deduct %d points from balance %d", -adjustment.Amount, balance), nil)
+	}
+	return m.apply(models.Transaction{UserID: adjustment.UserID, Amount: adjustment.Amount, Kind: models.TransactionAdjustment,
+		Reason: adjustment.Reason, Reference: adjustment.Reference, CreatedBy: &adjustment.AdminID}), nil
+}
+
+func (m *MockBalanceRepository) GetUsersWithExpiredPoints(ctx context.Context) ([]int64, error) {
+	var userIDs []int64
+	for userID := int64(1); userID <= int64(len(m.balances)); userID++ {
+		if m.expired[userID] > 0 {
+			userIDs = append(userIDs, userID)
+		}
+	}
+	return userIDs, nil
+}
+
+func (m *MockBalanceRepository) ExpireUserPoints(ctx context.Context, userID int64) (int, error) {
+	if m.broken[userID] {
+		return 0, errors.NewInternal("failed to lock expired credits", nil)
+	}
+	amount := min(m.expired[userID], max(m.balances[userID], 0))
+	delete(m.expired, userID)
+	if amount > 0 {
+		m.apply(models.Transaction{UserID: userID, Amount: -amount, Kind: models.TransactionExpiry})
+	}
+	return amount, nil
+}
+
+func (m *MockBalanceRepository) GetCurrencies(ctx context.Context) ([]models.Currency, error) {
+	return nil, nil
+}
+
+func newBalanceService(repo *MockBalanceRepository) *service2.BalanceService {
+	logger, _ := zap.NewDevelopment()
+	return service2.NewBalanceService(repo, logger, models.TransferLimits{MinBalance: 10})
+}
+
+func TestTransfer(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		actorID       int64
+		transfer      models.Transfer
+		expectedError error
+	}{
+		{
+			name:     "success",
+			actorID:  1,
+			transfer: models.Transfer{FromUserID: 1, ToUserID: 2, Amount: 40},
+		},
+		{
+			name:          "transfer to yourself",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 1, Amount: 10},
+			expectedError: errors.NewValidation("cannot transfer points to yourself", nil),
+		},
+		{
+			name:          "zero amount",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 2, Amount: 0},
+			expectedError: errors.NewValidation("minimum value for the Amount field is 1", nil),
+		},
+		{
+			name:          "negative amount",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 2, Amount: -5},
+			expectedError: errors.NewValidation("minimum value for the Amount field is 1", nil),
+		},
+		{
+			name:          "missing recipient",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, Amount: 10},
+			expectedError: errors.NewValidation("recipient is required", nil),
+		},
+		{
+			name:          "insufficient balance",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 2, Amount: 41},
+			expectedError: errors.NewValidation("insufficient balance: at least 10 points must remain after transfer", nil),
+		},
+		{
+			name:          "another user's balance",
+			actorID:       2,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 2, Amount: 10},
+			expectedError: errors.NewForbidden("cannot transfer points from another user's balance", nil),
+		},
+		{
+			name:          "unknown recipient",
+			actorID:       1,
+			transfer:      models.Transfer{FromUserID: 1, ToUserID: 3, Amount: 10},
+			expectedError: errors.NewNotFound("user with id 3 not found", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBalanceRepository{balances: map[int64]int{1: 50, 2: 5}}
+			service := newBalanceService(repo)
+
+			result, err := service.Transfer(ctx, tt.actorID, &tt.transfer)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Empty(t, repo.transactions)
+				assert.Equal(t, map[int64]int{1: 50, 2: 5}, repo.balances)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, -40, result.Outgoing.Amount)
+			assert.Equal(t, 10, result.Outgoing.BalanceAfter)
+			assert.Equal(t, 40, result.Incoming.Amount)
+			assert.Equal(t, 45, result.Incoming.BalanceAfter)
+		})
+	}
+}
+
+func TestAdjustBalance(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name            string
+		adjustment      models.Adjustment
+		expectedBalance int
+		expectedError   error
+	}{
+		{
+			name:            "credit",
+			adjustment:      models.Adjustment{UserID: 1, Amount: 25, Reason: models.AdjustmentGoodwill, Reference: "SUPPORT-1024"},
+			expectedBalance: 75,
+		},
+		{
+			name:            "deduction",
+			adjustment:      models.Adjustment{UserID: 1, Amount: -50, Reason: models.AdjustmentFraud},
+			expectedBalance: 0,
+		},
+		{
+			name:          "deduction below zero",
+			adjustment:    models.Adjustment{UserID: 1, Amount: -51, Reason: models.AdjustmentFraud},
+			expectedError: errors.NewValidation("insufficient balance: cannot deduct 51 points from balance 50", nil),
+		},
+		{
+			name:          "zero amount",
+			adjustment:    models.Adjustment{UserID: 1, Amount: 0, Reason: models.AdjustmentCorrection},
+			expectedError: errors.NewValidation("amount cannot be zero", nil),
+		},
+		{
+			name:          "missing reason",
+			adjustment:    models.Adjustment{UserID: 1, Amount: 10},
+			expectedError: errors.NewValidation("reason is required", nil),
+		},
+		{
+			name:          "unknown reason code",
+			adjustment:    models.Adjustment{UserID: 1, Amount: 10, Reason: "because"},
+			expectedError: errors.NewValidation("unknown reason code", nil),
+		},
+		{
+			name: "reference is too long",
+			adjustment: models.Adjustment{UserID: 1, Amount: 10, Reason: models.AdjustmentOther,
+				Reference: strings.Repeat("x", 256)},
+			expectedError: errors.NewValidation("reference cannot be longer than 255 characters", nil),
+		},
+		{
+			name:          "unknown user",
+			adjustment:    models.Adjustment{UserID: 3, Amount: 10, Reason: models.AdjustmentPromotion},
+			expectedError: errors.NewNotFound("user with id 3 not found", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &MockBalanceRepository{balances: map[int64]int{1: 50}}
+			service := newBalanceService(repo)
+			tt.adjustment.AdminID = 9
+
+			entry, err := service.AdjustBalance(ctx, &tt.adjustment)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Empty(t, repo.transactions)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedBalance, entry.BalanceAfter)
+			assert.Equal(t, tt.adjustment.Reason, entry.Reason)
+			assert.Equal(t, int64(9), *entry.CreatedBy)
+		})
+	}
+}
+
+func TestExpirePoints(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("expired credits are deducted", func(t *testing.T) {
+		repo := &MockBalanceRepository{
+			balances: map[int64]int{1: 100, 2: 30, 3: 50},
+			expired:  map[int64]int{1: 40, 2: 60},
+		}
+		service := newBalanceService(repo)
+
+		expired, err := service.ExpirePoints(ctx)
+		assert.NoError(t, err)
+		// У второго пользователя списывается не больше остатка баланса
+		assert.Equal(t, 70, expired)
+		assert.Equal(t, map[int64]int{1: 60, 2: 0, 3: 50}, repo.balances)
+		if assert.Len(t, repo.transactions, 2) {
+			assert.Equal(t, models.TransactionExpiry, repo.transactions[0].Kind)
+		}
+
+		// Повторный запуск ничего не списывает
+		expired, err = service.ExpirePoints(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, expired)
+	})
+
+	t.Run("failure for one user does not stop the others", func(t *testing.T) {
+		repo := &MockBalanceRepository{
+			balances: map[int64]int{1: 100, 2: 100, 3: 100},
+			expired:  map[int64]int{1: 10, 2: 20, 3: 30},
+			broken:   map[int64]bool{1: true},
+		}
+		service := newBalanceService(repo)
+
+		expired, err := service.ExpirePoints(ctx)
+		assert.Equal(t, errors.NewInternal("failed to expire points for 1 of 3 users", nil), err)
+		assert.Equal(t, 50, expired)
+		assert.Equal(t, map[int64]int{1: 100, 2: 80, 3: 70}, repo.balances)
+	})
+}
@@ -0,0 +1,79 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockBoostRepository реализует интерфейс repository.BoostRepository для тестирования.
+type MockBoostRepository struct {
+	createBoostFunc func(ctx context.Context, boost *models.BoostCreate) (int64, error)
+}
+
+func (m *MockBoostRepository) CreateBoost(ctx context.Context, boost *models.BoostCreate) (int64, error) {
+	return m.createBoostFunc(ctx, boost)
+}
+
+func (m *MockBoostRepository) GetBoosts(ctx context.Context, upcomingOnly bool) ([]models.Boost, error) {
+	return nil, nil
+}
+
+func TestCreateBoost(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	start := time.Date(2024, time.June, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(48 * time.Hour)
+	emptyType := ""
+
+	tests := []struct {
+		name          string
+		repo          *MockBoostRepository
+		req           *models.BoostCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success, empty task type means any task",
+			repo: &MockBoostRepository{
+				createBoostFunc: func(ctx context.Context, boost *models.BoostCreate) (int64, error) {
+					assert.Nil(t, boost.TaskType)
+					return 4, nil
+				},
+			},
+			req: &models.BoostCreate{Title: "Double points weekend", MultiplierPercent: 200,
+				TaskType: &emptyType, StartsAt: start, EndsAt: end},
+			expectedID:    4,
+			expectedError: nil,
+		},
+		{
+			name:          "multiplier does not boost",
+			repo:          &MockBoostRepository{},
+			req:           &models.BoostCreate{Title: "No-op", MultiplierPercent: 100, StartsAt: start, EndsAt: end},
+			expectedID:    0,
+			expectedError: errors.NewValidation("multiplier_percent must be greater than 100 and not greater than 1000", nil),
+		},
+		{
+			name:          "ends before start",
+			repo:          &MockBoostRepository{},
+			req:           &models.BoostCreate{Title: "Backwards", MultiplierPercent: 150, StartsAt: end, EndsAt: start},
+			expectedID:    0,
+			expectedError: errors.NewValidation("ends_at must be after starts_at", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewBoostService(tt.repo, logger)
+			id, err := service.CreateBoost(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockCampaignRepository реализует интерфейс repository.CampaignRepository для тестирования.
+type MockCampaignRepository struct {
+	createCampaignFunc       func(ctx context.Context, campaign *models.CampaignCreate) (int64, error)
+	updateCampaignStatusFunc func(ctx context.Context, campaignID int64, status string) (models.Campaign, error)
+}
+
+func (m *MockCampaignRepository) CreateCampaign(ctx context.Context, campaign *models.CampaignCreate) (int64, error) {
+	return m.createCampaignFunc(ctx, campaign)
+}
+
+func (m *MockCampaignRepository) GetCampaigns(ctx context.Context) ([]models.Campaign, error) {
+	return nil, nil
+}
+
+func (m *MockCampaignRepository) UpdateCampaignStatus(ctx context.Context, campaignID int64, status string) (models.Campaign, error) {
+	return m.updateCampaignStatusFunc(ctx, campaignID, status)
+}
+
+func (m *MockCampaignRepository) GetCampaignStats(ctx context.Context, campaignID int64) (models.CampaignStats, error) {
+	return models.CampaignStats{}, nil
+}
+
+func TestCreateCampaign(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	start := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC)
+	end := start.AddDate(0, 1, 0)
+
+	tests := []struct {
+		name          string
+		repo          *MockCampaignRepository
+		req           *models.CampaignCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success",
+			repo: &MockCampaignRepository{
+				createCampaignFunc: func(ctx context.Context, campaign *models.CampaignCreate) (int64, error) {
+					return 4, nil
+				},
+			},
+			req:           &models.CampaignCreate{Title: "Spring onboarding", Budget: 50000, StartsAt: start, EndsAt: end},
+			expectedID:    4,
+			expectedError: nil,
+		},
+		{
+			name:          "no budget",
+			repo:          &MockCampaignRepository{},
+			req:           &models.CampaignCreate{Title: "Free lunch", StartsAt: start, EndsAt: end},
+			expectedID:    0,
+			expectedError: errors.NewValidation("minimum value for the Budget field is 1", nil),
+		},
+		{
+			name:          "ends before start",
+			repo:          &MockCampaignRepository{},
+			req:           &models.CampaignCreate{Title: "Backwards", Budget: 100, StartsAt: end, EndsAt: start},
+			expectedID:    0,
+			expectedError: errors.NewValidation("ends_at must be after starts_at", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewCampaignService(tt.repo, logger)
+			id, err := service.CreateCampaign(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestUpdateCampaignStatus(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name             string
+		repo             *MockCampaignRepository
+		status           string
+		expectedCampaign models.Campaign
+		expectedError    error
+	}{
+		{
+			name: "success",
+			repo: &MockCampaignRepository{
+				updateCampaignStatusFunc: func(ctx context.Context, campaignID int64, status string) (models.Campaign, error) {
+					return models.Campaign{CampaignID: campaignID, Status: status}, nil
+				},
+			},
+			status:           models.CampaignLive,
+			expectedCampaign: models.Campaign{CampaignID: 4, Status: models.CampaignLive},
+			expectedError:    nil,
+		},
+		{
+			name: "invalid transition",
+			repo: &MockCampaignRepository{
+				updateCampaignStatusFunc: func(ctx context.Context, campaignID int64, status string) (models.Campaign, error) {
+					return models.Campaign{}, errors.NewValidation("cannot change campaign status from ended to live", nil)
+				},
+			},
+			status:           models.CampaignLive,
+			expectedCampaign: models.Campaign{},
+			expectedError:    errors.NewValidation("cannot change campaign status from ended to live", nil),
+		},
+		{
+			name:             "unknown status",
+			repo:             &MockCampaignRepository{},
+			status:           "archived",
+			expectedCampaign: models.Campaign{},
+			expectedError:    errors.NewBadRequest("unknown campaign status", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewCampaignService(tt.repo, logger)
+			campaign, err := service.UpdateCampaignStatus(ctx, 4, tt.status)
+			assert.Equal(t, tt.expectedCampaign, campaign)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
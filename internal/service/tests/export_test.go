@@ -0,0 +1,306 @@
+package tests
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockExportRepository хранит данные пользователей и очередь выгрузок в памяти для тестирования.
+type MockExportRepository struct {
+	users    map[int64]models.UserData
+	records  map[int64]int
+	exports  []*models.DataExport
+	contents map[int64][]byte
+	failures map[int64]string
+}
+
+func (m *MockExportRepository) CountUserRecords(ctx context.Context, userID int64) (int, error) {
+	return m.records[userID], nil
+}
+
+func (m *MockExportRepository) GetUserData(ctx context.Context, userID int64) (models.UserData, error) {
+	data, ok := m.users[userID]
+	if !ok {
+		return models.UserData{}, errors.NewNotFound(fmt.Sprintf("user with id %d not found", userID), nil)
+	}
+	data.ExportedAt = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	return data, nil
+}
+
+func (m *MockExportRepository) CreateExport(ctx context.Context, userID int64, format string) (models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.UserID == userID && export.Format == format &&
+			(export.Status == models.ExportStatusPending || export.Status == models.ExportStatusProcessing) {
+			return *export, nil
+		}
+	}
+	export := &models.DataExport{
+		ExportID:  int64(len(m.exports) + 1),
+		UserID:    userID,
+		Format:    format,
+		Status:    models.ExportStatusPending,
+		CreatedAt: time.Now(),
+	}
+	m.exports = append(m.exports, export)
+	return *export, nil
+}
+
+func (m *MockExportRepository) GetExport(ctx context.Context, userID, exportID int64) (models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.ExportID == exportID && export.UserID == userID {
+			return *export, nil
+		}
+	}
+	return models.DataExport{}, errors.NewNotFound("export not found", nil)
+}
+
+func (m *MockExportRepository) GetExportContent(ctx context.Context, exportID int64) ([]byte, error) {
+	return m.contents[exportID], nil
+}
+
+func (m *MockExportRepository) ClaimExport(ctx context.Context, staleAfter time.Duration) (models.DataExport, error) {
+	for _, export := range m.exports {
+		if export.Status == models.ExportStatusPending {
+			export.Status = models.ExportStatusProcessing
+			return *export, nil
+		}
+	}
+	return models.DataExport{}, errors.NewNotFound("no pending exports", nil)
+}
+
+func (m *MockExportRepository) CompleteExport(ctx context.Context, exportID int64, content []byte, ttl time.Duration) error {
+	if m.contents == nil {
+		m.contents = map[int64][]byte{}
+	}
+	m.contents[exportID] = content
+	return m.finishExport(exportID, models.ExportStatusReady, ttl)
+}
+
+func (m *MockExportRepository) FailExport(ctx context.Context, exportID int64, reason string, ttl time.Duration) error {
+	if m.failures == nil {
+		m.failures = map[int64]string{}
+	}
+	m.failures[exportID] = reason
+	return m.finishExport(exportID, models.ExportStatusFailed, ttl)
+}
+
+func (m *MockExportRepository) finishExport(exportID int64, status string, ttl time.Duration) error {
+	for _, export := range m.exports {
+		if export.ExportID == exportID {
+			now := time.Now()
+			expiresAt := now.Add(ttl)
+			export.Status = status
+			export.CompletedAt = &now
+			export.ExpiresAt = &expiresAt
+			return nil
+		}
+	}
+	return errors.NewNotFound("export not found", nil)
+}
+
+func (m *MockExportRepository) DeleteExpiredExports(ctx context.Context) (int64, error) {
+	var deleted int64
+	kept := m.exports[:0]
+	for _, export := range m.exports {
+		if export.ExpiresAt != nil && !export.ExpiresAt.After(time.Now()) {
+			deleted++
+			continue
+		}
+		kept = append(kept, export)
+	}
+	m.exports = kept
+	return deleted, nil
+}
+
+// newExportRepo возвращает репозиторий с пользователем john (ID 1), у которого одно выполнение и одна операция
+func newExportRepo() *MockExportRepository {
+	return &MockExportRepository{
+		users: map[int64]models.UserData{
+			1: {
+				Profile:      models.Profile{UserID: 1, Username: "john", Email: "john@example.com", EmailVerified: true},
+				Balances:     map[string]int{"points": 50},
+				Completions:  []models.TaskCompletion{{CompletionID: 3, UserID: 1, TaskID: 2, TaskTitle: "Follow us"}},
+				Transactions: []models.Transaction{{TransactionID: 4, UserID: 1, Amount: 50, Currency: "points", Kind: "task"}},
+				Referrals:    models.ExportReferrals{ReferCode: "JOHN42", InvitedUserIDs: []int64{5}},
+			},
+		},
+		records: map[int64]int{1: 2},
+	}
+}
+
+func newExportService(repo *MockExportRepository, mailer *MockMailSender) *service2.ExportService {
+	logger, _ := zap.NewDevelopment()
+	return service2.NewExportService(repo, logger, mailer, "http://localhost:8080/", models.ExportRules{
+		SyncMaxRecords: 10,
+		TTL:            72 * time.Hour,
+	})
+}
+
+func TestExportUserData(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("small account is exported as json", func(t *testing.T) {
+		service := newExportService(newExportRepo(), &MockMailSender{})
+
+		result, err := service.ExportUserData(ctx, 1, "")
+		assert.NoError(t, err)
+		assert.Nil(t, result.Export)
+		if !assert.NotNil(t, result.File) {
+			return
+		}
+		assert.Equal(t, "user-1-data-20240301.json", result.File.Name)
+		assert.Equal(t, "application/json", result.File.ContentType)
+
+		var data models.UserData
+		assert.NoError(t, json.Unmarshal(result.File.Content, &data))
+		assert.Equal(t, "john", data.Profile.Username)
+		assert.Equal(t, 50, data.Balances["points"])
+		assert.Len(t, data.Completions, 1)
+		assert.Len(t, data.Transactions, 1)
+		assert.Equal(t, []int64{5}, data.Referrals.InvitedUserIDs)
+	})
+
+	t.Run("zip contains a file per section", func(t *testing.T) {
+		service := newExportService(newExportRepo(), &MockMailSender{})
+
+		result, err := service.ExportUserData(ctx, 1, "ZIP")
+		assert.NoError(t, err)
+		if !assert.NotNil(t, result.File) {
+			return
+		}
+		assert.Equal(t, "user-1-data-20240301.zip", result.File.Name)
+		assert.Equal(t, "application/zip", result.File.ContentType)
+
+		archive, err := zip.NewReader(bytes.NewReader(result.File.Content), int64(len(result.File.Content)))
+		if !assert.NoError(t, err) {
+			return
+		}
+		var names []string
+		for _, f := range archive.File {
+			names = append(names, f.Name)
+		}
+		assert.Equal(t, []string{"profile.json", "completions.json", "transactions.json", "referrals.json", "sessions.json"}, names)
+	})
+
+	t.Run("unknown format", func(t *testing.T) {
+		service := newExportService(newExportRepo(), &MockMailSender{})
+
+		_, err := service.ExportUserData(ctx, 1, "csv")
+		assert.Equal(t, errors.NewValidation(`unknown export format "csv"`, nil), err)
+	})
+
+	t.Run("large account is queued", func(t *testing.T) {
+		repo := newExportRepo()
+		repo.records[1] = 11
+		service := newExportService(repo, &MockMailSender{})
+
+		result, err := service.ExportUserData(ctx, 1, "zip")
+		assert.NoError(t, err)
+		assert.Nil(t, result.File)
+		if !assert.NotNil(t, result.Export) {
+			return
+		}
+		assert.Equal(t, models.ExportStatusPending, result.Export.Status)
+		assert.Equal(t, "http://localhost:8080/api/users/me/exports/1", result.Export.StatusURL)
+		assert.Empty(t, result.Export.DownloadURL)
+
+		// Повторный запрос не ставит в очередь вторую выгрузку
+		again, err := service.ExportUserData(ctx, 1, "zip")
+		assert.NoError(t, err)
+		assert.Equal(t, result.Export.ExportID, again.Export.ExportID)
+		assert.Len(t, repo.exports, 1)
+	})
+}
+
+func TestProcessExports(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("queued export is prepared and downloaded", func(t *testing.T) {
+		repo := newExportRepo()
+		repo.records[1] = 11
+		mailer := &MockMailSender{}
+		service := newExportService(repo, mailer)
+
+		result, err := service.ExportUserData(ctx, 1, "json")
+		if !assert.NoError(t, err) {
+			return
+		}
+		exportID := result.Export.ExportID
+
+		_, err = service.DownloadExport(ctx, 1, exportID)
+		assert.Equal(t, errors.NewValidation("export is not ready yet", nil), err)
+
+		processed, err := service.ProcessExports(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, processed)
+
+		export, err := service.GetExport(ctx, 1, exportID)
+		assert.NoError(t, err)
+		assert.Equal(t, models.ExportStatusReady, export.Status)
+		assert.Equal(t, "http://localhost:8080/api/users/me/exports/1/download", export.DownloadURL)
+		assert.NotNil(t, export.ExpiresAt)
+
+		if assert.Len(t, mailer.sent, 1) {
+			assert.Equal(t, "john@example.com", mailer.sent[0].To)
+			assert.Contains(t, mailer.sent[0].Body, export.DownloadURL)
+		}
+
+		file, err := service.DownloadExport(ctx, 1, exportID)
+		assert.NoError(t, err)
+		assert.Equal(t, "application/json", file.ContentType)
+		assert.Contains(t, string(file.Content), `"username": "john"`)
+
+		// Выгрузка недоступна другим пользователям
+		_, err = service.DownloadExport(ctx, 2, exportID)
+		assert.True(t, errors.IsNotFound(err))
+
+		processed, err = service.ProcessExports(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, processed)
+	})
+
+	t.Run("failed export", func(t *testing.T) {
+		repo := newExportRepo()
+		repo.records[7] = 11
+		mailer := &MockMailSender{}
+		service := newExportService(repo, mailer)
+
+		result, err := service.ExportUserData(ctx, 7, "json")
+		if !assert.NoError(t, err) {
+			return
+		}
+
+		processed, err := service.ProcessExports(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, processed)
+		assert.Contains(t, repo.failures[result.Export.ExportID], "not found")
+		assert.Empty(t, mailer.sent)
+
+		_, err = service.DownloadExport(ctx, 7, result.Export.ExportID)
+		assert.Equal(t, errors.NewValidation("export failed, request a new one", nil), err)
+	})
+
+	t.Run("expired exports are deleted", func(t *testing.T) {
+		repo := newExportRepo()
+		expiresAt := time.Now().Add(-time.Minute)
+		repo.exports = []*models.DataExport{
+			{ExportID: 1, UserID: 1, Format: models.ExportFormatJSON, Status: models.ExportStatusReady, ExpiresAt: &expiresAt},
+		}
+		service := newExportService(repo, &MockMailSender{})
+
+		_, err := service.ProcessExports(ctx)
+		assert.NoError(t, err)
+		assert.Empty(t, repo.exports)
+	})
+}
@@ -0,0 +1,36 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/level"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLevelFor(t *testing.T) {
+	curve := models.LevelCurve{Thresholds: []int{100, 300, 600}}
+
+	tests := []struct {
+		name          string
+		lifetime      int
+		expectedLevel int
+		expectedNext  *int
+	}{
+		{name: "new user", lifetime: 0, expectedLevel: 1, expectedNext: intPtr(100)},
+		{name: "exactly at threshold", lifetime: 100, expectedLevel: 2, expectedNext: intPtr(300)},
+		{name: "between thresholds", lifetime: 450, expectedLevel: 3, expectedNext: intPtr(600)},
+		{name: "last level", lifetime: 10000, expectedLevel: 4, expectedNext: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expectedLevel, level.For(tt.lifetime, curve))
+			assert.Equal(t, tt.expectedNext, level.NextAt(tt.lifetime, curve))
+		})
+	}
+}
+
+func intPtr(v int) *int {
+	return &v
+}
@@ -0,0 +1,356 @@
+package tests
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/oidc"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+// mockOIDCProvider локальный провайдер OpenID Connect: discovery, JWKS и обмен кода на ID-токен
+type mockOIDCProvider struct {
+	server *httptest.Server
+	key    *rsa.PrivateKey
+	// claims данные пользователя в ID-токене, дополняют и переопределяют стандартные
+	claims jwt.MapClaims
+	// signKey ключ подписи ID-токена вместо опубликованного в JWKS
+	signKey *rsa.PrivateKey
+	// nonce и challenge из последней ссылки авторизации
+	nonce     string
+	challenge string
+}
+
+func newMockOIDCProvider(t *testing.T) *mockOIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	p := &mockOIDCProvider{key: key, claims: jwt.MapClaims{}}
+	p.server = httptest.NewServer(http.HandlerFunc(p.serveHTTP))
+	t.Cleanup(p.server.Close)
+	return p
+}
+
+func (p *mockOIDCProvider) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	switch r.URL.Path {
+	case "/.well-known/openid-configuration":
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 p.server.URL,
+			"authorization_endpoint": p.server.URL + "/authorize",
+			"token_endpoint":         p.server.URL + "/token",
+			"jwks_uri":               p.server.URL + "/jwks",
+		})
+	case "/jwks":
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": []map[string]string{{
+			"kid": "test-key",
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}}})
+	case "/token":
+		r.ParseForm()
+		if id, secret, ok := r.BasicAuth(); !ok || id != "client" || secret != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_client"})
+			return
+		}
+		if r.Form.Get("code") != "good-code" || oidc.Challenge(r.Form.Get("code_verifier")) != p.challenge {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer", "id_token": p.idToken()})
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// idToken подписывает ID-токен для последней ссылки авторизации
+func (p *mockOIDCProvider) idToken() string {
+	claims := jwt.MapClaims{
+		"iss":   p.server.URL,
+		"aud":   "client",
+		"sub":   "subject-1",
+		"iat":   time.Now().Unix(),
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"nonce": p.nonce,
+	}
+	for name, value := range p.claims {
+		claims[name] = value
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "test-key"
+	key := p.key
+	if p.signKey != nil {
+		key = p.signKey
+	}
+	signed, _ := token.SignedString(key)
+	return signed
+}
+
+// authorize имитирует вход пользователя у провайдера: запоминает nonce и code_challenge из ссылки и возвращает state
+func (p *mockOIDCProvider) authorize(t *testing.T, link string) string {
+	parsed, err := url.Parse(link)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	query := parsed.Query()
+	assert.Equal(t, p.server.URL+"/authorize", parsed.Scheme+"://"+parsed.Host+parsed.Path)
+	assert.Equal(t, "code", query.Get("response_type"))
+	assert.Equal(t, "client", query.Get("client_id"))
+	assert.Equal(t, "http://localhost:8080/auth/oidc/callback", query.Get("redirect_uri"))
+	assert.Equal(t, "openid email profile", query.Get("scope"))
+	assert.Equal(t, "S256", query.Get("code_challenge_method"))
+	p.nonce = query.Get("nonce")
+	p.challenge = query.Get("code_challenge")
+	return query.Get("state")
+}
+
+// newOIDCService создает AuthService, подключенный к локальному провайдеру
+func newOIDCService(repo *MockAuthRepository, provider *mockOIDCProvider) *service2.AuthService {
+	if repo.getUserByIDFunc == nil {
+		repo.getUserByIDFunc = func(ctx context.Context, userID int64) (*models.User, error) {
+			for _, user := range repo.users {
+				if user.ID == userID {
+					return user, nil
+				}
+			}
+			return nil, errors.NewNotFound("user not found", nil)
+		}
+	}
+	deps := authDependencies(repo, &MockMailSender{}, time.Hour)
+	deps.OIDC = oidc.NewProvider(oidc.Config{
+		Issuer:       provider.server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "http://localhost:8080/auth/oidc/callback",
+		Scopes:       []string{"email", "profile"},
+	}, provider.server.Client())
+	return service2.NewAuthService(deps)
+}
+
+// oidcLogin проходит вход через провайдера целиком и возвращает JWT
+func oidcLogin(t *testing.T, service *service2.AuthService, provider *mockOIDCProvider) (string, error) {
+	result, err := oidcLoginResult(t, service, provider)
+	if err != nil {
+		return "", err
+	}
+	return result.Token, nil
+}
+
+// oidcLoginResult проходит вход через провайдера целиком и возвращает результат обмена кода
+func oidcLoginResult(t *testing.T, service *service2.AuthService, provider *mockOIDCProvider) (*models.LoginResult, error) {
+	ctx := context.Background()
+	login, err := service.OIDCLogin(ctx)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	state := provider.authorize(t, login.URL)
+	assert.Equal(t, login.State, state)
+	return service.OIDCCallback(ctx, &models.OIDCCallback{Code: "good-code", State: state, CookieState: login.State})
+}
+
+func TestPKCEChallenge(t *testing.T) {
+	// Пример из RFC 7636, приложение B
+	assert.Equal(t, "E9Melhoa2OwvFrEMTJguCHaoeK1t8URWbuGJSstw-cM", oidc.Challenge("dBjftJeZ4CVP-mB92K27uhbUJU1p1r_wW1gFWFOEjXk"))
+
+	verifier, err := oidc.NewVerifier()
+	assert.NoError(t, err)
+	assert.Len(t, verifier, 43)
+}
+
+func TestOIDCLoginCreatesUser(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"email": "Jane@Example.com", "email_verified": true, "preferred_username": "jane doe"}
+	repo := &MockAuthRepository{users: map[string]*models.User{}}
+	service := newOIDCService(repo, provider)
+
+	token, err := oidcLogin(t, service, provider)
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), userID)
+	if assert.Len(t, repo.externalUsers, 1) {
+		created := repo.externalUsers[0]
+		assert.Equal(t, "jane_doe", created.Username)
+		assert.Equal(t, "jane@example.com", created.Email)
+		assert.True(t, created.EmailVerified)
+		assert.Equal(t, models.IdentityProviderOIDC, created.Provider)
+		assert.Equal(t, "subject-1", created.Subject)
+	}
+	assert.Empty(t, repo.oidcStates)
+
+	// повторный вход находит привязанного пользователя
+	token, err = oidcLogin(t, service, provider)
+	assert.NoError(t, err)
+	userID, err = service.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), userID)
+	assert.Len(t, repo.externalUsers, 1)
+
+	// занятое имя получает суффикс, неподтвержденный адрес не сохраняется
+	provider.claims = jwt.MapClaims{"sub": "subject-2", "email": "other@example.com", "email_verified": false, "preferred_username": "jane_doe"}
+	_, err = oidcLogin(t, service, provider)
+	assert.NoError(t, err)
+	if assert.Len(t, repo.externalUsers, 2) {
+		assert.Regexp(t, `^jane_doe_\d{4}$`, repo.externalUsers[1].Username)
+		assert.Empty(t, repo.externalUsers[1].Email)
+	}
+}
+
+func TestOIDCLoginLinksVerifiedEmail(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"email": "john@example.com", "email_verified": true}
+	john := &models.User{ID: 7, Username: "john", Email: sql.NullString{String: "john@example.com", Valid: true}, EmailVerified: true}
+	repo := &MockAuthRepository{users: map[string]*models.User{"john": john, "john@example.com": john}}
+	service := newOIDCService(repo, provider)
+
+	token, err := oidcLogin(t, service, provider)
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+	assert.Empty(t, repo.externalUsers)
+	assert.Equal(t, int64(7), repo.identities[models.IdentityProviderOIDC+"/subject-1"])
+
+	// аккаунт с неподтвержденным адресом не привязывается: создается новый пользователь без адреса
+	mallory := &models.User{ID: 8, Username: "mallory", Email: sql.NullString{String: "victim@example.com", Valid: true}}
+	repo.users["mallory"], repo.users["victim@example.com"] = mallory, mallory
+	provider.claims = jwt.MapClaims{"sub": "subject-2", "email": "victim@example.com", "email_verified": true}
+	token, err = oidcLogin(t, service, provider)
+	assert.NoError(t, err)
+	userID, err = service.ParseToken(ctx, token)
+	assert.NoError(t, err)
+	assert.NotEqual(t, int64(8), userID)
+	if assert.Len(t, repo.externalUsers, 1) {
+		assert.Equal(t, "user", repo.externalUsers[0].Username)
+		assert.Empty(t, repo.externalUsers[0].Email)
+	}
+}
+
+func TestOIDCLoginRequiresTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockOIDCProvider(t)
+	provider.claims = jwt.MapClaims{"email": "john@example.com", "email_verified": true}
+	repo := newTwoFactorUser(t)
+	john := repo.users["john"]
+	john.Email, john.EmailVerified = sql.NullString{String: "john@example.com", Valid: true}, true
+	repo.users["john@example.com"] = john
+	service := newOIDCService(repo, provider)
+	_, recoveryCodes := enableTwoFactor(t, service)
+
+	// вход через провайдера, в том числе с привязкой по адресу, не обходит второй шаг
+	result, err := oidcLoginResult(t, service, provider)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token)
+	assert.True(t, result.TwoFactorRequired)
+	assert.Equal(t, int64(7), repo.identities[models.IdentityProviderOIDC+"/subject-1"])
+
+	jwtToken, err := service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: result.ChallengeToken, Code: recoveryCodes[0]})
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, jwtToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+}
+
+func TestOIDCCallbackRejectsInvalidResponses(t *testing.T) {
+	ctx := context.Background()
+	provider := newMockOIDCProvider(t)
+	repo := &MockAuthRepository{users: map[string]*models.User{}}
+	service := newOIDCService(repo, provider)
+
+	invalidState := errors.NewValidation("invalid login state", nil)
+
+	// state не совпадает с cookie браузера
+	login, err := service.OIDCLogin(ctx)
+	assert.NoError(t, err)
+	state := provider.authorize(t, login.URL)
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Code: "good-code", State: state, CookieState: "other"})
+	assert.Equal(t, invalidState, err)
+
+	// ответ провайдера нельзя использовать повторно
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Code: "good-code", State: state, CookieState: state})
+	assert.NoError(t, err)
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Code: "good-code", State: state, CookieState: state})
+	assert.True(t, errors.IsValidation(err))
+
+	// отказ пользователя у провайдера
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Error: "access_denied"})
+	assert.True(t, errors.IsUnauthorized(err))
+
+	// неверный код авторизации
+	login, err = service.OIDCLogin(ctx)
+	assert.NoError(t, err)
+	state = provider.authorize(t, login.URL)
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Code: "bad-code", State: state, CookieState: state})
+	assert.True(t, errors.IsUnauthorized(err))
+
+	// code_verifier не соответствует code_challenge из ссылки
+	login, err = service.OIDCLogin(ctx)
+	assert.NoError(t, err)
+	state = provider.authorize(t, login.URL)
+	provider.challenge = oidc.Challenge("other-verifier")
+	_, err = service.OIDCCallback(ctx, &models.OIDCCallback{Code: "good-code", State: state, CookieState: state})
+	assert.True(t, errors.IsUnauthorized(err))
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	tests := []struct {
+		name    string
+		claims  jwt.MapClaims
+		signKey *rsa.PrivateKey
+	}{
+		{name: "nonce from another login", claims: jwt.MapClaims{"nonce": "other-nonce"}},
+		{name: "another client", claims: jwt.MapClaims{"aud": "other-client"}},
+		{name: "another issuer", claims: jwt.MapClaims{"iss": "https://evil.example.com"}},
+		{name: "expired", claims: jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "no subject", claims: jwt.MapClaims{"sub": ""}},
+		{name: "several audiences without azp", claims: jwt.MapClaims{"aud": []string{"client", "other-client"}}},
+		{name: "unknown signing key", signKey: otherKey},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider.claims, provider.signKey = tt.claims, tt.signKey
+			_, err := oidcLogin(t, service, provider)
+			assert.True(t, errors.IsUnauthorized(err), "got %v", err)
+		})
+	}
+	assert.Len(t, repo.externalUsers, 1)
+
+	disabled := newAuthService(repo, &MockMailSender{}, time.Hour)
+	_, err = disabled.OIDCLogin(ctx)
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestOIDCDiscoveryIssuerMismatch(t *testing.T) {
+	provider := newMockOIDCProvider(t)
+	client := oidc.NewProvider(oidc.Config{Issuer: provider.server.URL + "/other", ClientID: "client"}, provider.server.Client())
+	_, err := client.Discover(context.Background())
+	assert.Error(t, err)
+
+	client = oidc.NewProvider(oidc.Config{Issuer: provider.server.URL + "/", ClientID: "client"}, provider.server.Client())
+	metadata, err := client.Discover(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasSuffix(metadata.TokenEndpoint, "/token"))
+}
@@ -0,0 +1,212 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+// newProfileRepo возвращает репозиторий с пользователем john (ID 1) и занятым адресом jane@example.com
+func newProfileRepo() *MockAuthRepository {
+	return &MockAuthRepository{
+		users: map[string]*models.User{
+			"jane@example.com": {ID: 2, Username: "jane"},
+		},
+		profiles: map[int64]*models.Profile{
+			1: {UserID: 1, Username: "john", Email: "john@example.com", EmailVerified: true},
+		},
+	}
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		req           models.ProfileUpdate
+		expectedName  string
+		expectedURL   string
+		expectedError error
+	}{
+		{
+			name:         "display name and avatar are set",
+			req:          models.ProfileUpdate{DisplayName: strPtr("  John Doe "), AvatarURL: strPtr("https://example.com/a.png")},
+			expectedName: "John Doe",
+			expectedURL:  "https://example.com/a.png",
+		},
+		{
+			name:         "missing fields are not changed",
+			req:          models.ProfileUpdate{AvatarURL: strPtr("")},
+			expectedName: "Johnny",
+		},
+		{
+			name:          "nothing to update",
+			req:           models.ProfileUpdate{},
+			expectedError: errors.NewBadRequest("nothing to update", nil),
+		},
+		{
+			name:          "display name is too long",
+			req:           models.ProfileUpdate{DisplayName: strPtr(strings.Repeat("я", 101))},
+			expectedError: errors.NewValidation("display name cannot be longer than 100 characters", nil),
+		},
+		{
+			name:          "display name with control characters",
+			req:           models.ProfileUpdate{DisplayName: strPtr("John\nDoe")},
+			expectedError: errors.NewValidation("display name contains invalid characters", nil),
+		},
+		{
+			name:          "relative avatar url",
+			req:           models.ProfileUpdate{AvatarURL: strPtr("/avatars/a.png")},
+			expectedError: errors.NewValidation("avatar url must be an absolute http or https url", nil),
+		},
+		{
+			name:          "avatar url with another scheme",
+			req:           models.ProfileUpdate{AvatarURL: strPtr("javascript:alert(1)")},
+			expectedError: errors.NewValidation("avatar url must be an absolute http or https url", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newProfileRepo()
+			repo.profiles[1].DisplayName = "Johnny"
+			repo.profiles[1].AvatarURL = "https://example.com/old.png"
+			service := newAuthService(repo, &MockMailSender{}, time.Hour)
+
+			profile, err := service.UpdateProfile(ctx, 1, &tt.req)
+			if tt.expectedError != nil {
+				assert.Equal(t, tt.expectedError, err)
+				assert.Equal(t, "Johnny", repo.profiles[1].DisplayName)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedName, profile.DisplayName)
+			assert.Equal(t, tt.expectedURL, profile.AvatarURL)
+		})
+	}
+}
+
+func TestChangeEmail(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new email is used after confirmation", func(t *testing.T) {
+		repo := newProfileRepo()
+		mailer := &MockMailSender{}
+		service := newAuthService(repo, mailer, time.Hour)
+
+		profile, err := service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr(" John.New@Example.com")})
+		assert.NoError(t, err)
+		assert.Equal(t, "john@example.com", profile.Email)
+		assert.Equal(t, "john.new@example.com", profile.PendingEmail)
+		if !assert.Len(t, mailer.sent, 1) {
+			return
+		}
+		assert.Equal(t, "john.new@example.com", mailer.sent[0].To)
+		assert.Contains(t, mailer.sent[0].Body, "/auth/email/change?token=")
+
+		token := mailToken(t, mailer.sent[0])
+		assert.NoError(t, service.ConfirmEmailChange(ctx, token))
+		profile, err = service.GetProfile(ctx, 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "john.new@example.com", profile.Email)
+		assert.True(t, profile.EmailVerified)
+		assert.Empty(t, profile.PendingEmail)
+
+		assert.Equal(t, errors.NewValidation("token has already been used", nil), service.ConfirmEmailChange(ctx, token))
+	})
+
+	t.Run("new request cancels the previous link", func(t *testing.T) {
+		repo := newProfileRepo()
+		mailer := &MockMailSender{}
+		service := newAuthService(repo, mailer, time.Hour)
+
+		_, err := service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr("first@example.com")})
+		assert.NoError(t, err)
+		_, err = service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr("second@example.com")})
+		assert.NoError(t, err)
+		if !assert.Len(t, mailer.sent, 2) {
+			return
+		}
+
+		assert.Error(t, service.ConfirmEmailChange(ctx, mailToken(t, mailer.sent[0])))
+		assert.NoError(t, service.ConfirmEmailChange(ctx, mailToken(t, mailer.sent[1])))
+		assert.Equal(t, "second@example.com", repo.profiles[1].Email)
+	})
+
+	t.Run("email of another user", func(t *testing.T) {
+		repo := newProfileRepo()
+		mailer := &MockMailSender{}
+		service := newAuthService(repo, mailer, time.Hour)
+
+		_, err := service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr("jane@example.com"), DisplayName: strPtr("John")})
+		assert.Equal(t, errors.NewAlreadyExists("email is already in use", nil), err)
+		assert.Empty(t, mailer.sent)
+		assert.Empty(t, repo.profiles[1].DisplayName)
+	})
+
+	t.Run("current email is not sent again", func(t *testing.T) {
+		repo := newProfileRepo()
+		mailer := &MockMailSender{}
+		service := newAuthService(repo, mailer, time.Hour)
+
+		_, err := service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr("JOHN@example.com")})
+		assert.NoError(t, err)
+		assert.Empty(t, mailer.sent)
+	})
+
+	t.Run("email cannot be removed", func(t *testing.T) {
+		service := newAuthService(newProfileRepo(), &MockMailSender{}, time.Hour)
+
+		_, err := service.UpdateProfile(ctx, 1, &models.ProfileUpdate{Email: strPtr("")})
+		assert.Equal(t, errors.NewValidation("email is required", nil), err)
+	})
+
+	t.Run("invalid token", func(t *testing.T) {
+		service := newAuthService(newProfileRepo(), &MockMailSender{}, time.Hour)
+
+		assert.Equal(t, errors.NewBadRequest("token is required", nil), service.ConfirmEmailChange(ctx, ""))
+		assert.True(t, errors.IsValidation(service.ConfirmEmailChange(ctx, "not-a-token")))
+	})
+}
+
+func TestDeleteAccount(t *testing.T) {
+	ctx := context.Background()
+
+	repo := newProfileRepo()
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+
+	err := service.DeleteAccount(ctx, 1, &models.AccountDeleteRequest{Confirm: "jane"})
+	assert.Equal(t, errors.NewValidation("confirm must match the username", nil), err)
+	assert.Empty(t, repo.deletedUsers)
+
+	assert.NoError(t, service.DeleteAccount(ctx, 1, &models.AccountDeleteRequest{Confirm: "john"}))
+	assert.Equal(t, []int64{1}, repo.deletedUsers)
+
+	_, err = service.GetProfile(ctx, 1)
+	assert.True(t, errors.IsNotFound(err))
+	assert.True(t, errors.IsNotFound(service.DeleteAccount(ctx, 1, &models.AccountDeleteRequest{Confirm: "john"})))
+}
+
+func TestRegisterReservedUsername(t *testing.T) {
+	repo := &MockAuthRepository{
+		createUserFunc: func(ctx context.Context, user *models.CreateUser) (int64, error) {
+			t.Fatal("reserved username must not reach the repository")
+			return 0, nil
+		},
+	}
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+
+	_, err := service.Register(context.Background(), &models.CreateUser{
+		Username: models.DeletedUsernamePrefix + "7", Password: "securepassword123", Email: "john@example.com",
+	})
+	assert.Equal(t, errors.NewValidation("username is reserved", nil), err)
+}
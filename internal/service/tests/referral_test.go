@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockReferralRepository хранит переходы по реферальным ссылкам в памяти для тестирования.
+type MockReferralRepository struct {
+	owners map[string]int64
+	clicks []models.ReferralClick
+}
+
+func (m *MockReferralRepository) RecordClick(ctx context.Context, click *models.ReferralClick) (int64, error) {
+	if _, ok := m.owners[click.ReferCode]; !ok {
+		return 0, errors.NewNotFound("user with refer_code not found", nil)
+	}
+	click.ClickID = int64(len(m.clicks) + 1)
+	m.clicks = append(m.clicks, *click)
+	return click.ClickID, nil
+}
+
+func (m *MockReferralRepository) GetReferralStats(ctx context.Context, referCode string) (models.ReferralStats, error) {
+	userID, ok := m.owners[referCode]
+	if !ok {
+		return models.ReferralStats{}, errors.NewNotFound("user with refer_code not found", nil)
+	}
+	stats := models.ReferralStats{UserID: userID, ReferCode: referCode}
+	for _, click := range m.clicks {
+		if click.ReferCode == referCode {
+			stats.Clicks++
+		}
+	}
+	return stats, nil
+}
+
+func TestTrackClick(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	t.Run("click gets a random token and redirects to the landing page", func(t *testing.T) {
+		repo := &MockReferralRepository{owners: map[string]int64{"ABC123": 1}}
+		service := service2.NewReferralService(repo, logger, "https://example.com/welcome?utm=x")
+
+		first := models.ReferralClick{ReferCode: "ABC123"}
+		location, err := service.TrackClick(ctx, &first)
+		assert.NoError(t, err)
+		assert.Equal(t, "https://example.com/welcome?ref=ABC123&utm=x", location)
+		assert.Len(t, first.Token, 32)
+
+		second := models.ReferralClick{ReferCode: "ABC123"}
+		_, err = service.TrackClick(ctx, &second)
+		assert.NoError(t, err)
+		assert.NotEqual(t, first.Token, second.Token)
+		assert.Equal(t, first.Token, repo.clicks[0].Token)
+	})
+
+	t.Run("missing code", func(t *testing.T) {
+		service := service2.NewReferralService(&MockReferralRepository{}, logger, "/")
+
+		_, err := service.TrackClick(ctx, &models.ReferralClick{})
+		assert.Equal(t, errors.NewBadRequest("refer_code is required", nil), err)
+	})
+
+	t.Run("unknown code", func(t *testing.T) {
+		service := service2.NewReferralService(&MockReferralRepository{}, logger, "/")
+
+		_, err := service.TrackClick(ctx, &models.ReferralClick{ReferCode: "NOPE"})
+		assert.True(t, errors.IsNotFound(err))
+	})
+
+	t.Run("invalid landing url falls back to root", func(t *testing.T) {
+		repo := &MockReferralRepository{owners: map[string]int64{"ABC123": 1}}
+		service := service2.NewReferralService(repo, logger, "://bad")
+
+		location, err := service.TrackClick(ctx, &models.ReferralClick{ReferCode: "ABC123"})
+		assert.NoError(t, err)
+		assert.Equal(t, "/", location)
+	})
+}
+
+func TestGetReferralStats(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	repo := &MockReferralRepository{owners: map[string]int64{"ABC123": 7}}
+	service := service2.NewReferralService(repo, logger, "/")
+	_, err := service.TrackClick(ctx, &models.ReferralClick{ReferCode: "ABC123"})
+	assert.NoError(t, err)
+
+	stats, err := service.GetReferralStats(ctx, "ABC123")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), stats.UserID)
+	assert.Equal(t, int64(1), stats.Clicks)
+
+	_, err = service.GetReferralStats(ctx, "NOPE")
+	assert.True(t, errors.IsNotFound(err))
+}
@@ -0,0 +1,126 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockRewardRepository реализует интерфейс repository.RewardRepository для тестирования.
+type MockRewardRepository struct {
+	createRewardFunc           func(ctx context.Context, reward *models.RewardCreate) (int64, error)
+	redeemRewardFunc           func(ctx context.Context, userID, rewardID int64) (models.Redemption, error)
+	updateRedemptionStatusFunc func(ctx context.Context, redemptionID int64, status string) (models.Redemption, error)
+}
+
+func (m *MockRewardRepository) CreateReward(ctx context.Context, reward *models.RewardCreate) (int64, error) {
+	return m.createRewardFunc(ctx, reward)
+}
+
+func (m *MockRewardRepository) GetRewards(ctx context.Context) ([]models.Reward, error) {
+	return nil, nil
+}
+
+func (m *MockRewardRepository) RedeemReward(ctx context.Context, userID, rewardID int64) (models.Redemption, error) {
+	return m.redeemRewardFunc(ctx, userID, rewardID)
+}
+
+func (m *MockRewardRepository) GetUserRedemptions(ctx context.Context, userID int64) ([]models.Redemption, error) {
+	return nil, nil
+}
+
+func (m *MockRewardRepository) GetRedemptions(ctx context.Context, status string) ([]models.Redemption, error) {
+	return nil, nil
+}
+
+func (m *MockRewardRepository) UpdateRedemptionStatus(ctx context.Context, redemptionID int64, status string) (models.Redemption, error) {
+	return m.updateRedemptionStatusFunc(ctx, redemptionID, status)
+}
+
+func TestCreateReward(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	negative := -1
+
+	tests := []struct {
+		name          string
+		repo          *MockRewardRepository
+		req           *models.RewardCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success",
+			repo: &MockRewardRepository{
+				createRewardFunc: func(ctx context.Context, reward *models.RewardCreate) (int64, error) {
+					assert.Equal(t, 100, reward.Cost)
+					return 3, nil
+				},
+			},
+			req:        &models.RewardCreate{Title: "sticker pack", Cost: 100},
+			expectedID: 3,
+		},
+		{
+			name:          "zero cost",
+			repo:          &MockRewardRepository{},
+			req:           &models.RewardCreate{Title: "sticker pack", Cost: 0},
+			expectedError: errors.NewValidation("minimum value for the Cost field is 1", nil),
+		},
+		{
+			name:          "negative stock",
+			repo:          &MockRewardRepository{},
+			req:           &models.RewardCreate{Title: "sticker pack", Cost: 100, Stock: &negative},
+			expectedError: errors.NewValidation("stock cannot be negative", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewRewardService(tt.repo, logger)
+			id, err := service.CreateReward(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestUpdateRedemptionStatus(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		repo          *MockRewardRepository
+		status        string
+		expectedError error
+	}{
+		{
+			name: "success",
+			repo: &MockRewardRepository{
+				updateRedemptionStatusFunc: func(ctx context.Context, redemptionID int64, status string) (models.Redemption, error) {
+					return models.Redemption{RedemptionID: redemptionID, Status: status}, nil
+				},
+			},
+			status: models.RedemptionFulfilled,
+		},
+		{
+			name:          "unknown status",
+			repo:          &MockRewardRepository{},
+			status:        "shipped",
+			expectedError: errors.NewBadRequest("unknown redemption status", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewRewardService(tt.repo, logger)
+			_, err := service.UpdateRedemptionStatus(ctx, 1, tt.status)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
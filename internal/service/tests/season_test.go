@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockSeasonRepository реализует интерфейс repository.SeasonRepository для тестирования.
+type MockSeasonRepository struct {
+	createSeasonFunc func(ctx context.Context, season *models.SeasonCreate) (int64, error)
+	closeSeasonFunc  func(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error)
+}
+
+func (m *MockSeasonRepository) CreateSeason(ctx context.Context, season *models.SeasonCreate) (int64, error) {
+	return m.createSeasonFunc(ctx, season)
+}
+
+func (m *MockSeasonRepository) GetSeasons(ctx context.Context) ([]models.Season, error) {
+	return nil, nil
+}
+
+func (m *MockSeasonRepository) GetSeasonStandings(ctx context.Context, seasonID int64) (models.SeasonResult, error) {
+	return models.SeasonResult{}, nil
+}
+
+func (m *MockSeasonRepository) CloseSeason(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error) {
+	return m.closeSeasonFunc(ctx, closing)
+}
+
+func TestCreateSeason(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	future := time.Now().Add(30 * 24 * time.Hour)
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name          string
+		repo          *MockSeasonRepository
+		req           *models.SeasonCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success",
+			repo: &MockSeasonRepository{
+				createSeasonFunc: func(ctx context.Context, season *models.SeasonCreate) (int64, error) {
+					return 3, nil
+				},
+			},
+			req:           &models.SeasonCreate{Title: "Summer", EndsAt: &future},
+			expectedID:    3,
+			expectedError: nil,
+		},
+		{
+			name: "another season is open",
+			repo: &MockSeasonRepository{
+				createSeasonFunc: func(ctx context.Context, season *models.SeasonCreate) (int64, error) {
+					return 0, errors.NewAlreadyExists("another season is still open", nil)
+				},
+			},
+			req:           &models.SeasonCreate{Title: "Autumn"},
+			expectedID:    0,
+			expectedError: errors.NewAlreadyExists("another season is still open", nil),
+		},
+		{
+			name:          "empty title",
+			repo:          &MockSeasonRepository{},
+			req:           &models.SeasonCreate{},
+			expectedID:    0,
+			expectedError: errors.NewValidation("season title cannot be empty", nil),
+		},
+		{
+			name:          "ends in the past",
+			repo:          &MockSeasonRepository{},
+			req:           &models.SeasonCreate{Title: "Late", EndsAt: &past},
+			expectedID:    0,
+			expectedError: errors.NewValidation("ends_at must be in the future", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewSeasonService(tt.repo, logger)
+			id, err := service.CreateSeason(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestCloseSeason(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+	winnerID := int64(10)
+
+	tests := []struct {
+		name           string
+		repo           *MockSeasonRepository
+		closing        *models.SeasonClose
+		expectedResult models.SeasonResult
+		expectedError  error
+	}{
+		{
+			name: "success with prizes",
+			repo: &MockSeasonRepository{
+				closeSeasonFunc: func(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error) {
+					assert.Equal(t, []int{100, 50}, closing.Prizes)
+					return models.SeasonResult{
+						Season:    models.Season{SeasonID: 1, Status: models.SeasonClosed},
+						Standings: []models.SeasonStanding{{Rank: 1, UserID: &winnerID, Username: "alice", Score: 40, Prize: 100}},
+					}, nil
+				},
+			},
+			closing: &models.SeasonClose{SeasonID: 1, Prizes: []int{100, 50}, AdminID: 1},
+			expectedResult: models.SeasonResult{
+				Season:    models.Season{SeasonID: 1, Status: models.SeasonClosed},
+				Standings: []models.SeasonStanding{{Rank: 1, UserID: &winnerID, Username: "alice", Score: 40, Prize: 100}},
+			},
+			expectedError: nil,
+		},
+		{
+			name: "already closed",
+			repo: &MockSeasonRepository{
+				closeSeasonFunc: func(ctx context.Context, closing *models.SeasonClose) (models.SeasonResult, error) {
+					return models.SeasonResult{}, errors.NewAlreadyExists("season is already closed", nil)
+				},
+			},
+			closing:        &models.SeasonClose{SeasonID: 1, AdminID: 1},
+			expectedResult: models.SeasonResult{},
+			expectedError:  errors.NewAlreadyExists("season is already closed", nil),
+		},
+		{
+			name:           "negative prize",
+			repo:           &MockSeasonRepository{},
+			closing:        &models.SeasonClose{SeasonID: 1, Prizes: []int{100, -5}, AdminID: 1},
+			expectedResult: models.SeasonResult{},
+			expectedError:  errors.NewValidation("prize for rank 2 cannot be negative", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewSeasonService(tt.repo, logger)
+			result, err := service.CloseSeason(ctx, tt.closing)
+			assert.Equal(t, tt.expectedResult, result)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
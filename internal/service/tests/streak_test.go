@@ -0,0 +1,91 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/streak"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStreakAdvance(t *testing.T) {
+	rules := models.StreakRules{BonusPercent: 10, MaxBonusPercent: 30, Freezes: 1}
+	day := func(d int) time.Time {
+		return time.Date(2024, time.March, d, 15, 30, 0, 0, time.UTC)
+	}
+	lastDay := func(d int) *time.Time {
+		v := streak.Day(day(d))
+		return &v
+	}
+
+	tests := []struct {
+		name     string
+		current  models.Streak
+		day      time.Time
+		expected models.Streak
+	}{
+		{
+			name:     "first completion",
+			current:  models.Streak{},
+			day:      day(1),
+			expected: models.Streak{Current: 1, Longest: 1, LastActiveDay: lastDay(1), FreezesLeft: 1},
+		},
+		{
+			name:     "same day",
+			current:  models.Streak{Current: 3, Longest: 3, LastActiveDay: lastDay(5)},
+			day:      day(5),
+			expected: models.Streak{Current: 3, Longest: 3, LastActiveDay: lastDay(5), FreezesLeft: 1, BonusPercent: 20},
+		},
+		{
+			name:     "next day with bonus cap",
+			current:  models.Streak{Current: 4, Longest: 6, LastActiveDay: lastDay(5)},
+			day:      day(6),
+			expected: models.Streak{Current: 5, Longest: 6, LastActiveDay: lastDay(6), FreezesLeft: 1, BonusPercent: 30},
+		},
+		{
+			name:    "missed day covered by freeze",
+			current: models.Streak{Current: 2, Longest: 2, LastActiveDay: lastDay(5)},
+			day:     day(7),
+			expected: models.Streak{Current: 3, Longest: 3, LastActiveDay: lastDay(7), FreezesUsed: 1,
+				FreezesLeft: 0, BonusPercent: 20},
+		},
+		{
+			name:     "gap longer than freezes",
+			current:  models.Streak{Current: 2, Longest: 2, LastActiveDay: lastDay(5)},
+			day:      day(8),
+			expected: models.Streak{Current: 1, Longest: 2, LastActiveDay: lastDay(8), FreezesLeft: 1},
+		},
+		{
+			name:     "freeze restored after a week",
+			current:  models.Streak{Current: 6, Longest: 6, LastActiveDay: lastDay(5), FreezesUsed: 1},
+			day:      day(6),
+			expected: models.Streak{Current: 7, Longest: 7, LastActiveDay: lastDay(6), FreezesLeft: 1, BonusPercent: 30},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, streak.Advance(tt.current, tt.day, rules))
+		})
+	}
+}
+
+func TestStreakCurrent(t *testing.T) {
+	rules := models.StreakRules{BonusPercent: 10, MaxBonusPercent: 100, Freezes: 1}
+	last := time.Date(2024, time.March, 5, 0, 0, 0, 0, time.UTC)
+	saved := models.Streak{Current: 4, Longest: 4, LastActiveDay: &last}
+
+	// Пропущенный день еще можно закрыть заморозкой
+	assert.Equal(t, 4, streak.Current(saved, last.AddDate(0, 0, 2), rules).Current)
+	// Пропуск больше доступных заморозок обнуляет серию
+	broken := streak.Current(saved, last.AddDate(0, 0, 3), rules)
+	assert.Equal(t, 0, broken.Current)
+	assert.Equal(t, 0, broken.BonusPercent)
+	assert.Equal(t, 4, broken.Longest)
+}
+
+func TestStreakApply(t *testing.T) {
+	assert.Equal(t, 10, streak.Apply(10, 0))
+	assert.Equal(t, 13, streak.Apply(10, 35))
+}
@@ -3,7 +3,9 @@ package tests
 import (
 	"context"
 	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
@@ -15,7 +17,15 @@ import (
 type MockRepository struct {
 	createTaskFunc   func(ctx context.Context, req *models.TaskCreate) (int64, error)
 	completeTaskFunc func(ctx context.Context, userId, taskId int64) error
-	getAllTasksFunc  func(ctx context.Context) ([]models.Task, error)
+	getAllTasksFunc  func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error)
+
+	// Выполнения заданий, начисления за них и балансы для отмены выполнений
+	completions map[int64]*models.TaskCompletion
+	credits     map[int64][]models.Transaction
+	balances    map[int64]int
+	// Очки сезонов по пользователям и открытые сезоны
+	seasonScores map[int64]map[int64]int
+	openSeasons  map[int64]bool
 }
 
 func (m *MockRepository) CreateTask(ctx context.Context, req *models.TaskCreate) (int64, error) {
@@ -26,13 +36,69 @@ func (m *MockRepository) CompleteTask(ctx context.Context, userId, taskId int64)
 	return m.completeTaskFunc(ctx, userId, taskId)
 }
 
-func (m *MockRepository) GetAllTasks(ctx context.Context) ([]models.Task, error) {
-	return m.getAllTasksFunc(ctx)
+func (m *MockRepository) GetAllTasks(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+	return m.getAllTasksFunc(ctx, filter)
+}
+
+func (m *MockRepository) GetUserCompletions(ctx context.Context, userId int64) ([]models.TaskCompletion, error) {
+	return nil, nil
+}
+
+// RevokeCompletion повторяет поведение репозитория: начисления за выполнение списываются,
+// недостача coins считается от положительного остатка баланса. Балансы ведутся только в coins.
+// Очки уменьшаются в сезоне исходного начисления, если он еще открыт.
+func (m *MockRepository) RevokeCompletion(ctx context.Context, revocation *models.Revocation) (models.RevocationResult, error) {
+	completion, ok := m.completions[revocation.CompletionID]
+	if !ok {
+		return models.RevocationResult{}, errors.NewNotFound("completion not found", nil)
+	}
+	if completion.RevokedAt != nil {
+		return models.RevocationResult{}, errors.NewAlreadyExists("completion is already revoked", nil)
+	}
+
+	result := models.RevocationResult{}
+	for _, credit := range m.credits[revocation.CompletionID] {
+		if available := max(m.balances[credit.UserID], 0); credit.Currency == models.CurrencyCoins && credit.Amount > available {
+			result.Shortfall += credit.Amount - available
+		}
+		if credit.Currency == models.CurrencyCoins {
+			m.balances[credit.UserID] -= credit.Amount
+			if credit.SeasonID != nil && m.openSeasons[*credit.SeasonID] {
+				m.seasonScores[*credit.SeasonID][credit.UserID] -= credit.Amount
+			}
+		}
+		result.Clawbacks = append(result.Clawbacks, models.Transaction{
+			UserID:         credit.UserID,
+			Amount:         -credit.Amount,
+			Currency:       credit.Currency,
+			BalanceAfter:   m.balances[credit.UserID],
+			Kind:           models.TransactionReversal,
+			CounterpartyID: credit.CounterpartyID,
+			CreatedBy:      &revocation.AdminID,
+			SeasonID:       credit.SeasonID,
+		})
+	}
+
+	now := time.Now()
+	completion.RevokedAt = &now
+	completion.RevokedBy = &revocation.AdminID
+	completion.RevokeReason = revocation.Reason
+	result.Completion = *completion
+	return result, nil
+}
+
+func (m *MockRepository) CreateCategory(ctx context.Context, category *models.CategoryCreate) (int64, error) {
+	return 0, nil
+}
+
+func (m *MockRepository) GetCategories(ctx context.Context) ([]models.Category, error) {
+	return nil, nil
 }
 
 func TestCreateTask(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	ctx := context.Background()
+	campaignID := int64(7)
 
 	tests := []struct {
 		name          string
@@ -54,6 +120,26 @@ func TestCreateTask(t *testing.T) {
 			expectedID:    1,
 			expectedError: nil,
 		},
+		{
+			name: "prices in several currencies",
+			repo: &MockRepository{
+				createTaskFunc: func(ctx context.Context, req *models.TaskCreate) (int64, error) {
+					assert.Equal(t, 20, req.Price)
+					assert.Equal(t, map[string]int{"coins": 20, "xp": 100}, req.Prices)
+					return 2, nil
+				},
+			},
+			req:           &models.TaskCreate{Title: "quiz", Prices: map[string]int{"coins": 20, "xp": 100}},
+			expectedID:    2,
+			expectedError: nil,
+		},
+		{
+			name:          "non-positive currency price",
+			repo:          &MockRepository{},
+			req:           &models.TaskCreate{Title: "quiz", Prices: map[string]int{"xp": 0}},
+			expectedID:    0,
+			expectedError: errors.NewValidation("minimum price in currency \"xp\" is 1", nil),
+		},
 		{
 			name:          "invalid request",
 			repo:          &MockRepository{},
@@ -61,6 +147,25 @@ func TestCreateTask(t *testing.T) {
 			expectedID:    0,
 			expectedError: errors.NewValidation("task title cannot be empty", nil),
 		},
+		{
+			name:          "campaign task by non-admin",
+			repo:          &MockRepository{},
+			req:           &models.TaskCreate{Title: "valid title", Price: 10, CampaignID: &campaignID},
+			expectedID:    0,
+			expectedError: errors.NewForbidden("only administrators can add tasks to a campaign", nil),
+		},
+		{
+			name: "campaign task by admin",
+			repo: &MockRepository{
+				createTaskFunc: func(ctx context.Context, req *models.TaskCreate) (int64, error) {
+					assert.Equal(t, &campaignID, req.CampaignID)
+					return 3, nil
+				},
+			},
+			req:           &models.TaskCreate{Title: "valid title", Price: 10, CampaignID: &campaignID, ByAdmin: true},
+			expectedID:    3,
+			expectedError: nil,
+		},
 		{
 			name: "repository error",
 			repo: &MockRepository{
@@ -76,7 +181,7 @@ func TestCreateTask(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := service2.NewTaskService(tt.repo, logger)
+			service := service2.NewTaskService(tt.repo, logger, nil)
 			id, err := service.CreateTask(ctx, tt.req)
 			assert.Equal(t, tt.expectedID, id)
 			assert.Equal(t, tt.expectedError, err)
@@ -123,7 +228,7 @@ func TestCompleteTask(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := service2.NewTaskService(tt.repo, logger)
+			service := service2.NewTaskService(tt.repo, logger, nil)
 			err := service.CompleteTask(ctx, tt.userId, tt.taskId)
 			assert.Equal(t, tt.expectedError, err)
 		})
@@ -137,37 +242,229 @@ func TestGetAllTasks(t *testing.T) {
 	tests := []struct {
 		name          string
 		repo          *MockRepository
-		expectedTasks []models.Task
+		expectedPage  models.TaskPage
 		expectedError error
 	}{
 		{
 			name: "success",
 			repo: &MockRepository{
-				getAllTasksFunc: func(ctx context.Context) ([]models.Task, error) {
+				getAllTasksFunc: func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
 					return []models.Task{{TaskID: 1}}, nil
 				},
 			},
-			expectedTasks: []models.Task{{TaskID: 1}},
+			expectedPage:  models.TaskPage{Tasks: []models.Task{{TaskID: 1}}},
 			expectedError: nil,
 		},
 		{
 			name: "repository error",
 			repo: &MockRepository{
-				getAllTasksFunc: func(ctx context.Context) ([]models.Task, error) {
+				getAllTasksFunc: func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
 					return nil, errors.NewInternal("repo error", nil)
 				},
 			},
-			expectedTasks: nil,
+			expectedPage:  models.TaskPage{},
 			expectedError: errors.NewInternal("repo error", nil),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := service2.NewTaskService(tt.repo, logger)
-			tasks, err := service.GetAllTasks(ctx)
-			assert.Equal(t, tt.expectedTasks, tasks)
+			service := service2.NewTaskService(tt.repo, logger, nil)
+			page, err := service.GetAllTasks(ctx, models.TaskFilter{})
+			assert.Equal(t, tt.expectedPage, page)
 			assert.Equal(t, tt.expectedError, err)
 		})
 	}
 }
+
+func TestGetAllTasksPagination(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tasks := []models.Task{{TaskID: 4, Completions: 9}, {TaskID: 2, Completions: 5}, {TaskID: 7, Completions: 5}}
+	repo := &MockRepository{
+		getAllTasksFunc: func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+			assert.Equal(t, models.TaskSortPopular, filter.Sort)
+			if filter.After == nil {
+				assert.Equal(t, 3, filter.Limit)
+				return tasks, nil
+			}
+			assert.Equal(t, models.TaskCursor{Sort: models.TaskSortPopular, Value: 5, TaskID: 2}, *filter.After)
+			return tasks[2:], nil
+		},
+	}
+	service := service2.NewTaskService(repo, logger, nil)
+
+	page, err := service.GetAllTasks(ctx, models.TaskFilter{Sort: models.TaskSortPopular, Limit: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, tasks[:2], page.Tasks)
+	cursor := page.NextCursor
+	assert.NotEmpty(t, cursor)
+
+	page, err = service.GetAllTasks(ctx, models.TaskFilter{Sort: models.TaskSortPopular, Limit: 2, Cursor: cursor})
+	assert.NoError(t, err)
+	assert.Equal(t, tasks[2:], page.Tasks)
+	assert.Empty(t, page.NextCursor)
+
+	_, err = service.GetAllTasks(ctx, models.TaskFilter{Sort: models.TaskSortNewest, Cursor: "not-a-cursor"})
+	assert.True(t, errors.IsValidation(err))
+
+	// курсор, выданный для другой сортировки, не принимается
+	_, err = service.GetAllTasks(ctx, models.TaskFilter{Sort: models.TaskSortNewest, Cursor: cursor})
+	assert.True(t, errors.IsValidation(err))
+}
+
+func TestGetAllTasksWithoutLimit(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tasks := make([]models.Task, 120)
+	for i := range tasks {
+		tasks[i] = models.Task{TaskID: int64(i + 1)}
+	}
+	repo := &MockRepository{
+		getAllTasksFunc: func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+			assert.Equal(t, 0, filter.Limit)
+			return tasks, nil
+		},
+	}
+	service := service2.NewTaskService(repo, logger, nil)
+
+	// Без limit и cursor список не обрезается
+	page, err := service.GetAllTasks(ctx, models.TaskFilter{})
+	assert.NoError(t, err)
+	assert.Len(t, page.Tasks, 120)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestGetAllTasksValidation(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	repo := &MockRepository{
+		getAllTasksFunc: func(ctx context.Context, filter models.TaskFilter) ([]models.Task, error) {
+			assert.Equal(t, []string{"share", "twitter"}, filter.Tags)
+			assert.Equal(t, "social", filter.Category)
+			return nil, nil
+		},
+	}
+	service := service2.NewTaskService(repo, logger, nil)
+
+	page, err := service.GetAllTasks(ctx, models.TaskFilter{Category: " Social", Tags: []string{" Share", "twitter", "SHARE", ""}})
+	assert.NoError(t, err)
+	assert.Equal(t, []models.Task{}, page.Tasks)
+
+	_, err = service.GetAllTasks(ctx, models.TaskFilter{Sort: "random"})
+	assert.Equal(t, errors.NewValidation(`unknown sort "random"`, nil), err)
+
+	_, err = service.GetAllTasks(ctx, models.TaskFilter{Limit: 101})
+	assert.Equal(t, errors.NewValidation("limit must be between 1 and 100", nil), err)
+}
+
+// newRevocationRepo возвращает репозиторий с выполнением 7 пользователя 2, приглашенного пользователем 1:
+// пользователь получил 100 coins и 50 xp, пригласивший - 10 coins реферального бонуса.
+// Балансы в coins задаются параметрами.
+func newRevocationRepo(userBalance, referrerBalance int) *MockRepository {
+	userID, referrerID := int64(2), int64(1)
+	return &MockRepository{
+		completions: map[int64]*models.TaskCompletion{
+			7: {CompletionID: 7, UserID: userID, TaskID: 3},
+		},
+		credits: map[int64][]models.Transaction{
+			7: {
+				{UserID: referrerID, Amount: 10, Currency: models.CurrencyCoins, Kind: models.TransactionReferralBonus, CounterpartyID: &userID},
+				{UserID: userID, Amount: 100, Currency: models.CurrencyCoins, Kind: models.TransactionTaskReward},
+				{UserID: userID, Amount: 50, Currency: "xp", Kind: models.TransactionTaskReward},
+			},
+		},
+		balances: map[int64]int{referrerID: referrerBalance, userID: userBalance},
+	}
+}
+
+func TestRevokeCompletion(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	t.Run("reward and referral bonus are clawed back", func(t *testing.T) {
+		repo := newRevocationRepo(100, 10)
+		service := service2.NewTaskService(repo, logger, nil)
+
+		result, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: "duplicate account", AdminID: 9})
+		assert.NoError(t, err)
+		assert.Len(t, result.Clawbacks, 3)
+		assert.Equal(t, 0, result.Shortfall)
+		assert.Equal(t, "duplicate account", result.Completion.RevokeReason)
+		assert.Equal(t, int64(9), *result.Completion.RevokedBy)
+
+		referral := result.Clawbacks[0]
+		assert.Equal(t, int64(1), referral.UserID)
+		assert.Equal(t, -10, referral.Amount)
+		assert.Equal(t, int64(2), *referral.CounterpartyID)
+		assert.Equal(t, map[int64]int{1: 0, 2: 0}, repo.balances)
+	})
+
+	t.Run("spent balance goes negative and is reported as shortfall", func(t *testing.T) {
+		repo := newRevocationRepo(30, 4)
+		service := service2.NewTaskService(repo, logger, nil)
+
+		result, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: "fraud", AdminID: 9})
+		assert.NoError(t, err)
+		// 70 coins не хватило у пользователя и 6 у пригласившего; xp в недостачу не входит
+		assert.Equal(t, 76, result.Shortfall)
+		assert.Equal(t, map[int64]int{1: -6, 2: -70}, repo.balances)
+	})
+
+	t.Run("season score is reduced only in the season of the reward", func(t *testing.T) {
+		// Награда получена в закрытом сезоне 1, реферальный бонус - в открытом сезоне 2
+		closedSeason, openSeason := int64(1), int64(2)
+		repo := newRevocationRepo(100, 10)
+		repo.credits[7][0].SeasonID = &openSeason
+		repo.credits[7][1].SeasonID = &closedSeason
+		repo.seasonScores = map[int64]map[int64]int{
+			closedSeason: {2: 100},
+			openSeason:   {1: 25, 2: 40},
+		}
+		repo.openSeasons = map[int64]bool{openSeason: true}
+		service := service2.NewTaskService(repo, logger, nil)
+
+		result, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: "fraud", AdminID: 9})
+		assert.NoError(t, err)
+		assert.Equal(t, &closedSeason, result.Clawbacks[1].SeasonID)
+		// Итоги закрытого сезона и очки пользователя в текущем сезоне не меняются
+		assert.Equal(t, map[int64]map[int64]int{
+			closedSeason: {2: 100},
+			openSeason:   {1: 15, 2: 40},
+		}, repo.seasonScores)
+	})
+
+	t.Run("completion cannot be revoked twice", func(t *testing.T) {
+		repo := newRevocationRepo(100, 10)
+		service := service2.NewTaskService(repo, logger, nil)
+
+		_, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: "fraud", AdminID: 9})
+		assert.NoError(t, err)
+		balances := map[int64]int{1: repo.balances[1], 2: repo.balances[2]}
+
+		_, err = service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: "fraud", AdminID: 9})
+		assert.Equal(t, errors.NewAlreadyExists("completion is already revoked", nil), err)
+		assert.Equal(t, balances, repo.balances)
+	})
+
+	t.Run("unknown completion", func(t *testing.T) {
+		service := service2.NewTaskService(newRevocationRepo(100, 10), logger, nil)
+
+		_, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 8, Reason: "fraud", AdminID: 9})
+		assert.True(t, errors.IsNotFound(err))
+	})
+
+	t.Run("reason is validated", func(t *testing.T) {
+		repo := newRevocationRepo(100, 10)
+		service := service2.NewTaskService(repo, logger, nil)
+
+		_, err := service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, AdminID: 9})
+		assert.Equal(t, errors.NewValidation("reason is required", nil), err)
+		_, err = service.RevokeCompletion(ctx, &models.Revocation{CompletionID: 7, Reason: strings.Repeat("x", 256), AdminID: 9})
+		assert.Equal(t, errors.NewValidation("reason cannot be longer than 255 characters", nil), err)
+		assert.Nil(t, repo.completions[7].RevokedAt)
+	})
+}
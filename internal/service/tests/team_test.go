@@ -0,0 +1,194 @@
+package tests
+
+import (
+	"context"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"testing"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+)
+
+// MockTeamRepository реализует интерфейс repository.TeamRepository для тестирования.
+type MockTeamRepository struct {
+	createTeamFunc          func(ctx context.Context, team *models.TeamCreate) (int64, error)
+	joinTeamFunc            func(ctx context.Context, userID int64, inviteCode string, maxMembers int) (models.Team, error)
+	setCaptainFunc          func(ctx context.Context, captainID, teamID, newCaptainID int64) error
+	getTeamsLeaderboardFunc func(ctx context.Context, currency string) ([]models.TeamStanding, error)
+}
+
+func (m *MockTeamRepository) CreateTeam(ctx context.Context, team *models.TeamCreate) (int64, error) {
+	return m.createTeamFunc(ctx, team)
+}
+
+func (m *MockTeamRepository) JoinTeam(ctx context.Context, userID int64, inviteCode string, maxMembers int) (models.Team, error) {
+	return m.joinTeamFunc(ctx, userID, inviteCode, maxMembers)
+}
+
+func (m *MockTeamRepository) LeaveTeam(ctx context.Context, userID int64) error {
+	return nil
+}
+
+func (m *MockTeamRepository) SetCaptain(ctx context.Context, captainID, teamID, newCaptainID int64) error {
+	return m.setCaptainFunc(ctx, captainID, teamID, newCaptainID)
+}
+
+func (m *MockTeamRepository) RemoveMember(ctx context.Context, captainID, teamID, userID int64) error {
+	return nil
+}
+
+func (m *MockTeamRepository) GetTeam(ctx context.Context, teamID int64) (models.Team, error) {
+	return models.Team{}, nil
+}
+
+func (m *MockTeamRepository) GetUserTeam(ctx context.Context, userID int64) (models.Team, error) {
+	return models.Team{}, nil
+}
+
+func (m *MockTeamRepository) GetTeamsLeaderboard(ctx context.Context, currency string) ([]models.TeamStanding, error) {
+	return m.getTeamsLeaderboardFunc(ctx, currency)
+}
+
+func TestCreateTeam(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		repo          *MockTeamRepository
+		req           *models.TeamCreate
+		expectedID    int64
+		expectedError error
+	}{
+		{
+			name: "success, name is trimmed",
+			repo: &MockTeamRepository{
+				createTeamFunc: func(ctx context.Context, team *models.TeamCreate) (int64, error) {
+					assert.Equal(t, "Night Owls", team.Name)
+					return 5, nil
+				},
+			},
+			req:           &models.TeamCreate{Name: "  Night Owls ", UserID: 1},
+			expectedID:    5,
+			expectedError: nil,
+		},
+		{
+			name: "user already in a team",
+			repo: &MockTeamRepository{
+				createTeamFunc: func(ctx context.Context, team *models.TeamCreate) (int64, error) {
+					return 0, errors.NewAlreadyExists("user is already in a team", nil)
+				},
+			},
+			req:           &models.TeamCreate{Name: "Early Birds", UserID: 1},
+			expectedID:    0,
+			expectedError: errors.NewAlreadyExists("user is already in a team", nil),
+		},
+		{
+			name:          "empty name",
+			repo:          &MockTeamRepository{},
+			req:           &models.TeamCreate{Name: "   ", UserID: 1},
+			expectedID:    0,
+			expectedError: errors.NewValidation("team name cannot be empty", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewTeamService(tt.repo, logger, 10)
+			id, err := service.CreateTeam(ctx, tt.req)
+			assert.Equal(t, tt.expectedID, id)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestJoinTeam(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	repo := &MockTeamRepository{
+		joinTeamFunc: func(ctx context.Context, userID int64, inviteCode string, maxMembers int) (models.Team, error) {
+			assert.Equal(t, "AbCdEf", inviteCode)
+			assert.Equal(t, 3, maxMembers)
+			return models.Team{TeamID: 5, Name: "Night Owls"}, nil
+		},
+	}
+	service := service2.NewTeamService(repo, logger, 3)
+
+	team, err := service.JoinTeam(ctx, 2, "AbCdEf")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), team.TeamID)
+
+	_, err = service.JoinTeam(ctx, 2, "")
+	assert.Equal(t, errors.NewValidation("invite code is required", nil), err)
+}
+
+func TestSetCaptain(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	tests := []struct {
+		name          string
+		repo          *MockTeamRepository
+		captainID     int64
+		newCaptainID  int64
+		expectedError error
+	}{
+		{
+			name: "success",
+			repo: &MockTeamRepository{
+				setCaptainFunc: func(ctx context.Context, captainID, teamID, newCaptainID int64) error {
+					return nil
+				},
+			},
+			captainID:     1,
+			newCaptainID:  2,
+			expectedError: nil,
+		},
+		{
+			name: "not the captain",
+			repo: &MockTeamRepository{
+				setCaptainFunc: func(ctx context.Context, captainID, teamID, newCaptainID int64) error {
+					return errors.NewForbidden("only the team captain can manage the team", nil)
+				},
+			},
+			captainID:     3,
+			newCaptainID:  2,
+			expectedError: errors.NewForbidden("only the team captain can manage the team", nil),
+		},
+		{
+			name:          "already the captain",
+			repo:          &MockTeamRepository{},
+			captainID:     1,
+			newCaptainID:  1,
+			expectedError: errors.NewValidation("user is already the team captain", nil),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := service2.NewTeamService(tt.repo, logger, 10)
+			err := service.SetCaptain(ctx, tt.captainID, 5, tt.newCaptainID)
+			assert.Equal(t, tt.expectedError, err)
+		})
+	}
+}
+
+func TestGetTeamsLeaderboardDefaultsToCoins(t *testing.T) {
+	logger, _ := zap.NewDevelopment()
+	ctx := context.Background()
+
+	repo := &MockTeamRepository{
+		getTeamsLeaderboardFunc: func(ctx context.Context, currency string) ([]models.TeamStanding, error) {
+			assert.Equal(t, models.CurrencyCoins, currency)
+			return []models.TeamStanding{{TeamID: 5, Name: "Night Owls", Members: 3, Score: 120}}, nil
+		},
+	}
+	service := service2.NewTeamService(repo, logger, 10)
+
+	standings, err := service.GetTeamsLeaderboard(ctx, "")
+	assert.NoError(t, err)
+	assert.Len(t, standings, 1)
+}
@@ -0,0 +1,180 @@
+package tests
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/telegram"
+	"github.com/stretchr/testify/assert"
+)
+
+const testBotToken = "123456:ABC-DEF1234ghIkl-zyx57W2v1u123ew11"
+
+// signedTelegramLogin возвращает данные виджета, подписанные ботом testBotToken
+func signedTelegramLogin(id int64, username string, authDate time.Time) *models.TelegramLogin {
+	data := &models.TelegramLogin{ID: id, FirstName: "John", Username: username, AuthDate: authDate.Unix()}
+	data.Hash = telegram.Sign(testBotToken, data.Fields())
+	return data
+}
+
+// newTelegramService создает AuthService с включенным входом через Telegram
+func newTelegramService(repo *MockAuthRepository) *service2.AuthService {
+	repo.getUserByIDFunc = func(ctx context.Context, userID int64) (*models.User, error) {
+		for _, user := range repo.users {
+			if user.ID == userID {
+				return user, nil
+			}
+		}
+		return nil, errors.NewNotFound("user not found", nil)
+	}
+	deps := authDependencies(repo, &MockMailSender{}, time.Hour)
+	deps.TelegramBotToken = testBotToken
+	deps.TelegramAuthMaxAge = 5 * time.Minute
+	return service2.NewAuthService(deps)
+}
+
+func TestTelegramCheckAuthorization(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	fields := map[string]string{"id": "42", "first_name": "John", "username": "john", "auth_date": "1699999000"}
+	fields["hash"] = telegram.Sign(testBotToken, fields)
+	assert.NoError(t, telegram.CheckAuthorization(testBotToken, fields, now, time.Hour))
+
+	tests := []struct {
+		name   string
+		change func(fields map[string]string)
+		token  string
+		now    time.Time
+		want   error
+	}{
+		{name: "another bot", token: "654321:other", want: telegram.ErrInvalidHash},
+		{name: "changed field", change: func(f map[string]string) { f["id"] = "43" }, want: telegram.ErrInvalidHash},
+		{name: "added field", change: func(f map[string]string) { f["last_name"] = "Doe" }, want: telegram.ErrInvalidHash},
+		{name: "malformed hash", change: func(f map[string]string) { f["hash"] = "not-hex" }, want: telegram.ErrInvalidHash},
+		{name: "too old", now: now.Add(2 * time.Hour), want: telegram.ErrExpired},
+		{name: "from the future", now: now.Add(-time.Hour), want: telegram.ErrExpired},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := make(map[string]string, len(fields))
+			for key, value := range fields {
+				changed[key] = value
+			}
+			if tt.change != nil {
+				tt.change(changed)
+			}
+			token, at := testBotToken, now
+			if tt.token != "" {
+				token = tt.token
+			}
+			if !tt.now.IsZero() {
+				at = tt.now
+			}
+			assert.Equal(t, tt.want, telegram.CheckAuthorization(token, changed, at, time.Hour))
+		})
+	}
+}
+
+func TestTelegramLogin(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockAuthRepository{users: map[string]*models.User{"john_doe": {ID: 7, Username: "john_doe"}}}
+	service := newTelegramService(repo)
+
+	result, err := service.TelegramLogin(ctx, signedTelegramLogin(42, "john_doe", time.Now()))
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, result.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), userID)
+	if assert.Len(t, repo.externalUsers, 1) {
+		created := repo.externalUsers[0]
+		// имя из Telegram занято, подбирается имя с суффиксом
+		assert.Regexp(t, `^john_doe_\d{4}$`, created.Username)
+		assert.Empty(t, created.Email)
+		assert.Equal(t, models.IdentityProviderTelegram, created.Provider)
+		assert.Equal(t, "42", created.Subject)
+	}
+
+	// повторный вход находит привязанного пользователя
+	result, err = service.TelegramLogin(ctx, signedTelegramLogin(42, "john_doe", time.Now()))
+	assert.NoError(t, err)
+	userID, err = service.ParseToken(ctx, result.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(100), userID)
+	assert.Len(t, repo.externalUsers, 1)
+
+	// без имени пользователя в Telegram используется имя
+	_, err = service.TelegramLogin(ctx, signedTelegramLogin(43, "", time.Now()))
+	assert.NoError(t, err)
+	if assert.Len(t, repo.externalUsers, 2) {
+		assert.Equal(t, "John", repo.externalUsers[1].Username)
+	}
+
+	forged := signedTelegramLogin(44, "mallory", time.Now())
+	forged.ID = 42
+	_, err = service.TelegramLogin(ctx, forged)
+	assert.True(t, errors.IsUnauthorized(err))
+
+	// данные старше TelegramAuthMaxAge нельзя использовать повторно
+	_, err = service.TelegramLogin(ctx, signedTelegramLogin(42, "john_doe", time.Now().Add(-10*time.Minute)))
+	assert.True(t, errors.IsUnauthorized(err))
+
+	disabled := newAuthService(repo, &MockMailSender{}, time.Hour)
+	_, err = disabled.TelegramLogin(ctx, signedTelegramLogin(42, "john_doe", time.Now()))
+	assert.True(t, errors.IsNotFound(err))
+}
+
+func TestLinkTelegram(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockAuthRepository{users: map[string]*models.User{
+		"john": {ID: 7, Username: "john"},
+		"jane": {ID: 8, Username: "jane"},
+	}}
+	service := newTelegramService(repo)
+
+	assert.NoError(t, service.LinkTelegram(ctx, 7, signedTelegramLogin(42, "john", time.Now())))
+	assert.Equal(t, int64(7), repo.identities[models.IdentityProviderTelegram+"/"+strconv.Itoa(42)])
+	// повторная привязка того же аккаунта
+	assert.NoError(t, service.LinkTelegram(ctx, 7, signedTelegramLogin(42, "john", time.Now())))
+
+	err := service.LinkTelegram(ctx, 8, signedTelegramLogin(42, "john", time.Now()))
+	assert.Equal(t, errors.NewAlreadyExists("telegram account is linked to another user", nil), err)
+
+	err = service.LinkTelegram(ctx, 7, signedTelegramLogin(43, "john2", time.Now()))
+	assert.True(t, errors.IsAlreadyExists(err))
+
+	// вход через Telegram после привязки попадает в существующий аккаунт
+	result, err := service.TelegramLogin(ctx, signedTelegramLogin(42, "john", time.Now()))
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, result.Token)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+	assert.Empty(t, repo.externalUsers)
+
+	tampered := signedTelegramLogin(45, "jane", time.Now())
+	tampered.Username = "john"
+	assert.True(t, errors.IsUnauthorized(service.LinkTelegram(ctx, 8, tampered)))
+}
+
+func TestTelegramLoginRequiresTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	repo := &MockAuthRepository{users: map[string]*models.User{"john": {ID: 7, Username: "john"}}}
+	service := newTelegramService(repo)
+	assert.NoError(t, service.LinkTelegram(ctx, 7, signedTelegramLogin(42, "john", time.Now())))
+	_, recoveryCodes := enableTwoFactor(t, service)
+
+	// вход через Telegram не обходит второй шаг
+	result, err := service.TelegramLogin(ctx, signedTelegramLogin(42, "john", time.Now()))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token)
+	assert.True(t, result.TwoFactorRequired)
+
+	jwtToken, err := service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: result.ChallengeToken, Code: recoveryCodes[0]})
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, jwtToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+}
@@ -0,0 +1,85 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/service/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// rfcSecret секрет из тестовых векторов RFC 6238 ("12345678901234567890") в base32
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// последние 6 цифр восьмизначных кодов SHA1 из приложения B RFC 6238
+	tests := []struct {
+		unix     int64
+		expected string
+	}{
+		{unix: 59, expected: "287082"},
+		{unix: 1111111109, expected: "081804"},
+		{unix: 1111111111, expected: "050471"},
+		{unix: 1234567890, expected: "005924"},
+		{unix: 2000000000, expected: "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := totp.Code(rfcSecret, time.Unix(tt.unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, code)
+	}
+}
+
+func TestTOTPValidate(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+
+	step, ok := totp.Validate(rfcSecret, "050471", now, 0)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// код соседнего периода принимается из-за возможного расхождения часов
+	_, ok = totp.Validate(rfcSecret, "050471", now.Add(totp.Period), 0)
+	assert.True(t, ok)
+	_, ok = totp.Validate(rfcSecret, "050471", now.Add(2*totp.Period), 0)
+	assert.False(t, ok)
+
+	// однажды принятый код повторно не принимается
+	_, ok = totp.Validate(rfcSecret, "050471", now, step)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(rfcSecret, "050472", now, 0)
+	assert.False(t, ok)
+	_, ok = totp.Validate("not base32!", "050471", now, 0)
+	assert.False(t, ok)
+}
+
+func TestTOTPSecretAndURI(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+	assert.Len(t, secret, 32)
+	_, err = totp.Code(secret, time.Now())
+	assert.NoError(t, err)
+
+	assert.Equal(t, "otpauth://totp/UserTaskReward:john%20doe?algorithm=SHA1&digits=6&issuer=UserTaskReward&period=30&secret="+rfcSecret,
+		totp.URI("UserTaskReward", "john doe", rfcSecret))
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := totp.GenerateRecoveryCodes(10)
+	assert.NoError(t, err)
+	assert.Len(t, codes, 10)
+
+	seen := map[string]bool{}
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, code)
+		assert.False(t, seen[code])
+		seen[code] = true
+	}
+
+	// регистр, пробелы и разделитель при вводе не важны
+	hash := totp.HashRecoveryCode(codes[0])
+	assert.Equal(t, hash, totp.HashRecoveryCode(" "+strings.ToUpper(strings.ReplaceAll(codes[0], "-", ""))))
+	assert.NotEqual(t, hash, totp.HashRecoveryCode(codes[1]))
+}
@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	service2 "github.com/ZnNr/user-task-reward-controller/internal/service"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/totp"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newTwoFactorUser создает репозиторий с пользователем john (ID 7) и паролем secret-password
+func newTwoFactorUser(t *testing.T) *MockAuthRepository {
+	hash, err := bcrypt.GenerateFromPassword([]byte("secret-password"), bcrypt.MinCost)
+	assert.NoError(t, err)
+	user := &models.User{ID: 7, Username: "john", Password: string(hash)}
+	return &MockAuthRepository{
+		users: map[string]*models.User{"john": user},
+		getUserByIDFunc: func(ctx context.Context, userID int64) (*models.User, error) {
+			return user, nil
+		},
+	}
+}
+
+// enableTwoFactor подключает двухфакторную аутентификацию и возвращает секрет и резервные коды
+func enableTwoFactor(t *testing.T, service *service2.AuthService) (string, []string) {
+	ctx := context.Background()
+	setup, err := service.SetupTwoFactor(ctx, 7)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	code, err := totp.Code(setup.Secret, time.Now())
+	assert.NoError(t, err)
+	codes, err := service.EnableTwoFactor(ctx, 7, code)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return setup.Secret, codes.Codes
+}
+
+func TestTwoFactorEnrolment(t *testing.T) {
+	ctx := context.Background()
+	repo := newTwoFactorUser(t)
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+
+	_, err := service.EnableTwoFactor(ctx, 7, "123456")
+	assert.Equal(t, errors.NewValidation("two-factor setup has not been started", nil), err)
+
+	setup, err := service.SetupTwoFactor(ctx, 7)
+	assert.NoError(t, err)
+	assert.Contains(t, setup.URI, "otpauth://totp/UserTaskReward:john?")
+	assert.Contains(t, setup.URI, "secret="+setup.Secret)
+
+	// до подтверждения вход проходит без второго шага
+	result, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+
+	_, err = service.EnableTwoFactor(ctx, 7, "abcdef")
+	assert.Equal(t, errors.NewValidation("invalid two-factor code", nil), err)
+
+	code, err := totp.Code(setup.Secret, time.Now())
+	assert.NoError(t, err)
+	codes, err := service.EnableTwoFactor(ctx, 7, code)
+	assert.NoError(t, err)
+	assert.Len(t, codes.Codes, 10)
+
+	status, err := service.GetTwoFactorStatus(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TwoFactorStatus{Enabled: true, RecoveryCodesLeft: 10}, status)
+
+	_, err = service.SetupTwoFactor(ctx, 7)
+	assert.Equal(t, errors.NewValidation("two-factor authentication is already enabled", nil), err)
+}
+
+func TestLoginTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	repo := newTwoFactorUser(t)
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+	_, recoveryCodes := enableTwoFactor(t, service)
+
+	result, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token)
+	assert.True(t, result.TwoFactorRequired)
+	challenge := result.ChallengeToken
+
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: "000000"})
+	assert.Equal(t, errors.NewUnauthorized("invalid two-factor code", nil), err)
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: "forged", Code: recoveryCodes[0]})
+	assert.True(t, errors.IsValidation(err))
+
+	// после неверного кода тот же токен можно использовать повторно
+	jwtToken, err := service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: recoveryCodes[0]})
+	assert.NoError(t, err)
+	userID, err := service.ParseToken(ctx, jwtToken)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(7), userID)
+
+	// успешно использованный токен второго шага одноразовый, и резервный код на нем не расходуется
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: challenge, Code: recoveryCodes[1]})
+	assert.Equal(t, errors.NewValidation("token has already been used", nil), err)
+	assert.False(t, repo.recoveryCodes[totp.HashRecoveryCode(recoveryCodes[1])])
+
+	// резервный код одноразовый
+	result, err = service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.NoError(t, err)
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: result.ChallengeToken, Code: recoveryCodes[0]})
+	assert.Equal(t, errors.NewUnauthorized("invalid two-factor code", nil), err)
+
+	status, err := service.GetTwoFactorStatus(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, 9, status.RecoveryCodesLeft)
+}
+
+func TestLoginTwoFactorTOTPReplay(t *testing.T) {
+	ctx := context.Background()
+	repo := newTwoFactorUser(t)
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+	secret, _ := enableTwoFactor(t, service)
+
+	// код, которым подтверждено подключение, для входа уже не годится
+	code, err := totp.Code(secret, time.Now())
+	assert.NoError(t, err)
+	result, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.NoError(t, err)
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: result.ChallengeToken, Code: code})
+	assert.Equal(t, errors.NewUnauthorized("invalid two-factor code", nil), err)
+
+	// имитируем наступление следующего периода: последний принятый код стал старше проверяемого
+	repo.twoFactor.LastStep = totp.Step(time.Now()) - 2
+	_, err = service.LoginTwoFactor(ctx, &models.TwoFactorLoginRequest{ChallengeToken: result.ChallengeToken, Code: code})
+	assert.NoError(t, err)
+}
+
+func TestDisableTwoFactor(t *testing.T) {
+	ctx := context.Background()
+	repo := newTwoFactorUser(t)
+	service := newAuthService(repo, &MockMailSender{}, time.Hour)
+	_, recoveryCodes := enableTwoFactor(t, service)
+
+	err := service.DisableTwoFactor(ctx, 7, &models.TwoFactorDisableRequest{Password: "wrong-password", Code: recoveryCodes[0]})
+	assert.Equal(t, errors.NewForbidden("current password is incorrect", nil), err)
+	err = service.DisableTwoFactor(ctx, 7, &models.TwoFactorDisableRequest{Password: "secret-password", Code: "000000"})
+	assert.Equal(t, errors.NewForbidden("two-factor code is incorrect", nil), err)
+
+	newCodes, err := service.RegenerateRecoveryCodes(ctx, 7, recoveryCodes[0])
+	assert.NoError(t, err)
+	assert.Len(t, newCodes.Codes, 10)
+	// прежние коды больше не действуют
+	err = service.DisableTwoFactor(ctx, 7, &models.TwoFactorDisableRequest{Password: "secret-password", Code: recoveryCodes[1]})
+	assert.Equal(t, errors.NewForbidden("two-factor code is incorrect", nil), err)
+
+	assert.NoError(t, service.DisableTwoFactor(ctx, 7, &models.TwoFactorDisableRequest{Password: "secret-password", Code: newCodes.Codes[0]}))
+	status, err := service.GetTwoFactorStatus(ctx, 7)
+	assert.NoError(t, err)
+	assert.Equal(t, &models.TwoFactorStatus{}, status)
+
+	result, err := service.Login(ctx, &models.SignIn{Username: "john", Password: "secret-password"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+}
@@ -0,0 +1,139 @@
+// Package totp реализует одноразовые пароли по времени (RFC 6238) для двухфакторной аутентификации
+// и резервные коды, которыми можно войти без устройства с приложением-аутентификатором.
+package totp
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// Digits число цифр в коде
+	Digits = 6
+	// modulo 10^Digits
+	modulo = 1000000
+	// Period время действия одного кода
+	Period = 30 * time.Second
+	// Skew число соседних периодов, коды которых тоже принимаются, чтобы учесть расхождение часов
+	Skew = 1
+	// secretBytes длина секрета, рекомендованная RFC 4226
+	secretBytes = 20
+	// recoveryCodeLength число символов резервного кода без разделителя
+	recoveryCodeLength = 10
+	// recoveryAlphabet символы резервных кодов без похожих друг на друга 0/o и 1/l
+	recoveryAlphabet = "abcdefghijkmnpqrstuvwxyz23456789"
+)
+
+var encoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateSecret создает случайный секрет в кодировке base32, которую принимают приложения-аутентификаторы
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretBytes)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return encoding.EncodeToString(secret), nil
+}
+
+// URI возвращает otpauth-ссылку для добавления секрета в приложение, обычно в виде QR-кода
+func URI(issuer, account, secret string) string {
+	label := url.PathEscape(issuer + ":" + account)
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprint(Digits))
+	query.Set("period", fmt.Sprint(int(Period/time.Second)))
+	return "otpauth://totp/" + label + "?" + query.Encode()
+}
+
+// Step возвращает номер периода, к которому относится момент t
+func Step(t time.Time) int64 {
+	return t.Unix() / int64(Period/time.Second)
+}
+
+// Code возвращает код для секрета в момент t
+func Code(secret string, t time.Time) (string, error) {
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return "", err
+	}
+	return code(key, Step(t)), nil
+}
+
+// Validate проверяет код в момент t с учетом Skew и возвращает номер периода, которому он соответствует.
+// Коды периодов не позже lastStep не принимаются, чтобы один и тот же код нельзя было использовать повторно.
+func Validate(secret, value string, t time.Time, lastStep int64) (int64, bool) {
+	value = strings.ReplaceAll(value, " ", "")
+	if len(value) != Digits {
+		return 0, false
+	}
+	key, err := decodeSecret(secret)
+	if err != nil {
+		return 0, false
+	}
+	current := Step(t)
+	for step := current - Skew; step <= current+Skew; step++ {
+		if step <= lastStep {
+			continue
+		}
+		if hmac.Equal([]byte(code(key, step)), []byte(value)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// GenerateRecoveryCodes создает n резервных кодов вида xxxxx-xxxxx
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	buf := make([]byte, recoveryCodeLength)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, err
+		}
+		code := make([]byte, recoveryCodeLength)
+		for j, b := range buf {
+			// len(recoveryAlphabet) делит 256, поэтому распределение символов равномерное
+			code[j] = recoveryAlphabet[int(b)%len(recoveryAlphabet)]
+		}
+		codes[i] = string(code[:recoveryCodeLength/2]) + "-" + string(code[recoveryCodeLength/2:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode возвращает хэш резервного кода для хранения.
+// Регистр, пробелы и разделитель не учитываются.
+// Коды случайные и длинные, поэтому медленный хэш для них не нужен.
+func HashRecoveryCode(code string) string {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
+
+// decodeSecret декодирует секрет без учета регистра и пробелов
+func decodeSecret(secret string) ([]byte, error) {
+	secret = strings.ToUpper(strings.ReplaceAll(secret, " ", ""))
+	return encoding.DecodeString(strings.TrimRight(secret, "="))
+}
+
+// code вычисляет HOTP (RFC 4226) для номера периода
+func code(key []byte, step int64) string {
+	var counter [8]byte
+	binary.BigEndian.PutUint64(counter[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(counter[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", Digits, value%modulo)
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"context"
+	"github.com/ZnNr/user-task-reward-controller/internal/errors"
+	"github.com/ZnNr/user-task-reward-controller/internal/models"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/totp"
+	"go.uber.org/zap"
+	"strings"
+	"time"
+)
+
+// recoveryCodesCount число резервных кодов, выдаваемых пользователю
+const recoveryCodesCount = 10
+
+// GetTwoFactorStatus возвращает состояние двухфакторной аутентификации пользователя
+func (s *AuthService) GetTwoFactorStatus(ctx context.Context, userID int64) (*models.TwoFactorStatus, error) {
+	const op = "service.Auth.GetTwoFactorStatus"
+	logger := s.logger.With(zap.String("op", op))
+
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	return &models.TwoFactorStatus{Enabled: tf.Enabled, RecoveryCodesLeft: tf.RecoveryCodesLeft}, nil
+}
+
+// SetupTwoFactor создает новый секрет TOTP для пользователя.
+// Двухфакторная аутентификация включается только после подтверждения кодом в EnableTwoFactor.
+func (s *AuthService) SetupTwoFactor(ctx context.Context, userID int64) (*models.TwoFactorSetup, error) {
+	const op = "service.Auth.SetupTwoFactor"
+	logger := s.logger.With(zap.String("op", op))
+
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, errors.NewValidation("two-factor authentication is already enabled", nil)
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		logger.Error("cannot generate totp secret", zap.Error(err))
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	if err := s.repo.SetTOTPSecret(ctx, userID, secret); err != nil {
+		logger.Error("cannot save totp secret", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Two-factor setup started", zap.Int64("user_id", userID))
+	return &models.TwoFactorSetup{Secret: secret, URI: totp.URI(s.totpIssuer, user.Username, secret)}, nil
+}
+
+// EnableTwoFactor включает двухфакторную аутентификацию после проверки кода из приложения
+// и возвращает резервные коды. Коды показываются только один раз.
+func (s *AuthService) EnableTwoFactor(ctx context.Context, userID int64, code string) (*models.RecoveryCodes, error) {
+	const op = "service.Auth.EnableTwoFactor"
+	logger := s.logger.With(zap.String("op", op))
+
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.NewBadRequest("code is required", nil)
+	}
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if tf.Enabled {
+		return nil, errors.NewValidation("two-factor authentication is already enabled", nil)
+	}
+	if tf.Secret == "" {
+		return nil, errors.NewValidation("two-factor setup has not been started", nil)
+	}
+	step, ok := totp.Validate(tf.Secret, code, time.Now(), tf.LastStep)
+	if !ok {
+		logger.Info("invalid two-factor code", zap.Int64("user_id", userID))
+		return nil, errors.NewValidation("invalid two-factor code", nil)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		logger.Error("cannot generate recovery codes", zap.Error(err))
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	if err := s.repo.EnableTwoFactor(ctx, userID, step, hashes); err != nil {
+		logger.Error("cannot enable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Two-factor authentication enabled", zap.Int64("user_id", userID))
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+// DisableTwoFactor отключает двухфакторную аутентификацию.
+// Нужны текущий пароль и код из приложения или резервный код.
+func (s *AuthService) DisableTwoFactor(ctx context.Context, userID int64, req *models.TwoFactorDisableRequest) error {
+	const op = "service.Auth.DisableTwoFactor"
+	logger := s.logger.With(zap.String("op", op))
+
+	if req.Password == "" || strings.TrimSpace(req.Code) == "" {
+		return errors.NewBadRequest("password and code are required", nil)
+	}
+	user, err := s.repo.GetUserByID(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if !CheckPasswordHash(req.Password, user.Password) {
+		logger.Info("invalid current password", zap.Int64("user_id", userID))
+		return errors.NewForbidden("current password is incorrect", nil)
+	}
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	if !tf.Enabled {
+		return errors.NewValidation("two-factor authentication is not enabled", nil)
+	}
+	if ok, err := s.verifySecondFactor(ctx, userID, tf, req.Code); err != nil {
+		return err
+	} else if !ok {
+		return errors.NewForbidden("two-factor code is incorrect", nil)
+	}
+
+	if err := s.repo.DisableTwoFactor(ctx, userID); err != nil {
+		logger.Error("cannot disable two-factor authentication", zap.Int64("user_id", userID), zap.Error(err))
+		return err
+	}
+	logger.Info("Two-factor authentication disabled", zap.Int64("user_id", userID))
+	return nil
+}
+
+// RegenerateRecoveryCodes заменяет резервные коды пользователя новыми.
+// Прежние коды, в том числе неиспользованные, перестают действовать.
+func (s *AuthService) RegenerateRecoveryCodes(ctx context.Context, userID int64, code string) (*models.RecoveryCodes, error) {
+	const op = "service.Auth.RegenerateRecoveryCodes"
+	logger := s.logger.With(zap.String("op", op))
+
+	if strings.TrimSpace(code) == "" {
+		return nil, errors.NewBadRequest("code is required", nil)
+	}
+	tf, err := s.repo.GetTwoFactor(ctx, userID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+	if !tf.Enabled {
+		return nil, errors.NewValidation("two-factor authentication is not enabled", nil)
+	}
+	if ok, err := s.verifySecondFactor(ctx, userID, tf, code); err != nil {
+		return nil, err
+	} else if !ok {
+		return nil, errors.NewForbidden("two-factor code is incorrect", nil)
+	}
+
+	codes, hashes, err := newRecoveryCodes()
+	if err != nil {
+		logger.Error("cannot generate recovery codes", zap.Error(err))
+		return nil, errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+	if err := s.repo.ReplaceRecoveryCodes(ctx, userID, hashes); err != nil {
+		logger.Error("cannot save recovery codes", zap.Int64("user_id", userID), zap.Error(err))
+		return nil, err
+	}
+
+	logger.Info("Recovery codes regenerated", zap.Int64("user_id", userID))
+	return &models.RecoveryCodes{Codes: codes}, nil
+}
+
+// LoginTwoFactor завершает вход: обменивает токен второго шага и код на JWT.
+// Токен и код используются в одной транзакции, токен проверяется первым.
+// Неверный код учитывается как неудачная попытка входа; токен остается действительным до истечения срока.
+func (s *AuthService) LoginTwoFactor(ctx context.Context, req *models.TwoFactorLoginRequest) (string, error) {
+	const op = "service.Auth.LoginTwoFactor"
+	logger := s.logger.With(zap.String("op", op))
+
+	if strings.TrimSpace(req.Code) == "" {
+		return "", errors.NewBadRequest("code is required", nil)
+	}
+	claims, err := s.parseMailToken(models.TokenPurposeLoginChallenge, req.ChallengeToken)
+	if err != nil {
+		logger.Info("invalid login challenge", zap.Error(err))
+		return "", err
+	}
+
+	user, err := s.repo.GetUserByID(ctx, claims.UserID)
+	if err != nil {
+		logger.Error("cannot get user", zap.Int64("user_id", claims.UserID), zap.Error(err))
+		return "", err
+	}
+	login := &models.SignIn{Username: user.Username, IP: req.IP}
+	if err := s.checkLoginBlock(ctx, login); err != nil {
+		logger.Warn("login blocked", zap.Int64("user_id", user.ID), zap.String("ip", req.IP))
+		return "", err
+	}
+
+	tf, err := s.repo.GetTwoFactor(ctx, user.ID)
+	if err != nil {
+		logger.Error("cannot get two-factor state", zap.Int64("user_id", user.ID), zap.Error(err))
+		return "", err
+	}
+	if !tf.Enabled {
+		// Двухфакторную аутентификацию отключили после выдачи токена, вход нужно начать заново
+		return "", errors.NewValidation("invalid token", nil)
+	}
+	factor, ok := parseSecondFactor(tf, req.Code)
+	if ok {
+		ok, err = s.repo.UseLoginChallenge(ctx, claims.TokenID, user.ID, factor)
+		if errors.IsNotFound(err) {
+			return "", errors.NewValidation("invalid token", err)
+		} else if err != nil {
+			logger.Info("cannot use login challenge", zap.Int64("user_id", user.ID), zap.Error(err))
+			return "", err
+		}
+	}
+	if !ok {
+		logger.Info("invalid two-factor code", zap.Int64("user_id", user.ID))
+		s.recordLoginFailure(ctx, login)
+		return "", errors.NewUnauthorized("invalid two-factor code", nil)
+	}
+	if factor.RecoveryCodeHash != "" {
+		logger.Info("Recovery code used", zap.Int64("user_id", user.ID), zap.Int("recovery_codes_left", tf.RecoveryCodesLeft-1))
+	}
+	s.resetLoginFailures(ctx, login)
+
+	token, err := s.generateToken(*user)
+	if err != nil {
+		logger.Error("cannot generate token", zap.Int64("user_id", user.ID), zap.Error(err))
+		return "", errors.NewInternal(errors.ErrorMessage[errors.Internal], err)
+	}
+
+	logger.Info("User logged in successfully with two-factor code", zap.Int64("user_id", user.ID))
+	return token, nil
+}
+
+// verifySecondFactor проверяет код из приложения или резервный код и отмечает его использованным.
+// Неверный код не считается ошибкой: возвращается false.
+func (s *AuthService) verifySecondFactor(ctx context.Context, userID int64, tf models.TwoFactor, code string) (bool, error) {
+	factor, ok := parseSecondFactor(tf, code)
+	if !ok {
+		return false, nil
+	}
+	if factor.RecoveryCodeHash == "" {
+		err := s.repo.UseTOTPStep(ctx, userID, factor.Step)
+		if errors.IsValidation(err) {
+			// Тот же код одновременно принят в другом запросе
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := s.repo.UseRecoveryCode(ctx, userID, factor.RecoveryCodeHash)
+	if errors.IsNotFound(err) {
+		return false, nil
+	} else if err != nil {
+		s.logger.Error("cannot use recovery code", zap.Int64("user_id", userID), zap.Error(err))
+		return false, err
+	}
+	s.logger.Info("Recovery code used", zap.Int64("user_id", userID), zap.Int("recovery_codes_left", tf.RecoveryCodesLeft-1))
+	return true, nil
+}
+
+// parseSecondFactor определяет, введен код из приложения или резервный код.
+// false означает, что код заведомо неверный и проверять его в базе не нужно.
+func parseSecondFactor(tf models.TwoFactor, code string) (models.SecondFactor, bool) {
+	code = strings.TrimSpace(code)
+	if step, ok := totp.Validate(tf.Secret, code, time.Now(), tf.LastStep); ok {
+		return models.SecondFactor{Step: step}, true
+	}
+	if len(strings.ReplaceAll(code, " ", "")) == totp.Digits {
+		return models.SecondFactor{}, false
+	}
+	return models.SecondFactor{RecoveryCodeHash: totp.HashRecoveryCode(code)}, true
+}
+
+// newRecoveryCodes создает резервные коды и их хэши для хранения
+func newRecoveryCodes() ([]string, []string, error) {
+	codes, err := totp.GenerateRecoveryCodes(recoveryCodesCount)
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = totp.HashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
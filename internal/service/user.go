@@ -5,20 +5,32 @@ import (
 	"github.com/ZnNr/user-task-reward-controller/internal/errors"
 	"github.com/ZnNr/user-task-reward-controller/internal/models"
 	"github.com/ZnNr/user-task-reward-controller/internal/repository"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/level"
+	"github.com/ZnNr/user-task-reward-controller/internal/service/streak"
 	"go.uber.org/zap"
 )
 
+// upcomingExpirationsLimit количество ближайших сгораний баллов в информации о пользователе
+const upcomingExpirationsLimit = 5
+
 // UserService представляет собой службу управления пользователями
 type UserService struct {
-	repo   repository.UserRepository
-	logger *zap.Logger
+	repo         repository.UserRepository
+	logger       *zap.Logger
+	achievements achievementEvaluator
+	streakRules  models.StreakRules
+	levels       models.LevelCurve
 }
 
 // NewUserService создает новый экземпляр UserService
-func NewUserService(repo repository.UserRepository, logger *zap.Logger) *UserService {
+func NewUserService(repo repository.UserRepository, logger *zap.Logger, achievements achievementEvaluator,
+	streakRules models.StreakRules, levels models.LevelCurve) *UserService {
 	return &UserService{
-		repo:   repo,
-		logger: logger,
+		repo:         repo,
+		logger:       logger,
+		achievements: achievements,
+		streakRules:  streakRules,
+		levels:       levels,
 	}
 }
 
@@ -33,25 +45,58 @@ func (u *UserService) GetUserInfo(ctx context.Context, userId int64) (models.Use
 		logger.Error("Failed to fetch user info", zap.Error(err))
 		return models.User{}, err
 	}
+	user.Level = level.For(user.LifetimePoints, u.levels)
+	user.NextLevelAt = level.NextAt(user.LifetimePoints, u.levels)
+	user.Balances, err = u.repo.GetUserBalances(ctx, userId)
+	if err != nil {
+		logger.Error("Failed to fetch user balances", zap.Error(err))
+		return models.User{}, err
+	}
+	userStreak, today, err := u.repo.GetUserStreak(ctx, userId)
+	if err != nil {
+		logger.Error("Failed to fetch user streak", zap.Error(err))
+		return models.User{}, err
+	}
+	userStreak = streak.Current(userStreak, today, u.streakRules)
+	user.Streak = &userStreak
+	user.UpcomingExpirations, err = u.repo.GetUpcomingExpirations(ctx, userId, upcomingExpirationsLimit)
+	if err != nil {
+		logger.Error("Failed to fetch upcoming expirations", zap.Error(err))
+		return models.User{}, err
+	}
 	logger.Info("User info fetched successfully", zap.Int64("user_id", userId))
 	return user, nil
 }
 
-// GetUsersLeaderboard возвращает список пользователей, отсортированный по балансу
-func (u *UserService) GetUsersLeaderboard(ctx context.Context) ([]models.User, error) {
+// GetUsersLeaderboard возвращает список пользователей, отсортированный по балансу в указанной валюте.
+// Пустая валюта означает coins.
+func (u *UserService) GetUsersLeaderboard(ctx context.Context, currency string) ([]models.User, error) {
 	const op = "service.User.GetUsersLeaderboard"
 	logger := u.logger.With(zap.String("op", op))
 
-	logger.Debug("Fetching users leaderboard")
-	users, err := u.repo.GetUsersLeaderboard(ctx)
+	currency = leaderboardCurrency(currency)
+
+	logger.Debug("Fetching users leaderboard", zap.String("currency", currency))
+	users, err := u.repo.GetUsersLeaderboard(ctx, currency)
 	if err != nil {
 		logger.Error("Failed to fetch leaderboard", zap.Error(err))
 		return nil, err
 	}
+	for i := range users {
+		users[i].Level = level.For(users[i].LifetimePoints, u.levels)
+	}
 	logger.Info("Leaderboard fetched successfully", zap.Int("users_count", len(users)))
 	return users, nil
 }
 
+// leaderboardCurrency возвращает валюту таблицы лидеров; пустая валюта означает coins.
+func leaderboardCurrency(currency string) string {
+	if currency == "" {
+		return models.CurrencyCoins
+	}
+	return currency
+}
+
 // GetUserID возвращает ID пользователя по имени пользователя или email
 func (u *UserService) GetUserID(ctx context.Context, usernameOrEmail string) (int64, error) {
 	const op = "service.User.GetUserID"
@@ -77,11 +122,26 @@ func (u *UserService) ReferrerCode(ctx context.Context, userId int64, refCode st
 	logger := u.logger.With(zap.String("op", op))
 
 	logger.Debug("Saving referrer code", zap.Int64("user_id", userId), zap.String("ref_code", refCode))
-	err := u.repo.ReferrerCode(ctx, userId, refCode)
+	referrerID, err := u.repo.ReferrerCode(ctx, userId, refCode)
 	if err != nil {
 		logger.Error("Failed to save referrer code", zap.Error(err))
 		return err
 	}
 	logger.Info("Referrer code saved successfully", zap.Int64("user_id", userId), zap.String("ref_code", refCode))
+	// Новый приглашенный может открыть достижение пригласившему
+	evaluateAchievements(ctx, u.achievements, referrerID, logger)
 	return nil
 }
+
+// IsAdmin проверяет, является ли пользователь администратором
+func (u *UserService) IsAdmin(ctx context.Context, userId int64) (bool, error) {
+	const op = "service.User.IsAdmin"
+	logger := u.logger.With(zap.String("op", op))
+
+	isAdmin, err := u.repo.IsAdmin(ctx, userId)
+	if err != nil {
+		logger.Error("Failed to check admin role", zap.Int64("user_id", userId), zap.Error(err))
+		return false, err
+	}
+	return isAdmin, nil
+}
@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS referral_click_id;
+
+DROP TABLE IF EXISTS referral_clicks;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS referral_clicks
+(
+    click_id SERIAL PRIMARY KEY,
+    refer_code VARCHAR(255) not null,
+    user_agent TEXT DEFAULT null,
+    referrer TEXT DEFAULT null,
+    clicked_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS referral_clicks_refer_code_idx ON referral_clicks (refer_code);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS referral_click_id INT references referral_clicks (click_id) on delete set null;
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS redemptions;
+
+DROP TABLE IF EXISTS rewards;
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_admin;
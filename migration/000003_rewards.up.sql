@@ -0,0 +1,27 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_admin BOOLEAN not null DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS rewards
+(
+    reward_id SERIAL PRIMARY KEY,
+    title VARCHAR(255) not null,
+    description VARCHAR(255) DEFAULT null,
+    cost INT not null CHECK (cost > 0),
+    stock INT DEFAULT null CHECK (stock >= 0),
+    per_user_limit INT DEFAULT null CHECK (per_user_limit > 0),
+    active BOOLEAN not null DEFAULT true,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS redemptions
+(
+    redemption_id SERIAL PRIMARY KEY,
+    reward_id int references rewards (reward_id) on delete restrict not null,
+    user_id int references users (user_id) on delete cascade not null,
+    cost INT not null,
+    status VARCHAR(32) not null DEFAULT 'pending',
+    created_at TIMESTAMP not null DEFAULT now(),
+    updated_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS redemptions_user_id_idx ON redemptions (user_id);
+CREATE INDEX IF NOT EXISTS redemptions_status_idx ON redemptions (status);
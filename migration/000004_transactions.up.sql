@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS transactions
+(
+    transaction_id SERIAL PRIMARY KEY,
+    user_id int references users (user_id) on delete cascade not null,
+    amount INT not null,
+    balance_after INT not null,
+    kind VARCHAR(32) not null,
+    counterparty_id int references users (user_id) on delete set null,
+    reference VARCHAR(255) DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS transactions_user_id_idx ON transactions (user_id, created_at);
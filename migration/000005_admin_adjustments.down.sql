@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS created_by;
+ALTER TABLE transactions DROP COLUMN IF EXISTS reason;
@@ -0,0 +1,2 @@
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS reason VARCHAR(64) DEFAULT null;
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS created_by int references users (user_id) on delete set null;
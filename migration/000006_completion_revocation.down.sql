@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS transactions_reference_idx;
+
+ALTER TABLE task_complete DROP COLUMN IF EXISTS revoke_reason;
+ALTER TABLE task_complete DROP COLUMN IF EXISTS revoked_by;
+ALTER TABLE task_complete DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE task_complete DROP COLUMN IF EXISTS completed_at;
@@ -0,0 +1,6 @@
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS completed_at TIMESTAMP not null DEFAULT now();
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMP DEFAULT null;
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS revoked_by int references users (user_id) on delete set null;
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS revoke_reason VARCHAR(255) DEFAULT null;
+
+CREATE INDEX IF NOT EXISTS transactions_reference_idx ON transactions (reference);
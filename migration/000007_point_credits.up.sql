@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS point_credits
+(
+    credit_id SERIAL PRIMARY KEY,
+    user_id int references users (user_id) on delete cascade not null,
+    transaction_id int references transactions (transaction_id) on delete set null,
+    amount INT not null,
+    remaining INT not null CHECK (remaining >= 0),
+    expires_at TIMESTAMP DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS point_credits_open_idx ON point_credits (user_id, created_at) WHERE remaining > 0;
+CREATE INDEX IF NOT EXISTS point_credits_expires_at_idx ON point_credits (expires_at) WHERE remaining > 0;
+
+-- Баллы, накопленные до появления срока действия, переносятся одной бессрочной записью
+INSERT INTO point_credits (user_id, amount, remaining)
+SELECT user_id, balance, balance FROM users WHERE balance > 0;
@@ -0,0 +1,7 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS currency;
+
+DROP TABLE IF EXISTS task_prices;
+
+DROP TABLE IF EXISTS user_balances;
+
+DROP TABLE IF EXISTS currencies;
@@ -0,0 +1,41 @@
+CREATE TABLE IF NOT EXISTS currencies
+(
+    code VARCHAR(32) PRIMARY KEY,
+    title VARCHAR(255) not null,
+    spendable BOOLEAN not null DEFAULT false
+);
+
+INSERT INTO currencies (code, title, spendable) VALUES
+    ('coins', 'Coins', true),
+    ('xp', 'Experience', false)
+ON CONFLICT (code) DO NOTHING;
+
+-- Балансы по валютам; баланс в coins дублирует users.balance
+CREATE TABLE IF NOT EXISTS user_balances
+(
+    user_id int references users (user_id) on delete cascade not null,
+    currency VARCHAR(32) references currencies (code) not null,
+    balance INT not null DEFAULT 0,
+    PRIMARY KEY (user_id, currency)
+);
+
+CREATE INDEX IF NOT EXISTS user_balances_leaderboard_idx ON user_balances (currency, balance DESC);
+
+INSERT INTO user_balances (user_id, currency, balance)
+SELECT user_id, 'coins', balance FROM users
+ON CONFLICT (user_id, currency) DO NOTHING;
+
+-- Стоимость задания по валютам; tasks.price дублирует стоимость в coins
+CREATE TABLE IF NOT EXISTS task_prices
+(
+    task_id int references tasks (task_id) on delete cascade not null,
+    currency VARCHAR(32) references currencies (code) not null,
+    amount INT not null CHECK (amount > 0),
+    PRIMARY KEY (task_id, currency)
+);
+
+INSERT INTO task_prices (task_id, currency, amount)
+SELECT task_id, 'coins', price FROM tasks WHERE price > 0
+ON CONFLICT (task_id, currency) DO NOTHING;
+
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS currency VARCHAR(32) not null DEFAULT 'coins' references currencies (code);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS user_achievements;
+
+DROP TABLE IF EXISTS achievements;
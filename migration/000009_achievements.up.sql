@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS achievements
+(
+    achievement_id SERIAL PRIMARY KEY,
+    code VARCHAR(64) not null unique,
+    title VARCHAR(255) not null,
+    description VARCHAR(255) DEFAULT null,
+    -- Условие выдачи: значение метрики пользователя достигло порога
+    metric VARCHAR(32) not null,
+    threshold INT not null CHECK (threshold > 0),
+    bonus INT not null DEFAULT 0 CHECK (bonus >= 0),
+    bonus_currency VARCHAR(32) not null DEFAULT 'coins' references currencies (code),
+    active BOOLEAN not null DEFAULT true,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_achievements
+(
+    user_id int references users (user_id) on delete cascade not null,
+    achievement_id int references achievements (achievement_id) on delete cascade not null,
+    awarded_at TIMESTAMP not null DEFAULT now(),
+    PRIMARY KEY (user_id, achievement_id)
+);
+
+INSERT INTO achievements (code, title, description, metric, threshold, bonus) VALUES
+    ('first_task', 'First task', 'Complete your first task', 'completed_tasks', 1, 0),
+    ('ten_tasks', '10 tasks', 'Complete 10 tasks', 'completed_tasks', 10, 10),
+    ('first_referral', 'First referral', 'Invite your first friend', 'referrals', 1, 0),
+    ('ten_referrals', '10 referrals', 'Invite 10 friends', 'referrals', 10, 50)
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,3 @@
+DELETE FROM achievements WHERE code = 'week_streak';
+
+DROP TABLE IF EXISTS user_streaks;
@@ -0,0 +1,28 @@
+CREATE TABLE IF NOT EXISTS user_streaks
+(
+    user_id int PRIMARY KEY references users (user_id) on delete cascade,
+    current_streak INT not null DEFAULT 0,
+    longest_streak INT not null DEFAULT 0,
+    last_active_day DATE DEFAULT null,
+    freezes_used INT not null DEFAULT 0 CHECK (freezes_used >= 0),
+    updated_at TIMESTAMP not null DEFAULT now()
+);
+
+-- Серии по уже выполненным заданиям: дни подряд группируются по разнице между датой и номером дня
+WITH days AS (
+    SELECT DISTINCT user_id, completed_at::date AS day
+    FROM task_complete WHERE revoked_at IS NULL
+), runs AS (
+    SELECT user_id, COUNT(*) AS length, MAX(day) AS last_day
+    FROM (SELECT user_id, day, day - (ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY day))::int AS run
+          FROM days) numbered
+    GROUP BY user_id, run
+)
+INSERT INTO user_streaks (user_id, current_streak, longest_streak, last_active_day)
+SELECT user_id, (array_agg(length ORDER BY last_day DESC))[1], MAX(length), MAX(last_day)
+FROM runs GROUP BY user_id
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO achievements (code, title, description, metric, threshold, bonus) VALUES
+    ('week_streak', '7-day streak', 'Complete tasks 7 days in a row', 'longest_streak', 7, 20)
+ON CONFLICT (code) DO NOTHING;
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS level_ups;
+
+DROP INDEX IF EXISTS users_lifetime_points_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS level_reached;
+
+ALTER TABLE users DROP COLUMN IF EXISTS lifetime_points;
@@ -0,0 +1,23 @@
+-- Баллы, заработанные за все время: не уменьшаются при тратах и переводах
+ALTER TABLE users ADD COLUMN IF NOT EXISTS lifetime_points INT not null DEFAULT 0;
+-- Наибольший уровень, за который выдан бонус. У существующих пользователей NULL:
+-- уровень будет зафиксирован без бонусов при следующем начислении. Новые пользователи начинают с первого.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS level_reached INT DEFAULT null;
+ALTER TABLE users ALTER COLUMN level_reached SET DEFAULT 1;
+
+UPDATE users u SET lifetime_points = GREATEST(t.earned, 0)
+FROM (SELECT user_id, SUM(amount) AS earned FROM transactions
+      WHERE currency = 'coins' AND kind IN ('task_reward', 'referral_bonus', 'achievement_bonus', 'reversal')
+      GROUP BY user_id) t
+WHERE t.user_id = u.user_id;
+
+CREATE INDEX IF NOT EXISTS users_lifetime_points_idx ON users (lifetime_points DESC);
+
+CREATE TABLE IF NOT EXISTS level_ups
+(
+    user_id int references users (user_id) on delete cascade not null,
+    level INT not null,
+    bonus INT not null DEFAULT 0,
+    reached_at TIMESTAMP not null DEFAULT now(),
+    PRIMARY KEY (user_id, level)
+);
@@ -0,0 +1,7 @@
+ALTER TABLE task_complete DROP COLUMN IF EXISTS streak_bonus_percent;
+ALTER TABLE task_complete DROP COLUMN IF EXISTS boost_id;
+ALTER TABLE task_complete DROP COLUMN IF EXISTS multiplier_percent;
+
+DROP TABLE IF EXISTS boosts;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS task_type;
@@ -0,0 +1,23 @@
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS task_type VARCHAR(32) not null DEFAULT 'general';
+
+-- Временные множители наград. Пустые task_type и min_level означают, что ограничения нет.
+CREATE TABLE IF NOT EXISTS boosts
+(
+    boost_id SERIAL PRIMARY KEY,
+    title VARCHAR(255) not null,
+    multiplier_percent INT not null CHECK (multiplier_percent > 100),
+    task_type VARCHAR(32) DEFAULT null,
+    min_level INT DEFAULT null CHECK (min_level > 0),
+    starts_at TIMESTAMP not null,
+    ends_at TIMESTAMP not null,
+    created_by int references users (user_id) on delete set null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+CREATE INDEX IF NOT EXISTS boosts_period_idx ON boosts (starts_at, ends_at);
+
+-- Как была рассчитана награда за выполнение
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS multiplier_percent INT not null DEFAULT 100;
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS boost_id int references boosts (boost_id) on delete set null;
+ALTER TABLE task_complete ADD COLUMN IF NOT EXISTS streak_bonus_percent INT not null DEFAULT 0;
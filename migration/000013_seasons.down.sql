@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS season_standings;
+
+DROP TABLE IF EXISTS season_scores;
+
+DROP TABLE IF EXISTS seasons;
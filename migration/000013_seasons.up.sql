@@ -0,0 +1,37 @@
+CREATE TABLE IF NOT EXISTS seasons
+(
+    season_id SERIAL PRIMARY KEY,
+    title VARCHAR(255) not null,
+    status VARCHAR(16) not null DEFAULT 'open',
+    starts_at TIMESTAMP not null DEFAULT now(),
+    ends_at TIMESTAMP DEFAULT null,
+    closed_at TIMESTAMP DEFAULT null,
+    closed_by int references users (user_id) on delete set null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+-- Открытым может быть только один сезон
+CREATE UNIQUE INDEX IF NOT EXISTS seasons_single_open_idx ON seasons (status) WHERE status = 'open';
+
+-- Очки сезона начисляются вместе с заработанными баллами
+CREATE TABLE IF NOT EXISTS season_scores
+(
+    season_id int references seasons (season_id) on delete cascade not null,
+    user_id int references users (user_id) on delete cascade not null,
+    score INT not null DEFAULT 0,
+    PRIMARY KEY (season_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS season_scores_ranking_idx ON season_scores (season_id, score DESC);
+
+-- Итоговые места, зафиксированные при закрытии сезона
+CREATE TABLE IF NOT EXISTS season_standings
+(
+    season_id int references seasons (season_id) on delete cascade not null,
+    rank INT not null,
+    user_id int references users (user_id) on delete set null,
+    username VARCHAR(255) not null,
+    score INT not null,
+    prize INT not null DEFAULT 0,
+    PRIMARY KEY (season_id, rank)
+);
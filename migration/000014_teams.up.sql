@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS teams
+(
+    team_id SERIAL PRIMARY KEY,
+    name VARCHAR(255) not null unique,
+    invite_code VARCHAR(255) not null unique,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+-- Пользователь может состоять только в одной команде
+CREATE TABLE IF NOT EXISTS team_members
+(
+    user_id int references users (user_id) on delete cascade PRIMARY KEY,
+    team_id int references teams (team_id) on delete cascade not null,
+    role VARCHAR(16) not null DEFAULT 'member',
+    joined_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS team_members_team_id_idx ON team_members (team_id);
+
+-- У команды ровно один капитан
+CREATE UNIQUE INDEX IF NOT EXISTS team_members_single_captain_idx ON team_members (team_id) WHERE role = 'captain';
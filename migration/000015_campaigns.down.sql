@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS tasks_campaign_id_idx;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS campaign_id;
+
+DROP TABLE IF EXISTS campaigns;
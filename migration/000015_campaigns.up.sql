@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS campaigns
+(
+    campaign_id SERIAL PRIMARY KEY,
+    title VARCHAR(255) not null,
+    description VARCHAR(255) DEFAULT null,
+    budget INT not null CHECK (budget > 0),
+    -- Баллы в coins, выплаченные за задания кампании: награды исполнителям и реферальные бонусы
+    spent INT not null DEFAULT 0 CHECK (spent >= 0),
+    status VARCHAR(16) not null DEFAULT 'draft',
+    starts_at TIMESTAMP not null,
+    ends_at TIMESTAMP not null,
+    created_by int references users (user_id) on delete set null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    CHECK (ends_at > starts_at)
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS campaign_id int references campaigns (campaign_id) on delete set null;
+
+CREATE INDEX IF NOT EXISTS tasks_campaign_id_idx ON tasks (campaign_id);
@@ -0,0 +1,13 @@
+DROP INDEX IF EXISTS task_complete_task_id_idx;
+
+DROP INDEX IF EXISTS tasks_search_vector_idx;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS search_vector;
+
+DROP TABLE IF EXISTS task_tags;
+
+DROP INDEX IF EXISTS tasks_category_id_idx;
+
+ALTER TABLE tasks DROP COLUMN IF EXISTS category_id;
+
+DROP TABLE IF EXISTS categories;
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS categories
+(
+    category_id SERIAL PRIMARY KEY,
+    code VARCHAR(64) not null unique,
+    title VARCHAR(255) not null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS category_id int references categories (category_id) on delete set null;
+
+CREATE INDEX IF NOT EXISTS tasks_category_id_idx ON tasks (category_id);
+
+-- Произвольные метки задания, хранятся в нижнем регистре
+CREATE TABLE IF NOT EXISTS task_tags
+(
+    task_id int references tasks (task_id) on delete cascade not null,
+    tag VARCHAR(32) not null,
+    PRIMARY KEY (task_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS task_tags_tag_idx ON task_tags (tag);
+
+-- Полнотекстовый поиск по названию и описанию. Конфигурация simple не зависит от языка текста.
+ALTER TABLE tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(description, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS tasks_search_vector_idx ON tasks USING GIN (search_vector);
+
+-- Популярность задания считается по выполнениям
+CREATE INDEX IF NOT EXISTS task_complete_task_id_idx ON task_complete (task_id);
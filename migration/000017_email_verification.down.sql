@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS auth_tokens_user_id_idx;
+
+DROP TABLE IF EXISTS auth_tokens;
+
+DROP INDEX IF EXISTS users_email_lower_idx;
+
+ALTER TABLE users DROP COLUMN IF EXISTS email_verified_at;
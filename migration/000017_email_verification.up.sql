@@ -0,0 +1,20 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified_at TIMESTAMP DEFAULT null;
+
+-- Адреса хранятся в нижнем регистре, индекс защищает от дублей, записанных в обход нормализации
+CREATE UNIQUE INDEX IF NOT EXISTS users_email_lower_idx ON users (lower(email));
+
+-- Одноразовые токены, отправляемые пользователю по почте.
+-- Сам токен подписан и не хранится, по token_id проверяется, что он еще не использован.
+CREATE TABLE IF NOT EXISTS auth_tokens
+(
+    token_id VARCHAR(64) PRIMARY KEY,
+    user_id int not null references users (user_id) on delete cascade,
+    purpose VARCHAR(32) not null,
+    -- Адрес, на который отправлен токен подтверждения; после смены адреса токен недействителен
+    email VARCHAR(255) DEFAULT null,
+    expires_at TIMESTAMP not null,
+    used_at TIMESTAMP DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS auth_tokens_user_id_idx ON auth_tokens (user_id, purpose);
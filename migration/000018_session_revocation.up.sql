@@ -0,0 +1,2 @@
+-- Версия сессий пользователя: входит в JWT, увеличение отзывает все выданные токены
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INT not null DEFAULT 0;
@@ -0,0 +1,11 @@
+-- Неудачные попытки входа по имени пользователя и по IP-адресу.
+-- Счетчик сбрасывается после успешного входа или после периода без неудачных попыток.
+CREATE TABLE IF NOT EXISTS login_attempts
+(
+    scope VARCHAR(16) not null,
+    key VARCHAR(255) not null,
+    failures INT not null DEFAULT 0,
+    last_failure_at TIMESTAMP not null DEFAULT now(),
+    locked_until TIMESTAMP DEFAULT null,
+    PRIMARY KEY (scope, key)
+);
@@ -0,0 +1,16 @@
+-- Двухфакторная аутентификация по TOTP.
+-- totp_secret сохраняется при начале подключения, а действует только после подтверждения кодом (totp_enabled_at).
+-- totp_last_step номер периода последнего принятого кода, чтобы код нельзя было использовать повторно.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_secret VARCHAR(64) DEFAULT null;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_enabled_at TIMESTAMP DEFAULT null;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS totp_last_step BIGINT not null DEFAULT 0;
+
+-- Резервные коды для входа без приложения-аутентификатора, хранятся только хэши
+CREATE TABLE IF NOT EXISTS recovery_codes
+(
+    user_id int not null references users (user_id) on delete cascade,
+    code_hash CHAR(64) not null,
+    used_at TIMESTAMP DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    PRIMARY KEY (user_id, code_hash)
+);
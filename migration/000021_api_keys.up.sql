@@ -0,0 +1,14 @@
+-- Ключи для интеграций, которые обращаются к API без входа пользователя.
+-- Хранится только SHA-256 ключа; prefix - начало ключа, по которому администратор его узнает.
+CREATE TABLE IF NOT EXISTS api_keys
+(
+    api_key_id SERIAL PRIMARY KEY,
+    name VARCHAR(100) not null,
+    prefix VARCHAR(16) not null,
+    key_hash CHAR(64) not null UNIQUE,
+    scopes TEXT[] not null,
+    created_by int references users (user_id) on delete set null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    last_used_at TIMESTAMP DEFAULT null,
+    revoked_at TIMESTAMP DEFAULT null
+);
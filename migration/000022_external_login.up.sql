@@ -0,0 +1,24 @@
+-- Учетные записи внешних провайдеров входа, привязанные к пользователям.
+-- subject - постоянный идентификатор пользователя у провайдера.
+CREATE TABLE IF NOT EXISTS user_identities
+(
+    provider VARCHAR(32) not null,
+    subject VARCHAR(255) not null,
+    user_id int not null references users (user_id) on delete cascade,
+    email VARCHAR(255) DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    PRIMARY KEY (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+
+-- Незавершенные входы через OpenID Connect: state из ссылки провайдера, nonce ID-токена и code_verifier PKCE.
+-- Запись удаляется при возврате от провайдера, поэтому ответ нельзя использовать повторно.
+CREATE TABLE IF NOT EXISTS oidc_login_states
+(
+    state VARCHAR(64) PRIMARY KEY,
+    nonce VARCHAR(64) not null,
+    code_verifier VARCHAR(128) not null,
+    expires_at TIMESTAMP not null,
+    created_at TIMESTAMP not null DEFAULT now()
+);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS user_identities_user_id_idx ON user_identities (user_id);
+DROP INDEX IF EXISTS user_identities_user_provider_idx;
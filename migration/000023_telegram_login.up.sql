@@ -0,0 +1,4 @@
+-- К пользователю привязывается не больше одной учетной записи каждого провайдера:
+-- интеграции по привязанному аккаунту Telegram проверяют выполнение заданий
+CREATE UNIQUE INDEX IF NOT EXISTS user_identities_user_provider_idx ON user_identities (user_id, provider);
+DROP INDEX IF EXISTS user_identities_user_id_idx;
@@ -0,0 +1,6 @@
+-- Данные профиля, которые пользователь меняет сам
+ALTER TABLE users ADD COLUMN IF NOT EXISTS display_name VARCHAR(100) DEFAULT null;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS avatar_url VARCHAR(2048) DEFAULT null;
+-- Время удаления аккаунта: персональные данные стерты, строка пользователя
+-- и история начислений сохраняются, чтобы не нарушить учет у пригласивших
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP DEFAULT null;
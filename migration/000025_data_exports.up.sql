@@ -0,0 +1,18 @@
+-- Выгрузки персональных данных, которые готовятся в фоне для больших аккаунтов.
+-- Готовый архив хранится в базе до expires_at и отдается только владельцу.
+CREATE TABLE IF NOT EXISTS data_exports
+(
+    export_id SERIAL PRIMARY KEY,
+    user_id int not null references users (user_id) on delete cascade,
+    format VARCHAR(8) not null,
+    status VARCHAR(16) not null DEFAULT 'pending',
+    content BYTEA DEFAULT null,
+    error TEXT DEFAULT null,
+    created_at TIMESTAMP not null DEFAULT now(),
+    started_at TIMESTAMP DEFAULT null,
+    completed_at TIMESTAMP DEFAULT null,
+    expires_at TIMESTAMP DEFAULT null
+);
+
+CREATE INDEX IF NOT EXISTS data_exports_user_id_idx ON data_exports (user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS data_exports_queue_idx ON data_exports (created_at) WHERE status IN ('pending', 'processing');
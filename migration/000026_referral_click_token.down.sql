@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS referral_clicks_token_idx;
+ALTER TABLE referral_clicks DROP COLUMN IF EXISTS token;
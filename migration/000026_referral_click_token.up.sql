@@ -0,0 +1,4 @@
+-- Случайный идентификатор перехода для cookie атрибуции: по последовательному click_id
+-- регистрацию можно было бы приписать чужому переходу
+ALTER TABLE referral_clicks ADD COLUMN IF NOT EXISTS token VARCHAR(64) DEFAULT null;
+CREATE UNIQUE INDEX IF NOT EXISTS referral_clicks_token_idx ON referral_clicks (token);
@@ -0,0 +1,2 @@
+ALTER TABLE boosts ALTER COLUMN starts_at TYPE TIMESTAMP USING starts_at AT TIME ZONE 'UTC';
+ALTER TABLE boosts ALTER COLUMN ends_at TYPE TIMESTAMP USING ends_at AT TIME ZONE 'UTC';
@@ -0,0 +1,4 @@
+-- Окна бустов хранятся с часовым поясом: значения TIMESTAMP сравнивались с now()
+-- в часовом поясе сессии, и буст включался или выключался со сдвигом
+ALTER TABLE boosts ALTER COLUMN starts_at TYPE TIMESTAMPTZ USING starts_at AT TIME ZONE 'UTC';
+ALTER TABLE boosts ALTER COLUMN ends_at TYPE TIMESTAMPTZ USING ends_at AT TIME ZONE 'UTC';
@@ -0,0 +1,2 @@
+ALTER TABLE oidc_login_states ALTER COLUMN expires_at TYPE TIMESTAMP;
+ALTER TABLE auth_tokens ALTER COLUMN expires_at TYPE TIMESTAMP;
@@ -0,0 +1,4 @@
+-- Срок действия одноразовых токенов и состояний входа сравнивается с now() в базе,
+-- поэтому хранится с часовым поясом
+ALTER TABLE auth_tokens ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
+ALTER TABLE oidc_login_states ALTER COLUMN expires_at TYPE TIMESTAMPTZ;
@@ -0,0 +1,10 @@
+-- Какие начисления погасило списание. По ним возврат списания получает исходный срок действия баллов,
+-- иначе отмена обмена продлевала бы срок действия
+CREATE TABLE IF NOT EXISTS credit_consumptions
+(
+    transaction_id int references transactions (transaction_id) on delete cascade not null,
+    credit_id int references point_credits (credit_id) on delete cascade not null,
+    amount INT not null CHECK (amount > 0)
+);
+
+CREATE INDEX IF NOT EXISTS credit_consumptions_transaction_idx ON credit_consumptions (transaction_id);
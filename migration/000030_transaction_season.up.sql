@@ -0,0 +1,7 @@
+-- Сезон, в очки которого вошла операция. Отмена начисления уменьшает очки того же сезона, а не текущего
+ALTER TABLE transactions ADD COLUMN IF NOT EXISTS season_id int references seasons (season_id) on delete set null;
+
+-- Операции, сделанные до появления колонки, относятся к сезону, открытому в момент операции
+UPDATE transactions t SET season_id = s.season_id FROM seasons s
+WHERE t.currency = 'coins' AND t.kind IN ('task_reward', 'referral_bonus', 'achievement_bonus', 'reversal')
+    AND t.created_at >= s.starts_at AND (s.closed_at IS NULL OR t.created_at < s.closed_at);